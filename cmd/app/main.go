@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"github.com/AlecSmith96/dopbox/pkg/adapters"
 	"github.com/AlecSmith96/dopbox/pkg/entities"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"time"
+	"syscall"
 )
 
 func main() {
@@ -35,8 +37,50 @@ func main() {
 		os.Exit(1)
 	}
 
+	if conf.ConflictPolicy != "" && !entities.IsConflictPolicy(conf.ConflictPolicy) {
+		slog.Error("unknown conflict policy", "policy", conf.ConflictPolicy)
+		os.Exit(1)
+	}
+
+	if !entities.IsSymlinkPolicy(conf.SymlinkPolicy) {
+		slog.Error("unknown symlink policy", "policy", conf.SymlinkPolicy)
+		os.Exit(1)
+	}
+
+	clientID := conf.ClientID
+	if clientID == "" {
+		clientID, err = os.Hostname()
+		if err != nil {
+			slog.Error("getting hostname", "err", err)
+			os.Exit(1)
+		}
+	}
+	if !entities.IsReplicaID(clientID) || clientID == entities.ServerReplicaID {
+		slog.Error("invalid client id", "clientId", clientID)
+		os.Exit(1)
+	}
+
 	// init dependencies
-	httpClient := adapters.NewHTTPClient(http.DefaultClient, conf.BaseURL)
+	pendingUploadTracker, err := adapters.NewPendingUploadTracker(conf.StateFile + ".uploads")
+	if err != nil {
+		slog.Error("loading pending uploads", "err", err)
+		os.Exit(1)
+	}
+
+	etagTracker, err := adapters.NewETagTracker(conf.StateFile + ".etags")
+	if err != nil {
+		slog.Error("loading etags", "err", err)
+		os.Exit(1)
+	}
+
+	versionVectorTracker, err := adapters.NewVersionVectorTracker(conf.StateFile+".versions", clientID)
+	if err != nil {
+		slog.Error("loading version vectors", "err", err)
+		os.Exit(1)
+	}
+
+	httpClient := adapters.NewHTTPClient(http.DefaultClient, conf.BaseURL, pendingUploadTracker, etagTracker,
+		conf.ConflictPolicy, adapters.NewConflictLog(conf.StateFile+".conflicts"), versionVectorTracker)
 	serverLive := false
 	slog.Info("checking sever liveness")
 	for !serverLive {
@@ -44,48 +88,212 @@ func main() {
 	}
 	slog.Info("server live!")
 
-	directoryMonitor, err := adapters.NewDirectoryMonitor(sourceDirectory)
+	syncStateStore, err := adapters.NewSyncStateStore(conf.StateFile, sourceDirectory, conf.PreserveOwnership)
+	if err != nil {
+		slog.Error("loading sync state", "err", err)
+		os.Exit(1)
+	}
+	defer func() {
+		err := syncStateStore.Close()
+		if err != nil {
+			slog.Error("saving sync state", "err", err)
+		}
+	}()
+
+	ignoreMatcher, err := adapters.NewIgnoreMatcher(sourceDirectory, conf.IgnorePatterns)
+	if err != nil {
+		slog.Error("parsing ignore patterns", "err", err)
+		os.Exit(1)
+	}
+
+	// the inotify watches are added before the snapshot is taken so that no changes are missed in between, any events
+	// received during the initial sync are queued by the kernel until the watcher runs
+	var inotifyWatcher *adapters.InotifyWatcher
+	if conf.MonitorMode != adapters.MonitorModePoll {
+		inotifyWatcher, err = adapters.NewInotifyWatcher(sourceDirectory, ignoreMatcher)
+		if err != nil {
+			slog.Warn("unable to watch source directory with inotify, falling back to polling", "err", err)
+		}
+	}
+
+	// the snapshot acknowledged in the previous run saves hashing every file again when the directory monitor starts
+	acknowledgedSnapshot := syncStateStore.AcknowledgedSnapshot()
+	directoryMonitor, err := adapters.NewDirectoryMonitor(sourceDirectory, ignoreMatcher, conf.PreserveOwnership, acknowledgedSnapshot)
 	if err != nil {
 		slog.Error("creating directory monitor", "err", err)
 		os.Exit(1)
 	}
 
-	eventProcessor := adapters.NewEventProcessor(httpClient, sourceDirectory)
+	// events dropped by the kernel while the watcher can't keep up are recovered by comparing the source directory
+	// with the directory monitor's snapshot
+	if inotifyWatcher != nil {
+		inotifyWatcher.RescanWith(directoryMonitor)
+	}
+
+	eventProcessor := adapters.NewEventProcessor(httpClient, sourceDirectory, !conf.DisableDeltaSync, conf.PreserveOwnership, conf.SymlinkPolicy)
+
+	outbox, err := adapters.NewOutbox(conf.StateFile + ".outbox")
+	if err != nil {
+		slog.Error("loading outbox", "err", err)
+		os.Exit(1)
+	}
+	outboxSender := adapters.NewOutboxSender(outbox, eventProcessor, syncStateStore, adapters.RetryPolicy{
+		InitialDelay: conf.RetryInitialDelay,
+		MaxDelay:     conf.RetryMaxDelay,
+	}, adapters.BatchPolicy{
+		MaxEvents: conf.BatchMaxEvents,
+		MaxBytes:  conf.BatchMaxBytes,
+		Window:    conf.BatchWindow,
+	}, conf.SendConcurrency)
+
+	// in two-way mode changes made to the destination by the server or other apps are pulled into the source directory
+	var changePuller *adapters.ChangePuller
+	if conf.TwoWaySync {
+		changePuller, err = adapters.NewChangePuller(httpClient, versionVectorTracker, etagTracker, sourceDirectory, conf.StateFile+".downloads")
+		if err != nil {
+			slog.Error("creating change puller", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
 
-	// make sure all files in source directory on startup get created in destination
-	syncEvents := directoryMonitor.SyncDestinationWithSource()
+	// send anything left in the outbox by the previous run before working out what has changed since, as the sync state
+	// only reflects those events once they have been acknowledged
+	err = outboxSender.Flush(ctx)
+	if err != nil {
+		slog.Info("shutting down before the outbox was sent", "err", err)
+		return
+	}
+
+	// make sure all changes made to the source directory while the app was stopped get replicated in the destination
+	syncEvents := directoryMonitor.SyncDestinationWithSource(syncStateStore.AcknowledgedSnapshot())
 	for _, event := range syncEvents {
-		err := eventProcessor.ProcessEvent(event)
+		err := queueEvent(outbox, syncStateStore, changePuller, event)
 		if err != nil {
-			slog.Error("processing event", "err", err)
-			continue
+			slog.Error("queueing event", "err", err)
+			os.Exit(1)
 		}
 	}
 
-	if len(syncEvents) > 1 {
-		slog.Info("synced existing files in destination")
+	err = outboxSender.Flush(ctx)
+	if err != nil {
+		slog.Info("shutting down before offline changes were sent", "err", err)
+		return
+	}
+
+	if changePuller != nil {
+		err = changePuller.PullChanges(ctx)
+		if err != nil {
+			slog.Error("pulling changes from server", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	// then compare the source with what is actually in the destination, to catch anything the sync state doesn't know
+	// about such as changes made directly to the destination or a destination that wasn't empty to begin with
+	manifest, err := httpClient.GetManifest()
+	if err != nil {
+		slog.Error("fetching destination manifest", "err", err)
+		os.Exit(1)
+	}
+
+	// files only in the destination are pulled rather than deleted when syncing in both directions
+	reconcileEvents := directoryMonitor.ReconcileWithDestination(manifest, !conf.DisableDeletePropagation && changePuller == nil)
+	for _, event := range reconcileEvents {
+		err := queueEvent(outbox, syncStateStore, changePuller, event)
+		if err != nil {
+			slog.Error("queueing event", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(syncEvents)+len(reconcileEvents) > 0 {
+		slog.Info("queued changes to sync existing files in destination", "offlineChanges", len(syncEvents), "reconciledChanges", len(reconcileEvents))
+	}
+
+	if changePuller != nil {
+		err = changePuller.DownloadMissing(manifest)
+		if err != nil {
+			slog.Error("downloading files missing from source directory", "err", err)
+			os.Exit(1)
+		}
 	}
 
 	eventChannel := make(chan entities.FilesystemEvent)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
-	// runs the command line application to poll directory for file changes
+	// rapid changes to the same file, such as an editor saving it in several steps, are collapsed into as few events as
+	// possible before they are queued
+	watcherChannel := eventChannel
+	if conf.DebounceDelay > 0 {
+		watcherChannel = make(chan entities.FilesystemEvent)
+		eventCoalescer := adapters.NewEventCoalescer(conf.DebounceDelay, syncStateStore.RecordedContents)
+		go func() {
+			err := eventCoalescer.Run(ctx, watcherChannel, eventChannel)
+			if err != nil {
+				slog.Error("coalescing file events", "err", err)
+			}
+		}()
+	}
+
+	// sends queued events to the server in the background, so that a server outage doesn't hold up the watcher
+	senderDone := make(chan struct{})
 	go func() {
-		slog.Info("polling for file events")
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
+		defer close(senderDone)
+		err := outboxSender.Run(ctx)
+		if err != nil {
+			slog.Error("sending queued events", "err", err)
+		}
+	}()
+	defer func() {
+		cancel()
+		<-senderDone
+	}()
+
+	if changePuller != nil {
+		pullerDone := make(chan struct{})
+		go func() {
+			defer close(pullerDone)
+			err := changePuller.Run(ctx, conf.PullRetryDelay)
+			if err != nil {
+				slog.Error("pulling changes from server", "err", err)
+			}
+		}()
+		defer func() {
+			cancel()
+			<-pullerDone
+		}()
+	}
+
+	// runs the command line application to watch the directory for file changes
+	go func() {
+		defer cancel()
 
-		for {
-			select {
-			case <-ticker.C:
-				err = directoryMonitor.PollForFileChanges(eventChannel)
+		if inotifyWatcher != nil {
+			slog.Info("watching for file events")
+			err := inotifyWatcher.Run(ctx, watcherChannel)
+			if !errors.Is(err, adapters.ErrWatchLimitReached) {
 				if err != nil {
-					slog.Error("polling for file changes", "err", err)
-					cancel()
+					slog.Error("watching for file changes", "err", err)
 				}
+				return
+			}
+
+			// inotify has published every change up to this point, so polling carries on from the current state
+			slog.Warn("inotify watch limit reached, falling back to polling")
+			err = directoryMonitor.ResetSnapshot()
+			if err != nil {
+				slog.Error("resetting directory snapshot", "err", err)
+				return
 			}
 		}
+
+		slog.Info("polling for file events")
+		err := directoryMonitor.Run(ctx, watcherChannel)
+		if err != nil {
+			slog.Error("polling for file changes", "err", err)
+		}
 	}()
 
 	// process any file events
@@ -97,17 +305,49 @@ func main() {
 				return
 			}
 
-			err := eventProcessor.ProcessEvent(event)
+			err := queueEvent(outbox, syncStateStore, changePuller, event)
 			if err != nil {
-				slog.Error("processing event", "err", err)
+				slog.Error("queueing event", "err", err)
 				continue
 			}
-
-			slog.Info("processed filesystem event", "operation", event.Operation, "filePath", event.Name)
 		case <-ctx.Done():
 			slog.Debug("shutting down listener")
 			return
 		}
 	}
+}
+
+// queueEvent is a function that records an event in the sync state and queues it in the outbox to be sent to the
+// server, along with any events needed to keep the hard links to a file linked in the destination or to carry the
+// metadata of a moved file over to it. The outbox sender marks each one as acknowledged once the server has applied it,
+// unacknowledged events are sent again the next time the app starts. Events for changes pulled from the server by
+// changePuller, if there is one, are acknowledged straight away rather than sent back.
+func queueEvent(outbox *adapters.Outbox, syncStateStore *adapters.SyncStateStore, changePuller *adapters.ChangePuller, event entities.FilesystemEvent) error {
+	for _, event := range syncStateStore.EventsToQueue(event) {
+		err := syncStateStore.RecordEvent(event)
+		if err != nil {
+			return err
+		}
+
+		if changePuller != nil {
+			echo, err := changePuller.RecordLocalEvent(event)
+			if err != nil {
+				return err
+			}
+			if echo {
+				err = syncStateStore.AcknowledgeEvent(event)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		err = outbox.Enqueue(event)
+		if err != nil {
+			return err
+		}
+	}
 
+	return nil
 }
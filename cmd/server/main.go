@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"github.com/AlecSmith96/dopbox/pkg/adapters"
 	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -33,8 +36,87 @@ func main() {
 	}
 
 	// init dependencies
-	fileWriter := adapters.NewFileWriter(destinationDirectory)
-	router := drivers.NewRouter(destinationDirectory, fileWriter)
+	versionStore, err := adapters.NewVersionStore(filepath.Join(conf.ServerDataDirectory, "versions"), destinationDirectory, adapters.VersionRetention{
+		MaxVersions: conf.VersionRetentionCount,
+		MaxAge:      conf.VersionRetentionAge,
+	})
+	if err != nil {
+		slog.Error("creating version store", "err", err)
+		os.Exit(1)
+	}
+	trash, err := adapters.NewTrash(filepath.Join(conf.ServerDataDirectory, "trash"), destinationDirectory, adapters.TrashRetention{
+		MaxAge:     conf.TrashRetentionAge,
+		MaxEntries: conf.TrashMaxEntries,
+	})
+	if err != nil {
+		slog.Error("creating trash", "err", err)
+		os.Exit(1)
+	}
+	if !entities.IsConflictPolicy(conf.ServerConflictPolicy) {
+		slog.Error("unknown conflict policy", "policy", conf.ServerConflictPolicy)
+		os.Exit(1)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		slog.Error("getting hostname", "err", err)
+		os.Exit(1)
+	}
+	conflictResolver := adapters.NewConflictResolver(destinationDirectory, conf.ServerConflictPolicy, host,
+		adapters.NewConflictLog(filepath.Join(conf.ServerDataDirectory, "conflicts.log")))
+	fileWriter := adapters.NewFileWriter(destinationDirectory, versionStore, trash)
+	fileReader := adapters.NewFileReader(destinationDirectory)
+	uploadManager, err := adapters.NewUploadManager(filepath.Join(conf.ServerDataDirectory, "uploads"))
+	if err != nil {
+		slog.Error("creating upload manager", "err", err)
+		os.Exit(1)
+	}
+	changeFeed, err := adapters.NewChangeFeed(filepath.Join(conf.ServerDataDirectory, "file-versions.json"),
+		filepath.Join(conf.ServerDataDirectory, "changes.log"), destinationDirectory)
+	if err != nil {
+		slog.Error("creating change feed", "err", err)
+		os.Exit(1)
+	}
+	defer changeFeed.Close()
+
+	// in two-way mode changes made directly to the destination are added to the change feed so that apps can pull them
+	if conf.TwoWaySync {
+		directoryMonitor, err := adapters.NewDirectoryMonitor(destinationDirectory, nil, false, nil)
+		if err != nil {
+			slog.Error("creating destination directory monitor", "err", err)
+			os.Exit(1)
+		}
+
+		go watchDestination(directoryMonitor, changeFeed)
+	}
+
+	go versionStore.Run(context.Background())
+	go trash.Run(context.Background())
+
+	router := drivers.NewRouter(destinationDirectory, usecases.NewPathLocker(), fileWriter, fileReader, uploadManager, versionStore, trash, conflictResolver, changeFeed)
 
 	router.Run()
 }
+
+// watchDestination is a function that polls the destination directory for changes and records the ones that weren't
+// made by the server in response to a request in the change feed.
+func watchDestination(directoryMonitor *adapters.DirectoryMonitor, changeFeed *adapters.ChangeFeed) {
+	eventChannel := make(chan entities.FilesystemEvent)
+	go func() {
+		err := directoryMonitor.Run(context.Background(), eventChannel)
+		if err != nil {
+			slog.Error("polling destination for file changes", "err", err)
+		}
+	}()
+
+	slog.Info("watching destination for file events")
+	for event := range eventChannel {
+		recorded, err := changeFeed.RecordDestinationEvent(event)
+		if err != nil {
+			slog.Error("recording destination change", "err", err, "path", event.Name)
+			continue
+		}
+		if recorded {
+			slog.Debug("recorded destination change", "path", event.Name, "operation", event.Operation)
+		}
+	}
+}
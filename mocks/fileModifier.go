@@ -12,6 +12,7 @@ package mock_adapters
 import (
 	reflect "reflect"
 
+	entities "github.com/AlecSmith96/dopbox/pkg/entities"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -38,6 +39,20 @@ func (m *MockFileModifier) EXPECT() *MockFileModifierMockRecorder {
 	return m.recorder
 }
 
+// ApplyDelta mocks base method.
+func (m *MockFileModifier) ApplyDelta(arg0 string, arg1 entities.Delta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyDelta", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyDelta indicates an expected call of ApplyDelta.
+func (mr *MockFileModifierMockRecorder) ApplyDelta(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyDelta", reflect.TypeOf((*MockFileModifier)(nil).ApplyDelta), arg0, arg1)
+}
+
 // CreateFile mocks base method.
 func (m *MockFileModifier) CreateFile(arg0 string, arg1 []byte, arg2 bool) error {
 	m.ctrl.T.Helper()
@@ -52,6 +67,34 @@ func (mr *MockFileModifierMockRecorder) CreateFile(arg0, arg1, arg2 any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFile", reflect.TypeOf((*MockFileModifier)(nil).CreateFile), arg0, arg1, arg2)
 }
 
+// CreateHardLink mocks base method.
+func (m *MockFileModifier) CreateHardLink(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHardLink", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateHardLink indicates an expected call of CreateHardLink.
+func (mr *MockFileModifierMockRecorder) CreateHardLink(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHardLink", reflect.TypeOf((*MockFileModifier)(nil).CreateHardLink), arg0, arg1)
+}
+
+// CreateSymlink mocks base method.
+func (m *MockFileModifier) CreateSymlink(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSymlink", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSymlink indicates an expected call of CreateSymlink.
+func (mr *MockFileModifierMockRecorder) CreateSymlink(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSymlink", reflect.TypeOf((*MockFileModifier)(nil).CreateSymlink), arg0, arg1)
+}
+
 // DeleteFile mocks base method.
 func (m *MockFileModifier) DeleteFile(arg0 string) error {
 	m.ctrl.T.Helper()
@@ -66,6 +109,20 @@ func (mr *MockFileModifierMockRecorder) DeleteFile(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockFileModifier)(nil).DeleteFile), arg0)
 }
 
+// InstallFile mocks base method.
+func (m *MockFileModifier) InstallFile(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallFile", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InstallFile indicates an expected call of InstallFile.
+func (mr *MockFileModifierMockRecorder) InstallFile(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallFile", reflect.TypeOf((*MockFileModifier)(nil).InstallFile), arg0, arg1)
+}
+
 // RenameFile mocks base method.
 func (m *MockFileModifier) RenameFile(arg0, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -80,6 +137,20 @@ func (mr *MockFileModifierMockRecorder) RenameFile(arg0, arg1 any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameFile", reflect.TypeOf((*MockFileModifier)(nil).RenameFile), arg0, arg1)
 }
 
+// SetMetadata mocks base method.
+func (m *MockFileModifier) SetMetadata(arg0 string, arg1 entities.FileMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMetadata", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMetadata indicates an expected call of SetMetadata.
+func (mr *MockFileModifierMockRecorder) SetMetadata(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMetadata", reflect.TypeOf((*MockFileModifier)(nil).SetMetadata), arg0, arg1)
+}
+
 // UpdateFile mocks base method.
 func (m *MockFileModifier) UpdateFile(arg0 string, arg1 []byte) error {
 	m.ctrl.T.Helper()
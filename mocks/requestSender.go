@@ -12,6 +12,7 @@ package mock_adapters
 import (
 	reflect "reflect"
 
+	entities "github.com/AlecSmith96/dopbox/pkg/entities"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -38,18 +39,48 @@ func (m *MockRequestSender) EXPECT() *MockRequestSenderMockRecorder {
 	return m.recorder
 }
 
+// GetManifest mocks base method.
+func (m *MockRequestSender) GetManifest() ([]entities.ManifestEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetManifest")
+	ret0, _ := ret[0].([]entities.ManifestEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetManifest indicates an expected call of GetManifest.
+func (mr *MockRequestSenderMockRecorder) GetManifest() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManifest", reflect.TypeOf((*MockRequestSender)(nil).GetManifest))
+}
+
+// SendBatch mocks base method.
+func (m *MockRequestSender) SendBatch(arg0 []entities.BatchOperation) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendBatch", arg0)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendBatch indicates an expected call of SendBatch.
+func (mr *MockRequestSenderMockRecorder) SendBatch(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendBatch", reflect.TypeOf((*MockRequestSender)(nil).SendBatch), arg0)
+}
+
 // SendCreateRequest mocks base method.
-func (m *MockRequestSender) SendCreateRequest(arg0 string, arg1 []byte, arg2 bool) error {
+func (m *MockRequestSender) SendCreateRequest(arg0 string, arg1 []byte, arg2 bool, arg3 entities.FileMetadata) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendCreateRequest", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "SendCreateRequest", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SendCreateRequest indicates an expected call of SendCreateRequest.
-func (mr *MockRequestSenderMockRecorder) SendCreateRequest(arg0, arg1, arg2 any) *gomock.Call {
+func (mr *MockRequestSenderMockRecorder) SendCreateRequest(arg0, arg1, arg2, arg3 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendCreateRequest", reflect.TypeOf((*MockRequestSender)(nil).SendCreateRequest), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendCreateRequest", reflect.TypeOf((*MockRequestSender)(nil).SendCreateRequest), arg0, arg1, arg2, arg3)
 }
 
 // SendDeleteRequest mocks base method.
@@ -66,6 +97,48 @@ func (mr *MockRequestSenderMockRecorder) SendDeleteRequest(arg0 any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDeleteRequest", reflect.TypeOf((*MockRequestSender)(nil).SendDeleteRequest), arg0)
 }
 
+// SendDelta mocks base method.
+func (m *MockRequestSender) SendDelta(arg0, arg1 string, arg2 entities.FileMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendDelta", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendDelta indicates an expected call of SendDelta.
+func (mr *MockRequestSenderMockRecorder) SendDelta(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDelta", reflect.TypeOf((*MockRequestSender)(nil).SendDelta), arg0, arg1, arg2)
+}
+
+// SendHardLinkRequest mocks base method.
+func (m *MockRequestSender) SendHardLinkRequest(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendHardLinkRequest", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendHardLinkRequest indicates an expected call of SendHardLinkRequest.
+func (mr *MockRequestSenderMockRecorder) SendHardLinkRequest(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendHardLinkRequest", reflect.TypeOf((*MockRequestSender)(nil).SendHardLinkRequest), arg0, arg1)
+}
+
+// SendMetadataRequest mocks base method.
+func (m *MockRequestSender) SendMetadataRequest(arg0 string, arg1 entities.FileMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMetadataRequest", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMetadataRequest indicates an expected call of SendMetadataRequest.
+func (mr *MockRequestSenderMockRecorder) SendMetadataRequest(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMetadataRequest", reflect.TypeOf((*MockRequestSender)(nil).SendMetadataRequest), arg0, arg1)
+}
+
 // SendRenameRequest mocks base method.
 func (m *MockRequestSender) SendRenameRequest(arg0, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -80,16 +153,44 @@ func (mr *MockRequestSenderMockRecorder) SendRenameRequest(arg0, arg1 any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendRenameRequest", reflect.TypeOf((*MockRequestSender)(nil).SendRenameRequest), arg0, arg1)
 }
 
+// SendSymlinkRequest mocks base method.
+func (m *MockRequestSender) SendSymlinkRequest(arg0, arg1 string, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendSymlinkRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendSymlinkRequest indicates an expected call of SendSymlinkRequest.
+func (mr *MockRequestSenderMockRecorder) SendSymlinkRequest(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendSymlinkRequest", reflect.TypeOf((*MockRequestSender)(nil).SendSymlinkRequest), arg0, arg1, arg2)
+}
+
 // SendUpdateRequest mocks base method.
-func (m *MockRequestSender) SendUpdateRequest(arg0 string, arg1 []byte) error {
+func (m *MockRequestSender) SendUpdateRequest(arg0 string, arg1 []byte, arg2 entities.FileMetadata) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendUpdateRequest", arg0, arg1)
+	ret := m.ctrl.Call(m, "SendUpdateRequest", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SendUpdateRequest indicates an expected call of SendUpdateRequest.
-func (mr *MockRequestSenderMockRecorder) SendUpdateRequest(arg0, arg1 any) *gomock.Call {
+func (mr *MockRequestSenderMockRecorder) SendUpdateRequest(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendUpdateRequest", reflect.TypeOf((*MockRequestSender)(nil).SendUpdateRequest), arg0, arg1, arg2)
+}
+
+// UploadFile mocks base method.
+func (m *MockRequestSender) UploadFile(arg0, arg1 string, arg2 entities.FileMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadFile", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UploadFile indicates an expected call of UploadFile.
+func (mr *MockRequestSenderMockRecorder) UploadFile(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendUpdateRequest", reflect.TypeOf((*MockRequestSender)(nil).SendUpdateRequest), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadFile", reflect.TypeOf((*MockRequestSender)(nil).UploadFile), arg0, arg1, arg2)
 }
@@ -0,0 +1,163 @@
+package adapters
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// temporaryFilePattern matches the names replaceFile gives its temporary files.
+var temporaryFilePattern = regexp.MustCompile(`^\..+\.tmp-[0-9]+$`)
+
+// writeFileAtomically is a function that replaces the contents of the file at path with data, so that path never
+// contains partially written data.
+func writeFileAtomically(path string, data []byte, perm os.FileMode) error {
+	return replaceFile(path, perm, func(writer io.Writer) error {
+		_, err := writer.Write(data)
+		return err
+	})
+}
+
+// replaceFile is a function that writes a new version of the file at path using write. The contents go to a temporary
+// file in the same directory, which is synced to disk and renamed over path, then the directory is synced so that the
+// rename itself survives a crash. Anything reading path sees either the old or the new contents, never a mix of the two.
+// If write returns an error the temporary file is removed and path is left untouched.
+func replaceFile(path string, perm os.FileMode, write func(writer io.Writer) error) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+
+	err = write(tempFile)
+	if err == nil {
+		err = tempFile.Chmod(perm)
+	}
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return syncDirectory(filepath.Dir(path))
+}
+
+// replaceSymlink is a function that puts a symlink to target at path, replacing any file or symlink already there. The
+// symlink is created under a temporary name in the same directory and renamed over path, so in the same way as
+// replaceFile anything reading path sees either the old or the new entry.
+func replaceSymlink(path, target string) error {
+	tempPath, err := reserveTempPath(path)
+	if err != nil {
+		return err
+	}
+
+	err = os.Symlink(target, tempPath)
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return syncDirectory(filepath.Dir(path))
+}
+
+// replaceHardLink is a function that atomically replaces whatever is at path with a hard link to the file at
+// linkedPath, in the same way as replaceSymlink. Nothing is done if path is already a link to the file.
+func replaceHardLink(linkedPath, path string) error {
+	linkedInfo, err := os.Lstat(linkedPath)
+	if err != nil {
+		return err
+	}
+
+	// renaming a link over another link to the same file leaves both of them in place
+	info, err := os.Lstat(path)
+	if err == nil && os.SameFile(info, linkedInfo) {
+		return nil
+	}
+
+	tempPath, err := reserveTempPath(path)
+	if err != nil {
+		return err
+	}
+
+	err = os.Link(linkedPath, tempPath)
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return syncDirectory(filepath.Dir(path))
+}
+
+// reserveTempPath is a function that returns an unused temporary name next to path, in the same form as replaceFile
+// uses so that watchers skip it in the same way, for an entry that can't be created through os.CreateTemp.
+func reserveTempPath(path string) (string, error) {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tempPath := tempFile.Name()
+
+	err = tempFile.Close()
+	if err == nil {
+		err = os.Remove(tempPath)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return "", err
+	}
+
+	return tempPath, nil
+}
+
+// syncDirectory is a function that flushes a directory's entries to disk, making any files created, renamed or removed
+// within it durable. A directory that no longer exists has nothing to sync.
+func syncDirectory(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// fileModeOrDefault is a function that returns the permissions of the file at path, along with its setuid, setgid and
+// sticky bits, or defaultMode if it doesn't exist, so that replacing a file keeps its permissions.
+func fileModeOrDefault(path string, defaultMode os.FileMode) os.FileMode {
+	info, err := os.Stat(path)
+	if err != nil {
+		return defaultMode
+	}
+
+	return info.Mode() & entities.PreservedModeBits
+}
+
+// isTemporaryFile is a function that returns whether path is one of the temporary files written by replaceFile, which
+// only exist until they are renamed into place.
+func isTemporaryFile(path string) bool {
+	return temporaryFilePattern.MatchString(filepath.Base(path))
+}
@@ -0,0 +1,458 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// changeFeedPageSize is the most changes returned by a single call to ChangesSince.
+const changeFeedPageSize = 1000
+
+// changeFeedCheckpointInterval is how many changes are recorded between saves of the version vectors. The changes
+// since the last save are in the log, so they are applied again when the feed is loaded.
+const changeFeedCheckpointInterval = 1000
+
+// ErrInvalidChangeLog is returned when the change log can't be loaded because it has been corrupted.
+var ErrInvalidChangeLog = errors.New("invalid change log")
+
+// ChangeFeed is a struct that keeps an ordered record of the changes made to the destination directory, whether by a
+// request from an app or directly to the destination, so that each app can pull the changes made by everyone else.
+// Every change is appended to a log file before it is acknowledged, so the feed, its sequence numbers and the replica
+// that made each change outlive the server. It also keeps the version vector of every file, including those changed
+// before the log was started, which are saved every changeFeedCheckpointInterval changes and when the feed is closed.
+type ChangeFeed struct {
+	mu              sync.Mutex
+	filePath        string
+	logPath         string
+	destinationPath string
+	feedID          string
+	log             *os.File
+	changes         []entities.Change
+	files           map[string]fileState
+	checkpoint      uint64
+	updated         chan struct{}
+}
+
+var _ ChangeLog = &ChangeFeed{}
+
+// fileState is the persisted state of a path in the destination directory as of its most recent change. Deleted paths
+// keep their version vector, so that a file created again at the path follows on from the one that was deleted.
+type fileState struct {
+	Version     entities.VersionVector `json:"version"`
+	IsDirectory bool                   `json:"isDirectory,omitempty"`
+	Hash        string                 `json:"hash,omitempty"`
+	Deleted     bool                   `json:"deleted,omitempty"`
+}
+
+// fileStateCheckpoint is the saved state of every path in the destination directory, as of the change with the given
+// sequence number in the feed with the given ID.
+type fileStateCheckpoint struct {
+	FeedID   string               `json:"feedId,omitempty"`
+	Sequence uint64               `json:"sequence"`
+	Files    map[string]fileState `json:"files"`
+}
+
+// changeLogHeader is the first line of the change log, identifying the feed the changes that follow belong to.
+type changeLogHeader struct {
+	FeedID string `json:"feedId"`
+}
+
+// NewChangeFeed is a function that creates a ChangeFeed for the destination directory, loading the version vectors left
+// at filePath and the changes left in the log at logPath by a previous run. A new feed, with a new ID, is started if
+// there is no log.
+func NewChangeFeed(filePath, logPath, destinationPath string) (*ChangeFeed, error) {
+	feed := &ChangeFeed{
+		filePath:        filePath,
+		logPath:         logPath,
+		destinationPath: destinationPath,
+		changes:         make([]entities.Change, 0),
+		files:           make(map[string]fileState),
+		updated:         make(chan struct{}),
+	}
+
+	fileBytes, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading version vectors: %w", err)
+	}
+	checkpoint := fileStateCheckpoint{Files: feed.files}
+	if err == nil {
+		err = json.Unmarshal(fileBytes, &checkpoint)
+		if err != nil {
+			return nil, fmt.Errorf("decoding version vectors: %w", err)
+		}
+		if checkpoint.Files != nil {
+			feed.files = checkpoint.Files
+		}
+	}
+
+	err = feed.loadLog(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+// ChangeLog is an interface that sets out the functions implemented by the ChangeFeed. This allows for mocking of the
+// ChangeFeed functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/changeLog.go  . "ChangeLog"
+type ChangeLog interface {
+	RecordChange(change entities.Change) (entities.Change, error)
+	ChangesSince(ctx context.Context, sequence uint64, wait time.Duration) (entities.ChangeFeedPage, error)
+}
+
+// RecordChange is a function that adds a change to the end of the feed. The file's version vector is merged with the
+// one the change was made against, and the change is counted against its origin. A rename carries the version vector
+// and contents of the path it was moved from. The recorded change is returned.
+func (feed *ChangeFeed) RecordChange(change entities.Change) (entities.Change, error) {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	return feed.record(change)
+}
+
+// RecordDestinationEvent is a function that records a change found by watching the destination directory, attributed
+// to the server. Events for changes the feed already knows about, because the server made them in response to a
+// request, are dropped so that they aren't sent back to the app that made them, as are changes to metadata, which the
+// feed doesn't track. It returns whether the change was recorded.
+func (feed *ChangeFeed) RecordDestinationEvent(event entities.FilesystemEvent) (bool, error) {
+	if isTemporaryFile(event.Name) || event.Operation == entities.OperationMetadata {
+		return false, nil
+	}
+
+	change := entities.Change{
+		Path:        feed.relativePath(event.Name),
+		Operation:   event.Operation,
+		IsDirectory: event.FileContents.IsDirectory,
+		Hash:        event.FileContents.Hash,
+		LinkTarget:  event.FileContents.LinkTarget,
+		Origin:      entities.ServerReplicaID,
+	}
+	if event.Operation == entities.OperationRenamed {
+		if isTemporaryFile(event.PreviousPath) {
+			// a file being written atomically, which is the same as it being created
+			change.Operation = entities.OperationCreated
+		} else {
+			change.PreviousPath = feed.relativePath(event.PreviousPath)
+		}
+	}
+
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	if feed.isCurrent(change, event.Name) {
+		return false, nil
+	}
+
+	_, err := feed.record(change)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ChangesSince is a function that returns the changes in the feed after the given sequence number, oldest first and at
+// most changeFeedPageSize at a time. If there are none it waits up to wait for one to be recorded, or until the context
+// is cancelled, before returning.
+func (feed *ChangeFeed) ChangesSince(ctx context.Context, sequence uint64, wait time.Duration) (entities.ChangeFeedPage, error) {
+	page, updated := feed.changesSince(sequence)
+	if len(page.Changes) > 0 || wait <= 0 {
+		return page, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-updated:
+		page, _ = feed.changesSince(sequence)
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	return page, nil
+}
+
+// Close is a function that saves the version vectors and closes the change log.
+func (feed *ChangeFeed) Close() error {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	return errors.Join(feed.save(), feed.log.Close())
+}
+
+// changesSince is a function that returns a page of the changes after sequence, along with a channel that is closed when
+// the next change is recorded.
+func (feed *ChangeFeed) changesSince(sequence uint64) (entities.ChangeFeedPage, <-chan struct{}) {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	page := entities.ChangeFeedPage{
+		FeedID:  feed.feedID,
+		Latest:  uint64(len(feed.changes)),
+		Changes: make([]entities.Change, 0),
+	}
+
+	// sequence numbers start at one and have no gaps, so the change after sequence is at index sequence
+	if sequence >= uint64(len(feed.changes)) {
+		return page, feed.updated
+	}
+	end := min(sequence+changeFeedPageSize, uint64(len(feed.changes)))
+	page.Changes = append(page.Changes, feed.changes[sequence:end]...)
+
+	return page, feed.updated
+}
+
+func (feed *ChangeFeed) record(change entities.Change) (entities.Change, error) {
+	version := feed.files[change.Path].Version
+	if change.Operation == entities.OperationRenamed {
+		previous := feed.files[change.PreviousPath]
+		version = version.Merge(previous.Version)
+		if !previous.Deleted && change.Hash == "" {
+			change.IsDirectory = previous.IsDirectory
+			change.Hash = previous.Hash
+		}
+	}
+
+	change.Version = version.Merge(change.Version).Increment(change.Origin)
+	change.Sequence = uint64(len(feed.changes)) + 1
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now().UTC()
+	}
+
+	err := feed.appendLog(change)
+	if err != nil {
+		return entities.Change{}, err
+	}
+	feed.changes = append(feed.changes, change)
+
+	feed.applyState(change)
+	if change.Sequence-feed.checkpoint >= changeFeedCheckpointInterval {
+		err = feed.save()
+		if err != nil {
+			slog.Warn("saving version vectors", "err", err)
+		}
+	}
+
+	// wake anything waiting for a new change
+	close(feed.updated)
+	feed.updated = make(chan struct{})
+
+	return change, nil
+}
+
+// applyState is a function that updates the state of the paths a change affects.
+func (feed *ChangeFeed) applyState(change entities.Change) {
+	switch change.Operation {
+	case entities.OperationCreated, entities.OperationModified:
+		feed.files[change.Path] = fileState{
+			Version:     change.Version,
+			IsDirectory: change.IsDirectory,
+			Hash:        change.Hash,
+		}
+
+	case entities.OperationDeleted:
+		feed.deleteDescendants(change.Path)
+		feed.files[change.Path] = fileState{Version: change.Version, Deleted: true}
+
+	case entities.OperationRenamed:
+		feed.moveDescendants(change.PreviousPath, change.Path)
+		feed.files[change.PreviousPath] = fileState{Version: change.Version, Deleted: true}
+		feed.files[change.Path] = fileState{
+			Version:     change.Version,
+			IsDirectory: change.IsDirectory,
+			Hash:        change.Hash,
+		}
+	}
+}
+
+// isCurrent is a function that returns whether the feed's state for a path already reflects a change found in the
+// destination directory.
+func (feed *ChangeFeed) isCurrent(change entities.Change, path string) bool {
+	state, exists := feed.files[change.Path]
+
+	switch change.Operation {
+	case entities.OperationCreated, entities.OperationModified:
+		return exists && !state.Deleted && state.IsDirectory == change.IsDirectory && state.Hash == change.Hash
+
+	case entities.OperationDeleted:
+		// a file replaced by renaming a new one over it looks like a delete and a create to the DirectoryMonitor
+		_, err := os.Lstat(path)
+		if err == nil {
+			return true
+		}
+		return exists && state.Deleted
+
+	case entities.OperationRenamed:
+		previous := feed.files[change.PreviousPath]
+		return previous.Deleted && exists && !state.Deleted && (state.IsDirectory || state.Hash == change.Hash)
+	}
+
+	return false
+}
+
+// deleteDescendants is a function that marks everything within path as deleted.
+func (feed *ChangeFeed) deleteDescendants(path string) {
+	for trackedPath, state := range feed.files {
+		if strings.HasPrefix(trackedPath, path+"/") {
+			state.Deleted = true
+			feed.files[trackedPath] = state
+		}
+	}
+}
+
+// moveDescendants is a function that moves the state of everything within oldPath to newPath, leaving the old paths
+// deleted.
+func (feed *ChangeFeed) moveDescendants(oldPath, newPath string) {
+	for trackedPath, state := range feed.files {
+		if !strings.HasPrefix(trackedPath, oldPath+"/") || state.Deleted {
+			continue
+		}
+		feed.files[newPath+strings.TrimPrefix(trackedPath, oldPath)] = state
+		feed.files[trackedPath] = fileState{Version: state.Version, Deleted: true}
+	}
+}
+
+func (feed *ChangeFeed) relativePath(path string) string {
+	relativePath, err := filepath.Rel(feed.destinationPath, path)
+	if err != nil {
+		return path
+	}
+
+	return "/" + filepath.ToSlash(relativePath)
+}
+
+// loadLog is a function that reads the changes in the change log, or starts a new log if there isn't one, and opens it
+// for appending. The changes made after the checkpoint are applied on top of it, and are all of them if the checkpoint
+// was saved for a different log. A partially written final line, left if the server stopped mid write, is removed.
+func (feed *ChangeFeed) loadLog(checkpoint fileStateCheckpoint) error {
+	logBytes, err := os.ReadFile(feed.logPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading change log: %w", err)
+	}
+
+	if len(logBytes) == 0 {
+		return feed.startLog()
+	}
+
+	complete := bytes.LastIndexByte(logBytes, '\n') + 1
+	if complete < len(logBytes) {
+		slog.Warn("removing partially written change log entry")
+	}
+
+	lines := bytes.Split(logBytes[:complete], []byte("\n"))
+	var header changeLogHeader
+	err = json.Unmarshal(lines[0], &header)
+	if err != nil || header.FeedID == "" {
+		return fmt.Errorf("decoding change log header: %w", errors.Join(err, ErrInvalidChangeLog))
+	}
+	feed.feedID = header.FeedID
+
+	for _, line := range lines[1:] {
+		if len(line) == 0 {
+			continue
+		}
+
+		var change entities.Change
+		err = json.Unmarshal(line, &change)
+		if err != nil {
+			return fmt.Errorf("decoding change log entry: %w", err)
+		}
+		if change.Sequence != uint64(len(feed.changes))+1 {
+			return fmt.Errorf("change %d out of sequence: %w", change.Sequence, ErrInvalidChangeLog)
+		}
+
+		feed.changes = append(feed.changes, change)
+		if checkpoint.FeedID != feed.feedID || change.Sequence > checkpoint.Sequence {
+			feed.applyState(change)
+		}
+	}
+
+	feed.log, err = os.OpenFile(feed.logPath, os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening change log: %w", err)
+	}
+	err = feed.log.Truncate(int64(complete))
+	if err == nil {
+		_, err = feed.log.Seek(int64(complete), io.SeekStart)
+	}
+	if err != nil {
+		return fmt.Errorf("opening change log: %w", err)
+	}
+
+	return feed.save()
+}
+
+// startLog is a function that starts a new change log, under a new feed ID.
+func (feed *ChangeFeed) startLog() error {
+	feed.feedID = strconv.FormatInt(time.Now().UnixNano(), 10)
+	headerBytes, err := json.Marshal(changeLogHeader{FeedID: feed.feedID})
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(feed.logPath, append(headerBytes, '\n'), 0o600)
+	if err != nil {
+		return fmt.Errorf("writing change log: %w", err)
+	}
+
+	feed.log, err = os.OpenFile(feed.logPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening change log: %w", err)
+	}
+
+	return nil
+}
+
+// appendLog is a function that appends a change to the change log. The change is on disk by the time it returns.
+func (feed *ChangeFeed) appendLog(change entities.Change) error {
+	changeBytes, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	_, err = feed.log.Write(append(changeBytes, '\n'))
+	if err == nil {
+		err = feed.log.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("writing change log: %w", err)
+	}
+
+	return nil
+}
+
+// save is a function that saves the version vectors as of the most recent change in the feed.
+func (feed *ChangeFeed) save() error {
+	checkpoint := fileStateCheckpoint{
+		FeedID:   feed.feedID,
+		Sequence: uint64(len(feed.changes)),
+		Files:    feed.files,
+	}
+	fileBytes, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(feed.filePath, fileBytes, 0o600)
+	if err != nil {
+		return err
+	}
+	feed.checkpoint = checkpoint.Sequence
+
+	return nil
+}
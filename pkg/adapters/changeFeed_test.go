@@ -0,0 +1,254 @@
+package adapters
+
+import (
+	"context"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChangeFeed_RecordChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	feed, err := NewChangeFeed(filepath.Join(t.TempDir(), "file-versions.json"), filepath.Join(t.TempDir(), "changes.log"), t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	created, err := feed.RecordChange(entities.Change{Path: "/a.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(created.Sequence).To(Equal(uint64(1)))
+	g.Expect(created.Version).To(Equal(entities.VersionVector{"laptop": 1}))
+
+	// the change is made against a version that has also seen a change the server hasn't
+	modified, err := feed.RecordChange(entities.Change{
+		Path:      "/a.go",
+		Operation: entities.OperationModified,
+		Hash:      "def",
+		Origin:    "desktop",
+		Version:   entities.VersionVector{"desktop": 2},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(modified.Sequence).To(Equal(uint64(2)))
+	g.Expect(modified.Version).To(Equal(entities.VersionVector{"desktop": 3, "laptop": 1}))
+
+	renamed, err := feed.RecordChange(entities.Change{Path: "/b.go", PreviousPath: "/a.go", Operation: entities.OperationRenamed, Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(renamed.Hash).To(Equal("def"))
+	g.Expect(renamed.Version).To(Equal(entities.VersionVector{"desktop": 3, "laptop": 2}))
+
+	deleted, err := feed.RecordChange(entities.Change{Path: "/b.go", Operation: entities.OperationDeleted, Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(deleted.Version).To(Equal(entities.VersionVector{"desktop": 3, "laptop": 3}))
+
+	// a file created again where one was deleted follows on from the deleted one
+	recreated, err := feed.RecordChange(entities.Change{Path: "/b.go", Operation: entities.OperationCreated, Hash: "ghi", Origin: "desktop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recreated.Version).To(Equal(entities.VersionVector{"desktop": 4, "laptop": 3}))
+}
+
+func TestChangeFeed_ChangesSince(t *testing.T) {
+	g := NewGomegaWithT(t)
+	feed, err := NewChangeFeed(filepath.Join(t.TempDir(), "file-versions.json"), filepath.Join(t.TempDir(), "changes.log"), t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	for i := 0; i < changeFeedPageSize+5; i++ {
+		_, err := feed.RecordChange(entities.Change{Path: "/a.go", Operation: entities.OperationModified, Origin: "laptop"})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	page, err := feed.ChangesSince(context.Background(), 0, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Latest).To(Equal(uint64(changeFeedPageSize + 5)))
+	g.Expect(page.Changes).To(HaveLen(changeFeedPageSize))
+	g.Expect(page.Changes[0].Sequence).To(Equal(uint64(1)))
+
+	page, err = feed.ChangesSince(context.Background(), changeFeedPageSize, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Changes).To(HaveLen(5))
+	g.Expect(page.Changes[0].Sequence).To(Equal(uint64(changeFeedPageSize + 1)))
+
+	page, err = feed.ChangesSince(context.Background(), changeFeedPageSize+5, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Changes).To(BeEmpty())
+}
+
+func TestChangeFeed_PersistsChangesBetweenRuns(t *testing.T) {
+	g := NewGomegaWithT(t)
+	filePath := filepath.Join(t.TempDir(), "file-versions.json")
+	logPath := filepath.Join(t.TempDir(), "changes.log")
+	feed, err := NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = feed.RecordChange(entities.Change{Path: "/dir", Operation: entities.OperationCreated, IsDirectory: true, Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = feed.RecordChange(entities.Change{Path: "/dir/a.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "desktop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = feed.RecordChange(entities.Change{Path: "/moved", PreviousPath: "/dir", Operation: entities.OperationRenamed, Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(feed.Close()).To(Succeed())
+
+	reloaded, err := NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reloaded.feedID).To(Equal(feed.feedID))
+
+	page, err := reloaded.ChangesSince(context.Background(), 1, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Latest).To(Equal(uint64(3)))
+	g.Expect(page.Changes).To(HaveLen(2))
+	g.Expect(page.Changes[0].Origin).To(Equal("desktop"))
+	g.Expect(page.Changes[1].PreviousPath).To(Equal("/dir"))
+
+	// files within a renamed directory keep their own version vectors
+	g.Expect(reloaded.files["/moved/a.go"]).To(Equal(fileState{Version: entities.VersionVector{"desktop": 1}, Hash: "abc"}))
+	g.Expect(reloaded.files["/dir/a.go"].Deleted).To(BeTrue())
+
+	modified, err := reloaded.RecordChange(entities.Change{Path: "/moved/a.go", Operation: entities.OperationModified, Hash: "def", Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(modified.Sequence).To(Equal(uint64(4)))
+	g.Expect(modified.Version).To(Equal(entities.VersionVector{"desktop": 1, "laptop": 1}))
+}
+
+func TestChangeFeed_RebuildsVersionVectorsFromLog(t *testing.T) {
+	g := NewGomegaWithT(t)
+	filePath := filepath.Join(t.TempDir(), "file-versions.json")
+	logPath := filepath.Join(t.TempDir(), "changes.log")
+	feed, err := NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	for _, change := range []entities.Change{
+		{Path: "/dir", Operation: entities.OperationCreated, IsDirectory: true, Origin: "laptop"},
+		{Path: "/dir/a.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "laptop"},
+		{Path: "/moved", PreviousPath: "/dir", Operation: entities.OperationRenamed, Origin: "laptop"},
+		{Path: "/dir", Operation: entities.OperationCreated, IsDirectory: true, Origin: "laptop"},
+		{Path: "/dir/b.go", Operation: entities.OperationCreated, Hash: "def", Origin: "laptop"},
+	} {
+		_, err = feed.RecordChange(change)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	// the version vectors are only saved when the feed is closed
+	g.Expect(filePath).ToNot(BeAnExistingFile())
+	g.Expect(feed.Close()).To(Succeed())
+	g.Expect(filePath).To(BeAnExistingFile())
+
+	feed, err = NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = feed.RecordChange(entities.Change{Path: "/moved/a.go", Operation: entities.OperationModified, Hash: "ghi", Origin: "desktop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	// stop without saving, as if the server had been killed
+	g.Expect(feed.log.Close()).To(Succeed())
+
+	reloaded, err := NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reloaded.files["/moved/a.go"]).To(Equal(fileState{Version: entities.VersionVector{"desktop": 1, "laptop": 1}, Hash: "ghi"}))
+	// the rename saved before the checkpoint isn't applied again to files created at its old path since
+	g.Expect(reloaded.files["/dir/b.go"].Deleted).To(BeFalse())
+	g.Expect(reloaded.files).ToNot(HaveKey("/moved/b.go"))
+}
+
+func TestChangeFeed_RemovesPartiallyWrittenChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	filePath := filepath.Join(t.TempDir(), "file-versions.json")
+	logPath := filepath.Join(t.TempDir(), "changes.log")
+	feed, err := NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = feed.RecordChange(entities.Change{Path: "/a.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(feed.Close()).To(Succeed())
+
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = logFile.Write([]byte(`{"sequence":2,"path":"/b`))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(logFile.Close()).To(Succeed())
+
+	reloaded, err := NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	recorded, err := reloaded.RecordChange(entities.Change{Path: "/b.go", Operation: entities.OperationCreated, Hash: "def", Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorded.Sequence).To(Equal(uint64(2)))
+	g.Expect(reloaded.Close()).To(Succeed())
+
+	reloaded, err = NewChangeFeed(filePath, logPath, t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	page, err := reloaded.ChangesSince(context.Background(), 0, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Changes).To(HaveLen(2))
+	g.Expect(page.Changes[1].Path).To(Equal("/b.go"))
+}
+
+func TestChangeFeed_WaitsForChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	feed, err := NewChangeFeed(filepath.Join(t.TempDir(), "file-versions.json"), filepath.Join(t.TempDir(), "changes.log"), t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = feed.RecordChange(entities.Change{Path: "/a.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "laptop"})
+	}()
+
+	page, err := feed.ChangesSince(context.Background(), 0, time.Minute)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Changes).To(HaveLen(1))
+	g.Expect(page.Changes[0].Path).To(Equal("/a.go"))
+
+	// nothing is recorded after it, so the wait runs out
+	page, err = feed.ChangesSince(context.Background(), 1, 10*time.Millisecond)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Changes).To(BeEmpty())
+	g.Expect(page.Latest).To(Equal(uint64(1)))
+}
+
+func TestChangeFeed_RecordDestinationEvent(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	feed, err := NewChangeFeed(filepath.Join(t.TempDir(), "file-versions.json"), filepath.Join(t.TempDir(), "changes.log"), destination)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = feed.RecordChange(entities.Change{Path: "/a.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "laptop"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the event for the change the server made for the app isn't recorded again
+	recorded, err := feed.RecordDestinationEvent(entities.FilesystemEvent{
+		Name:         filepath.Join(destination, "a.go"),
+		Operation:    entities.OperationRenamed,
+		PreviousPath: filepath.Join(destination, ".a.go.tmp-123"),
+		FileContents: entities.FileContents{Hash: "abc"},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorded).To(BeFalse())
+
+	recorded, err = feed.RecordDestinationEvent(entities.FilesystemEvent{
+		Name:         filepath.Join(destination, ".b.go.tmp-456"),
+		Operation:    entities.OperationCreated,
+		FileContents: entities.FileContents{Hash: "def"},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorded).To(BeFalse())
+
+	// a file replaced on disk by renaming another over it still exists, so its delete event is ignored
+	g.Expect(os.WriteFile(filepath.Join(destination, "a.go"), []byte("a"), 0o644)).To(Succeed())
+	recorded, err = feed.RecordDestinationEvent(entities.FilesystemEvent{
+		Name:      filepath.Join(destination, "a.go"),
+		Operation: entities.OperationDeleted,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorded).To(BeFalse())
+
+	recorded, err = feed.RecordDestinationEvent(entities.FilesystemEvent{
+		Name:         filepath.Join(destination, "a.go"),
+		Operation:    entities.OperationModified,
+		FileContents: entities.FileContents{Hash: "changed"},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorded).To(BeTrue())
+
+	page, err := feed.ChangesSince(context.Background(), 1, 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.Changes).To(HaveLen(1))
+	g.Expect(page.Changes[0].Path).To(Equal("/a.go"))
+	g.Expect(page.Changes[0].Origin).To(Equal(entities.ServerReplicaID))
+	g.Expect(page.Changes[0].Version).To(Equal(entities.VersionVector{"laptop": 1, "server": 1}))
+}
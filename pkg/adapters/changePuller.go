@@ -0,0 +1,538 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// changePollWait is how long each request for changes waits for one to be made before the ChangePuller asks again.
+const changePollWait = 30 * time.Second
+
+// ChangePuller is a struct that pulls the changes other replicas have made to the destination directory from the
+// server's change feed and applies them to the source directory. A change is only applied if its version vector follows
+// on from the one the app holds for the file, and never over a local change the server hasn't seen yet. The state each
+// applied change left behind is remembered, so that the events the app's own watcher publishes for it can be told
+// apart from changes made by the user and aren't sent back to the server.
+type ChangePuller struct {
+	mu               sync.Mutex
+	fetcher          ChangeFetcher
+	versions         VersionVectorStore
+	etags            ETagStore
+	sourceDirectory  string
+	stagingDirectory string
+	applied          map[string]appliedEntry
+}
+
+// appliedEntry is the state a pulled change left a path in the source directory in.
+type appliedEntry struct {
+	Exists      bool
+	IsDirectory bool
+	Hash        string
+}
+
+// ChangeFetcher is an interface that sets out the functions used by the ChangePuller to read from the server. This
+// allows for mocking of the RequestClient functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/changeFetcher.go  . "ChangeFetcher"
+type ChangeFetcher interface {
+	GetChanges(ctx context.Context, since uint64, wait time.Duration) (entities.ChangeFeedPage, error)
+	DownloadFile(path string, writer io.Writer) error
+}
+
+var _ ChangeFetcher = &RequestClient{}
+
+// NewChangePuller is a function that creates a ChangePuller for the source directory. Files are downloaded into
+// stagingDirectory before being moved into place, so the source directory never contains a partial download.
+func NewChangePuller(fetcher ChangeFetcher, versions VersionVectorStore, etags ETagStore, sourceDirectory, stagingDirectory string) (*ChangePuller, error) {
+	err := os.MkdirAll(stagingDirectory, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangePuller{
+		fetcher:          fetcher,
+		versions:         versions,
+		etags:            etags,
+		sourceDirectory:  filepath.Clean(sourceDirectory),
+		stagingDirectory: stagingDirectory,
+		applied:          make(map[string]appliedEntry),
+	}, nil
+}
+
+// Run is a function that long-polls the server for changes, applying each as soon as it is made, until the context is
+// cancelled. Failing to pull is logged and tried again after retryDelay.
+func (puller *ChangePuller) Run(ctx context.Context, retryDelay time.Duration) error {
+	for {
+		err := puller.pull(ctx, changePollWait)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("pulling changes from server", "err", err)
+
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// PullChanges is a function that applies every change in the server's change feed since the last one pulled. If the
+// server has started a new feed since then, the new feed is read from the start.
+func (puller *ChangePuller) PullChanges(ctx context.Context) error {
+	return puller.pull(ctx, 0)
+}
+
+// pull is a function that applies the changes in the server's change feed since the last one pulled, waiting up to wait
+// for one to be made if there are none.
+func (puller *ChangePuller) pull(ctx context.Context, wait time.Duration) error {
+	feedID, sequence := puller.versions.Cursor()
+
+	for {
+		page, err := puller.fetcher.GetChanges(ctx, sequence, wait)
+		if err != nil {
+			return err
+		}
+
+		if page.FeedID != feedID {
+			feedID = page.FeedID
+			if sequence != 0 {
+				sequence = 0
+				continue
+			}
+		}
+
+		for _, change := range page.Changes {
+			err := puller.applyChange(change)
+			if err != nil {
+				return err
+			}
+
+			sequence = change.Sequence
+			err = puller.versions.SetCursor(feedID, sequence)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(page.Changes) == 0 || sequence >= page.Latest {
+			return puller.versions.SetCursor(feedID, sequence)
+		}
+		// the rest of the feed is already waiting, so there is no need to wait for it
+		wait = 0
+	}
+}
+
+// DownloadMissing is a function that downloads every file and directory in the server's manifest that is missing from
+// the source directory.
+func (puller *ChangePuller) DownloadMissing(manifest []entities.ManifestEntry) error {
+	for _, entry := range manifest {
+		localPath := puller.localPath(entry.Path)
+		_, err := os.Lstat(localPath)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		switch {
+		case entry.IsDirectory:
+			err = os.MkdirAll(localPath, 0o755)
+		case entry.IsSymlink:
+			_, err = puller.createSymlink(entry.Path, entry.LinkTarget, localPath)
+		default:
+			_, err = puller.downloadFile(entry.Path, entry.Hash, localPath)
+		}
+		if err != nil {
+			return err
+		}
+
+		puller.recordApplied(localPath)
+	}
+
+	return nil
+}
+
+// RecordLocalEvent is a function that returns whether an event published by the app's watcher is for a change the
+// ChangePuller applied, which the server already has. Any other event is a local change, which is counted against the
+// app in the file's version vector, apart from changes to metadata, which leave the file's version as it is.
+func (puller *ChangePuller) RecordLocalEvent(event entities.FilesystemEvent) (bool, error) {
+	if puller.isEcho(event) {
+		return true, nil
+	}
+	if event.Operation == entities.OperationMetadata {
+		return false, nil
+	}
+
+	puller.mu.Lock()
+	delete(puller.applied, filepath.Clean(event.Name))
+	puller.mu.Unlock()
+
+	path := event.Name
+	if event.Operation == entities.OperationRenamed {
+		path = event.PreviousPath
+	}
+
+	return false, puller.versions.RecordLocalChange(puller.clientPath(path))
+}
+
+// applyChange is a function that applies a single change from the server's change feed to the source directory.
+func (puller *ChangePuller) applyChange(change entities.Change) error {
+	if change.Origin == puller.versions.ClientID() {
+		// the app's own change, which is already in the source directory
+		return puller.versions.MergeVersion(change.Path, change.Version)
+	}
+
+	localVersion := puller.versions.GetVersion(change.Path)
+	if change.Operation == entities.OperationRenamed {
+		localVersion = localVersion.Merge(puller.versions.GetVersion(change.PreviousPath))
+	}
+
+	switch change.Version.Compare(localVersion) {
+	case entities.VersionAfter:
+	case entities.VersionsConcurrent:
+		// the local change is still to be sent, the server resolves the conflict when it is
+		slog.Info("skipping change made concurrently with a local change", "path", change.Path)
+		return nil
+	default:
+		return nil
+	}
+
+	localPath := puller.localPath(change.Path)
+	applied, err := puller.apply(change, localPath)
+	if err != nil || !applied {
+		return err
+	}
+
+	puller.recordApplied(localPath)
+	return puller.versions.MergeVersion(change.Path, change.Version)
+}
+
+// apply is a function that makes a change to localPath, returning false if it was skipped because localPath has local
+// changes the server hasn't seen.
+func (puller *ChangePuller) apply(change entities.Change, localPath string) (bool, error) {
+	switch change.Operation {
+	case entities.OperationCreated, entities.OperationModified:
+		if change.IsDirectory {
+			return true, os.MkdirAll(localPath, 0o755)
+		}
+		if change.LinkTarget != "" {
+			return puller.createSymlink(change.Path, change.LinkTarget, localPath)
+		}
+		if change.HardLinkOf != "" {
+			return puller.createHardLink(change.Path, change.HardLinkOf, change.Hash, localPath)
+		}
+		return puller.downloadFile(change.Path, change.Hash, localPath)
+
+	case entities.OperationDeleted:
+		if puller.hasUnsyncedChanges(localPath) {
+			slog.Info("skipping delete of file with local changes", "path", change.Path)
+			return false, nil
+		}
+
+		err := os.RemoveAll(localPath)
+		if err != nil {
+			return false, err
+		}
+		return true, puller.etags.RemoveETags(change.Path)
+
+	case entities.OperationRenamed:
+		if puller.hasUnsyncedChanges(localPath) {
+			slog.Info("skipping rename over file with local changes", "path", change.Path)
+			return false, nil
+		}
+
+		previousPath := puller.localPath(change.PreviousPath)
+		_, err := os.Lstat(previousPath)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return false, err
+			}
+			// the app never had the file that was renamed, so it is fetched from its new path instead
+			if change.IsDirectory {
+				return true, os.MkdirAll(localPath, 0o755)
+			}
+			if change.LinkTarget != "" {
+				return puller.createSymlink(change.Path, change.LinkTarget, localPath)
+			}
+			return puller.downloadFile(change.Path, change.Hash, localPath)
+		}
+
+		err = os.MkdirAll(filepath.Dir(localPath), 0o755)
+		if err != nil {
+			return false, err
+		}
+		err = moveEntry(previousPath, localPath)
+		if err != nil {
+			return false, err
+		}
+		puller.recordApplied(previousPath)
+
+		err = puller.etags.MoveETags(change.PreviousPath, change.Path)
+		if err != nil {
+			return false, err
+		}
+		return true, puller.versions.MoveVersions(change.PreviousPath, change.Path)
+	}
+
+	slog.Warn("unknown change operation", "operation", change.Operation)
+	return false, nil
+}
+
+// downloadFile is a function that replaces the file at localPath with the server's copy of path. If localPath already
+// has the contents described by hash nothing is downloaded, and if it has been changed since the app last synced it the
+// download is skipped and false returned.
+func (puller *ChangePuller) downloadFile(path, hash, localPath string) (bool, error) {
+	matches, replaceable, err := puller.checkLocalCopy(path, hash, localPath)
+	if err != nil || !replaceable {
+		return false, err
+	}
+	if matches {
+		return true, puller.etags.SetETag(path, entities.ETag(hash))
+	}
+
+	tempFile, err := os.CreateTemp(puller.stagingDirectory, "download-*")
+	if err != nil {
+		return false, err
+	}
+	tempPath := tempFile.Name()
+
+	hasher := sha256.New()
+	err = puller.fetcher.DownloadFile(path, io.MultiWriter(tempFile, hasher))
+	if err == nil {
+		err = tempFile.Chmod(0o644)
+	}
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(localPath), 0o755)
+	}
+	if err == nil {
+		err = moveEntry(tempPath, localPath)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return false, err
+	}
+
+	return true, puller.etags.SetETag(path, entities.ETag(hex.EncodeToString(hasher.Sum(nil))))
+}
+
+// createSymlink is a function that replaces the entry at localPath with a symlink to target, the server's copy of path.
+// In the same way as downloadFile, nothing is done if localPath is already that symlink, and false is returned if it has
+// been changed since the app last synced it.
+func (puller *ChangePuller) createSymlink(path, target, localPath string) (bool, error) {
+	hash := entities.LinkTargetHash(target)
+	matches, replaceable, err := puller.checkLocalCopy(path, hash, localPath)
+	if err != nil || !replaceable {
+		return false, err
+	}
+
+	if !matches {
+		err = os.MkdirAll(filepath.Dir(localPath), 0o755)
+		if err == nil {
+			err = replaceSymlink(localPath, target)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, puller.etags.SetETag(path, entities.ETag(hash))
+}
+
+// createHardLink is a function that replaces the entry at localPath with a hard link to the local copy of linkedPath, as
+// the server's copy of path is a link to its copy of linkedPath. If the local copy isn't a file with the contents
+// described by hash, the server's copy of path is downloaded instead.
+func (puller *ChangePuller) createHardLink(path, linkedPath, hash, localPath string) (bool, error) {
+	localLinkedPath := puller.localPath(linkedPath)
+	info, err := os.Lstat(localLinkedPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return puller.downloadFile(path, hash, localPath)
+	}
+	linkedHash, err := hashFile(localLinkedPath)
+	if err != nil || linkedHash != hash {
+		return puller.downloadFile(path, hash, localPath)
+	}
+
+	_, replaceable, err := puller.checkLocalCopy(path, hash, localPath)
+	if err != nil || !replaceable {
+		return false, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(localPath), 0o755)
+	if err == nil {
+		err = replaceHardLink(localLinkedPath, localPath)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, puller.etags.SetETag(path, entities.ETag(hash))
+}
+
+// checkLocalCopy is a function that compares the entry at localPath with the server's copy of path, whose hash is
+// given. It returns whether they match, and whether localPath can be replaced, which it can't be if it is a directory
+// or has been changed since the app last synced it.
+func (puller *ChangePuller) checkLocalCopy(path, hash, localPath string) (bool, bool, error) {
+	info, err := os.Lstat(localPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, true, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	if info.IsDir() {
+		slog.Info("skipping download over local directory", "path", path)
+		return false, false, nil
+	}
+
+	localHash, err := hashEntry(localPath)
+	if err != nil {
+		return false, false, err
+	}
+	if localHash == hash {
+		return true, true, nil
+	}
+
+	etag, _ := puller.etags.GetETag(path)
+	if entities.ETag(localHash) != etag {
+		slog.Info("skipping download over file with local changes", "path", path)
+		return false, false, nil
+	}
+
+	return false, true, nil
+}
+
+// hasUnsyncedChanges is a function that returns whether any file at or within localPath differs from the version last
+// synced with the server.
+func (puller *ChangePuller) hasUnsyncedChanges(localPath string) bool {
+	unsynced := false
+	_ = filepath.WalkDir(localPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		hash, err := hashEntry(path)
+		etag, _ := puller.etags.GetETag(puller.clientPath(path))
+		if err != nil || entities.ETag(hash) != etag {
+			unsynced = true
+			return fs.SkipAll
+		}
+
+		return nil
+	})
+
+	return unsynced
+}
+
+// recordApplied is a function that remembers the state a pulled change left localPath, and anything within it, in.
+func (puller *ChangePuller) recordApplied(localPath string) {
+	puller.mu.Lock()
+	defer puller.mu.Unlock()
+
+	localPath = filepath.Clean(localPath)
+	for path := range puller.applied {
+		if path == localPath || strings.HasPrefix(path, localPath+string(filepath.Separator)) {
+			delete(puller.applied, path)
+		}
+	}
+
+	err := filepath.WalkDir(localPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		entry := appliedEntry{Exists: true, IsDirectory: d.IsDir()}
+		if !d.IsDir() {
+			entry.Hash, err = hashEntry(path)
+			if err != nil {
+				return err
+			}
+		}
+		puller.applied[path] = entry
+
+		return nil
+	})
+	if err != nil {
+		puller.applied[localPath] = appliedEntry{Exists: false}
+	}
+}
+
+// isEcho is a function that returns whether an event matches the state a pulled change left its path in.
+func (puller *ChangePuller) isEcho(event entities.FilesystemEvent) bool {
+	puller.mu.Lock()
+	defer puller.mu.Unlock()
+
+	name := filepath.Clean(event.Name)
+	switch event.Operation {
+	case entities.OperationCreated, entities.OperationModified:
+		entry, exists := puller.applied[name]
+		if !exists || !entry.Exists || entry.IsDirectory != event.FileContents.IsDirectory {
+			return false
+		}
+		return entry.IsDirectory || entry.Hash == event.FileContents.Hash
+
+	case entities.OperationDeleted:
+		entry, exists := puller.appliedAncestor(name)
+		return exists && !entry.Exists
+
+	case entities.OperationRenamed:
+		entry, exists := puller.applied[name]
+		previous, previousExists := puller.appliedAncestor(filepath.Clean(event.PreviousPath))
+		return exists && entry.Exists && previousExists && !previous.Exists
+	}
+
+	return false
+}
+
+// appliedAncestor is a function that returns the applied state of path, or of the closest directory containing it that
+// has one. Deleting or moving a directory takes everything within it along too.
+func (puller *ChangePuller) appliedAncestor(path string) (appliedEntry, bool) {
+	for {
+		entry, exists := puller.applied[path]
+		if exists {
+			return entry, true
+		}
+
+		parent := filepath.Dir(path)
+		if path == puller.sourceDirectory || parent == path {
+			return appliedEntry{}, false
+		}
+		path = parent
+	}
+}
+
+// localPath is a function that returns where a path sent to the server is in the source directory.
+func (puller *ChangePuller) localPath(path string) string {
+	return filepath.Join(puller.sourceDirectory, filepath.FromSlash(path))
+}
+
+// clientPath is a function that returns the path sent to the server for a path in the source directory.
+func (puller *ChangePuller) clientPath(localPath string) string {
+	relativePath, err := filepath.Rel(puller.sourceDirectory, localPath)
+	if err != nil {
+		return localPath
+	}
+
+	return "/" + filepath.ToSlash(relativePath)
+}
@@ -0,0 +1,262 @@
+package adapters
+
+import (
+	"context"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// contentsHash is the SHA-256 hash of "some content".
+const contentsHash = "290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"
+
+func newTestChangePuller(t *testing.T, fetcher ChangeFetcher) (*ChangePuller, *VersionVectorTracker, *ETagTracker, string) {
+	source := t.TempDir()
+	versions := newTestVersionTracker(t)
+	etags, err := NewETagTracker(filepath.Join(t.TempDir(), "etags"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	puller, err := NewChangePuller(fetcher, versions, etags, source, filepath.Join(t.TempDir(), "downloads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return puller, versions, etags, source
+}
+
+func downloadContents(path string, writer io.Writer) error {
+	_, err := writer.Write([]byte("some content"))
+	return err
+}
+
+func TestChangePuller_AppliesRemoteChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFetcher := mock_adapters.NewMockChangeFetcher(ctrl)
+	puller, versions, etags, source := newTestChangePuller(t, mockFetcher)
+
+	mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{
+		FeedID: "feed",
+		Latest: 3,
+		Changes: []entities.Change{
+			{Sequence: 1, Path: "/dir", Operation: entities.OperationCreated, IsDirectory: true, Origin: "desktop", Version: entities.VersionVector{"desktop": 1}},
+			{Sequence: 2, Path: "/dir/a.go", Operation: entities.OperationCreated, Hash: contentsHash, Origin: "desktop", Version: entities.VersionVector{"desktop": 1}},
+			{Sequence: 3, Path: "/mine.go", Operation: entities.OperationCreated, Origin: "laptop", Version: entities.VersionVector{"laptop": 1}},
+		},
+	}, nil).Times(1)
+	mockFetcher.EXPECT().DownloadFile("/dir/a.go", gomock.Any()).DoAndReturn(downloadContents).Times(1)
+
+	g.Expect(puller.PullChanges(context.Background())).To(Succeed())
+
+	contents, err := os.ReadFile(filepath.Join(source, "dir", "a.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(contents)).To(Equal("some content"))
+	etag, _ := etags.GetETag("/dir/a.go")
+	g.Expect(etag).To(Equal(entities.ETag(contentsHash)))
+	g.Expect(versions.GetVersion("/dir/a.go")).To(Equal(entities.VersionVector{"desktop": 1}))
+	g.Expect(versions.GetVersion("/mine.go")).To(Equal(entities.VersionVector{"laptop": 1}))
+	feedID, sequence := versions.Cursor()
+	g.Expect(feedID).To(Equal("feed"))
+	g.Expect(sequence).To(Equal(uint64(3)))
+
+	// the watcher's events for the downloaded file aren't local changes
+	echo, err := puller.RecordLocalEvent(entities.FilesystemEvent{
+		Name:         filepath.Join(source, "dir", "a.go"),
+		Operation:    entities.OperationCreated,
+		FileContents: entities.FileContents{Hash: contentsHash},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(echo).To(BeTrue())
+
+	echo, err = puller.RecordLocalEvent(entities.FilesystemEvent{
+		Name:         filepath.Join(source, "dir", "a.go"),
+		Operation:    entities.OperationModified,
+		FileContents: entities.FileContents{Hash: "edited"},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(echo).To(BeFalse())
+	g.Expect(versions.GetVersion("/dir/a.go")).To(Equal(entities.VersionVector{"desktop": 1, "laptop": 1}))
+}
+
+func TestChangePuller_SkipsChangesThatDontFollowOnFromLocalVersion(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFetcher := mock_adapters.NewMockChangeFetcher(ctrl)
+	puller, versions, _, source := newTestChangePuller(t, mockFetcher)
+
+	g.Expect(versions.MergeVersion("/seen.go", entities.VersionVector{"desktop": 2})).To(Succeed())
+	g.Expect(versions.MergeVersion("/pending.go", entities.VersionVector{"desktop": 1})).To(Succeed())
+	g.Expect(versions.RecordLocalChange("/pending.go")).To(Succeed())
+
+	mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{
+		FeedID: "feed",
+		Latest: 2,
+		Changes: []entities.Change{
+			{Sequence: 1, Path: "/seen.go", Operation: entities.OperationDeleted, Origin: "desktop", Version: entities.VersionVector{"desktop": 2}},
+			{Sequence: 2, Path: "/pending.go", Operation: entities.OperationModified, Hash: contentsHash, Origin: "desktop", Version: entities.VersionVector{"desktop": 2}},
+		},
+	}, nil).Times(1)
+	mockFetcher.EXPECT().DownloadFile(gomock.Any(), gomock.Any()).Times(0)
+
+	g.Expect(os.WriteFile(filepath.Join(source, "seen.go"), []byte("seen"), 0o644)).To(Succeed())
+
+	g.Expect(puller.PullChanges(context.Background())).To(Succeed())
+	g.Expect(filepath.Join(source, "seen.go")).To(BeAnExistingFile())
+	g.Expect(versions.GetVersion("/pending.go")).To(Equal(entities.VersionVector{"desktop": 1, "laptop": 1}))
+}
+
+func TestChangePuller_SkipsFilesWithUnsyncedChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFetcher := mock_adapters.NewMockChangeFetcher(ctrl)
+	puller, versions, etags, source := newTestChangePuller(t, mockFetcher)
+
+	g.Expect(etags.SetETag("/a.go", `"synced"`)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(source, "a.go"), []byte("edited"), 0o644)).To(Succeed())
+
+	mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{
+		FeedID: "feed",
+		Latest: 2,
+		Changes: []entities.Change{
+			{Sequence: 1, Path: "/a.go", Operation: entities.OperationModified, Hash: contentsHash, Origin: "desktop", Version: entities.VersionVector{"desktop": 1}},
+			{Sequence: 2, Path: "/a.go", Operation: entities.OperationDeleted, Origin: "desktop", Version: entities.VersionVector{"desktop": 2}},
+		},
+	}, nil).Times(1)
+	mockFetcher.EXPECT().DownloadFile(gomock.Any(), gomock.Any()).Times(0)
+
+	g.Expect(puller.PullChanges(context.Background())).To(Succeed())
+
+	contents, err := os.ReadFile(filepath.Join(source, "a.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(contents)).To(Equal("edited"))
+	g.Expect(versions.GetVersion("/a.go")).To(BeEmpty())
+}
+
+func TestChangePuller_AppliesRenamesAndDeletes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFetcher := mock_adapters.NewMockChangeFetcher(ctrl)
+	puller, versions, etags, source := newTestChangePuller(t, mockFetcher)
+
+	g.Expect(os.MkdirAll(filepath.Join(source, "dir"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(source, "dir", "a.go"), []byte("some content"), 0o644)).To(Succeed())
+	g.Expect(etags.SetETag("/dir/a.go", entities.ETag(contentsHash))).To(Succeed())
+
+	mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{
+		FeedID: "feed",
+		Latest: 2,
+		Changes: []entities.Change{
+			{Sequence: 1, Path: "/moved", PreviousPath: "/dir", Operation: entities.OperationRenamed, IsDirectory: true, Origin: "desktop", Version: entities.VersionVector{"desktop": 1}},
+			{Sequence: 2, Path: "/moved/a.go", Operation: entities.OperationDeleted, Origin: "server", Version: entities.VersionVector{"server": 1}},
+		},
+	}, nil).Times(1)
+
+	g.Expect(puller.PullChanges(context.Background())).To(Succeed())
+
+	g.Expect(filepath.Join(source, "dir")).ToNot(BeADirectory())
+	g.Expect(filepath.Join(source, "moved")).To(BeADirectory())
+	g.Expect(filepath.Join(source, "moved", "a.go")).ToNot(BeAnExistingFile())
+	_, exists := etags.GetETag("/moved/a.go")
+	g.Expect(exists).To(BeFalse())
+	g.Expect(versions.GetVersion("/moved")).To(Equal(entities.VersionVector{"desktop": 1}))
+
+	echo, err := puller.RecordLocalEvent(entities.FilesystemEvent{
+		Name:         filepath.Join(source, "moved"),
+		PreviousPath: filepath.Join(source, "dir"),
+		Operation:    entities.OperationRenamed,
+		FileContents: entities.FileContents{IsDirectory: true},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(echo).To(BeTrue())
+
+	echo, err = puller.RecordLocalEvent(entities.FilesystemEvent{
+		Name:      filepath.Join(source, "moved", "a.go"),
+		Operation: entities.OperationDeleted,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(echo).To(BeTrue())
+}
+
+func TestChangePuller_RestartsFromNewFeed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFetcher := mock_adapters.NewMockChangeFetcher(ctrl)
+	puller, versions, _, _ := newTestChangePuller(t, mockFetcher)
+
+	g.Expect(versions.SetCursor("old", 5)).To(Succeed())
+
+	gomock.InOrder(
+		mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(5), time.Duration(0)).Return(entities.ChangeFeedPage{FeedID: "new", Latest: 1}, nil),
+		mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{
+			FeedID: "new",
+			Latest: 1,
+			Changes: []entities.Change{
+				{Sequence: 1, Path: "/dir", Operation: entities.OperationCreated, IsDirectory: true, Origin: "desktop", Version: entities.VersionVector{"desktop": 1}},
+			},
+		}, nil),
+	)
+
+	g.Expect(puller.PullChanges(context.Background())).To(Succeed())
+	feedID, sequence := versions.Cursor()
+	g.Expect(feedID).To(Equal("new"))
+	g.Expect(sequence).To(Equal(uint64(1)))
+}
+
+func TestChangePuller_AppliesRemoteSymlinks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFetcher := mock_adapters.NewMockChangeFetcher(ctrl)
+	puller, _, etags, source := newTestChangePuller(t, mockFetcher)
+
+	mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{
+		FeedID: "feed",
+		Latest: 2,
+		Changes: []entities.Change{
+			{Sequence: 1, Path: "/dir/link", Operation: entities.OperationCreated, Hash: entities.LinkTargetHash("a.go"), LinkTarget: "a.go", Origin: "desktop", Version: entities.VersionVector{"desktop": 1}},
+			{Sequence: 2, Path: "/dir/link", Operation: entities.OperationModified, Hash: entities.LinkTargetHash("b.go"), LinkTarget: "b.go", Origin: "desktop", Version: entities.VersionVector{"desktop": 2}},
+		},
+	}, nil).Times(1)
+
+	g.Expect(puller.PullChanges(context.Background())).To(Succeed())
+
+	// the link is created without anything being downloaded
+	g.Expect(os.Readlink(filepath.Join(source, "dir", "link"))).To(Equal("b.go"))
+	etag, _ := etags.GetETag("/dir/link")
+	g.Expect(etag).To(Equal(entities.ETag(entities.LinkTargetHash("b.go"))))
+}
+
+func TestChangePuller_AppliesRemoteHardLinks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFetcher := mock_adapters.NewMockChangeFetcher(ctrl)
+	puller, _, etags, source := newTestChangePuller(t, mockFetcher)
+
+	g.Expect(os.WriteFile(filepath.Join(source, "a.go"), []byte("some content"), 0o644)).To(Succeed())
+
+	mockFetcher.EXPECT().GetChanges(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{
+		FeedID: "feed",
+		Latest: 1,
+		Changes: []entities.Change{
+			{Sequence: 1, Path: "/dir/b.go", Operation: entities.OperationCreated, Hash: contentsHash, HardLinkOf: "/a.go", Origin: "desktop", Version: entities.VersionVector{"desktop": 1}},
+		},
+	}, nil).Times(1)
+
+	g.Expect(puller.PullChanges(context.Background())).To(Succeed())
+
+	// the link is made to the local copy without anything being downloaded
+	linkedInfo, err := os.Stat(filepath.Join(source, "a.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+	info, err := os.Stat(filepath.Join(source, "dir", "b.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(os.SameFile(linkedInfo, info)).To(BeTrue())
+	etag, _ := etags.GetETag("/dir/b.go")
+	g.Expect(etag).To(Equal(entities.ETag(contentsHash)))
+}
@@ -3,13 +3,43 @@ package adapters
 import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"log/slog"
+	"time"
+)
+
+const (
+	MonitorModeInotify = "inotify"
+	MonitorModePoll    = "poll"
 )
 
 type Config struct {
-	SourceDirectory      string `yaml:"source-directory"`
-	DestinationDirectory string `yaml:"destination-directory"`
-	BaseURL              string `yaml:"base-url"`
-	UseAbsolutePaths     bool   `yaml:"use-absolute-paths"`
+	SourceDirectory          string        `yaml:"source-directory"`
+	DestinationDirectory     string        `yaml:"destination-directory"`
+	BaseURL                  string        `yaml:"base-url"`
+	UseAbsolutePaths         bool          `yaml:"use-absolute-paths"`
+	MonitorMode              string        `yaml:"monitor-mode" env-default:"inotify"`
+	StateFile                string        `yaml:"state-file" env-default:"dropbox-state.json"`
+	ServerDataDirectory      string        `yaml:"server-data-directory" env-default:".dropbox-server"`
+	DisableDeletePropagation bool          `yaml:"disable-delete-propagation"`
+	DisableDeltaSync         bool          `yaml:"disable-delta-sync"`
+	RetryInitialDelay        time.Duration `yaml:"retry-initial-delay" env-default:"1s"`
+	RetryMaxDelay            time.Duration `yaml:"retry-max-delay" env-default:"5m"`
+	VersionRetentionCount    int           `yaml:"version-retention-count" env-default:"10"`
+	VersionRetentionAge      time.Duration `yaml:"version-retention-age" env-default:"720h"`
+	TrashRetentionAge        time.Duration `yaml:"trash-retention-age" env-default:"720h"`
+	TrashMaxEntries          int           `yaml:"trash-max-entries" env-default:"1000"`
+	ConflictPolicy           string        `yaml:"conflict-policy"`
+	ServerConflictPolicy     string        `yaml:"server-conflict-policy" env-default:"keep-both"`
+	TwoWaySync               bool          `yaml:"two-way-sync"`
+	ClientID                 string        `yaml:"client-id"`
+	PullRetryDelay           time.Duration `yaml:"pull-retry-delay" env-default:"5s"`
+	BatchMaxEvents           int           `yaml:"batch-max-events" env-default:"100"`
+	BatchMaxBytes            int64         `yaml:"batch-max-bytes" env-default:"4194304"`
+	BatchWindow              time.Duration `yaml:"batch-window" env-default:"100ms"`
+	SendConcurrency          int           `yaml:"send-concurrency" env-default:"4"`
+	DebounceDelay            time.Duration `yaml:"debounce-delay" env-default:"500ms"`
+	IgnorePatterns           []string      `yaml:"ignore-patterns"`
+	PreserveOwnership        bool          `yaml:"preserve-ownership"`
+	SymlinkPolicy            string        `yaml:"symlink-policy" env-default:"keep"`
 }
 
 func NewConfig() (*Config, error) {
@@ -0,0 +1,92 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// ConflictLog is a struct that keeps a record of every conflict and how it was resolved, one JSON object per line in an
+// append only file.
+type ConflictLog struct {
+	mu       sync.Mutex
+	filePath string
+}
+
+var _ ConflictRecorder = &ConflictLog{}
+
+// NewConflictLog is a function that creates a ConflictLog which appends to the file at filePath.
+func NewConflictLog(filePath string) *ConflictLog {
+	return &ConflictLog{
+		filePath: filePath,
+	}
+}
+
+// ConflictRecorder is an interface that sets out the functions implemented by the ConflictLog. This allows for mocking
+// of the ConflictLog functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/conflictRecorder.go  . "ConflictRecorder"
+type ConflictRecorder interface {
+	RecordConflict(conflict entities.Conflict) error
+}
+
+// RecordConflict is a function that appends a conflict to the log. The conflict is on disk by the time it returns.
+func (log *ConflictLog) RecordConflict(conflict entities.Conflict) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	conflictBytes, err := json.Marshal(conflict)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(log.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(conflictBytes, '\n'))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// ListConflicts is a function that returns every conflict in the log, oldest first.
+func (log *ConflictLog) ListConflicts() ([]entities.Conflict, error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	conflicts := make([]entities.Conflict, 0)
+
+	file, err := os.Open(log.filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return conflicts, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var conflict entities.Conflict
+		err = json.Unmarshal(scanner.Bytes(), &conflict)
+		if err != nil {
+			// a partially written final line is expected if the process stopped mid write
+			slog.Warn("skipping unreadable conflict log entry", "err", err)
+			continue
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts, scanner.Err()
+}
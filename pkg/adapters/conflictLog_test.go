@@ -0,0 +1,40 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConflictLog_RecordAndList(t *testing.T) {
+	g := NewGomegaWithT(t)
+	log := NewConflictLog(filepath.Join(t.TempDir(), "conflicts.log"))
+
+	conflicts, err := log.ListConflicts()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflicts).To(BeEmpty())
+
+	g.Expect(log.RecordConflict(entities.Conflict{Path: "/a.go", Policy: entities.ConflictPolicyFail})).To(Succeed())
+	g.Expect(log.RecordConflict(entities.Conflict{Path: "/b.go", Policy: entities.ConflictPolicySourceWins})).To(Succeed())
+
+	conflicts, err = log.ListConflicts()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflicts).To(HaveLen(2))
+	g.Expect(conflicts[0].Path).To(Equal("/a.go"))
+	g.Expect(conflicts[1].Path).To(Equal("/b.go"))
+}
+
+func TestConflictLog_SkipsPartiallyWrittenEntry(t *testing.T) {
+	g := NewGomegaWithT(t)
+	filePath := filepath.Join(t.TempDir(), "conflicts.log")
+	log := NewConflictLog(filePath)
+
+	g.Expect(os.WriteFile(filePath, []byte(`{"path":"/a.go","policy":"fail"}`+"\n"+`{"path":"/b.g`), 0o600)).To(Succeed())
+
+	conflicts, err := log.ListConflicts()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflicts).To(HaveLen(1))
+	g.Expect(conflicts[0].Path).To(Equal("/a.go"))
+}
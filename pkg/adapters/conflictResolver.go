@@ -0,0 +1,137 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ConflictResolver is a struct that resolves conflicts on the server, where a request changes a file in the destination
+// directory that has changed since the client last saw it. Conflicts are resolved with the policy sent by the client,
+// or the server's default policy if there isn't one, and are recorded in the conflict log.
+type ConflictResolver struct {
+	destinationPath string
+	defaultPolicy   string
+	host            string
+	log             *ConflictLog
+}
+
+var _ ConflictHandler = &ConflictResolver{}
+
+// NewConflictResolver is a function that creates a ConflictResolver for the destination directory. host is used to name
+// the conflicted copies it makes.
+func NewConflictResolver(destinationPath, defaultPolicy, host string, log *ConflictLog) *ConflictResolver {
+	return &ConflictResolver{
+		destinationPath: destinationPath,
+		defaultPolicy:   defaultPolicy,
+		host:            host,
+		log:             log,
+	}
+}
+
+// ConflictHandler is an interface that sets out the functions implemented by the ConflictResolver. This allows for
+// mocking of the ConflictResolver functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/conflictHandler.go  . "ConflictHandler"
+type ConflictHandler interface {
+	ResolveConflict(path string, conflict entities.Conflict) (entities.Conflict, error)
+	ListConflicts() ([]entities.Conflict, error)
+}
+
+// ResolveConflict is a function that settles on the policy for a conflict on the file at path and records it. If both
+// copies are to be kept, the destination's copy is moved aside to a conflicted copy so that the change can be applied
+// at path. It is up to the caller to apply or drop the change according to the returned conflict's policy.
+func (resolver *ConflictResolver) ResolveConflict(path string, conflict entities.Conflict) (entities.Conflict, error) {
+	if conflict.Policy == "" {
+		conflict.Policy = resolver.defaultPolicy
+	}
+	if conflict.DetectedAt.IsZero() {
+		conflict.DetectedAt = time.Now().UTC()
+	}
+
+	if conflict.Policy == entities.ConflictPolicyKeepBoth {
+		copyPath, err := resolver.preserveConflictedCopy(path, conflict.DetectedAt)
+		if err != nil {
+			return entities.Conflict{}, fmt.Errorf("keeping conflicted copy: %w", err)
+		}
+		conflict.ConflictedCopy = copyPath
+	}
+
+	slog.Warn("resolved conflict", "path", conflict.Path, "operation", conflict.Operation, "policy", conflict.Policy,
+		"conflictedCopy", conflict.ConflictedCopy)
+
+	// the conflict has already been acted on, so failing to log it doesn't fail the request
+	err := resolver.log.RecordConflict(conflict)
+	if err != nil {
+		slog.Error("recording conflict", "path", conflict.Path, "err", err)
+	}
+
+	return conflict, nil
+}
+
+// ListConflicts is a function that returns every conflict the server has resolved, oldest first.
+func (resolver *ConflictResolver) ListConflicts() ([]entities.Conflict, error) {
+	return resolver.log.ListConflicts()
+}
+
+// preserveConflictedCopy is a function that moves the file or directory at path to a sibling named as a conflicted copy,
+// returning the new path relative to the destination directory. Nothing is moved if path doesn't exist.
+func (resolver *ConflictResolver) preserveConflictedCopy(path string, detectedAt time.Time) (string, error) {
+	_, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	copyPath := conflictedCopyPath(path, resolver.host, detectedAt)
+	for attempt := 2; ; attempt++ {
+		_, err = os.Lstat(copyPath)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		copyPath = conflictedCopyPath(path, fmt.Sprintf("%s %d", resolver.host, attempt), detectedAt)
+	}
+
+	err = os.Rename(path, copyPath)
+	if err != nil {
+		return "", err
+	}
+
+	err = syncDirectory(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+
+	relativePath, err := filepath.Rel(resolver.destinationPath, copyPath)
+	if err != nil {
+		return "", err
+	}
+
+	return "/" + filepath.ToSlash(relativePath), nil
+}
+
+// conflictedCopyPath is a function that returns the path of a conflicted copy of the file at path, in the form
+// "name (conflicted copy <host> <date>).ext".
+func conflictedCopyPath(path, host string, detectedAt time.Time) string {
+	base := filepath.Base(path)
+	extension := filepath.Ext(base)
+	if extension == base {
+		// a dotfile such as .bashrc is all name and no extension
+		extension = ""
+	}
+
+	name := fmt.Sprintf("%s (conflicted copy %s %s)%s", strings.TrimSuffix(base, extension), host,
+		detectedAt.Format(time.DateOnly), extension)
+
+	return filepath.Join(filepath.Dir(path), name)
+}
@@ -0,0 +1,73 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConflictResolver_KeepBothMovesDestinationCopyAside(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	resolver := NewConflictResolver(destination, entities.ConflictPolicyKeepBoth, "host", NewConflictLog(filepath.Join(t.TempDir(), "conflicts.log")))
+	path := filepath.Join(destination, "file.go")
+	detectedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	g.Expect(os.WriteFile(path, []byte("first"), 0o644)).To(Succeed())
+	conflict, err := resolver.ResolveConflict(path, entities.Conflict{Path: "/file.go", Operation: entities.OperationModified, DetectedAt: detectedAt})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflict.Policy).To(Equal(entities.ConflictPolicyKeepBoth))
+	g.Expect(conflict.ConflictedCopy).To(Equal("/file (conflicted copy host 2026-10-18).go"))
+	g.Expect(path).ToNot(BeAnExistingFile())
+	g.Expect(os.ReadFile(filepath.Join(destination, "file (conflicted copy host 2026-10-18).go"))).To(Equal([]byte("first")))
+
+	// a second conflict on the same day doesn't replace the first copy
+	g.Expect(os.WriteFile(path, []byte("second"), 0o644)).To(Succeed())
+	conflict, err = resolver.ResolveConflict(path, entities.Conflict{Path: "/file.go", Operation: entities.OperationModified, DetectedAt: detectedAt})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflict.ConflictedCopy).To(Equal("/file (conflicted copy host 2 2026-10-18).go"))
+	g.Expect(os.ReadFile(filepath.Join(destination, "file (conflicted copy host 2 2026-10-18).go"))).To(Equal([]byte("second")))
+
+	conflicts, err := resolver.ListConflicts()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflicts).To(HaveLen(2))
+	g.Expect(conflicts[0].ConflictedCopy).To(Equal("/file (conflicted copy host 2026-10-18).go"))
+	g.Expect(conflicts[1].ConflictedCopy).To(Equal("/file (conflicted copy host 2 2026-10-18).go"))
+}
+
+func TestConflictResolver_RequestedPolicyOverridesDefault(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	resolver := NewConflictResolver(destination, entities.ConflictPolicyKeepBoth, "host", NewConflictLog(filepath.Join(t.TempDir(), "conflicts.log")))
+	path := filepath.Join(destination, "file.go")
+
+	g.Expect(os.WriteFile(path, []byte("contents"), 0o644)).To(Succeed())
+	conflict, err := resolver.ResolveConflict(path, entities.Conflict{Path: "/file.go", Policy: entities.ConflictPolicyFail})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflict.Policy).To(Equal(entities.ConflictPolicyFail))
+	g.Expect(conflict.ConflictedCopy).To(BeEmpty())
+	g.Expect(conflict.DetectedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("contents")))
+}
+
+func TestConflictResolver_KeepBothMissingFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	resolver := NewConflictResolver(destination, entities.ConflictPolicyKeepBoth, "host", NewConflictLog(filepath.Join(t.TempDir(), "conflicts.log")))
+
+	conflict, err := resolver.ResolveConflict(filepath.Join(destination, "file.go"), entities.Conflict{Path: "/file.go"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(conflict.ConflictedCopy).To(BeEmpty())
+}
+
+func TestConflictedCopyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	detectedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	g.Expect(conflictedCopyPath("/dest/dir/file.tar.gz", "host", detectedAt)).To(Equal("/dest/dir/file.tar (conflicted copy host 2026-10-18).gz"))
+	g.Expect(conflictedCopyPath("/dest/.bashrc", "host", detectedAt)).To(Equal("/dest/.bashrc (conflicted copy host 2026-10-18)"))
+	g.Expect(conflictedCopyPath("/dest/Makefile", "host", detectedAt)).To(Equal("/dest/Makefile (conflicted copy host 2026-10-18)"))
+}
@@ -0,0 +1,281 @@
+package adapters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"math"
+)
+
+const (
+	// deltaSyncThreshold is the smallest modified file that is sent as a delta, for anything smaller the signature
+	// would cost about as much as sending the whole file.
+	deltaSyncThreshold = 64 << 10
+
+	minDeltaBlockSize = 2 << 10
+	maxDeltaBlockSize = 128 << 10
+)
+
+// ErrDeltaUnavailable is returned when a file cannot be sent as a delta, such as when the server does not have a copy
+// of it or most of the file has changed. Callers should fall back to sending the whole file when they receive it.
+var ErrDeltaUnavailable = errors.New("delta transfer unavailable")
+
+// deltaBlockSize is a function that picks the block size used to sign a file. Like rsync, it grows with the square root
+// of the file size so that the number of blocks, and so the size of the signature, grows slowly for large files.
+func deltaBlockSize(size int64) int {
+	blockSize := int(math.Sqrt(float64(size)))
+	return min(max(blockSize, minDeltaBlockSize), maxDeltaBlockSize)
+}
+
+// buildFileSignature is a function that splits the contents of reader into blocks of blockSize bytes and returns the
+// checksums of each block, along with a hash of the whole file.
+func buildFileSignature(reader io.Reader, blockSize int) (entities.FileSignature, error) {
+	signature := entities.FileSignature{
+		BlockSize: blockSize,
+		Blocks:    make([]entities.BlockSignature, 0),
+	}
+
+	hasher := sha256.New()
+	block := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(reader, block)
+		if n > 0 {
+			hasher.Write(block[:n])
+			strong := sha256.Sum256(block[:n])
+			signature.Blocks = append(signature.Blocks, entities.BlockSignature{
+				Weak:   newRollingChecksum(block[:n]).digest(),
+				Strong: hex.EncodeToString(strong[:]),
+			})
+			signature.Size += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return entities.FileSignature{}, err
+		}
+	}
+
+	signature.Hash = hex.EncodeToString(hasher.Sum(nil))
+	return signature, nil
+}
+
+// computeDelta is a function that compares the contents of reader against the signature of the server's copy of a file
+// and returns the operations needed to turn the server's copy into it, along with a hash of the contents. Like rsync, a
+// rolling checksum is used to find blocks the server already has at any offset, not just at block boundaries, so
+// insertions and deletions only cost the bytes that changed. If more than literalLimit bytes would need to be sent,
+// ErrDeltaUnavailable is returned.
+func computeDelta(reader io.Reader, signature entities.FileSignature, literalLimit int) ([]entities.DeltaOperation, string, error) {
+	blockSize := signature.BlockSize
+	if blockSize <= 0 {
+		return nil, "", ErrDeltaUnavailable
+	}
+
+	blocksByWeak := make(map[uint32][]int, len(signature.Blocks))
+	for index, block := range signature.Blocks {
+		blocksByWeak[block.Weak] = append(blocksByWeak[block.Weak], index)
+	}
+
+	hasher := sha256.New()
+	window := &deltaWindow{
+		reader: io.TeeReader(reader, hasher),
+		buf:    make([]byte, 0, 2*blockSize),
+	}
+
+	operations := make([]entities.DeltaOperation, 0)
+	var literal []byte
+	literalSize := 0
+	flushLiteral := func() {
+		if len(literal) > 0 {
+			operations = append(operations, entities.DeltaOperation{Data: literal})
+			literal = nil
+		}
+	}
+
+	err := window.fill(blockSize)
+	if err != nil {
+		return nil, "", err
+	}
+	n := window.available(blockSize)
+	checksum := newRollingChecksum(window.bytes(n))
+
+	for n > 0 {
+		current := window.bytes(n)
+		index, matched := matchBlock(blocksByWeak, signature.Blocks, checksum.digest(), current)
+		if matched {
+			flushLiteral()
+			operations = appendBlockCopy(operations, index)
+
+			window.pos += n
+			err = window.fill(blockSize)
+			if err != nil {
+				return nil, "", err
+			}
+			n = window.available(blockSize)
+			checksum = newRollingChecksum(window.bytes(n))
+			continue
+		}
+
+		// no block starts here, so the first byte of the window has to be sent and the window moves along by one
+		out := current[0]
+		literal = append(literal, out)
+		literalSize++
+		if literalSize > literalLimit {
+			return nil, "", ErrDeltaUnavailable
+		}
+
+		window.pos++
+		err = window.fill(n)
+		if err != nil {
+			return nil, "", err
+		}
+		if window.available(n) == n {
+			checksum.roll(out, window.bytes(n)[n-1])
+		} else {
+			// the end of the file has been reached, so the window shrinks
+			checksum.rollOut(out)
+			n--
+		}
+	}
+	flushLiteral()
+
+	return operations, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// applyDelta is a function that writes the file described by delta to writer, copying blocks from base where possible.
+func applyDelta(base io.ReaderAt, baseSize int64, delta entities.Delta, writer io.Writer) error {
+	if delta.BlockSize <= 0 {
+		return entities.ErrInvalidDelta
+	}
+	blockSize := int64(delta.BlockSize)
+	blockCount := (baseSize + blockSize - 1) / blockSize
+
+	for _, operation := range delta.Operations {
+		if operation.BlockCount == 0 {
+			_, err := writer.Write(operation.Data)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		start := int64(operation.BlockIndex)
+		end := start + int64(operation.BlockCount)
+		if start < 0 || operation.BlockCount < 0 || end > blockCount {
+			return entities.ErrInvalidDelta
+		}
+
+		offset := start * blockSize
+		length := min(end*blockSize, baseSize) - offset
+		_, err := io.Copy(writer, io.NewSectionReader(base, offset, length))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchBlock is a function that returns the index of a block with the same checksums as data. The strong hash is only
+// calculated when the weak checksum matches.
+func matchBlock(blocksByWeak map[uint32][]int, blocks []entities.BlockSignature, weak uint32, data []byte) (int, bool) {
+	candidates, exists := blocksByWeak[weak]
+	if !exists {
+		return 0, false
+	}
+
+	sum := sha256.Sum256(data)
+	strong := hex.EncodeToString(sum[:])
+	for _, index := range candidates {
+		if blocks[index].Strong == strong {
+			return index, true
+		}
+	}
+
+	return 0, false
+}
+
+// appendBlockCopy is a function that adds a copy of a single block to the operations, extending the previous copy if it
+// ends at the block before.
+func appendBlockCopy(operations []entities.DeltaOperation, index int) []entities.DeltaOperation {
+	if len(operations) > 0 {
+		last := &operations[len(operations)-1]
+		if last.BlockCount > 0 && last.BlockIndex+last.BlockCount == index {
+			last.BlockCount++
+			return operations
+		}
+	}
+
+	return append(operations, entities.DeltaOperation{BlockIndex: index, BlockCount: 1})
+}
+
+// deltaWindow is a struct that buffers the data being compared against a signature, so that the window can slide along
+// it one byte at a time without reading the whole file into memory.
+type deltaWindow struct {
+	reader io.Reader
+	buf    []byte
+	pos    int
+	eof    bool
+}
+
+// fill is a function that reads until at least n bytes are buffered from pos onwards, or the end of the data is reached.
+func (window *deltaWindow) fill(n int) error {
+	for len(window.buf)-window.pos < n && !window.eof {
+		if window.pos > 0 {
+			window.buf = window.buf[:copy(window.buf, window.buf[window.pos:])]
+			window.pos = 0
+		}
+
+		read, err := window.reader.Read(window.buf[len(window.buf):cap(window.buf)])
+		window.buf = window.buf[:len(window.buf)+read]
+		if errors.Is(err, io.EOF) {
+			window.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (window *deltaWindow) available(n int) int {
+	return min(n, len(window.buf)-window.pos)
+}
+
+func (window *deltaWindow) bytes(n int) []byte {
+	return window.buf[window.pos : window.pos+n]
+}
+
+// rollingChecksum is the weak checksum used by rsync. It can be moved along by one byte in constant time, by removing
+// the byte leaving the window and adding the byte entering it.
+type rollingChecksum struct {
+	a, b   uint32
+	length uint32
+}
+
+func newRollingChecksum(data []byte) rollingChecksum {
+	checksum := rollingChecksum{length: uint32(len(data))}
+	for i, c := range data {
+		checksum.a += uint32(c)
+		checksum.b += uint32(len(data)-i) * uint32(c)
+	}
+
+	return checksum
+}
+
+func (checksum *rollingChecksum) roll(out, in byte) {
+	checksum.a += uint32(in) - uint32(out)
+	checksum.b += checksum.a - checksum.length*uint32(out)
+}
+
+func (checksum *rollingChecksum) rollOut(out byte) {
+	checksum.a -= uint32(out)
+	checksum.b -= checksum.length * uint32(out)
+	checksum.length--
+}
+
+func (checksum rollingChecksum) digest() uint32 {
+	return (checksum.b&0xffff)<<16 | checksum.a&0xffff
+}
@@ -0,0 +1,104 @@
+package adapters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"math/rand"
+	"testing"
+)
+
+func deltaRoundTrip(t *testing.T, base, updated []byte, blockSize int) ([]entities.DeltaOperation, []byte) {
+	t.Helper()
+	g := NewGomegaWithT(t)
+
+	signature, err := buildFileSignature(bytes.NewReader(base), blockSize)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	operations, hash, err := computeDelta(bytes.NewReader(updated), signature, len(updated))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	expectedHash := sha256.Sum256(updated)
+	g.Expect(hash).To(Equal(hex.EncodeToString(expectedHash[:])))
+
+	var rebuilt bytes.Buffer
+	err = applyDelta(bytes.NewReader(base), int64(len(base)), entities.Delta{
+		Hash:       hash,
+		BlockSize:  blockSize,
+		Operations: operations,
+	}, &rebuilt)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return operations, rebuilt.Bytes()
+}
+
+func literalSize(operations []entities.DeltaOperation) int {
+	size := 0
+	for _, operation := range operations {
+		size += len(operation.Data)
+	}
+	return size
+}
+
+func TestComputeDelta_SingleByteChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	base := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(base)
+	updated := bytes.Clone(base)
+	updated[5000] ^= 0xff
+
+	operations, rebuilt := deltaRoundTrip(t, base, updated, 1000)
+	g.Expect(rebuilt).To(Equal(updated))
+	// only the block containing the changed byte is sent
+	g.Expect(literalSize(operations)).To(Equal(1000))
+}
+
+func TestComputeDelta_InsertionAndDeletion(t *testing.T) {
+	g := NewGomegaWithT(t)
+	base := make([]byte, 10000)
+	rand.New(rand.NewSource(2)).Read(base)
+
+	inserted := append(append(bytes.Clone(base[:3500]), []byte("some new content")...), base[3500:]...)
+	operations, rebuilt := deltaRoundTrip(t, base, inserted, 1000)
+	g.Expect(rebuilt).To(Equal(inserted))
+	g.Expect(literalSize(operations)).To(BeNumerically("<", 1100))
+
+	deleted := append(bytes.Clone(base[:2000]), base[2100:]...)
+	operations, rebuilt = deltaRoundTrip(t, base, deleted, 1000)
+	g.Expect(rebuilt).To(Equal(deleted))
+	g.Expect(literalSize(operations)).To(BeNumerically("<", 1000))
+}
+
+func TestComputeDelta_ShortFinalBlock(t *testing.T) {
+	g := NewGomegaWithT(t)
+	base := []byte("some content that does not fill the final block")
+
+	operations, rebuilt := deltaRoundTrip(t, base, base, 16)
+	g.Expect(rebuilt).To(Equal(base))
+	g.Expect(operations).To(Equal([]entities.DeltaOperation{{BlockIndex: 0, BlockCount: 3}}))
+
+	operations, rebuilt = deltaRoundTrip(t, base, nil, 16)
+	g.Expect(rebuilt).To(BeEmpty())
+	g.Expect(operations).To(BeEmpty())
+}
+
+func TestComputeDelta_LiteralLimitExceeded(t *testing.T) {
+	g := NewGomegaWithT(t)
+	signature, err := buildFileSignature(bytes.NewReader([]byte("some content")), 4)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, _, err = computeDelta(bytes.NewReader([]byte("different data")), signature, 4)
+	g.Expect(err).To(MatchError(ErrDeltaUnavailable))
+}
+
+func TestApplyDelta_BlockOutOfRange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var rebuilt bytes.Buffer
+	err := applyDelta(bytes.NewReader([]byte("some content")), 12, entities.Delta{
+		BlockSize:  4,
+		Operations: []entities.DeltaOperation{{BlockIndex: 2, BlockCount: 2}},
+	}, &rebuilt)
+	g.Expect(err).To(MatchError(entities.ErrInvalidDelta))
+}
@@ -1,24 +1,40 @@
 package adapters
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"github.com/AlecSmith96/dopbox/pkg/entities"
 	"io/fs"
 	"log/slog"
-	"os"
+	"maps"
 	"path/filepath"
-	"syscall"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 )
 
+// pollInterval is how often the DirectoryMonitor rebuilds its snapshot of the source directory.
+const pollInterval = 1 * time.Second
+
 type DirectoryMonitor struct {
-	rootPath         string
-	previousSnapshot map[string]entities.FileContents
+	rootPath          string
+	ignoreMatcher     *IgnoreMatcher
+	preserveOwnership bool
+	previousSnapshot  map[string]entities.FileContents
 }
 
-func NewDirectoryMonitor(root string) (*DirectoryMonitor, error) {
+// NewDirectoryMonitor is a function that creates a DirectoryMonitor for root, leaving out any paths ignoreMatcher
+// ignores. ignoreMatcher can be nil, in which case nothing is ignored. A change to the owner of a file is only reported
+// if preserveOwnership is set. knownSnapshot, such as the snapshot acknowledged by the server in the previous run, is
+// used when building the first snapshot so that files unchanged since it was taken aren't hashed again. It can be nil.
+func NewDirectoryMonitor(root string, ignoreMatcher *IgnoreMatcher, preserveOwnership bool, knownSnapshot map[string]entities.FileContents) (*DirectoryMonitor, error) {
 	monitor := &DirectoryMonitor{
-		rootPath: root,
+		rootPath:          root,
+		ignoreMatcher:     ignoreMatcher,
+		preserveOwnership: preserveOwnership,
 	}
+	monitor.previousSnapshot = monitor.withRootDevice(knownSnapshot)
 
 	initialSnapshot, err := monitor.BuildSnapshot(root)
 	if err != nil {
@@ -29,96 +45,520 @@ func NewDirectoryMonitor(root string) (*DirectoryMonitor, error) {
 	return monitor, nil
 }
 
-// SyncDestinationWithSource is a function that returns all files in the filesystem as CREATED events to be sent to
-// insert them in the destination directory. This assumes the destination directory starts out as an empty dir, although
-// any existing files will be ignored. (Existing files could cause unexpected errors such as a file already exists).
-func (monitor *DirectoryMonitor) SyncDestinationWithSource() []entities.FilesystemEvent {
-	syncEvents := make([]entities.FilesystemEvent, 0)
+// SyncDestinationWithSource is a function that returns the events needed to replay the changes made to the source
+// directory while the app was stopped. The snapshot acknowledged by the server in the previous run is compared with the
+// current state, so renames are sent as renames rather than as new files. It returns no events if there is no snapshot
+// from a previous run, in which case ReconcileWithDestination sends every file.
+func (monitor *DirectoryMonitor) SyncDestinationWithSource(acknowledgedSnapshot map[string]entities.FileContents) []entities.FilesystemEvent {
+	if acknowledgedSnapshot == nil {
+		return nil
+	}
+
+	return monitor.diffSnapshots(monitor.withRootDevice(acknowledgedSnapshot), monitor.previousSnapshot)
+}
+
+// withRootDevice is a function that returns a copy of a snapshot saved by a previous run, with the entries saved before
+// devices were recorded assumed to be on the device the source directory is on now.
+func (monitor *DirectoryMonitor) withRootDevice(snapshot map[string]entities.FileContents) map[string]entities.FileContents {
+	snapshot = maps.Clone(snapshot)
+	if len(snapshot) == 0 {
+		return snapshot
+	}
+
+	root, err := readFileContents(monitor.rootPath, nil)
+	if err != nil {
+		return snapshot
+	}
+	for path, contents := range snapshot {
+		if contents.Device == 0 {
+			contents.Device = root.Device
+			snapshot[path] = contents
+		}
+	}
+
+	return snapshot
+}
+
+// ReconcileWithDestination is a function that compares the manifest of the destination directory with the current
+// state of the source directory. It returns the events needed to make the destination match the source: files missing
+// from the destination are created, as hard links to the destination's copy of another link to them if it has one,
+// files whose contents differ and symlinks whose targets differ are modified, entries whose mode or modification time
+// differ have their metadata updated and, if deleteExtraneous is set, entries that only exist in the destination are
+// deleted, unless they are ignored. Deletes are returned first, followed by creates ordered so that parent directories
+// come before their contents.
+func (monitor *DirectoryMonitor) ReconcileWithDestination(manifest []entities.ManifestEntry, deleteExtraneous bool) []entities.FilesystemEvent {
+	destinationEntries := make(map[string]entities.ManifestEntry, len(manifest))
+	for _, entry := range manifest {
+		destinationEntries[filepath.Join(monitor.rootPath, filepath.FromSlash(entry.Path))] = entry
+	}
+
+	deletes := make([]entities.FilesystemEvent, 0)
+	creates := make([]entities.FilesystemEvent, 0)
+	modifies := make([]entities.FilesystemEvent, 0)
+	pathsByID := pathsByFileID(monitor.previousSnapshot)
+
 	for path, metadata := range monitor.previousSnapshot {
 		if path == monitor.rootPath {
 			continue
 		}
 
-		syncEvents = append(syncEvents, entities.FilesystemEvent{
-			Name:         path,
-			Operation:    entities.OperationCreated,
-			FileContents: metadata,
-		})
+		entry, exists := destinationEntries[path]
+		switch {
+		case !exists:
+			creates = append(creates, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationCreated,
+				HardLinkOf:   monitor.linkInDestination(path, metadata, pathsByID, destinationEntries),
+				FileContents: metadata,
+			})
+
+		case entry.IsDirectory != metadata.IsDirectory || entry.IsSymlink != metadata.IsSymlink:
+			// the destination has a different type of entry to the source, such as a file where the source has a
+			// directory, so it has to be replaced
+			deletes = append(deletes, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationDeleted,
+				FileContents: entities.FileContents{IsDirectory: entry.IsDirectory, IsSymlink: entry.IsSymlink},
+			})
+			creates = append(creates, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationCreated,
+				FileContents: metadata,
+			})
+
+		case !metadata.IsDirectory && (entry.Size != metadata.Size || entry.Hash != metadata.Hash):
+			modifies = append(modifies, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationModified,
+				FileContents: metadata,
+			})
+
+		// servers that don't report the mode of their entries can't have their metadata compared, and the manifest
+		// doesn't include owners
+		case entry.Mode != 0 && metadata.MetadataDiffers(entities.FileContents{
+			IsDirectory: entry.IsDirectory,
+			ModTime:     entry.ModifiedAt(),
+			Mode:        entry.Mode,
+		}, false):
+			modifies = append(modifies, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationMetadata,
+				FileContents: metadata,
+			})
+		}
+	}
+
+	if deleteExtraneous {
+		for path, entry := range destinationEntries {
+			if _, exists := monitor.previousSnapshot[path]; exists {
+				continue
+			}
+			if monitor.ignoreMatcher.Ignored(path, entry.IsDirectory) {
+				continue
+			}
+
+			// deleting a directory removes everything within it, so only the top most extraneous entry is deleted
+			_, parentExists := destinationEntries[filepath.Dir(path)]
+			_, parentInSource := monitor.previousSnapshot[filepath.Dir(path)]
+			if parentExists && !parentInSource {
+				continue
+			}
+
+			deletes = append(deletes, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationDeleted,
+				FileContents: entities.FileContents{IsDirectory: entry.IsDirectory},
+			})
+		}
+	}
+
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].Name < deletes[j].Name })
+	sort.Slice(creates, func(i, j int) bool { return creates[i].Name < creates[j].Name })
+
+	events := append(deletes, creates...)
+	return append(events, modifies...)
+}
+
+// linkInDestination is a function that returns another hard link to the file at path that the destination already has
+// a copy of, so that the destination's copy can be linked to rather than the file being sent again.
+func (monitor *DirectoryMonitor) linkInDestination(path string, metadata entities.FileContents, pathsByID map[entities.FileID][]string, destinationEntries map[string]entities.ManifestEntry) string {
+	if !metadata.IsHardLinked() {
+		return ""
 	}
 
-	return syncEvents
+	for _, linkPath := range pathsByID[metadata.ID()] {
+		entry, exists := destinationEntries[linkPath]
+		if linkPath != path && exists && !entry.IsDirectory && !entry.IsSymlink && entry.Hash == metadata.Hash {
+			return linkPath
+		}
+	}
+
+	return ""
+}
+
+// Run is a function that polls the source directory for changes every pollInterval until the context is cancelled.
+func (monitor *DirectoryMonitor) Run(ctx context.Context, eventChan chan<- entities.FilesystemEvent) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := monitor.PollForFileChanges(eventChan)
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// ResetSnapshot is a function that replaces the previous snapshot with the current state of the source directory. It
+// is used when taking over from another FileWatcher that has already published the changes up to this point.
+func (monitor *DirectoryMonitor) ResetSnapshot() error {
+	snapshot, err := monitor.BuildSnapshot(monitor.rootPath)
+	if err != nil {
+		return err
+	}
+	monitor.previousSnapshot = snapshot
+
+	return nil
+}
+
+// applyEvent is a function that brings the previous snapshot up to date with an event published by another FileWatcher,
+// so that it can be compared with the source directory later on.
+func (monitor *DirectoryMonitor) applyEvent(event entities.FilesystemEvent) {
+	switch event.Operation {
+	case entities.OperationCreated, entities.OperationModified, entities.OperationMetadata:
+		monitor.previousSnapshot[event.Name] = event.FileContents
+
+	case entities.OperationRenamed:
+		for path, contents := range monitor.previousSnapshot {
+			if isWithin(path, event.PreviousPath) {
+				delete(monitor.previousSnapshot, path)
+				monitor.previousSnapshot[event.Name+strings.TrimPrefix(path, event.PreviousPath)] = contents
+			}
+		}
+		delete(monitor.previousSnapshot, event.PreviousPath)
+		monitor.previousSnapshot[event.Name] = event.FileContents
+
+	case entities.OperationDeleted:
+		for path := range monitor.previousSnapshot {
+			if isWithin(path, event.Name) {
+				delete(monitor.previousSnapshot, path)
+			}
+		}
+		delete(monitor.previousSnapshot, event.Name)
+	}
 }
 
 // PollForFileChanges is a function that build a snapshot of the current state of the source directory and compares it
 // with the previous snapshot. Any differences between them are then calculated and an entities.FilesystemEvent is put on
 // the event channel.
-func (monitor *DirectoryMonitor) PollForFileChanges(eventChan chan entities.FilesystemEvent) error {
+func (monitor *DirectoryMonitor) PollForFileChanges(eventChan chan<- entities.FilesystemEvent) error {
 	currentSnapshot, err := monitor.BuildSnapshot(monitor.rootPath)
 	if err != nil {
+		// a partial snapshot would have everything that wasn't reached deleted, so it waits for the next poll
 		slog.Error("building snapshot of directory", "err", err)
+		return nil
 	}
 
-	// use inodes to compare files as they dont change, allows for tracking filename changes
-	previousFilepathByInodes := make(map[uint64]string, len(monitor.previousSnapshot))
-	for path, metadata := range monitor.previousSnapshot {
-		previousFilepathByInodes[metadata.Inode] = path
+	for _, event := range monitor.diffSnapshots(monitor.previousSnapshot, currentSnapshot) {
+		eventChan <- event
 	}
 
-	currentFilepathByInodes := make(map[uint64]string, len(currentSnapshot))
-	for path, metadata := range currentSnapshot {
-		currentFilepathByInodes[metadata.Inode] = path
+	monitor.previousSnapshot = currentSnapshot
+	return nil
+}
+
+// diffSnapshots is a function that compares two snapshots of the source directory and returns the events needed to
+// turn the previous snapshot into the current one. Paths are compared in order, so that when a file with several hard
+// links is renamed or linked again, the event for its first path comes before the others. A rename comes before
+// anything else put at the path it moves from, and an entry replaced by one of a different kind is deleted before its
+// replacement is created.
+func (monitor *DirectoryMonitor) diffSnapshots(previousSnapshot, currentSnapshot map[string]entities.FileContents) []entities.FilesystemEvent {
+	paths := make([]string, 0, len(currentSnapshot))
+	for _, path := range slices.Sorted(maps.Keys(currentSnapshot)) {
+		if path != monitor.rootPath {
+			paths = append(paths, path)
+		}
 	}
 
-	for path, metadata := range currentSnapshot {
-		oldPath, exists := previousFilepathByInodes[metadata.Inode]
-		if !exists {
-			eventChan <- entities.FilesystemEvent{
+	renames, renamedFrom := monitor.findRenames(paths, previousSnapshot, currentSnapshot)
+
+	events := make([]entities.FilesystemEvent, 0)
+	// paths whose entry has been replaced by a new one of the same kind, which is a change to the path rather than a
+	// delete and a create, such as a file saved by writing a copy of it and renaming that over the original
+	replacedPaths := make(map[string]bool)
+	// paths whose entry has been replaced by one of a different kind, which can only be created once the old one has
+	// been deleted
+	recreatedPaths := newPathSet()
+
+	for _, path := range paths {
+		metadata := currentSnapshot[path]
+		previous, existed := previousSnapshot[path]
+
+		switch {
+		case renames[path] != "":
+			events = append(events, entities.FilesystemEvent{
 				Name:         path,
-				Operation:    entities.OperationCreated,
+				Operation:    entities.OperationRenamed,
+				PreviousPath: renames[path],
 				FileContents: metadata,
+			})
+
+		case existed && previous.ID() == metadata.ID():
+			events = appendChange(events, path, previous, metadata, monitor.preserveOwnership)
+
+		case existed && !renamedFrom[path] && previous.SameKind(metadata):
+			replacedPaths[path] = true
+			events = appendChange(events, path, previous, metadata, monitor.preserveOwnership)
+
+		default:
+			if existed && !renamedFrom[path] {
+				recreatedPaths.add(entities.FilesystemEvent{Name: path})
 			}
+			events = append(events, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationCreated,
+				FileContents: metadata,
+			})
+		}
+	}
+
+	// anything put at or within a recreated path has to wait until the entry that was there has been deleted
+	recreated := make([]entities.FilesystemEvent, 0)
+	events = slices.DeleteFunc(events, func(event entities.FilesystemEvent) bool {
+		if recreatedPaths.overlaps(entities.FilesystemEvent{Name: event.Name}) {
+			recreated = append(recreated, event)
+			return true
+		}
+		return false
+	})
+	events = orderVacatingRenames(events)
+
+	for _, path := range slices.Sorted(maps.Keys(previousSnapshot)) {
+		if path == monitor.rootPath {
 			continue
 		}
+		metadata := previousSnapshot[path]
 
-		if oldPath != path {
-			eventChan <- entities.FilesystemEvent{
+		// a path is only deleted once the file it had is no longer at it, which for a file with several hard links may
+		// leave the file at its other paths. Paths that have become ignored are left as they are in the destination
+		current, exists := currentSnapshot[path]
+		if exists && (current.ID() == metadata.ID() || replacedPaths[path]) {
+			continue
+		}
+		if !renamedFrom[path] && !monitor.ignoreMatcher.Ignored(path, metadata.IsDirectory) {
+			events = append(events, entities.FilesystemEvent{
 				Name:         path,
-				Operation:    entities.OperationRenamed,
-				PreviousPath: oldPath,
+				Operation:    entities.OperationDeleted,
 				FileContents: metadata,
-			}
+			})
+		}
+	}
+
+	return append(events, recreated...)
+}
+
+// findRenames is a function that works out which of the paths in the current snapshot have been moved there from
+// another path, returning the path each one was moved from and the set of paths moved from. Inodes are reused once a
+// file has been deleted, so an entry with the same device and inode as one that was at another path is only taken to
+// have been moved if it still has the same contents. A file that was copied to a new path and then deleted, as some
+// tools move files, is taken to have been moved too.
+func (monitor *DirectoryMonitor) findRenames(paths []string, previousSnapshot, currentSnapshot map[string]entities.FileContents) (map[string]string, map[string]bool) {
+	previousPathsByID := pathsByFileID(previousSnapshot)
+	renames := make(map[string]string)
+	renamedFrom := make(map[string]bool)
+
+	// a file at a new path is a rename if one of the paths it was at has gone, and another hard link to it if not
+	for _, path := range paths {
+		previousPaths := previousPathsByID[currentSnapshot[path].ID()]
+		if slices.Contains(previousPaths, path) {
 			continue
 		}
 
-		previousFileVersion, exists := monitor.previousSnapshot[path]
-		if exists {
-			if !bytes.Equal(metadata.Data, previousFileVersion.Data) {
-				eventChan <- entities.FilesystemEvent{
-					Name:         path,
-					Operation:    entities.OperationModified,
-					FileContents: metadata,
-				}
-			}
+		oldPath, renamed := movedFrom(path, previousPaths, previousSnapshot, currentSnapshot, renamedFrom)
+		if renamed {
+			renames[path] = oldPath
+			renamedFrom[oldPath] = true
 		}
 	}
 
-	for path, metadata := range monitor.previousSnapshot {
-		_, exists := currentFilepathByInodes[metadata.Inode]
-		if !exists {
-			eventChan <- entities.FilesystemEvent{
-				Name:         path,
-				Operation:    entities.OperationDeleted,
-				FileContents: metadata,
-			}
+	// files that have gone, grouped by their contents
+	deletedByHash := make(map[string][]string)
+	for _, path := range slices.Sorted(maps.Keys(previousSnapshot)) {
+		metadata := previousSnapshot[path]
+		_, exists := currentSnapshot[path]
+		if exists || renamedFrom[path] || !isMoveCandidate(metadata) || monitor.ignoreMatcher.Ignored(path, false) {
+			continue
 		}
+		deletedByHash[metadata.Hash] = append(deletedByHash[metadata.Hash], path)
 	}
 
-	monitor.previousSnapshot = currentSnapshot
-	return nil
+	for _, path := range paths {
+		metadata := currentSnapshot[path]
+		_, existed := previousSnapshot[path]
+		if existed || renames[path] != "" || !isMoveCandidate(metadata) ||
+			stillLinked(previousPathsByID[metadata.ID()], metadata.ID(), currentSnapshot) {
+			continue
+		}
+
+		oldPath, copied := copiedFrom(path, metadata, deletedByHash[metadata.Hash], previousSnapshot, renamedFrom)
+		if copied {
+			renames[path] = oldPath
+			renamedFrom[oldPath] = true
+		}
+	}
+
+	return renames, renamedFrom
+}
+
+// pathsByFileID is a function that groups the paths in a snapshot by the file they are, in order. Directories and
+// symlinks only ever have one path, a file has one for each of its hard links.
+func pathsByFileID(snapshot map[string]entities.FileContents) map[entities.FileID][]string {
+	paths := make(map[entities.FileID][]string, len(snapshot))
+	for _, path := range slices.Sorted(maps.Keys(snapshot)) {
+		id := snapshot[path].ID()
+		paths[id] = append(paths[id], path)
+	}
+
+	return paths
+}
+
+// movedFrom is a function that returns the first of the paths the entry now at path was previously at that no longer
+// has it at it, hasn't already been used as the old path of another rename and had the same contents. A directory has
+// only been moved if everything that was inside it is still there.
+func movedFrom(path string, previousPaths []string, previousSnapshot, currentSnapshot map[string]entities.FileContents, renamedFrom map[string]bool) (string, bool) {
+	metadata := currentSnapshot[path]
+	for _, previousPath := range previousPaths {
+		current, exists := currentSnapshot[previousPath]
+		if renamedFrom[previousPath] || (exists && current.ID() == metadata.ID()) {
+			continue
+		}
+		if !previousSnapshot[previousPath].SameContents(metadata) {
+			continue
+		}
+		if metadata.IsDirectory && !entriesMoved(previousPath, path, previousSnapshot, currentSnapshot) {
+			continue
+		}
+		return previousPath, true
+	}
+
+	return "", false
+}
+
+// entriesMoved is a function that returns whether every entry that was within the directory at oldPath is now at the
+// same place within newPath.
+func entriesMoved(oldPath, newPath string, previousSnapshot, currentSnapshot map[string]entities.FileContents) bool {
+	for path, metadata := range previousSnapshot {
+		if !isWithin(path, oldPath) {
+			continue
+		}
+
+		current, exists := currentSnapshot[newPath+strings.TrimPrefix(path, oldPath)]
+		if !exists || current.ID() != metadata.ID() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// copiedFrom is a function that returns which of the deleted paths the file at path is a copy of, preferring one with
+// the same name, and false if it isn't a copy of any that haven't already been used as the old path of a rename.
+func copiedFrom(path string, metadata entities.FileContents, deletedPaths []string, previousSnapshot map[string]entities.FileContents, renamedFrom map[string]bool) (string, bool) {
+	candidates := slices.DeleteFunc(slices.Clone(deletedPaths), func(deletedPath string) bool {
+		return renamedFrom[deletedPath] || !previousSnapshot[deletedPath].SameContents(metadata)
+	})
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	for _, candidate := range candidates {
+		if filepath.Base(candidate) == filepath.Base(path) {
+			return candidate, true
+		}
+	}
+
+	return candidates[0], true
+}
+
+// isMoveCandidate is a function that returns whether a file can be matched with a copy of it by its contents. Empty
+// files all have the same contents, so they are never matched.
+func isMoveCandidate(metadata entities.FileContents) bool {
+	return !metadata.IsDirectory && !metadata.IsSymlink && metadata.Size > 0 && metadata.Hash != ""
+}
+
+// stillLinked is a function that returns whether a file with the given id is still at any of the paths it was at
+// before, which makes a new path for it another hard link to it rather than a copy of something else.
+func stillLinked(previousPaths []string, id entities.FileID, currentSnapshot map[string]entities.FileContents) bool {
+	return slices.ContainsFunc(previousPaths, func(previousPath string) bool {
+		current, exists := currentSnapshot[previousPath]
+		return exists && current.ID() == id
+	})
+}
+
+// appendChange is a function that appends the event for a path whose entry has changed from previous to current, if
+// there is one. Owners are only compared if withOwner is set.
+func appendChange(events []entities.FilesystemEvent, path string, previous, current entities.FileContents, withOwner bool) []entities.FilesystemEvent {
+	switch {
+	case !current.IsDirectory && (current.Hash != previous.Hash || current.IsSymlink != previous.IsSymlink):
+		return append(events, entities.FilesystemEvent{
+			Name:         path,
+			Operation:    entities.OperationModified,
+			FileContents: current,
+		})
+	case current.MetadataDiffers(previous, withOwner):
+		return append(events, entities.FilesystemEvent{
+			Name:         path,
+			Operation:    entities.OperationMetadata,
+			FileContents: current,
+		})
+	}
+
+	return events
+}
+
+// orderVacatingRenames is a function that moves each rename in front of anything else put at the path it moves from,
+// which would otherwise be moved along with it, keeping the events in order otherwise. The renames in a chain, such as
+// b to c and then a to b, come out in the order they have to be applied in.
+func orderVacatingRenames(events []entities.FilesystemEvent) []entities.FilesystemEvent {
+	renamesFrom := make(map[string]int)
+	for i, event := range events {
+		if event.Operation == entities.OperationRenamed {
+			renamesFrom[event.PreviousPath] = i
+		}
+	}
+
+	ordered := make([]entities.FilesystemEvent, 0, len(events))
+	placed := make([]bool, len(events))
+	var place func(i int)
+	place = func(i int) {
+		if placed[i] {
+			return
+		}
+		placed[i] = true
+
+		if rename, exists := renamesFrom[events[i].Name]; exists {
+			place(rename)
+		}
+		ordered = append(ordered, events[i])
+	}
+	for i := range events {
+		place(i)
+	}
+
+	return ordered
 }
 
 // BuildSnapshot is a function that uses the built in filepath.WalkDir function to traverse the root directory and build
-// a map of filepaths to entities.FileContents. It stores whether each file is a directory, its contents and its inode.
+// a map of filepaths to entities.FileContents. It stores whether each file is a directory, its inode, size, modification
+// time and a hash of its contents. Files whose inode, size and modification time are unchanged since the previous
+// snapshot are not rehashed. Entries removed while the snapshot is being built are left out. Ignored paths are left
+// out too, and the ignore file in each directory is loaded again before what is inside it is walked, so changes to the
+// ignore files take effect on the next snapshot.
 func (monitor *DirectoryMonitor) BuildSnapshot(root string) (map[string]entities.FileContents, error) {
 	directoryMap := make(map[string]entities.FileContents)
 
@@ -128,45 +568,38 @@ func (monitor *DirectoryMonitor) BuildSnapshot(root string) (map[string]entities
 			return nil
 		}
 
-		// exclude source directory entry
-		if d.Name() == path {
+		if monitor.ignoreMatcher.Ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
 			return nil
 		}
+		if d.IsDir() {
+			monitor.ignoreMatcher.LoadDirectory(path)
+		}
 
-		// retrieve FileInfo to get iNode of file
-		info, err := d.Info()
-		if err != nil {
-			slog.Error("getting file info", "path", path, "err", err)
-			return err
+		// exclude source directory entry
+		if d.Name() == path {
+			return nil
 		}
 
-		// get the iNode of the file to track name changes later on
-		st, ok := info.Sys().(*syscall.Stat_t)
-		if !ok {
-			slog.Error("unexpected Sys() type", "path", path)
-			return err
+		var previous *entities.FileContents
+		if previousContents, exists := monitor.previousSnapshot[path]; exists {
+			previous = &previousContents
 		}
 
-		// dont try to get content if file is a directory
-		if d.IsDir() {
-			directoryMap[path] = entities.FileContents{
-				Inode:       st.Ino,
-				IsDirectory: true,
-			}
+		contents, err := readFileContents(path, previous)
+		if errors.Is(err, fs.ErrNotExist) {
+			// the entry was removed after it was listed, which the next snapshot picks up
+			slog.Debug("entry removed while building snapshot", "path", path)
 			return nil
 		}
-
-		data, err := os.ReadFile(path)
 		if err != nil {
-			slog.Error("read file", "path", path, "err", err)
+			slog.Error("reading file contents", "path", path, "err", err)
 			return err
 		}
 
-		directoryMap[path] = entities.FileContents{
-			Inode:       st.Ino,
-			Data:        data,
-			IsDirectory: false,
-		}
+		directoryMap[path] = contents
 		return nil
 	})
 
@@ -0,0 +1,466 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectoryMonitor_BuildSnapshot_ReusesHashOfUnchangedFiles(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "file.go")
+	g.Expect(os.WriteFile(path, []byte("some content"), 0o644)).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// a file with the same inode, size and modification time is not read again
+	previous := monitor.previousSnapshot[path]
+	previous.Hash = "previous-hash"
+	monitor.previousSnapshot[path] = previous
+
+	snapshot, err := monitor.BuildSnapshot(root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(snapshot[path].Hash).To(Equal("previous-hash"))
+	g.Expect(snapshot[path].Data).To(BeNil())
+}
+
+func TestNewDirectoryMonitor_ReusesHashesOfKnownSnapshot(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	unchanged := filepath.Join(root, "unchanged.go")
+	modified := filepath.Join(root, "modified.go")
+	g.Expect(os.WriteFile(unchanged, []byte("some content"), 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(modified, []byte("some content"), 0o644)).To(Succeed())
+
+	unchangedContents, err := readFileContents(unchanged, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	modifiedContents, err := readFileContents(modified, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// a snapshot saved by a previous run, from before devices were recorded
+	unchangedContents.Hash, unchangedContents.Device = "known-hash", 0
+	modifiedContents.Hash, modifiedContents.Size = "known-hash", modifiedContents.Size-1
+	monitor, err := NewDirectoryMonitor(root, nil, false, map[string]entities.FileContents{
+		unchanged: unchangedContents,
+		modified:  modifiedContents,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(monitor.previousSnapshot[unchanged].Hash).To(Equal("known-hash"))
+	g.Expect(monitor.previousSnapshot[modified].Hash).ToNot(Equal("known-hash"))
+}
+
+func TestDirectoryMonitor_PollForFileChanges_DetectsModifiedContents(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "file.go")
+	g.Expect(os.WriteFile(path, []byte("some content"), 0o644)).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(os.WriteFile(path, []byte("some other content"), 0o644)).To(Succeed())
+
+	eventChan := make(chan entities.FilesystemEvent, 10)
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event := <-eventChan
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+	g.Expect(event.FileContents.Size).To(Equal(int64(len("some other content"))))
+}
+
+func TestDirectoryMonitor_ReconcileWithDestination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(root, "dir"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "dir", "missing.go"), []byte("missing"), 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "changed.go"), []byte("changed"), 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "same.go"), []byte("same"), 0o644)).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	sameHash, err := hashFile(filepath.Join(root, "same.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	manifest := []entities.ManifestEntry{
+		{Path: "/changed.go", Size: 3, Hash: "old"},
+		{Path: "/same.go", Size: 4, Hash: sameHash},
+		{Path: "/extra", IsDirectory: true},
+		{Path: "/extra/file.go", Size: 1, Hash: "abc"},
+	}
+
+	events := monitor.ReconcileWithDestination(manifest, true)
+	g.Expect(events).To(HaveLen(4))
+	g.Expect(events[0].Operation).To(Equal(entities.OperationDeleted))
+	g.Expect(events[0].Name).To(Equal(filepath.Join(root, "extra")))
+	g.Expect(events[1].Operation).To(Equal(entities.OperationCreated))
+	g.Expect(events[1].Name).To(Equal(filepath.Join(root, "dir")))
+	g.Expect(events[2].Operation).To(Equal(entities.OperationCreated))
+	g.Expect(events[2].Name).To(Equal(filepath.Join(root, "dir", "missing.go")))
+	g.Expect(events[3].Operation).To(Equal(entities.OperationModified))
+	g.Expect(events[3].Name).To(Equal(filepath.Join(root, "changed.go")))
+
+	events = monitor.ReconcileWithDestination(manifest, false)
+	g.Expect(events).To(HaveLen(3))
+	g.Expect(events[0].Operation).To(Equal(entities.OperationCreated))
+}
+
+func TestDirectoryMonitor_PollForFileChanges_IgnoredPaths(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("node_modules/\n"), 0o644)).To(Succeed())
+	g.Expect(os.Mkdir(filepath.Join(root, "node_modules"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "node_modules", "index.js"), nil, 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "file.log"), nil, 0o644)).To(Succeed())
+	ignoreMatcher, err := NewIgnoreMatcher(root, []string{"*.log"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	monitor, err := NewDirectoryMonitor(root, ignoreMatcher, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(monitor.previousSnapshot).To(HaveKey(filepath.Join(root, IgnoreFileName)))
+	g.Expect(monitor.previousSnapshot).ToNot(HaveKey(filepath.Join(root, "node_modules")))
+	g.Expect(monitor.previousSnapshot).ToNot(HaveKey(filepath.Join(root, "file.log")))
+
+	// the directory stops being ignored and the log file starts being synced, but the ignore file becoming ignored
+	// doesn't delete it from the destination
+	g.Expect(os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("/.dropboxignore\n!*.log\n"), 0o644)).To(Succeed())
+
+	eventChan := make(chan entities.FilesystemEvent, 10)
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	close(eventChan)
+
+	created := make([]string, 0)
+	for event := range eventChan {
+		g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+		created = append(created, event.Name)
+	}
+	g.Expect(created).To(ConsistOf(
+		filepath.Join(root, "node_modules"),
+		filepath.Join(root, "node_modules", "index.js"),
+		filepath.Join(root, "file.log"),
+	))
+}
+
+func TestDirectoryMonitor_MetadataChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "run.sh")
+	g.Expect(os.WriteFile(path, []byte("echo"), 0o644)).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// making the file executable leaves its contents as they are
+	g.Expect(os.Chmod(path, 0o755)).To(Succeed())
+
+	eventChan := make(chan entities.FilesystemEvent, 10)
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event := <-eventChan
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationMetadata))
+	g.Expect(event.FileContents.Mode).To(Equal(os.FileMode(0o755)))
+
+	// the destination has the same contents but the old mode
+	contents := monitor.previousSnapshot[path]
+	modTime := contents.ModTime
+	events := monitor.ReconcileWithDestination([]entities.ManifestEntry{
+		{Path: "/run.sh", Size: contents.Size, Hash: contents.Hash, Mode: 0o644, ModTime: &modTime},
+	}, false)
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0].Operation).To(Equal(entities.OperationMetadata))
+
+	// a server that doesn't report modes is left alone
+	g.Expect(monitor.ReconcileWithDestination([]entities.ManifestEntry{
+		{Path: "/run.sh", Size: contents.Size, Hash: contents.Hash},
+	}, false)).To(BeEmpty())
+}
+
+func TestDirectoryMonitor_OwnerChangesOnlyWhenPreservingOwnership(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "file.go")
+	g.Expect(os.WriteFile(path, []byte("contents"), 0o644)).To(Succeed())
+
+	for _, preserveOwnership := range []bool{false, true} {
+		monitor, err := NewDirectoryMonitor(root, nil, preserveOwnership, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		// the file was owned by someone else when the snapshot was taken
+		contents := monitor.previousSnapshot[path]
+		contents.UID++
+		monitor.previousSnapshot[path] = contents
+
+		eventChan := make(chan entities.FilesystemEvent, 10)
+		g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+		if !preserveOwnership {
+			g.Expect(eventChan).To(BeEmpty())
+			continue
+		}
+
+		g.Expect(eventChan).To(HaveLen(1))
+		event := <-eventChan
+		g.Expect(event.Name).To(Equal(path))
+		g.Expect(event.Operation).To(Equal(entities.OperationMetadata))
+	}
+}
+
+func TestDirectoryMonitor_Symlinks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "link")
+	g.Expect(os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(os.Symlink("a.txt", path)).To(Succeed())
+
+	eventChan := make(chan entities.FilesystemEvent, 10)
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event := <-eventChan
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+	g.Expect(event.FileContents.IsSymlink).To(BeTrue())
+	g.Expect(event.FileContents.LinkTarget).To(Equal("a.txt"))
+	g.Expect(event.FileContents.Hash).To(Equal(entities.LinkTargetHash("a.txt")))
+
+	// pointing the link somewhere else is a modification of the link, not a new one
+	g.Expect(os.Remove(path)).To(Succeed())
+	g.Expect(os.Symlink("b.txt", path)).To(Succeed())
+
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event = <-eventChan
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+	g.Expect(event.FileContents.LinkTarget).To(Equal("b.txt"))
+
+	// the destination has a file where the link should be
+	events := monitor.ReconcileWithDestination([]entities.ManifestEntry{
+		{Path: "/a.txt", Size: 1, Hash: monitor.previousSnapshot[filepath.Join(root, "a.txt")].Hash},
+		{Path: "/link", Size: 5, Hash: entities.LinkTargetHash("b.txt")},
+	}, false)
+	g.Expect(events).To(HaveLen(2))
+	g.Expect(events[0].Operation).To(Equal(entities.OperationDeleted))
+	g.Expect(events[1].Operation).To(Equal(entities.OperationCreated))
+	g.Expect(events[1].FileContents.IsSymlink).To(BeTrue())
+
+	// and nothing is done once it has the link
+	g.Expect(monitor.ReconcileWithDestination([]entities.ManifestEntry{
+		{Path: "/a.txt", Size: 1, Hash: monitor.previousSnapshot[filepath.Join(root, "a.txt")].Hash},
+		{Path: "/link", Size: 5, Hash: entities.LinkTargetHash("b.txt"), IsSymlink: true, LinkTarget: "b.txt"},
+	}, false)).To(BeEmpty())
+}
+
+func TestDirectoryMonitor_HardLinks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "a.txt")
+	g.Expect(os.WriteFile(path, []byte("a"), 0o644)).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// a second link to the file is a new file, not a rename of the first
+	g.Expect(os.Link(path, filepath.Join(root, "b.txt"))).To(Succeed())
+
+	eventChan := make(chan entities.FilesystemEvent, 10)
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event := <-eventChan
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "b.txt")))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+	g.Expect(event.FileContents.IsHardLinked()).To(BeTrue())
+	g.Expect(event.FileContents.ID()).To(Equal(monitor.previousSnapshot[path].ID()))
+
+	// moving one of the links is a rename of that link alone
+	g.Expect(os.Rename(filepath.Join(root, "b.txt"), filepath.Join(root, "c.txt"))).To(Succeed())
+
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event = <-eventChan
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "c.txt")))
+	g.Expect(event.Operation).To(Equal(entities.OperationRenamed))
+	g.Expect(event.PreviousPath).To(Equal(filepath.Join(root, "b.txt")))
+
+	// and removing one deletes it even though the file is still at its other path
+	g.Expect(os.Remove(path)).To(Succeed())
+
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event = <-eventChan
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationDeleted))
+}
+
+func TestDirectoryMonitor_ReconcileWithDestination_HardLinks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)).To(Succeed())
+	g.Expect(os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt"))).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	contents := monitor.previousSnapshot[filepath.Join(root, "a.txt")]
+
+	// the destination's copy of the first link is linked to rather than the file being sent again
+	events := monitor.ReconcileWithDestination([]entities.ManifestEntry{
+		{Path: "/a.txt", Size: contents.Size, Hash: contents.Hash},
+	}, false)
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0].Name).To(Equal(filepath.Join(root, "b.txt")))
+	g.Expect(events[0].Operation).To(Equal(entities.OperationCreated))
+	g.Expect(events[0].HardLinkOf).To(Equal(filepath.Join(root, "a.txt")))
+
+	// but not if its copy is out of date
+	events = monitor.ReconcileWithDestination([]entities.ManifestEntry{
+		{Path: "/a.txt", Size: 3, Hash: "old"},
+	}, false)
+	g.Expect(events).To(HaveLen(2))
+	for _, event := range events {
+		g.Expect(event.HardLinkOf).To(BeEmpty())
+	}
+}
+
+func TestDirectoryMonitor_DiffSnapshots_Renames(t *testing.T) {
+	file := func(inode uint64, contents string) entities.FileContents {
+		return entities.FileContents{Device: 1, Inode: inode, Size: int64(len(contents)), Hash: "hash-" + contents}
+	}
+	directory := func(inode uint64) entities.FileContents {
+		return entities.FileContents{IsDirectory: true, Device: 1, Inode: inode}
+	}
+	type expectedEvent struct {
+		operation    string
+		name         string
+		previousPath string
+	}
+
+	tests := map[string]struct {
+		previous map[string]entities.FileContents
+		current  map[string]entities.FileContents
+		expected []expectedEvent
+	}{
+		"a file moved with the same contents is renamed": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "a")},
+			current:  map[string]entities.FileContents{"/root/b": file(2, "a")},
+			expected: []expectedEvent{{entities.OperationRenamed, "/root/b", "/root/a"}},
+		},
+		"a reused inode with other contents is a delete and a create": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "a")},
+			current:  map[string]entities.FileContents{"/root/b": file(2, "b")},
+			expected: []expectedEvent{{entities.OperationCreated, "/root/b", ""}, {entities.OperationDeleted, "/root/a", ""}},
+		},
+		"a copy of a deleted file is renamed, preferring one with the same name": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "a"), "/root/b": file(3, "a")},
+			current:  map[string]entities.FileContents{"/root/dir": directory(4), "/root/dir/b": file(5, "a")},
+			expected: []expectedEvent{
+				{entities.OperationCreated, "/root/dir", ""},
+				{entities.OperationRenamed, "/root/dir/b", "/root/b"},
+				{entities.OperationDeleted, "/root/a", ""},
+			},
+		},
+		"empty files aren't matched by their contents": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "")},
+			current:  map[string]entities.FileContents{"/root/b": file(3, "")},
+			expected: []expectedEvent{{entities.OperationCreated, "/root/b", ""}, {entities.OperationDeleted, "/root/a", ""}},
+		},
+		"a file replaced by a copy is modified": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "a")},
+			current:  map[string]entities.FileContents{"/root/a": file(3, "b")},
+			expected: []expectedEvent{{entities.OperationModified, "/root/a", ""}},
+		},
+		"a file is moved before another is created at its old path": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "a")},
+			current:  map[string]entities.FileContents{"/root/a": file(3, "b"), "/root/b": file(2, "a")},
+			expected: []expectedEvent{{entities.OperationRenamed, "/root/b", "/root/a"}, {entities.OperationCreated, "/root/a", ""}},
+		},
+		"a chain of moves is renamed in the order they happened": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "a"), "/root/b": file(3, "b")},
+			current:  map[string]entities.FileContents{"/root/b": file(2, "a"), "/root/c": file(3, "b")},
+			expected: []expectedEvent{{entities.OperationRenamed, "/root/c", "/root/b"}, {entities.OperationRenamed, "/root/b", "/root/a"}},
+		},
+		"a file replaced by a directory is deleted before the directory is created": {
+			previous: map[string]entities.FileContents{"/root/a": file(2, "a")},
+			current:  map[string]entities.FileContents{"/root/a": directory(3), "/root/a/x": file(4, "x")},
+			expected: []expectedEvent{
+				{entities.OperationDeleted, "/root/a", ""},
+				{entities.OperationCreated, "/root/a", ""},
+				{entities.OperationCreated, "/root/a/x", ""},
+			},
+		},
+		"a reused directory inode whose contents have gone is a delete and a create": {
+			previous: map[string]entities.FileContents{"/root/a": directory(2), "/root/a/x": file(3, "x")},
+			current:  map[string]entities.FileContents{"/root/b": directory(2)},
+			expected: []expectedEvent{
+				{entities.OperationCreated, "/root/b", ""},
+				{entities.OperationDeleted, "/root/a", ""},
+				{entities.OperationDeleted, "/root/a/x", ""},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			monitor := &DirectoryMonitor{rootPath: "/root"}
+
+			events := monitor.diffSnapshots(test.previous, test.current)
+			actual := make([]expectedEvent, 0, len(events))
+			for _, event := range events {
+				actual = append(actual, expectedEvent{event.Operation, event.Name, event.PreviousPath})
+			}
+			g.Expect(actual).To(Equal(test.expected))
+		})
+	}
+}
+
+func TestDirectoryMonitor_CopyThenDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "a.txt")
+	g.Expect(os.WriteFile(path, []byte("some content"), 0o644)).To(Succeed())
+
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// moving a file by copying it and deleting the original is a rename
+	g.Expect(os.Mkdir(filepath.Join(root, "dir"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "dir", "a.txt"), []byte("some content"), 0o644)).To(Succeed())
+	g.Expect(os.Remove(path)).To(Succeed())
+
+	eventChan := make(chan entities.FilesystemEvent, 10)
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(2))
+
+	g.Expect((<-eventChan).Name).To(Equal(filepath.Join(root, "dir")))
+	event := <-eventChan
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "dir", "a.txt")))
+	g.Expect(event.Operation).To(Equal(entities.OperationRenamed))
+	g.Expect(event.PreviousPath).To(Equal(path))
+
+	// and saving a file by renaming a new copy over it is a change to it
+	temporaryPath := filepath.Join(root, "dir", ".a.txt.tmp")
+	g.Expect(os.WriteFile(temporaryPath, []byte("new content"), 0o644)).To(Succeed())
+	g.Expect(os.Rename(temporaryPath, filepath.Join(root, "dir", "a.txt"))).To(Succeed())
+
+	g.Expect(monitor.PollForFileChanges(eventChan)).To(Succeed())
+	g.Expect(eventChan).To(HaveLen(1))
+
+	event = <-eventChan
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "dir", "a.txt")))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+}
@@ -0,0 +1,127 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ETagTracker is a struct that persists the last version token the server gave each file, keyed by the path sent to
+// the server, so that requests changing a file can be made conditional on the server's copy not having changed since.
+type ETagTracker struct {
+	mu       sync.Mutex
+	filePath string
+	etags    map[string]string
+}
+
+var _ ETagStore = &ETagTracker{}
+
+// NewETagTracker is a function that loads the version tokens recorded by a previous run of the app.
+func NewETagTracker(filePath string) (*ETagTracker, error) {
+	tracker := &ETagTracker{
+		filePath: filePath,
+		etags:    make(map[string]string),
+	}
+
+	etagBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return tracker, nil
+		}
+		return nil, fmt.Errorf("reading etags: %w", err)
+	}
+
+	err = json.Unmarshal(etagBytes, &tracker.etags)
+	if err != nil {
+		return nil, fmt.Errorf("decoding etags: %w", err)
+	}
+
+	return tracker, nil
+}
+
+// ETagStore is an interface that sets out the functions implemented by the ETagTracker. This allows for mocking of the
+// ETagTracker functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/etagStore.go  . "ETagStore"
+type ETagStore interface {
+	GetETag(path string) (string, bool)
+	SetETag(path, etag string) error
+	MoveETags(oldPath, newPath string) error
+	RemoveETags(path string) error
+	ReplaceETags(etags map[string]string) error
+}
+
+// GetETag is a function that returns the last version token the server gave the file at path, if there is one.
+func (tracker *ETagTracker) GetETag(path string) (string, bool) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	etag, exists := tracker.etags[path]
+	return etag, exists
+}
+
+// SetETag is a function that records the version token the server gave the file at path.
+func (tracker *ETagTracker) SetETag(path, etag string) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.etags[path] == etag {
+		return nil
+	}
+
+	tracker.etags[path] = etag
+	return tracker.save()
+}
+
+// MoveETags is a function that moves the version token of oldPath, and of anything within it, to newPath once the server
+// has renamed it. The contents are unchanged by a rename, so the tokens are too.
+func (tracker *ETagTracker) MoveETags(oldPath, newPath string) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	for path, etag := range tracker.etags {
+		if path != oldPath && !strings.HasPrefix(path, oldPath+"/") {
+			continue
+		}
+		delete(tracker.etags, path)
+		tracker.etags[newPath+strings.TrimPrefix(path, oldPath)] = etag
+	}
+
+	return tracker.save()
+}
+
+// RemoveETags is a function that forgets the version token of path, and of anything within it, once the server has
+// deleted it.
+func (tracker *ETagTracker) RemoveETags(path string) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	for trackedPath := range tracker.etags {
+		if trackedPath == path || strings.HasPrefix(trackedPath, path+"/") {
+			delete(tracker.etags, trackedPath)
+		}
+	}
+
+	return tracker.save()
+}
+
+// ReplaceETags is a function that replaces every version token with those from a fresh listing of the server's files.
+func (tracker *ETagTracker) ReplaceETags(etags map[string]string) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.etags = etags
+	return tracker.save()
+}
+
+func (tracker *ETagTracker) save() error {
+	etagBytes, err := json.Marshal(tracker.etags)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(tracker.filePath, etagBytes, 0o600)
+}
@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	. "github.com/onsi/gomega"
+	"path/filepath"
+	"testing"
+)
+
+func TestETagTracker_PersistsETags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	filePath := filepath.Join(t.TempDir(), "etags")
+
+	tracker, err := NewETagTracker(filePath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(tracker.ReplaceETags(map[string]string{
+		"/dir/a.go": `"a"`,
+		"/dir/b.go": `"b"`,
+		"/dirty.go": `"c"`,
+	})).To(Succeed())
+	g.Expect(tracker.MoveETags("/dir", "/moved")).To(Succeed())
+	g.Expect(tracker.SetETag("/new.go", `"d"`)).To(Succeed())
+
+	reloaded, err := NewETagTracker(filePath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reloaded.etags).To(Equal(map[string]string{
+		"/moved/a.go": `"a"`,
+		"/moved/b.go": `"b"`,
+		"/dirty.go":   `"c"`,
+		"/new.go":     `"d"`,
+	}))
+
+	g.Expect(reloaded.RemoveETags("/moved")).To(Succeed())
+	_, exists := reloaded.GetETag("/moved/a.go")
+	g.Expect(exists).To(BeFalse())
+	etag, exists := reloaded.GetETag("/dirty.go")
+	g.Expect(exists).To(BeTrue())
+	g.Expect(etag).To(Equal(`"c"`))
+}
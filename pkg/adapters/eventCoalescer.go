@@ -0,0 +1,366 @@
+package adapters
+
+import (
+	"cmp"
+	"context"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxCoalesceDelay is the longest the EventCoalescer holds on to a change to a file that never stops changing, such as
+// a log file, unless the quiet period is longer.
+const maxCoalesceDelay = 30 * time.Second
+
+// EventCoalescer is a struct that sits between the watchers of the source directory and the outbox, holding on to the
+// events for each path until the path has been quiet for a while and collapsing them into as few events as possible:
+//   - a create followed by modifies is a create of the latest contents
+//   - a create followed by a delete is nothing at all
+//   - modifies and changes to metadata are replaced by the latest one, or by a delete
+//   - a file deleted and then created again is modified
+//   - a file created and then renamed is only created where it ends up
+//   - a file created as a copy of one that is then deleted, as some tools move files, is renamed from it
+//
+// Anything that can't be collapsed is sent in the order it happened, and an event is never sent before one that came
+// before it on the same path, on a directory above it or on anything within it.
+type EventCoalescer struct {
+	quietPeriod      time.Duration
+	recordedContents func(string) (entities.FileContents, bool)
+	pending          map[string]*coalescedEvent
+	sequence         uint64
+}
+
+// coalescedEvent is a struct that holds the event waiting to be sent for a path. The sequence number and the time it
+// was first seen are those of the first event it was collapsed from.
+type coalescedEvent struct {
+	event     entities.FilesystemEvent
+	sequence  uint64
+	firstSeen time.Time
+	deadline  time.Time
+}
+
+// NewEventCoalescer is a function that creates an EventCoalescer which sends the events for a path once nothing has
+// happened to it for quietPeriod. recordedContents looks up what a deleted file had in it, for deletes reported without
+// its contents. It can be nil, in which case those deletes are never matched with a copy of the file.
+func NewEventCoalescer(quietPeriod time.Duration, recordedContents func(string) (entities.FileContents, bool)) *EventCoalescer {
+	return &EventCoalescer{
+		quietPeriod:      quietPeriod,
+		recordedContents: recordedContents,
+		pending:          make(map[string]*coalescedEvent),
+	}
+}
+
+// Run is a function that reads events from events and publishes the coalesced events to eventChan until the context
+// is cancelled. Events still waiting when it is cancelled are dropped, the changes they describe are picked up from the
+// sync state the next time the app starts. If events is closed, everything waiting is published straight away.
+func (coalescer *EventCoalescer) Run(ctx context.Context, events <-chan entities.FilesystemEvent, eventChan chan<- entities.FilesystemEvent) error {
+	timer := time.NewTimer(coalescer.quietPeriod)
+	defer timer.Stop()
+
+	for {
+		var wake <-chan time.Time
+		deadline, waiting := coalescer.nextDeadline()
+		if waiting {
+			timer.Reset(time.Until(deadline))
+			wake = timer.C
+		}
+
+		var released []entities.FilesystemEvent
+		open := true
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				open = false
+				released = coalescer.release(slices.Collect(maps.Keys(coalescer.pending))...)
+				break
+			}
+			released = coalescer.add(event, time.Now())
+		case <-wake:
+		}
+		released = append(released, coalescer.due(time.Now())...)
+
+		for _, event := range released {
+			select {
+			case <-ctx.Done():
+				return nil
+			case eventChan <- event:
+			}
+		}
+
+		if !open {
+			return nil
+		}
+	}
+}
+
+// add is a function that collapses an event into the one waiting for its path, if there is one, and returns any events
+// that have to be sent straight away because they can't be collapsed with it.
+func (coalescer *EventCoalescer) add(event entities.FilesystemEvent, now time.Time) []entities.FilesystemEvent {
+	if event.Operation == entities.OperationRenamed && event.PreviousPath != "" && event.PreviousPath != event.Name {
+		return coalescer.addRename(event, now)
+	}
+
+	waiting, exists := coalescer.pending[event.Name]
+	if !exists && event.Operation == entities.OperationDeleted {
+		copyPath, copied := coalescer.copyOf(event)
+		if copied {
+			created := coalescer.pending[copyPath]
+			delete(coalescer.pending, copyPath)
+			return coalescer.addRename(entities.FilesystemEvent{
+				Name:         copyPath,
+				Operation:    entities.OperationRenamed,
+				PreviousPath: event.Name,
+				FileContents: created.event.FileContents,
+			}, now)
+		}
+	}
+	if !exists {
+		coalescer.queue(event, coalescer.nextSequence(), now, now)
+		return nil
+	}
+
+	collapsed, keep, ok := collapseEvents(waiting.event, event)
+	if !ok {
+		released := coalescer.release(event.Name)
+		coalescer.queue(event, coalescer.nextSequence(), now, now)
+		return released
+	}
+
+	if !keep {
+		delete(coalescer.pending, event.Name)
+		return nil
+	}
+
+	waiting.event = collapsed
+	waiting.deadline = coalescer.deadline(waiting.firstSeen, now)
+	return nil
+}
+
+// addRename is a function that queues a rename. Anything waiting at the path it moves over is sent first, as is
+// anything waiting at the path it moves from, unless that is a create, in which case the file is only created where it
+// ends up. Changes waiting for anything within a renamed directory are moved with it, to be sent after the rename.
+func (coalescer *EventCoalescer) addRename(event entities.FilesystemEvent, now time.Time) []entities.FilesystemEvent {
+	releasing := []string{event.Name}
+	for path := range coalescer.pending {
+		if isWithin(path, event.Name) {
+			releasing = append(releasing, path)
+		}
+	}
+
+	sequence, firstSeen := coalescer.nextSequence(), now
+	waiting, exists := coalescer.pending[event.PreviousPath]
+	if exists && waiting.event.Operation == entities.OperationCreated {
+		delete(coalescer.pending, event.PreviousPath)
+		event.Operation = entities.OperationCreated
+		event.PreviousPath = ""
+		sequence, firstSeen = waiting.sequence, waiting.firstSeen
+	} else if exists {
+		releasing = append(releasing, event.PreviousPath)
+	}
+
+	released := coalescer.release(releasing...)
+	previousPath := event.PreviousPath
+	if event.Operation == entities.OperationCreated {
+		previousPath = waiting.event.Name
+	}
+	coalescer.queue(event, sequence, firstSeen, now)
+	coalescer.moveWithin(previousPath, event.Name)
+
+	return released
+}
+
+// copyOf is a function that returns the path of a waiting create for a copy of the file deleted by event, preferring
+// one with the same name and then the earliest.
+func (coalescer *EventCoalescer) copyOf(event entities.FilesystemEvent) (string, bool) {
+	deleted := event.FileContents
+	if deleted.Hash == "" && !deleted.IsDirectory && coalescer.recordedContents != nil {
+		deleted, _ = coalescer.recordedContents(event.Name)
+	}
+	if !isMoveCandidate(deleted) {
+		return "", false
+	}
+
+	copies := make([]*coalescedEvent, 0)
+	for _, waiting := range coalescer.pending {
+		if waiting.event.Operation == entities.OperationCreated && waiting.event.HardLinkOf == "" &&
+			waiting.event.FileContents.SameContents(deleted) {
+			copies = append(copies, waiting)
+		}
+	}
+	if len(copies) == 0 {
+		return "", false
+	}
+	slices.SortFunc(copies, compareSequence)
+
+	for _, waiting := range copies {
+		if filepath.Base(waiting.event.Name) == filepath.Base(event.Name) {
+			return waiting.event.Name, true
+		}
+	}
+
+	return copies[0].event.Name, true
+}
+
+// moveWithin is a function that moves the events waiting for anything within a directory that has been renamed from
+// oldPath to newPath. They are queued after the rename, as on the server their paths only exist once it has been
+// applied.
+func (coalescer *EventCoalescer) moveWithin(oldPath, newPath string) {
+	moved := make([]*coalescedEvent, 0)
+	for path, waiting := range coalescer.pending {
+		if isWithin(path, oldPath) {
+			moved = append(moved, waiting)
+			delete(coalescer.pending, path)
+		}
+	}
+	slices.SortFunc(moved, compareSequence)
+
+	for _, waiting := range moved {
+		waiting.event.Name = newPath + strings.TrimPrefix(waiting.event.Name, oldPath)
+		if isWithin(waiting.event.PreviousPath, oldPath) {
+			waiting.event.PreviousPath = newPath + strings.TrimPrefix(waiting.event.PreviousPath, oldPath)
+		}
+		waiting.sequence = coalescer.nextSequence()
+		coalescer.pending[waiting.event.Name] = waiting
+	}
+}
+
+// due is a function that returns the events whose paths have been quiet for long enough, along with anything they have
+// to be sent after.
+func (coalescer *EventCoalescer) due(now time.Time) []entities.FilesystemEvent {
+	paths := make([]string, 0)
+	for path, waiting := range coalescer.pending {
+		if !waiting.deadline.After(now) {
+			paths = append(paths, path)
+		}
+	}
+
+	return coalescer.release(paths...)
+}
+
+// release is a function that stops waiting for the given paths and returns their events, along with those of anything
+// queued before them that overlaps their paths, in the order they were queued.
+func (coalescer *EventCoalescer) release(paths ...string) []entities.FilesystemEvent {
+	releasing := make([]*coalescedEvent, 0, len(paths))
+	overlapping := newPathSet()
+	var latest uint64
+	for _, path := range paths {
+		waiting, exists := coalescer.pending[path]
+		if !exists {
+			continue
+		}
+		delete(coalescer.pending, path)
+		releasing = append(releasing, waiting)
+		overlapping.add(waiting.event)
+		latest = max(latest, waiting.sequence)
+	}
+
+	// anything a released event has to follow may itself have to follow something queued before it, so the queue is
+	// worked through from newest to oldest
+	earlier := make([]*coalescedEvent, 0)
+	for _, waiting := range coalescer.pending {
+		if waiting.sequence < latest {
+			earlier = append(earlier, waiting)
+		}
+	}
+	slices.SortFunc(earlier, compareSequence)
+	for _, waiting := range slices.Backward(earlier) {
+		if overlapping.overlaps(waiting.event) {
+			delete(coalescer.pending, waiting.event.Name)
+			releasing = append(releasing, waiting)
+			overlapping.add(waiting.event)
+		}
+	}
+
+	slices.SortFunc(releasing, compareSequence)
+	events := make([]entities.FilesystemEvent, 0, len(releasing))
+	for _, waiting := range releasing {
+		events = append(events, waiting.event)
+	}
+
+	return events
+}
+
+// nextDeadline is a function that returns when the next event is due to be sent, and false if none are waiting.
+func (coalescer *EventCoalescer) nextDeadline() (time.Time, bool) {
+	var next time.Time
+	for _, waiting := range coalescer.pending {
+		if next.IsZero() || waiting.deadline.Before(next) {
+			next = waiting.deadline
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+func (coalescer *EventCoalescer) queue(event entities.FilesystemEvent, sequence uint64, firstSeen, now time.Time) {
+	coalescer.pending[event.Name] = &coalescedEvent{
+		event:     event,
+		sequence:  sequence,
+		firstSeen: firstSeen,
+		deadline:  coalescer.deadline(firstSeen, now),
+	}
+}
+
+// deadline is a function that returns when an event last changed at now should be sent, which is once the quiet period
+// has passed, or maxCoalesceDelay after it was first seen if that is sooner.
+func (coalescer *EventCoalescer) deadline(firstSeen, now time.Time) time.Time {
+	quiet := now.Add(coalescer.quietPeriod)
+	latest := firstSeen.Add(max(maxCoalesceDelay, coalescer.quietPeriod))
+	if latest.Before(quiet) {
+		return latest
+	}
+
+	return quiet
+}
+
+func (coalescer *EventCoalescer) nextSequence() uint64 {
+	coalescer.sequence++
+	return coalescer.sequence
+}
+
+// collapseEvents is a function that returns the single event that has the same effect as previous followed by next on
+// the same path. keep is false if together they have no effect, and ok is false if they can't be collapsed.
+func collapseEvents(previous, next entities.FilesystemEvent) (collapsed entities.FilesystemEvent, keep bool, ok bool) {
+	switch {
+	case previous.Operation == entities.OperationCreated && next.Operation == entities.OperationDeleted:
+		return entities.FilesystemEvent{}, false, true
+
+	case previous.Operation == entities.OperationCreated &&
+		(next.Operation == entities.OperationModified || next.Operation == entities.OperationCreated ||
+			next.Operation == entities.OperationMetadata):
+		previous.FileContents = next.FileContents
+		return previous, true, true
+
+	case previous.Operation == entities.OperationModified && next.Operation == entities.OperationMetadata:
+		// modifying a file sends its metadata along with its contents
+		previous.FileContents = next.FileContents
+		return previous, true, true
+
+	case (previous.Operation == entities.OperationModified || previous.Operation == entities.OperationMetadata) &&
+		(next.Operation == entities.OperationModified || next.Operation == entities.OperationDeleted ||
+			next.Operation == entities.OperationMetadata):
+		return next, true, true
+
+	case previous.Operation == entities.OperationDeleted && next.Operation == entities.OperationCreated &&
+		!previous.FileContents.IsDirectory && !next.FileContents.IsDirectory:
+		// the file is replaced rather than removed, which on the server is a change to its contents
+		next.Operation = entities.OperationModified
+		return next, true, true
+	}
+
+	return entities.FilesystemEvent{}, false, false
+}
+
+// isWithin is a function that returns whether path is inside the directory dir.
+func isWithin(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func compareSequence(a, b *coalescedEvent) int {
+	return cmp.Compare(a.sequence, b.sequence)
+}
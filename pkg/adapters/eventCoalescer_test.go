@@ -0,0 +1,187 @@
+package adapters
+
+import (
+	"context"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestEventCoalescer_CollapsesEventsOnTheSamePath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	coalescer := NewEventCoalescer(time.Second, nil)
+	now := time.Now()
+
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/a.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Data: []byte("a")}}, now)).To(BeEmpty())
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/a.go", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("ab")}}, now)).To(BeEmpty())
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/b.go", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("b")}}, now)).To(BeEmpty())
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/b.go", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("bc")}}, now)).To(BeEmpty())
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/c.go", Operation: entities.OperationCreated}, now)).To(BeEmpty())
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/c.go", Operation: entities.OperationDeleted}, now)).To(BeEmpty())
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/d.go", Operation: entities.OperationDeleted}, now)).To(BeEmpty())
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/d.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Data: []byte("d")}}, now)).To(BeEmpty())
+
+	g.Expect(coalescer.due(now.Add(time.Second - time.Millisecond))).To(BeEmpty())
+	g.Expect(coalescer.due(now.Add(time.Second))).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/a.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Data: []byte("ab")}},
+		{Name: "./source/b.go", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("bc")}},
+		{Name: "./source/d.go", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("d")}},
+	}))
+	g.Expect(coalescer.pending).To(BeEmpty())
+}
+
+func TestEventCoalescer_CollapsesMetadataChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	coalescer := NewEventCoalescer(time.Second, nil)
+	now := time.Now()
+
+	// a file written and then made executable is sent as a single update with its final mode
+	coalescer.add(entities.FilesystemEvent{Name: "./source/a.sh", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("a"), Mode: 0o644}}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/a.sh", Operation: entities.OperationMetadata, FileContents: entities.FileContents{Data: []byte("a"), Mode: 0o755}}, now)
+	// metadata changes on a file that is then deleted aren't sent
+	coalescer.add(entities.FilesystemEvent{Name: "./source/b.sh", Operation: entities.OperationMetadata, FileContents: entities.FileContents{Mode: 0o755}}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/b.sh", Operation: entities.OperationDeleted}, now)
+	// a metadata change followed by a write is sent as the write
+	coalescer.add(entities.FilesystemEvent{Name: "./source/c.sh", Operation: entities.OperationMetadata, FileContents: entities.FileContents{Mode: 0o755}}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/c.sh", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("c"), Mode: 0o755}}, now)
+
+	g.Expect(coalescer.due(now.Add(time.Second))).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/a.sh", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("a"), Mode: 0o755}},
+		{Name: "./source/b.sh", Operation: entities.OperationDeleted},
+		{Name: "./source/c.sh", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("c"), Mode: 0o755}},
+	}))
+}
+
+func TestEventCoalescer_WaitsForPathToBeQuiet(t *testing.T) {
+	g := NewGomegaWithT(t)
+	coalescer := NewEventCoalescer(time.Second, nil)
+	now := time.Now()
+
+	coalescer.add(entities.FilesystemEvent{Name: "./source/a.log", Operation: entities.OperationModified}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/a.log", Operation: entities.OperationModified}, now.Add(900*time.Millisecond))
+	g.Expect(coalescer.due(now.Add(time.Second))).To(BeEmpty())
+	g.Expect(coalescer.due(now.Add(1900 * time.Millisecond))).To(HaveLen(1))
+
+	// a file that never stops changing is still sent eventually
+	for i := range 40 {
+		coalescer.add(entities.FilesystemEvent{Name: "./source/a.log", Operation: entities.OperationModified}, now.Add(time.Duration(i)*time.Second))
+	}
+	g.Expect(coalescer.due(now.Add(maxCoalesceDelay))).To(HaveLen(1))
+}
+
+func TestEventCoalescer_SendsEventsThatCannotBeCollapsedInOrder(t *testing.T) {
+	g := NewGomegaWithT(t)
+	coalescer := NewEventCoalescer(time.Second, nil)
+	now := time.Now()
+
+	coalescer.add(entities.FilesystemEvent{Name: "./source/dir/a.go", Operation: entities.OperationModified}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/b.go", Operation: entities.OperationModified}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/dir", Operation: entities.OperationDeleted, FileContents: entities.FileContents{IsDirectory: true}}, now)
+
+	// recreating the directory can't be collapsed with deleting it, so the delete and everything it has to follow is sent
+	released := coalescer.add(entities.FilesystemEvent{Name: "./source/dir", Operation: entities.OperationCreated, FileContents: entities.FileContents{IsDirectory: true}}, now)
+	g.Expect(released).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/dir/a.go", Operation: entities.OperationModified},
+		{Name: "./source/dir", Operation: entities.OperationDeleted, FileContents: entities.FileContents{IsDirectory: true}},
+	}))
+
+	g.Expect(coalescer.due(now.Add(time.Second))).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/b.go", Operation: entities.OperationModified},
+		{Name: "./source/dir", Operation: entities.OperationCreated, FileContents: entities.FileContents{IsDirectory: true}},
+	}))
+}
+
+func TestEventCoalescer_Renames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	coalescer := NewEventCoalescer(time.Second, nil)
+	now := time.Now()
+
+	// a file saved to a temporary name and renamed over the original
+	coalescer.add(entities.FilesystemEvent{Name: "./source/a.go.tmp", Operation: entities.OperationCreated}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/a.go.tmp", Operation: entities.OperationModified, FileContents: entities.FileContents{Data: []byte("a")}}, now)
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/a.go", PreviousPath: "./source/a.go.tmp", Operation: entities.OperationRenamed, FileContents: entities.FileContents{Data: []byte("a")}}, now)).To(BeEmpty())
+
+	// a directory renamed with changes waiting for what is inside it
+	coalescer.add(entities.FilesystemEvent{Name: "./source/old/b.go", Operation: entities.OperationModified}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/old", Operation: entities.OperationModified, FileContents: entities.FileContents{IsDirectory: true}}, now)
+	released := coalescer.add(entities.FilesystemEvent{Name: "./source/new", PreviousPath: "./source/old", Operation: entities.OperationRenamed, FileContents: entities.FileContents{IsDirectory: true}}, now)
+	g.Expect(released).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/old/b.go", Operation: entities.OperationModified},
+		{Name: "./source/old", Operation: entities.OperationModified, FileContents: entities.FileContents{IsDirectory: true}},
+	}))
+	coalescer.add(entities.FilesystemEvent{Name: "./source/new/c.go", Operation: entities.OperationModified}, now)
+
+	g.Expect(coalescer.due(now.Add(time.Second))).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/a.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Data: []byte("a")}},
+		{Name: "./source/new", PreviousPath: "./source/old", Operation: entities.OperationRenamed, FileContents: entities.FileContents{IsDirectory: true}},
+		{Name: "./source/new/c.go", Operation: entities.OperationModified},
+	}))
+}
+
+func TestEventCoalescer_RenameMovesChangesWithinDirectory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	coalescer := NewEventCoalescer(time.Second, nil)
+	now := time.Now()
+
+	coalescer.add(entities.FilesystemEvent{Name: "./source/old", Operation: entities.OperationCreated, FileContents: entities.FileContents{IsDirectory: true}}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/old/a.go", Operation: entities.OperationCreated}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/old/b.go", PreviousPath: "./source/old/a.go", Operation: entities.OperationRenamed}, now)
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/new", PreviousPath: "./source/old", Operation: entities.OperationRenamed, FileContents: entities.FileContents{IsDirectory: true}}, now)).To(BeEmpty())
+
+	g.Expect(coalescer.due(now.Add(time.Second))).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/new", Operation: entities.OperationCreated, FileContents: entities.FileContents{IsDirectory: true}},
+		{Name: "./source/new/b.go", Operation: entities.OperationCreated},
+	}))
+}
+
+func TestEventCoalescer_CopyThenDeleteIsRenamed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	contents := entities.FileContents{Inode: 2, Size: 1, Hash: "hash-a"}
+	recorded := map[string]entities.FileContents{
+		"./source/a.go": {Inode: 1, Size: 1, Hash: "hash-a"},
+		"./source/b.go": {Inode: 3, Size: 0, Hash: "hash-empty"},
+	}
+	coalescer := NewEventCoalescer(time.Second, func(path string) (entities.FileContents, bool) {
+		contents, exists := recorded[path]
+		return contents, exists
+	})
+	now := time.Now()
+
+	// a file copied to another directory and then deleted is moved there
+	coalescer.add(entities.FilesystemEvent{Name: "./source/copy.go", Operation: entities.OperationCreated, FileContents: contents}, now)
+	coalescer.add(entities.FilesystemEvent{Name: "./source/dir/a.go", Operation: entities.OperationCreated, FileContents: contents}, now)
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/a.go", Operation: entities.OperationDeleted}, now)).To(BeEmpty())
+	// empty files are never matched by their contents
+	coalescer.add(entities.FilesystemEvent{Name: "./source/c.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Inode: 4, Hash: "hash-empty"}}, now)
+	g.Expect(coalescer.add(entities.FilesystemEvent{Name: "./source/b.go", Operation: entities.OperationDeleted}, now)).To(BeEmpty())
+
+	g.Expect(coalescer.due(now.Add(time.Second))).To(Equal([]entities.FilesystemEvent{
+		{Name: "./source/copy.go", Operation: entities.OperationCreated, FileContents: contents},
+		{Name: "./source/dir/a.go", PreviousPath: "./source/a.go", Operation: entities.OperationRenamed, FileContents: contents},
+		{Name: "./source/c.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Inode: 4, Hash: "hash-empty"}},
+		{Name: "./source/b.go", Operation: entities.OperationDeleted},
+	}))
+}
+
+func TestEventCoalescer_Run(t *testing.T) {
+	g := NewGomegaWithT(t)
+	coalescer := NewEventCoalescer(10*time.Millisecond, nil)
+	events := make(chan entities.FilesystemEvent)
+	eventChan := make(chan entities.FilesystemEvent, 10)
+
+	done := make(chan error)
+	go func() {
+		done <- coalescer.Run(context.Background(), events, eventChan)
+	}()
+
+	events <- entities.FilesystemEvent{Name: "./source/a.go", Operation: entities.OperationCreated}
+	events <- entities.FilesystemEvent{Name: "./source/a.go", Operation: entities.OperationModified}
+	g.Eventually(eventChan).Should(Receive(Equal(entities.FilesystemEvent{Name: "./source/a.go", Operation: entities.OperationCreated})))
+
+	// closing the input sends anything still waiting
+	events <- entities.FilesystemEvent{Name: "./source/b.go", Operation: entities.OperationModified}
+	close(events)
+	g.Eventually(done).Should(Receive(BeNil()))
+	g.Expect(eventChan).To(Receive(Equal(entities.FilesystemEvent{Name: "./source/b.go", Operation: entities.OperationModified})))
+}
@@ -0,0 +1,75 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"path/filepath"
+)
+
+// pathSet is a struct that holds the paths of events that haven't been sent yet, so that an event can be held back
+// until everything queued before it on the same path, on a directory above it or on anything within it has been sent.
+// This keeps a file's changes in order, and a directory's create, rename or delete in order with the changes to what is
+// inside it, while events on unrelated paths are sent concurrently.
+type pathSet struct {
+	paths     map[string]struct{}
+	ancestors map[string]struct{}
+}
+
+func newPathSet() *pathSet {
+	return &pathSet{
+		paths:     make(map[string]struct{}),
+		ancestors: make(map[string]struct{}),
+	}
+}
+
+// add is a function that adds the paths an event refers to to the set.
+func (set *pathSet) add(event entities.FilesystemEvent) {
+	for _, path := range eventPaths(event) {
+		set.paths[path] = struct{}{}
+		for _, ancestor := range pathAncestors(path) {
+			set.ancestors[ancestor] = struct{}{}
+		}
+	}
+}
+
+// overlaps is a function that returns whether any path an event refers to is in the set, is a directory above a path
+// in the set or is within one.
+func (set *pathSet) overlaps(event entities.FilesystemEvent) bool {
+	for _, path := range eventPaths(event) {
+		if _, exists := set.paths[path]; exists {
+			return true
+		}
+		if _, exists := set.ancestors[path]; exists {
+			return true
+		}
+		for _, ancestor := range pathAncestors(path) {
+			if _, exists := set.paths[ancestor]; exists {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// eventPaths is a function that returns the paths an event changes, which for a rename is both where the file was
+// moved from and where it was moved to. A new hard link also depends on the file it links to, so that is included too.
+func eventPaths(event entities.FilesystemEvent) []string {
+	switch {
+	case event.Operation == entities.OperationRenamed && event.PreviousPath != "":
+		return []string{filepath.Clean(event.Name), filepath.Clean(event.PreviousPath)}
+	case event.HardLinkOf != "":
+		return []string{filepath.Clean(event.Name), filepath.Clean(event.HardLinkOf)}
+	}
+
+	return []string{filepath.Clean(event.Name)}
+}
+
+// pathAncestors is a function that returns every directory above a cleaned path.
+func pathAncestors(path string) []string {
+	ancestors := make([]string, 0)
+	for parent := filepath.Dir(path); parent != path; path, parent = parent, filepath.Dir(parent) {
+		ancestors = append(ancestors, parent)
+	}
+
+	return ancestors
+}
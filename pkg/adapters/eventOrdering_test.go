@@ -0,0 +1,23 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestPathSet_Overlaps(t *testing.T) {
+	g := NewGomegaWithT(t)
+	set := newPathSet()
+	set.add(entities.FilesystemEvent{Name: "./source/dir/a.go", Operation: entities.OperationModified})
+	set.add(entities.FilesystemEvent{Name: "./source/new", PreviousPath: "./source/old", Operation: entities.OperationRenamed})
+
+	g.Expect(set.overlaps(entities.FilesystemEvent{Name: "./source/dir/a.go", Operation: entities.OperationDeleted})).To(BeTrue())
+	g.Expect(set.overlaps(entities.FilesystemEvent{Name: "./source/dir", Operation: entities.OperationDeleted})).To(BeTrue())
+	g.Expect(set.overlaps(entities.FilesystemEvent{Name: "./source/old/b.go", Operation: entities.OperationCreated})).To(BeTrue())
+	g.Expect(set.overlaps(entities.FilesystemEvent{Name: "./source/c.go", PreviousPath: "./source/new/c.go", Operation: entities.OperationRenamed})).To(BeTrue())
+	g.Expect(set.overlaps(entities.FilesystemEvent{Name: "./source/link.go", HardLinkOf: "./source/dir/a.go", Operation: entities.OperationCreated})).To(BeTrue())
+
+	g.Expect(set.overlaps(entities.FilesystemEvent{Name: "./source/dir/b.go", Operation: entities.OperationCreated})).To(BeFalse())
+	g.Expect(set.overlaps(entities.FilesystemEvent{Name: "./source/dir2", Operation: entities.OperationCreated})).To(BeFalse())
+}
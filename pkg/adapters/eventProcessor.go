@@ -5,15 +5,30 @@ import (
 	"fmt"
 	"github.com/AlecSmith96/dopbox/pkg/entities"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
+// inlineUploadLimit is the largest file that is sent in the body of a single create or update request, anything larger
+// is streamed to the server in chunks.
+const inlineUploadLimit = 1 << 20
+
 type EventProcessor struct {
-	requestSender RequestSender
-	sourcePath    string
+	requestSender     RequestSender
+	sourcePath        string
+	deltaSync         bool
+	preserveOwnership bool
+	symlinkPolicy     string
 }
 
-func NewEventProcessor(requestSender RequestSender, sourcePath string) *EventProcessor {
+var _ EventSender = &EventProcessor{}
+
+// NewEventProcessor is a function that creates an EventProcessor for the source directory at sourcePath. If deltaSync
+// is true, modified files are sent as a delta against the server's copy where possible. Each file's mode and modification
+// time are sent along with it, as is its owner if preserveOwnership is true. symlinkPolicy decides how symlinks with
+// absolute targets, or targets outside of the source directory, are replicated.
+func NewEventProcessor(requestSender RequestSender, sourcePath string, deltaSync, preserveOwnership bool, symlinkPolicy string) *EventProcessor {
 	var path string
 	trimmedSourcePath := strings.Split(sourcePath, "./")
 	if len(trimmedSourcePath) != 2 {
@@ -23,8 +38,11 @@ func NewEventProcessor(requestSender RequestSender, sourcePath string) *EventPro
 	}
 
 	return &EventProcessor{
-		requestSender: requestSender,
-		sourcePath:    path,
+		requestSender:     requestSender,
+		sourcePath:        path,
+		deltaSync:         deltaSync,
+		preserveOwnership: preserveOwnership,
+		symlinkPolicy:     symlinkPolicy,
 	}
 }
 
@@ -32,26 +50,54 @@ func NewEventProcessor(requestSender RequestSender, sourcePath string) *EventPro
 //
 //go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/requestSender.go  . "RequestSender"
 type RequestSender interface {
-	SendCreateRequest(path string, data []byte, isDirectory bool) error
+	SendCreateRequest(path string, data []byte, isDirectory bool, metadata entities.FileMetadata) error
 	SendDeleteRequest(path string) error
 	SendRenameRequest(oldPath, newPath string) error
-	SendUpdateRequest(path string, data []byte) error
+	SendUpdateRequest(path string, data []byte, metadata entities.FileMetadata) error
+	SendMetadataRequest(path string, metadata entities.FileMetadata) error
+	SendSymlinkRequest(path, target string, replace bool) error
+	SendHardLinkRequest(path, linkedPath string) error
+	GetManifest() ([]entities.ManifestEntry, error)
+	UploadFile(path, localPath string, metadata entities.FileMetadata) error
+	SendDelta(path, localPath string, metadata entities.FileMetadata) error
+	SendBatch(operations []entities.BatchOperation) ([]error, error)
 }
 
 // ProcessEvent is a function that takes a filesystem event and sends the appropriate request to the http server to
 // replicate it in the destination directory.
-// If a message fails to send, it will log an error message. ideally it would be put on a queue to be processed later
-// and prevent data loss.
+// If a message fails to send the error is returned, events are queued in the Outbox so that they are sent again.
 func (processor *EventProcessor) ProcessEvent(event entities.FilesystemEvent) error {
 	trimmedPath := strings.Split(event.Name, processor.sourcePath)
 	if len(trimmedPath) != 2 {
 		return errors.New("invalid trimmed path produced")
 	}
 	filePathWithoutSource := trimmedPath[1]
+	metadata := event.FileContents.Metadata(processor.preserveOwnership)
+
+	if event.FileContents.IsSymlink && (event.Operation == entities.OperationCreated || event.Operation == entities.OperationModified) {
+		return processor.processSymlink(event, filePathWithoutSource)
+	}
+	if event.Operation == entities.OperationCreated && event.HardLinkOf != "" {
+		return processor.processHardLink(event, filePathWithoutSource)
+	}
 
 	switch event.Operation {
 	case entities.OperationCreated:
-		err := processor.requestSender.SendCreateRequest(filePathWithoutSource, event.FileContents.Data, event.FileContents.IsDirectory)
+		if isLargeFile(event) {
+			err := processor.requestSender.UploadFile(filePathWithoutSource, event.Name, metadata)
+			if err != nil {
+				slog.Error("uploading file", "err", err)
+				return err
+			}
+			break
+		}
+
+		data, err := loadFileData(event)
+		if err != nil {
+			return err
+		}
+
+		err = processor.requestSender.SendCreateRequest(filePathWithoutSource, data, event.FileContents.IsDirectory, metadata)
 		if err != nil {
 			slog.Error("processing create request", "err", err)
 			return err
@@ -77,12 +123,45 @@ func (processor *EventProcessor) ProcessEvent(event entities.FilesystemEvent) er
 		}
 
 	case entities.OperationModified:
-		err := processor.requestSender.SendUpdateRequest(filePathWithoutSource, event.FileContents.Data)
+		if processor.deltaSync && isDeltaCandidate(event) {
+			err := processor.requestSender.SendDelta(filePathWithoutSource, event.Name, metadata)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, ErrDeltaUnavailable) {
+				slog.Error("sending delta", "err", err)
+				return err
+			}
+			slog.Debug("unable to send delta, sending whole file", "path", event.Name)
+		}
+
+		if isLargeFile(event) {
+			err := processor.requestSender.UploadFile(filePathWithoutSource, event.Name, metadata)
+			if err != nil {
+				slog.Error("uploading file", "err", err)
+				return err
+			}
+			break
+		}
+
+		data, err := loadFileData(event)
+		if err != nil {
+			return err
+		}
+
+		err = processor.requestSender.SendUpdateRequest(filePathWithoutSource, data, metadata)
 		if err != nil {
 			slog.Error("processing create request", "err", err)
 			return err
 		}
 
+	case entities.OperationMetadata:
+		err := processor.requestSender.SendMetadataRequest(filePathWithoutSource, metadata)
+		if err != nil {
+			slog.Error("processing metadata request", "err", err)
+			return err
+		}
+
 	default:
 		slog.Error("unknown event operation", "operation", event.Operation)
 		return fmt.Errorf("unknown event operation: %s", event.Operation)
@@ -90,3 +169,263 @@ func (processor *EventProcessor) ProcessEvent(event entities.FilesystemEvent) er
 
 	return nil
 }
+
+// processSymlink is a function that replicates a created or retargeted symlink in the destination directory, with its
+// target adjusted by the symlink policy. A symlink the policy skips isn't sent, and if it was retargeted the destination's
+// copy, which points at its old target, is deleted.
+func (processor *EventProcessor) processSymlink(event entities.FilesystemEvent, path string) error {
+	target, ok := processor.linkTarget(event)
+	if !ok {
+		slog.Info("skipping symlink", "path", event.Name, "target", event.FileContents.LinkTarget, "policy", processor.symlinkPolicy)
+		if event.Operation != entities.OperationModified {
+			return nil
+		}
+
+		err := processor.requestSender.SendDeleteRequest(path)
+		if err != nil {
+			slog.Error("processing delete request", "err", err)
+			return err
+		}
+		return nil
+	}
+
+	err := processor.requestSender.SendSymlinkRequest(path, target, event.Operation == entities.OperationModified)
+	if err != nil {
+		slog.Error("processing symlink request", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// processHardLink is a function that replicates a new hard link to a file by linking to the destination's copy of it,
+// rather than sending the file's contents again. If the destination doesn't have a copy to link to, the file is sent as
+// a new file instead.
+func (processor *EventProcessor) processHardLink(event entities.FilesystemEvent, path string) error {
+	linkedPath, err := processor.trimSourcePath(event.HardLinkOf)
+	if err != nil {
+		return err
+	}
+
+	err = processor.requestSender.SendHardLinkRequest(path, linkedPath)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrHardLinkUnavailable) {
+		slog.Error("processing hard link request", "err", err)
+		return err
+	}
+
+	slog.Debug("unable to link to destination copy, sending whole file", "path", event.Name, "hardLinkOf", event.HardLinkOf)
+	event.HardLinkOf = ""
+	return processor.ProcessEvent(event)
+}
+
+// linkTarget is a function that returns the target a symlink is replicated with, and false if the symlink policy skips
+// it. Relative targets within the source directory are always kept as they are, the policy only applies to absolute
+// targets and to relative targets that lead outside of the source directory.
+func (processor *EventProcessor) linkTarget(event entities.FilesystemEvent) (string, bool) {
+	target := event.FileContents.LinkTarget
+	linkPath, err := filepath.Abs(event.Name)
+	if err != nil {
+		return "", false
+	}
+	sourceRoot, err := filepath.Abs(processor.sourcePath)
+	if err != nil {
+		return "", false
+	}
+
+	resolvedTarget := target
+	if !filepath.IsAbs(target) {
+		resolvedTarget = filepath.Join(filepath.Dir(linkPath), target)
+	}
+	resolvedTarget = filepath.Clean(resolvedTarget)
+	withinSource := resolvedTarget == sourceRoot || isWithin(resolvedTarget, sourceRoot)
+	if withinSource && !filepath.IsAbs(target) {
+		return target, true
+	}
+
+	switch processor.symlinkPolicy {
+	case entities.SymlinkPolicyKeep:
+		return target, true
+
+	case entities.SymlinkPolicyRewriteRelative:
+		if !withinSource {
+			return "", false
+		}
+		relativeTarget, err := filepath.Rel(filepath.Dir(linkPath), resolvedTarget)
+		if err != nil {
+			return "", false
+		}
+		return relativeTarget, true
+
+	default:
+		return "", false
+	}
+}
+
+// IsBatchable is a function that returns whether an event can be sent to the server as part of a batch. Files that are
+// streamed to the server or sent as a delta need requests of their own, as do symlinks the symlink policy skips and new
+// hard links, which are sent as a new file if there is nothing to link to.
+func (processor *EventProcessor) IsBatchable(event entities.FilesystemEvent) bool {
+	if event.Operation == entities.OperationCreated && event.HardLinkOf != "" {
+		return false
+	}
+
+	if event.FileContents.IsSymlink && (event.Operation == entities.OperationCreated || event.Operation == entities.OperationModified) {
+		_, ok := processor.linkTarget(event)
+		return ok
+	}
+
+	switch event.Operation {
+	case entities.OperationRenamed, entities.OperationDeleted, entities.OperationMetadata:
+		return true
+	case entities.OperationCreated:
+		return !isLargeFile(event)
+	case entities.OperationModified:
+		return !isLargeFile(event) && !(processor.deltaSync && isDeltaCandidate(event))
+	default:
+		return false
+	}
+}
+
+// ProcessBatch is a function that sends a list of events that are all batchable to the server in a single request,
+// returning the error each one would have returned from ProcessEvent. Events are sent in order, so if one can't be
+// sent, none of those after it are sent either and they are given the same error. The second return value is set if
+// the request failed, in which case every event should be sent again.
+func (processor *EventProcessor) ProcessBatch(events []entities.FilesystemEvent) ([]error, error) {
+	errs := make([]error, len(events))
+	operations := make([]entities.BatchOperation, 0, len(events))
+	indexes := make([]int, 0, len(events))
+	for i, event := range events {
+		operation, err := processor.batchOperation(event)
+		if errors.Is(err, os.ErrNotExist) {
+			errs[i] = err
+			continue
+		}
+		if err != nil {
+			for j := i; j < len(events); j++ {
+				errs[j] = err
+			}
+			break
+		}
+
+		operations = append(operations, operation)
+		indexes = append(indexes, i)
+	}
+
+	if len(operations) == 0 {
+		return errs, nil
+	}
+
+	results, err := processor.requestSender.SendBatch(operations)
+	if err != nil {
+		slog.Error("processing batch request", "err", err)
+		return nil, err
+	}
+
+	for i, index := range indexes {
+		errs[index] = results[i]
+	}
+
+	return errs, nil
+}
+
+// batchOperation is a function that returns the operation within a batch that replicates an event.
+func (processor *EventProcessor) batchOperation(event entities.FilesystemEvent) (entities.BatchOperation, error) {
+	path, err := processor.trimSourcePath(event.Name)
+	if err != nil {
+		return entities.BatchOperation{}, err
+	}
+
+	operation := entities.BatchOperation{
+		Operation: event.Operation,
+		Path:      path,
+	}
+
+	switch event.Operation {
+	case entities.OperationCreated, entities.OperationModified:
+		if event.FileContents.IsSymlink {
+			target, ok := processor.linkTarget(event)
+			if !ok {
+				return entities.BatchOperation{}, fmt.Errorf("symlink %s is skipped by the symlink policy", event.Name)
+			}
+			operation.LinkTarget = target
+			break
+		}
+
+		operation.IsDirectory = event.FileContents.IsDirectory
+		operation.Data, err = loadFileData(event)
+		if err != nil {
+			return entities.BatchOperation{}, err
+		}
+		operation.Headers = processor.metadataHeaders(event)
+
+	case entities.OperationMetadata:
+		operation.Headers = processor.metadataHeaders(event)
+
+	case entities.OperationRenamed:
+		operation.PreviousPath, err = processor.trimSourcePath(event.PreviousPath)
+		if err != nil {
+			return entities.BatchOperation{}, err
+		}
+
+	case entities.OperationDeleted:
+		// the path is all a delete needs
+
+	default:
+		return entities.BatchOperation{}, fmt.Errorf("unknown event operation: %s", event.Operation)
+	}
+
+	return operation, nil
+}
+
+// metadataHeaders is a function that returns the headers that carry the metadata of the file an event refers to.
+func (processor *EventProcessor) metadataHeaders(event entities.FilesystemEvent) map[string]string {
+	metadata := event.FileContents.Metadata(processor.preserveOwnership)
+	if metadata.IsZero() {
+		return nil
+	}
+
+	return map[string]string{
+		"X-File-Metadata": metadata.String(),
+	}
+}
+
+// trimSourcePath is a function that returns the path within the source directory that the server knows path by.
+func (processor *EventProcessor) trimSourcePath(path string) (string, error) {
+	trimmedPath := strings.Split(path, processor.sourcePath)
+	if len(trimmedPath) != 2 {
+		return "", errors.New("invalid trimmed path produced")
+	}
+
+	return trimmedPath[1], nil
+}
+
+// isLargeFile is a function that returns whether the file an event refers to is too large to send in a single request.
+func isLargeFile(event entities.FilesystemEvent) bool {
+	return !event.FileContents.IsDirectory && event.FileContents.Data == nil && event.FileContents.Size > inlineUploadLimit
+}
+
+// isDeltaCandidate is a function that returns whether the file an event refers to is large enough to be worth sending as
+// a delta.
+func isDeltaCandidate(event entities.FilesystemEvent) bool {
+	return !event.FileContents.IsDirectory && event.FileContents.Data == nil && event.FileContents.Size >= deltaSyncThreshold
+}
+
+// loadFileData is a function that returns the contents of the file an event refers to. Snapshots only hold a hash of
+// each file, so the contents are read from the source directory at the point the event is sent rather than when the
+// change is detected.
+func loadFileData(event entities.FilesystemEvent) ([]byte, error) {
+	if event.FileContents.Data != nil || event.FileContents.IsDirectory || event.FileContents.IsSymlink || event.FileContents.Size == 0 {
+		return event.FileContents.Data, nil
+	}
+
+	data, err := os.ReadFile(event.Name)
+	if err != nil {
+		slog.Error("reading file contents", "path", event.Name, "err", err)
+		return nil, err
+	}
+
+	return data, nil
+}
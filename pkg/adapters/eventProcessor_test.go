@@ -6,7 +6,10 @@ import (
 	"github.com/AlecSmith96/dopbox/pkg/entities"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewEventProcessor_ProcessEvent_PathTrimmingReturnsErr(t *testing.T) {
@@ -14,7 +17,7 @@ func TestNewEventProcessor_ProcessEvent_PathTrimmingReturnsErr(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go/source/path",
@@ -26,7 +29,7 @@ func TestNewEventProcessor_ProcessEvent_PathTrimmingReturnsErr(t *testing.T) {
 		},
 	}
 
-	mockHTTPClient.EXPECT().SendCreateRequest("/file.go", nil, false).
+	mockHTTPClient.EXPECT().SendCreateRequest("/file.go", nil, false, entities.FileMetadata{}).
 		Return(nil).Times(0)
 
 	err := eventProcessor.ProcessEvent(event)
@@ -38,7 +41,7 @@ func TestNewEventProcessor_ProcessEvent_CreateHappyPath(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go",
@@ -50,7 +53,7 @@ func TestNewEventProcessor_ProcessEvent_CreateHappyPath(t *testing.T) {
 		},
 	}
 
-	mockHTTPClient.EXPECT().SendCreateRequest("/file.go", nil, false).
+	mockHTTPClient.EXPECT().SendCreateRequest("/file.go", nil, false, entities.FileMetadata{}).
 		Return(nil)
 
 	err := eventProcessor.ProcessEvent(event)
@@ -62,7 +65,7 @@ func TestNewEventProcessor_ProcessEvent_CreateReturnsErr(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go",
@@ -74,7 +77,7 @@ func TestNewEventProcessor_ProcessEvent_CreateReturnsErr(t *testing.T) {
 		},
 	}
 
-	mockHTTPClient.EXPECT().SendCreateRequest("/file.go", nil, false).
+	mockHTTPClient.EXPECT().SendCreateRequest("/file.go", nil, false, entities.FileMetadata{}).
 		Return(errors.New("an error occurred"))
 
 	err := eventProcessor.ProcessEvent(event)
@@ -86,7 +89,7 @@ func TestNewEventProcessor_ProcessEvent_RenameHappyPath(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:         "./source/path/new-file.go",
@@ -111,7 +114,7 @@ func TestNewEventProcessor_ProcessEvent_RenamePathTrimmingReturnsErr(t *testing.
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/new-file.go",
@@ -135,7 +138,7 @@ func TestNewEventProcessor_ProcessEvent_RenameReturnsErr(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:         "./source/path/new-file.go",
@@ -160,7 +163,7 @@ func TestNewEventProcessor_ProcessEvent_DeleteHappyPath(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go",
@@ -184,7 +187,7 @@ func TestNewEventProcessor_ProcessEvent_DeleteReturnsErr(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go",
@@ -208,7 +211,7 @@ func TestNewEventProcessor_ProcessEvent_UpdateHappyPath(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go",
@@ -220,7 +223,7 @@ func TestNewEventProcessor_ProcessEvent_UpdateHappyPath(t *testing.T) {
 		},
 	}
 
-	mockHTTPClient.EXPECT().SendUpdateRequest("/file.go", event.FileContents.Data).
+	mockHTTPClient.EXPECT().SendUpdateRequest("/file.go", event.FileContents.Data, entities.FileMetadata{}).
 		Return(nil)
 
 	err := eventProcessor.ProcessEvent(event)
@@ -232,7 +235,7 @@ func TestNewEventProcessor_ProcessEvent_UpdateReturnsErr(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go",
@@ -244,7 +247,7 @@ func TestNewEventProcessor_ProcessEvent_UpdateReturnsErr(t *testing.T) {
 		},
 	}
 
-	mockHTTPClient.EXPECT().SendUpdateRequest("/file.go", event.FileContents.Data).
+	mockHTTPClient.EXPECT().SendUpdateRequest("/file.go", event.FileContents.Data, entities.FileMetadata{}).
 		Return(errors.New("an error occurred"))
 
 	err := eventProcessor.ProcessEvent(event)
@@ -256,7 +259,7 @@ func TestNewEventProcessor_ProcessEvent_InvalidOperation(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
 
-	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path")
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
 
 	event := entities.FilesystemEvent{
 		Name:      "./source/path/file.go",
@@ -271,3 +274,318 @@ func TestNewEventProcessor_ProcessEvent_InvalidOperation(t *testing.T) {
 	err := eventProcessor.ProcessEvent(event)
 	g.Expect(err).To(MatchError("unknown event operation: INVALID_OPERATION"))
 }
+
+func TestNewEventProcessor_ProcessEvent_CreateReadsFileContentsFromSource(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	sourcePath := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(sourcePath, "file.go"), []byte("some content"), 0o644)).To(Succeed())
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, sourcePath, false, false, entities.SymlinkPolicyKeep)
+
+	event := entities.FilesystemEvent{
+		Name:      filepath.Join(sourcePath, "file.go"),
+		Operation: entities.OperationCreated,
+		FileContents: entities.FileContents{
+			IsDirectory: false,
+			Inode:       0,
+			Size:        int64(len("some content")),
+		},
+	}
+
+	mockHTTPClient.EXPECT().SendCreateRequest("/file.go", []byte("some content"), false, entities.FileMetadata{}).
+		Return(nil)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_ProcessEvent_LargeFilesAreUploadedInChunks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
+
+	event := entities.FilesystemEvent{
+		Name:      "./source/path/file.go",
+		Operation: entities.OperationModified,
+		FileContents: entities.FileContents{
+			IsDirectory: false,
+			Inode:       0,
+			Size:        inlineUploadLimit + 1,
+		},
+	}
+
+	mockHTTPClient.EXPECT().UploadFile("/file.go", "./source/path/file.go", entities.FileMetadata{}).
+		Return(nil)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_ProcessEvent_ModifiedFilesAreSentAsDelta(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", true, false, entities.SymlinkPolicyKeep)
+
+	event := entities.FilesystemEvent{
+		Name:      "./source/path/file.go",
+		Operation: entities.OperationModified,
+		FileContents: entities.FileContents{
+			Size: inlineUploadLimit + 1,
+		},
+	}
+
+	mockHTTPClient.EXPECT().SendDelta("/file.go", "./source/path/file.go", entities.FileMetadata{}).Return(nil)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_ProcessEvent_DeltaUnavailableSendsWholeFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", true, false, entities.SymlinkPolicyKeep)
+
+	event := entities.FilesystemEvent{
+		Name:      "./source/path/file.go",
+		Operation: entities.OperationModified,
+		FileContents: entities.FileContents{
+			Size: inlineUploadLimit + 1,
+		},
+	}
+
+	gomock.InOrder(
+		mockHTTPClient.EXPECT().SendDelta("/file.go", "./source/path/file.go", entities.FileMetadata{}).Return(ErrDeltaUnavailable),
+		mockHTTPClient.EXPECT().UploadFile("/file.go", "./source/path/file.go", entities.FileMetadata{}).Return(nil),
+	)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_IsBatchable(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	eventProcessor := NewEventProcessor(mock_adapters.NewMockRequestSender(ctrl), "./source/path", true, false, entities.SymlinkPolicyKeep)
+
+	g.Expect(eventProcessor.IsBatchable(entities.FilesystemEvent{Operation: entities.OperationDeleted})).To(BeTrue())
+	g.Expect(eventProcessor.IsBatchable(entities.FilesystemEvent{Operation: entities.OperationCreated, FileContents: entities.FileContents{Size: deltaSyncThreshold}})).To(BeTrue())
+	g.Expect(eventProcessor.IsBatchable(entities.FilesystemEvent{Operation: entities.OperationCreated, FileContents: entities.FileContents{Size: inlineUploadLimit + 1}})).To(BeFalse())
+	g.Expect(eventProcessor.IsBatchable(entities.FilesystemEvent{Operation: entities.OperationModified, FileContents: entities.FileContents{Size: 10}})).To(BeTrue())
+	g.Expect(eventProcessor.IsBatchable(entities.FilesystemEvent{Operation: entities.OperationModified, FileContents: entities.FileContents{Size: deltaSyncThreshold}})).To(BeFalse())
+}
+
+func TestNewEventProcessor_ProcessBatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	sourcePath := t.TempDir()
+	eventProcessor := NewEventProcessor(mockHTTPClient, sourcePath, false, false, entities.SymlinkPolicyKeep)
+
+	events := []entities.FilesystemEvent{
+		{Name: filepath.Join(sourcePath, "dir"), Operation: entities.OperationCreated, FileContents: entities.FileContents{IsDirectory: true}},
+		{Name: filepath.Join(sourcePath, "missing.go"), Operation: entities.OperationModified, FileContents: entities.FileContents{Size: 10}},
+		{Name: filepath.Join(sourcePath, "new.go"), PreviousPath: filepath.Join(sourcePath, "old.go"), Operation: entities.OperationRenamed},
+		{Name: filepath.Join(sourcePath, "gone.go"), Operation: entities.OperationDeleted},
+	}
+
+	conflictErr := &ConflictError{Path: "/new.go"}
+	mockHTTPClient.EXPECT().SendBatch([]entities.BatchOperation{
+		{Operation: entities.OperationCreated, Path: "/dir", IsDirectory: true},
+		{Operation: entities.OperationRenamed, Path: "/new.go", PreviousPath: "/old.go"},
+		{Operation: entities.OperationDeleted, Path: "/gone.go"},
+	}).Return([]error{nil, conflictErr, nil}, nil)
+
+	errs, err := eventProcessor.ProcessBatch(events)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs).To(HaveLen(4))
+	g.Expect(errs[0]).ToNot(HaveOccurred())
+	g.Expect(errs[1]).To(MatchError(os.ErrNotExist))
+	g.Expect(errs[2]).To(Equal(conflictErr))
+	g.Expect(errs[3]).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_ProcessBatch_StopsAtEventThatCannotBeSent(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
+
+	events := []entities.FilesystemEvent{
+		{Name: "./source/path/a.go", Operation: entities.OperationDeleted},
+		{Name: "./elsewhere/b.go", Operation: entities.OperationDeleted},
+		{Name: "./source/path/c.go", Operation: entities.OperationDeleted},
+	}
+
+	mockHTTPClient.EXPECT().SendBatch([]entities.BatchOperation{
+		{Operation: entities.OperationDeleted, Path: "/a.go"},
+	}).Return([]error{nil}, nil)
+
+	errs, err := eventProcessor.ProcessBatch(events)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs[0]).ToNot(HaveOccurred())
+	g.Expect(errs[1]).To(MatchError("invalid trimmed path produced"))
+	g.Expect(errs[2]).To(MatchError("invalid trimmed path produced"))
+}
+
+func TestNewEventProcessor_ProcessEvent_MetadataHappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, true, entities.SymlinkPolicyKeep)
+	modTime := time.Date(2025, 5, 19, 21, 51, 24, 0, time.UTC)
+
+	event := entities.FilesystemEvent{
+		Name:      "./source/path/run.sh",
+		Operation: entities.OperationMetadata,
+		FileContents: entities.FileContents{
+			Mode:    0o755,
+			ModTime: modTime,
+			UID:     1000,
+			GID:     100,
+		},
+	}
+
+	mockHTTPClient.EXPECT().SendMetadataRequest("/run.sh", entities.FileMetadata{Mode: 0o755, ModTime: modTime, HasOwner: true, UID: 1000, GID: 100}).
+		Return(nil)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_ProcessEvent_SymlinkPolicies(t *testing.T) {
+	sourcePath := t.TempDir()
+	insideTarget := filepath.Join(sourcePath, "dir", "target.txt")
+
+	tests := map[string]struct {
+		policy         string
+		target         string
+		expectedTarget string
+		skipped        bool
+	}{
+		"relative target within the source is kept": {
+			policy:         entities.SymlinkPolicySkip,
+			target:         "dir/target.txt",
+			expectedTarget: "dir/target.txt",
+		},
+		"absolute target is kept": {
+			policy:         entities.SymlinkPolicyKeep,
+			target:         "/etc/hosts",
+			expectedTarget: "/etc/hosts",
+		},
+		"absolute target within the source is rewritten": {
+			policy:         entities.SymlinkPolicyRewriteRelative,
+			target:         insideTarget,
+			expectedTarget: filepath.Join("dir", "target.txt"),
+		},
+		"absolute target outside the source is skipped when rewriting": {
+			policy:  entities.SymlinkPolicyRewriteRelative,
+			target:  "/etc/hosts",
+			skipped: true,
+		},
+		"relative target outside the source is skipped": {
+			policy:  entities.SymlinkPolicySkip,
+			target:  "../outside.txt",
+			skipped: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ctrl := gomock.NewController(t)
+			mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+			eventProcessor := NewEventProcessor(mockHTTPClient, sourcePath, false, false, test.policy)
+
+			event := entities.FilesystemEvent{
+				Name:         filepath.Join(sourcePath, "link"),
+				Operation:    entities.OperationCreated,
+				FileContents: entities.FileContents{IsSymlink: true, LinkTarget: test.target},
+			}
+
+			if !test.skipped {
+				mockHTTPClient.EXPECT().SendSymlinkRequest("/link", test.expectedTarget, false).Return(nil)
+			}
+
+			err := eventProcessor.ProcessEvent(event)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(eventProcessor.IsBatchable(event)).To(Equal(!test.skipped))
+		})
+	}
+}
+
+func TestNewEventProcessor_ProcessEvent_SkippedSymlinkModifiedIsDeleted(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicySkip)
+
+	event := entities.FilesystemEvent{
+		Name:         "./source/path/link",
+		Operation:    entities.OperationModified,
+		FileContents: entities.FileContents{IsSymlink: true, LinkTarget: "/etc/hosts"},
+	}
+
+	mockHTTPClient.EXPECT().SendDeleteRequest("/link").Return(nil)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_ProcessEvent_HardLink(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
+
+	event := entities.FilesystemEvent{
+		Name:         "./source/path/b.go",
+		Operation:    entities.OperationCreated,
+		HardLinkOf:   "./source/path/a.go",
+		FileContents: entities.FileContents{LinkCount: 2, Data: []byte("some content")},
+	}
+	g.Expect(eventProcessor.IsBatchable(event)).To(BeFalse())
+
+	mockHTTPClient.EXPECT().SendHardLinkRequest("/b.go", "/a.go").Return(nil)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestNewEventProcessor_ProcessEvent_HardLinkUnavailable(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockHTTPClient := mock_adapters.NewMockRequestSender(ctrl)
+
+	eventProcessor := NewEventProcessor(mockHTTPClient, "./source/path", false, false, entities.SymlinkPolicyKeep)
+
+	event := entities.FilesystemEvent{
+		Name:         "./source/path/b.go",
+		Operation:    entities.OperationCreated,
+		HardLinkOf:   "./source/path/a.go",
+		FileContents: entities.FileContents{LinkCount: 2, Data: []byte("some content")},
+	}
+
+	// the server has nothing to link to, so the file is sent instead
+	gomock.InOrder(
+		mockHTTPClient.EXPECT().SendHardLinkRequest("/b.go", "/a.go").Return(ErrHardLinkUnavailable),
+		mockHTTPClient.EXPECT().SendCreateRequest("/b.go", []byte("some content"), false, entities.FileMetadata{}).Return(nil),
+	)
+
+	err := eventProcessor.ProcessEvent(event)
+	g.Expect(err).ToNot(HaveOccurred())
+}
@@ -0,0 +1,290 @@
+package adapters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+type FileReader struct {
+	destinationPath string
+}
+
+var _ DestinationReader = &FileReader{}
+
+func NewFileReader(destinationPath string) *FileReader {
+	return &FileReader{
+		destinationPath: destinationPath,
+	}
+}
+
+// DestinationReader is an interface that sets out the functions implemented by the FileReader. This allows for mocking
+// of the FileReader functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/destinationReader.go  . "DestinationReader"
+type DestinationReader interface {
+	BuildManifest() ([]entities.ManifestEntry, error)
+	BuildSignature(path string) (entities.FileSignature, error)
+	FileETag(path string) (string, bool, error)
+	OpenFile(path string) (io.ReadSeekCloser, entities.TreeEntry, error)
+	ListTree(path string, recursive bool, cursor string, limit int) (entities.TreePage, error)
+}
+
+// BuildManifest is a function that walks the destination directory and returns an entry for every file and directory
+// within it, including the size and a hash of the contents of each file, the mode of every entry and the modification
+// time of each file. Symlinks are not followed, they are listed with their target and have no mode or modification time.
+func (reader *FileReader) BuildManifest() ([]entities.ManifestEntry, error) {
+	manifest := make([]entities.ManifestEntry, 0)
+
+	err := filepath.WalkDir(reader.destinationPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			slog.Error("error accessing path", "path", path, "err", err)
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(reader.destinationPath, path)
+		if err != nil {
+			return err
+		}
+
+		// exclude destination directory entry
+		if relativePath == "." {
+			return nil
+		}
+
+		entry := entities.ManifestEntry{
+			Path:        "/" + filepath.ToSlash(relativePath),
+			IsDirectory: d.IsDir(),
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			slog.Error("getting file info", "path", path, "err", err)
+			return err
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			entry.IsSymlink = true
+			entry.LinkTarget, err = os.Readlink(path)
+			if err != nil {
+				slog.Error("reading symlink", "path", path, "err", err)
+				return err
+			}
+			entry.Size = int64(len(entry.LinkTarget))
+			entry.Hash = entities.LinkTargetHash(entry.LinkTarget)
+
+			manifest = append(manifest, entry)
+			return nil
+		}
+		entry.Mode = info.Mode() & entities.PreservedModeBits
+
+		if !d.IsDir() {
+			entry.Size = info.Size()
+			modTime := info.ModTime()
+			entry.ModTime = &modTime
+
+			entry.Hash, err = hashFile(path)
+			if err != nil {
+				slog.Error("hashing file", "path", path, "err", err)
+				return err
+			}
+		}
+
+		manifest = append(manifest, entry)
+		return nil
+	})
+
+	return manifest, err
+}
+
+// BuildSignature is a function that returns the block signature of the file at path, which a client uses to work out
+// the delta between the file and its own copy.
+func (reader *FileReader) BuildSignature(path string) (entities.FileSignature, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		slog.Debug("unable to open file to build signature", "err", err)
+		return entities.FileSignature{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return entities.FileSignature{}, err
+	}
+
+	return buildFileSignature(file, deltaBlockSize(info.Size()))
+}
+
+// FileETag is a function that returns the version token of the file at path, and whether anything exists at path.
+// Directories have no contents, so they exist without a token. The token of a symlink comes from its target.
+func (reader *FileReader) FileETag(path string) (string, bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	if info.IsDir() {
+		return "", true, nil
+	}
+
+	hash, err := hashEntry(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	return entities.ETag(hash), true, nil
+}
+
+// OpenFile is a function that opens the file at path for reading, returning it along with a description of it. The
+// hash is of the contents of the opened file, so it matches what is read even if path is replaced in the meantime. The
+// caller is responsible for closing it. entities.ErrNotAFile is returned if path is a directory or a symlink, symlinks
+// may point outside of the destination directory so they are never followed.
+func (reader *FileReader) OpenFile(path string) (io.ReadSeekCloser, entities.TreeEntry, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if errors.Is(err, syscall.ELOOP) {
+		return nil, entities.TreeEntry{}, entities.ErrNotAFile
+	}
+	if err != nil {
+		return nil, entities.TreeEntry{}, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, entities.TreeEntry{}, err
+	}
+
+	if info.IsDir() {
+		_ = file.Close()
+		return nil, entities.TreeEntry{}, entities.ErrNotAFile
+	}
+
+	entry := reader.treeEntry(path, info)
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, entities.TreeEntry{}, err
+	}
+	entry.Hash = hex.EncodeToString(hasher.Sum(nil))
+
+	return file, entry, nil
+}
+
+// ListTree is a function that lists the entries within the directory at path, in the order they would be walked, up to
+// limit at a time. If recursive is true everything below path is listed, otherwise only its immediate children are.
+// The listing carries on after the entry named by cursor, the NextCursor of the previous page, or from the start if it
+// is empty. Symlinks are listed but not followed. entities.ErrNotADirectory is returned if path is a file.
+func (reader *FileReader) ListTree(path string, recursive bool, cursor string, limit int) (entities.TreePage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return entities.TreePage{}, err
+	}
+	if !info.IsDir() {
+		return entities.TreePage{}, entities.ErrNotADirectory
+	}
+
+	page := entities.TreePage{
+		Entries: make([]entities.TreeEntry, 0),
+	}
+
+	err = filepath.WalkDir(path, func(entryPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entryPath == path {
+			return nil
+		}
+
+		relativePath := reader.relativePath(entryPath)
+		if cursor != "" && compareWalkOrder(relativePath, cursor) <= 0 {
+			// everything within a directory before the cursor has been listed already, unless the cursor is the directory
+			// itself or inside it
+			if d.IsDir() && (!recursive || (relativePath != cursor && !strings.HasPrefix(cursor, relativePath+"/"))) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if len(page.Entries) == limit {
+			page.NextCursor = page.Entries[len(page.Entries)-1].Path
+			return fs.SkipAll
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := reader.treeEntry(entryPath, info)
+		if entry.Type == entities.EntryTypeFile {
+			entry.Hash, err = hashFile(entryPath)
+			if err != nil {
+				return err
+			}
+		}
+		page.Entries = append(page.Entries, entry)
+
+		if d.IsDir() && !recursive {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return entities.TreePage{}, err
+	}
+
+	return page, nil
+}
+
+// treeEntry is a function that describes the entry at path, without hashing its contents.
+func (reader *FileReader) treeEntry(path string, info fs.FileInfo) entities.TreeEntry {
+	entry := entities.TreeEntry{
+		Path:    reader.relativePath(path),
+		Type:    entities.EntryTypeFile,
+		Size:    info.Size(),
+		Mode:    fmt.Sprintf("%04o", info.Mode().Perm()),
+		ModTime: info.ModTime().UTC(),
+	}
+
+	switch {
+	case info.IsDir():
+		entry.Type = entities.EntryTypeDirectory
+		entry.Size = 0
+	case info.Mode()&fs.ModeSymlink != 0:
+		entry.Type = entities.EntryTypeSymlink
+		entry.LinkTarget, _ = os.Readlink(path)
+	}
+
+	return entry
+}
+
+func (reader *FileReader) relativePath(path string) string {
+	relativePath, err := filepath.Rel(reader.destinationPath, path)
+	if err != nil {
+		return path
+	}
+
+	return "/" + filepath.ToSlash(relativePath)
+}
+
+// compareWalkOrder is a function that compares two paths in the order filepath.WalkDir visits them, which sorts the
+// entries of each directory by name and visits a directory before anything within it.
+func compareWalkOrder(a, b string) int {
+	return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/"))
+}
@@ -0,0 +1,106 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileReader_ListTree(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(destination, "a", "b"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(destination, "a", "b", "c.go"), []byte("some content"), 0o600)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(destination, "a-b.go"), []byte("some content"), 0o644)).To(Succeed())
+	g.Expect(os.Symlink("a-b.go", filepath.Join(destination, "link"))).To(Succeed())
+	reader := NewFileReader(destination)
+
+	paths := func(page entities.TreePage) []string {
+		listed := make([]string, 0, len(page.Entries))
+		for _, entry := range page.Entries {
+			listed = append(listed, entry.Path)
+		}
+		return listed
+	}
+
+	page, err := reader.ListTree(destination, false, "", 10)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(paths(page)).To(Equal([]string{"/a", "/a-b.go", "/link"}))
+	g.Expect(page.NextCursor).To(BeEmpty())
+	g.Expect(page.Entries[0].Type).To(Equal(entities.EntryTypeDirectory))
+	g.Expect(page.Entries[1].Type).To(Equal(entities.EntryTypeFile))
+	g.Expect(page.Entries[1].Size).To(Equal(int64(12)))
+	g.Expect(page.Entries[1].Mode).To(Equal("0644"))
+	g.Expect(page.Entries[1].Hash).To(Equal(contentsHash))
+	g.Expect(page.Entries[2].Type).To(Equal(entities.EntryTypeSymlink))
+	g.Expect(page.Entries[2].Hash).To(BeEmpty())
+
+	// pages of a recursive listing carry on from inside a directory
+	page, err = reader.ListTree(destination, true, "", 2)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(paths(page)).To(Equal([]string{"/a", "/a/b"}))
+	g.Expect(page.NextCursor).To(Equal("/a/b"))
+
+	page, err = reader.ListTree(destination, true, page.NextCursor, 2)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(paths(page)).To(Equal([]string{"/a/b/c.go", "/a-b.go"}))
+	g.Expect(page.Entries[0].Mode).To(Equal("0600"))
+	g.Expect(page.NextCursor).To(Equal("/a-b.go"))
+
+	page, err = reader.ListTree(destination, true, page.NextCursor, 2)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(paths(page)).To(Equal([]string{"/link"}))
+	g.Expect(page.NextCursor).To(BeEmpty())
+
+	_, err = reader.ListTree(filepath.Join(destination, "a-b.go"), false, "", 10)
+	g.Expect(err).To(MatchError(entities.ErrNotADirectory))
+}
+
+func TestFileReader_OpenFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(destination, "a.go"), []byte("some content"), 0o644)).To(Succeed())
+	reader := NewFileReader(destination)
+
+	file, entry, err := reader.OpenFile(filepath.Join(destination, "a.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+	defer file.Close()
+	g.Expect(entry.Path).To(Equal("/a.go"))
+	g.Expect(entry.Size).To(Equal(int64(12)))
+	g.Expect(entry.Hash).To(Equal(contentsHash))
+
+	// the file is read from the start after being hashed
+	contents, err := io.ReadAll(file)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(contents)).To(Equal("some content"))
+
+	_, _, err = reader.OpenFile(destination)
+	g.Expect(err).To(MatchError(entities.ErrNotAFile))
+}
+
+func TestFileReader_Symlinks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret")
+	g.Expect(os.WriteFile(outside, []byte("secret"), 0o644)).To(Succeed())
+	g.Expect(os.Symlink(outside, filepath.Join(destination, "link"))).To(Succeed())
+	reader := NewFileReader(destination)
+
+	// the link is never followed out of the destination directory
+	_, _, err := reader.OpenFile(filepath.Join(destination, "link"))
+	g.Expect(err).To(MatchError(entities.ErrNotAFile))
+
+	manifest, err := reader.BuildManifest()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manifest).To(Equal([]entities.ManifestEntry{
+		{Path: "/link", Size: int64(len(outside)), Hash: entities.LinkTargetHash(outside), IsSymlink: true, LinkTarget: outside},
+	}))
+
+	etag, exists, err := reader.FileETag(filepath.Join(destination, "link"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeTrue())
+	g.Expect(etag).To(Equal(entities.ETag(entities.LinkTargetHash(outside))))
+}
@@ -0,0 +1,159 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+var (
+	// ErrWatchLimitReached is returned by the InotifyWatcher when the kernel refuses to add any more watches, callers
+	// should fall back to the polling DirectoryMonitor when they receive it.
+	ErrWatchLimitReached = errors.New("inotify watch limit reached")
+	// ErrInotifyUnsupported is returned when the InotifyWatcher is created on a platform without inotify.
+	ErrInotifyUnsupported = errors.New("inotify is not supported on this platform")
+)
+
+// FileWatcher is an interface that is implemented by anything that can observe the source directory and publish the
+// changes made to it as entities.FilesystemEvent values. Run blocks until the context is cancelled or an error occurs.
+type FileWatcher interface {
+	Run(ctx context.Context, eventChan chan<- entities.FilesystemEvent) error
+}
+
+var (
+	_ FileWatcher = &DirectoryMonitor{}
+	_ FileWatcher = &InotifyWatcher{}
+)
+
+// readFileContents is a function that builds the entities.FileContents for a single path, it is shared by the polling
+// and inotify watchers so that both produce identical events. The contents of the file are not read into memory, only
+// hashed. If previous is provided and the file's device, inode, size and modification time all match it, the file is
+// assumed to be unchanged and the previous hash is reused rather than reading the file again. Symlinks are not
+// followed, the target they point at is read instead.
+func readFileContents(path string, previous *entities.FileContents) (entities.FileContents, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return entities.FileContents{}, err
+	}
+
+	// get the device and iNode of the file to track name changes and hard links later on
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return entities.FileContents{}, errors.New("unexpected Sys() type")
+	}
+
+	// dont try to get content if file is a directory
+	if info.IsDir() {
+		return entities.FileContents{
+			Device:      uint64(st.Dev),
+			Inode:       st.Ino,
+			ModTime:     info.ModTime(),
+			Mode:        info.Mode() & entities.PreservedModeBits,
+			UID:         st.Uid,
+			GID:         st.Gid,
+			IsDirectory: true,
+		}, nil
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return entities.FileContents{}, err
+		}
+
+		return entities.FileContents{
+			Device:     uint64(st.Dev),
+			Inode:      st.Ino,
+			LinkCount:  uint64(st.Nlink),
+			Size:       int64(len(target)),
+			ModTime:    info.ModTime(),
+			UID:        st.Uid,
+			GID:        st.Gid,
+			IsSymlink:  true,
+			LinkTarget: target,
+			Hash:       entities.LinkTargetHash(target),
+		}, nil
+	}
+
+	contents := entities.FileContents{
+		Device:      uint64(st.Dev),
+		Inode:       st.Ino,
+		LinkCount:   uint64(st.Nlink),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		Mode:        info.Mode() & entities.PreservedModeBits,
+		UID:         st.Uid,
+		GID:         st.Gid,
+		IsDirectory: false,
+	}
+
+	if previous != nil && !previous.IsDirectory && previous.Hash != "" && previous.ID() == contents.ID() &&
+		previous.Size == contents.Size && previous.ModTime.Equal(contents.ModTime) {
+		contents.Hash = previous.Hash
+		return contents, nil
+	}
+
+	contents.Hash, err = hashFile(path)
+	if err != nil {
+		return entities.FileContents{}, err
+	}
+
+	return contents, nil
+}
+
+// readFileID is a function that returns the device and inode of the entry at path, without following it if it is a
+// symlink.
+func readFileID(path string) (entities.FileID, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return entities.FileID{}, err
+	}
+
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return entities.FileID{}, errors.New("unexpected Sys() type")
+	}
+
+	return entities.FileID{Device: uint64(st.Dev), Inode: st.Ino}, nil
+}
+
+// hashEntry is a function that returns the hash of the file at path, or the hash of its target if it is a symlink.
+func hashEntry(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return hashFile(path)
+	}
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+
+	return entities.LinkTargetHash(target), nil
+}
+
+// hashFile is a function that streams the contents of a file through SHA-256, returning the hex encoded digest.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
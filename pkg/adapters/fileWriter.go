@@ -1,21 +1,36 @@
 package adapters
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"syscall"
+	"time"
 )
 
 type FileWriter struct {
 	destinationPath string
+	versionStore    *VersionStore
+	trash           *Trash
 }
 
 var _ FileModifier = &FileWriter{}
 
-func NewFileWriter(destinationPath string) *FileWriter {
+// NewFileWriter is a function that creates a FileWriter for the destination directory. If versionStore is provided, the
+// previous contents of every file that is overwritten are kept in it. If trash is provided, deleted files and
+// directories are moved into it rather than removed.
+func NewFileWriter(destinationPath string, versionStore *VersionStore, trash *Trash) *FileWriter {
 	return &FileWriter{
 		destinationPath: destinationPath,
+		versionStore:    versionStore,
+		trash:           trash,
 	}
 }
 
@@ -28,16 +43,24 @@ type FileModifier interface {
 	DeleteFile(path string) error
 	RenameFile(oldPath, newPath string) error
 	UpdateFile(path string, data []byte) error
+	InstallFile(path, assembledPath string) error
+	ApplyDelta(path string, delta entities.Delta) error
+	SetMetadata(path string, metadata entities.FileMetadata) error
+	CreateSymlink(path, target string) error
+	CreateHardLink(linkedPath, path string) error
 }
 
+// defaultFileMode is the permissions given to files created in the destination directory.
+const defaultFileMode = 0o644
+
 // CreateFile is a function for creating a file at the given path, with the contents if provided. For any files that exist in sub directories it will
-// recursively create each sub directory before creating the file.
+// recursively create each sub directory before creating the file. The file is written atomically, see replaceFile.
 func (writer *FileWriter) CreateFile(path string, data []byte, isDirectory bool) error {
 	if isDirectory {
 		if err := os.MkdirAll(path, 0755); err != nil {
 			return fmt.Errorf("mkdir %q: %w", path, err)
 		}
-		return nil
+		return syncDirectory(filepath.Dir(path))
 	}
 
 	dir := filepath.Dir(path)
@@ -45,46 +68,137 @@ func (writer *FileWriter) CreateFile(path string, data []byte, isDirectory bool)
 		return err
 	}
 
-	file, err := os.Create(path)
+	err := writer.saveVersion(path)
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(path, data, fileModeOrDefault(path, defaultFileMode))
 	if err != nil {
 		slog.Debug("failed to create file", "err", err)
 		return err
 	}
 
-	if len(data) > 0 {
-		if _, err := file.Write(data); err != nil {
-			slog.Debug("failed to write contents to file", "err", err)
-			return err
-		}
+	return nil
+}
+
+// CreateSymlink is a function that creates a symlink at path pointing at target, creating any parent directories. Any
+// file or symlink already at path is replaced atomically, see replaceSymlink. The target is stored as it is and never
+// followed by the server.
+func (writer *FileWriter) CreateSymlink(path, target string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	err := writer.saveVersion(path)
+	if err != nil {
+		return err
+	}
+
+	err = replaceSymlink(path, target)
+	if err != nil {
+		slog.Debug("failed to create symlink", "err", err)
+		return err
 	}
 
 	return nil
 }
 
+// CreateHardLink is a function that creates a hard link at path to the file at linkedPath, creating any parent
+// directories. Anything already at path is replaced atomically, see replaceHardLink. entities.ErrNotAFile is returned if
+// linkedPath is a directory or a symlink.
+func (writer *FileWriter) CreateHardLink(linkedPath, path string) error {
+	info, err := os.Lstat(linkedPath)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return entities.ErrNotAFile
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	err = writer.saveVersion(path)
+	if err != nil {
+		return err
+	}
+
+	err = replaceHardLink(linkedPath, path)
+	if err != nil {
+		slog.Debug("failed to create hard link", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteFile is a function that deletes the file or directory at path. If the FileWriter has a trash, the entry is moved
+// into it so that it can be undeleted, otherwise it is removed along with everything within it.
 func (writer *FileWriter) DeleteFile(path string) error {
+	if writer.trash != nil {
+		err := writer.trash.MoveToTrash(path)
+		if err != nil {
+			slog.Debug("failed to move file to trash", "err", err)
+			return err
+		}
+		return nil
+	}
+
+	err := writer.saveVersion(path)
+	if err != nil {
+		return err
+	}
+
 	// RemoveAll also handles any sub content, so if the file is a directory with files within it, all files within it
 	// are removed as well
-	err := os.RemoveAll(path)
+	err = os.RemoveAll(path)
 	if err != nil {
 		slog.Debug("failed to delete file", "err", err)
 		return err
 	}
-	return nil
+	return syncDirectory(filepath.Dir(path))
 }
 
+// RenameFile is a function that moves the file or directory at oldPath to newPath, replacing anything already at
+// newPath.
 func (writer *FileWriter) RenameFile(oldPath, newPath string) error {
+	if oldPath != newPath {
+		err := writer.replaceRenameTarget(oldPath, newPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	err := os.Rename(oldPath, newPath)
 	if err != nil {
 		slog.Debug("unable to rename file", "err", err)
 		return err
 	}
 
-	return nil
+	err = syncDirectory(filepath.Dir(newPath))
+	if err != nil {
+		return err
+	}
+
+	if filepath.Dir(oldPath) == filepath.Dir(newPath) {
+		return nil
+	}
+	return syncDirectory(filepath.Dir(oldPath))
 }
 
+// UpdateFile is a function that replaces the contents of the file at path, keeping its permissions. The new contents are
+// written atomically, see replaceFile.
 func (writer *FileWriter) UpdateFile(path string, data []byte) error {
-	// WriteFile will remove all content in the file, then replace it with the new content
-	err := os.WriteFile(path, data, 0o644)
+	err := writer.saveVersion(path)
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(path, data, fileModeOrDefault(path, defaultFileMode))
 	if err != nil {
 		slog.Debug("failed to update files contents", "err", err)
 		return err
@@ -92,3 +206,163 @@ func (writer *FileWriter) UpdateFile(path string, data []byte) error {
 
 	return nil
 }
+
+// InstallFile is a function that moves a file assembled from an upload into place at path, replacing any existing file
+// and creating any parent directories. If the upload directory is on a different filesystem, the file is copied instead.
+// Either way the file at path is replaced atomically.
+func (writer *FileWriter) InstallFile(path, assembledPath string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	err := writer.saveVersion(path)
+	if err != nil {
+		return err
+	}
+
+	// uploads are assembled with private permissions, give them the same permissions as any other created file
+	mode := fileModeOrDefault(path, defaultFileMode)
+	if err := os.Chmod(assembledPath, mode); err != nil {
+		return err
+	}
+
+	// the upload manager syncs every chunk as it is written, so only the rename needs to be made durable
+	err = os.Rename(assembledPath, path)
+	if err == nil {
+		return syncDirectory(dir)
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		slog.Debug("unable to move uploaded file into place", "err", err)
+		return err
+	}
+
+	assembledFile, err := os.Open(assembledPath)
+	if err != nil {
+		return err
+	}
+	defer assembledFile.Close()
+
+	err = replaceFile(path, mode, func(file io.Writer) error {
+		_, err := io.Copy(file, assembledFile)
+		return err
+	})
+	if err != nil {
+		slog.Debug("failed to copy uploaded file into place", "err", err)
+		return err
+	}
+
+	return os.Remove(assembledPath)
+}
+
+// ApplyDelta is a function that builds the new version of the file at path from its current contents and a delta. The
+// new version is only moved into place if its hash matches the one in the delta, so the existing file is left untouched
+// if the delta was computed against a different version of it.
+func (writer *FileWriter) ApplyDelta(path string, delta entities.Delta) error {
+	base, err := os.Open(path)
+	if err != nil {
+		slog.Debug("unable to open file to apply delta", "err", err)
+		return err
+	}
+	defer base.Close()
+
+	info, err := base.Stat()
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(path, info.Mode()&entities.PreservedModeBits, func(file io.Writer) error {
+		hasher := sha256.New()
+		err := applyDelta(base, info.Size(), delta, io.MultiWriter(file, hasher))
+		if err != nil {
+			slog.Debug("failed to apply delta", "err", err)
+			return err
+		}
+
+		if hex.EncodeToString(hasher.Sum(nil)) != delta.Hash {
+			return entities.ErrDeltaHashMismatch
+		}
+
+		// the current contents are only kept once the delta is known to apply, just before they are replaced
+		return writer.saveVersion(path)
+	})
+}
+
+// SetMetadata is a function that gives the file or directory at path the mode, modification time and owner in metadata,
+// leaving any that aren't set as they are. Changing the owner usually needs the server to run as root, so if it isn't
+// permitted the owner is left as it is and a warning is logged rather than failing the request. Only the owner of a
+// symlink is changed, its mode and modification time would be set on the entry it points at.
+func (writer *FileWriter) SetMetadata(path string, metadata entities.FileMetadata) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		metadata.Mode, metadata.ModTime = 0, time.Time{}
+	}
+
+	if metadata.HasOwner {
+		err := os.Lchown(path, int(metadata.UID), int(metadata.GID))
+		if errors.Is(err, fs.ErrPermission) {
+			slog.Warn("not permitted to change owner of file", "path", path, "uid", metadata.UID, "gid", metadata.GID)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if metadata.Mode != 0 {
+		// changing the owner clears the setuid and setgid bits, so the mode is set afterwards
+		if err := os.Chmod(path, metadata.Mode); err != nil {
+			return err
+		}
+	}
+
+	if !metadata.ModTime.IsZero() {
+		// a zero access time is left unchanged
+		if err := os.Chtimes(path, time.Time{}, metadata.ModTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceRenameTarget is a function that gets anything at newPath out of the way of a rename. A file renamed over
+// another file replaces it, so only a version of it is kept. A directory can't be renamed over, or replace, an existing
+// entry, so the entry at newPath is deleted first.
+func (writer *FileWriter) replaceRenameTarget(oldPath, newPath string) error {
+	targetInfo, err := os.Lstat(newPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	sourceInfo, err := os.Lstat(oldPath)
+	if err != nil {
+		return err
+	}
+
+	if !targetInfo.IsDir() && !sourceInfo.IsDir() {
+		return writer.saveVersion(newPath)
+	}
+
+	return writer.DeleteFile(newPath)
+}
+
+// saveVersion is a function that keeps the current contents of path in the version store, if there is one, before they
+// are replaced.
+func (writer *FileWriter) saveVersion(path string) error {
+	if writer.versionStore == nil {
+		return nil
+	}
+
+	err := writer.versionStore.SaveVersion(path)
+	if err != nil {
+		slog.Error("saving previous version", "path", path, "err", err)
+		return err
+	}
+
+	return nil
+}
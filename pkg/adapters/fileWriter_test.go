@@ -0,0 +1,207 @@
+package adapters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWriter_CreateAndUpdateFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	writer := NewFileWriter(destination, nil, nil)
+	path := filepath.Join(destination, "sub", "file.go")
+
+	g.Expect(writer.CreateFile(path, []byte("some content"), false)).To(Succeed())
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("some content")))
+
+	g.Expect(os.Chmod(path, 0o600)).To(Succeed())
+	g.Expect(writer.UpdateFile(path, []byte("new"))).To(Succeed())
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("new")))
+
+	info, err := os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+}
+
+func TestReplaceFile_FailedWriteLeavesFileUntouched(t *testing.T) {
+	g := NewGomegaWithT(t)
+	directory := t.TempDir()
+	path := filepath.Join(directory, "file.go")
+	g.Expect(os.WriteFile(path, []byte("some content"), 0o644)).To(Succeed())
+
+	err := replaceFile(path, 0o644, func(writer io.Writer) error {
+		_, _ = writer.Write([]byte("partial"))
+		return errors.New("an error occurred")
+	})
+	g.Expect(err).To(MatchError("an error occurred"))
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("some content")))
+
+	entries, err := os.ReadDir(directory)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+}
+
+func TestFileWriter_ApplyDelta(t *testing.T) {
+	g := NewGomegaWithT(t)
+	versionStore, destination := newTestVersionStore(t, VersionRetention{MaxVersions: 10})
+	writer := NewFileWriter(destination, versionStore, nil)
+	path := filepath.Join(destination, "file.go")
+	g.Expect(os.WriteFile(path, []byte("some old content"), 0o644)).To(Succeed())
+
+	signature, err := buildFileSignature(bytes.NewReader([]byte("some old content")), 4)
+	g.Expect(err).ToNot(HaveOccurred())
+	operations, hash, err := computeDelta(bytes.NewReader([]byte("some new content")), signature, 16)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	mismatch := sha256.Sum256([]byte("something else"))
+	err = writer.ApplyDelta(path, entities.Delta{
+		Hash:       hex.EncodeToString(mismatch[:]),
+		BlockSize:  4,
+		Operations: operations,
+	})
+	g.Expect(err).To(MatchError(entities.ErrDeltaHashMismatch))
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("some old content")))
+	// a rejected delta doesn't keep a version of the file it left untouched
+	g.Expect(versionStore.ListVersions(path)).To(BeEmpty())
+
+	err = writer.ApplyDelta(path, entities.Delta{
+		Hash:       hash,
+		BlockSize:  4,
+		Operations: operations,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("some new content")))
+	g.Expect(versionStore.ListVersions(path)).To(HaveLen(1))
+}
+
+func TestFileWriter_ReplacingAFileKeepsSpecialModeBits(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	writer := NewFileWriter(destination, nil, nil)
+	path := filepath.Join(destination, "run.sh")
+	g.Expect(os.WriteFile(path, []byte("echo old"), 0o755)).To(Succeed())
+	g.Expect(os.Chmod(path, 0o755|os.ModeSetuid)).To(Succeed())
+
+	signature, err := buildFileSignature(bytes.NewReader([]byte("echo old")), 4)
+	g.Expect(err).ToNot(HaveOccurred())
+	operations, hash, err := computeDelta(bytes.NewReader([]byte("echo new")), signature, 16)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(writer.ApplyDelta(path, entities.Delta{Hash: hash, BlockSize: 4, Operations: operations})).To(Succeed())
+
+	info, err := os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode() & entities.PreservedModeBits).To(Equal(0o755 | os.ModeSetuid))
+
+	g.Expect(writer.UpdateFile(path, []byte("echo newer"))).To(Succeed())
+	info, err = os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode() & entities.PreservedModeBits).To(Equal(0o755 | os.ModeSetuid))
+}
+
+func TestFileWriter_RenameOverDirectory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	writer := NewFileWriter(destination, nil, nil)
+	source := filepath.Join(destination, "file.go")
+	target := filepath.Join(destination, "dir")
+
+	g.Expect(writer.CreateFile(source, []byte("contents"), false)).To(Succeed())
+	g.Expect(writer.CreateFile(filepath.Join(target, "nested.go"), []byte("nested"), false)).To(Succeed())
+
+	g.Expect(writer.RenameFile(source, target)).To(Succeed())
+	g.Expect(os.ReadFile(target)).To(Equal([]byte("contents")))
+	g.Expect(source).ToNot(BeAnExistingFile())
+}
+
+func TestFileWriter_SetMetadata(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	writer := NewFileWriter(destination, nil, nil)
+	path := filepath.Join(destination, "run.sh")
+	g.Expect(writer.CreateFile(path, []byte("echo"), false)).To(Succeed())
+
+	modTime := time.Date(2025, 5, 19, 21, 51, 24, 123456789, time.UTC)
+	g.Expect(writer.SetMetadata(path, entities.FileMetadata{
+		Mode:     0o750,
+		ModTime:  modTime,
+		HasOwner: true,
+		UID:      uint32(os.Getuid()),
+		GID:      uint32(os.Getgid()),
+	})).To(Succeed())
+
+	info, err := os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o750)))
+	g.Expect(info.ModTime().Equal(modTime)).To(BeTrue())
+
+	// anything that isn't set is left as it is
+	g.Expect(writer.SetMetadata(path, entities.FileMetadata{Mode: 0o700})).To(Succeed())
+	info, err = os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o700)))
+	g.Expect(info.ModTime().Equal(modTime)).To(BeTrue())
+
+	g.Expect(writer.SetMetadata(filepath.Join(destination, "missing"), entities.FileMetadata{Mode: 0o700})).
+		To(MatchError(os.ErrNotExist))
+}
+
+func TestFileWriter_CreateSymlink(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	writer := NewFileWriter(destination, nil, nil)
+	path := filepath.Join(destination, "sub", "link")
+
+	g.Expect(writer.CreateSymlink(path, "../a.txt")).To(Succeed())
+	g.Expect(os.Readlink(path)).To(Equal("../a.txt"))
+
+	// an existing file is replaced by the link rather than written through
+	g.Expect(os.Remove(path)).To(Succeed())
+	g.Expect(os.WriteFile(path, []byte("some content"), 0o644)).To(Succeed())
+	g.Expect(writer.CreateSymlink(path, "b.txt")).To(Succeed())
+	g.Expect(os.Readlink(path)).To(Equal("b.txt"))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+}
+
+func TestFileWriter_CreateHardLink(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	writer := NewFileWriter(destination, nil, nil)
+	linkedPath := filepath.Join(destination, "a.txt")
+	path := filepath.Join(destination, "sub", "b.txt")
+	g.Expect(os.WriteFile(linkedPath, []byte("some content"), 0o644)).To(Succeed())
+	g.Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(path, []byte("old"), 0o644)).To(Succeed())
+
+	g.Expect(writer.CreateHardLink(linkedPath, path)).To(Succeed())
+	linkedInfo, err := os.Stat(linkedPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	info, err := os.Stat(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(os.SameFile(linkedInfo, info)).To(BeTrue())
+
+	// linking again leaves the link, and no temporary files, behind
+	g.Expect(writer.CreateHardLink(linkedPath, path)).To(Succeed())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+
+	g.Expect(writer.CreateHardLink(filepath.Join(destination, "sub"), path)).To(MatchError(entities.ErrNotAFile))
+	g.Expect(writer.CreateHardLink(filepath.Join(destination, "missing.txt"), path)).To(MatchError(os.ErrNotExist))
+}
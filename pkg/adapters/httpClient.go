@@ -2,28 +2,79 @@ package adapters
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 )
 
+// uploadChunkSize is the size of each chunk sent by UploadFile, it bounds how much of a file is held in memory at once.
+const uploadChunkSize = 8 << 20
+
 type RequestClient struct {
-	client  HttpClient
-	baseURL string
+	client         HttpClient
+	baseURL        string
+	pendingUploads PendingUploadStore
+	etags          ETagStore
+	conflictPolicy string
+	conflicts      ConflictRecorder
+	versions       VersionVectorStore
 }
 
 var _ RequestSender = &RequestClient{}
 
-func NewHTTPClient(client HttpClient, baseURL string) *RequestClient {
+// NewHTTPClient is a function that creates a RequestClient for the server at baseURL. Requests that change a file are
+// made conditional on the server's copy being the version last recorded in etags. If the server's copy has changed, the
+// conflict is resolved with conflictPolicy, or the server's default policy if it is empty, and recorded in conflicts.
+// Every change is sent with the app's ID and the file's version vector from versions, which is updated with the vector
+// the server returns once the change is applied.
+func NewHTTPClient(client HttpClient, baseURL string, pendingUploads PendingUploadStore, etags ETagStore, conflictPolicy string, conflicts ConflictRecorder, versions VersionVectorStore) *RequestClient {
 	return &RequestClient{
-		client:  client,
-		baseURL: baseURL,
+		client:         client,
+		baseURL:        baseURL,
+		pendingUploads: pendingUploads,
+		etags:          etags,
+		conflictPolicy: conflictPolicy,
+		conflicts:      conflicts,
+		versions:       versions,
 	}
 }
 
+// StatusCodeError is returned when the server responds to a request with a status code other than 200.
+type StatusCodeError struct {
+	StatusCode int
+}
+
+func (e *StatusCodeError) Error() string {
+	return fmt.Sprintf("request failed with status code %d", e.StatusCode)
+}
+
+// ConflictError is returned when the server refuses to change a file because its copy is no longer the version the
+// client last saw, meaning something else has changed it since. ETag is the version token of the server's copy, it is
+// empty if the file no longer exists on the server.
+type ConflictError struct {
+	Path string
+	ETag string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict: %s has changed on the server", e.Path)
+}
+
+// ErrHardLinkUnavailable is returned when the server has no copy of the file a new hard link is linked to, callers
+// should fall back to sending the file's contents when they receive it.
+var ErrHardLinkUnavailable = errors.New("hard link target unavailable")
+
 // HttpClient is an interface used for mocking the actual http calls for testing
 //
 //go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/httpClient.go  . "HttpClient"
@@ -51,7 +102,7 @@ func (c *RequestClient) IsServerLive() bool {
 	return true
 }
 
-func (c *RequestClient) SendCreateRequest(path string, data []byte, isDirectory bool) error {
+func (c *RequestClient) SendCreateRequest(path string, data []byte, isDirectory bool, metadata entities.FileMetadata) error {
 	type createRequestBody struct {
 		Path        string `json:"path"`
 		Data        []byte `json:"data"`
@@ -73,6 +124,11 @@ func (c *RequestClient) SendCreateRequest(path string, data []byte, isDirectory
 		slog.Debug("error creating request", "err", err)
 		return err
 	}
+	if !isDirectory {
+		c.setPrecondition(req, path)
+	}
+	c.setVersion(req, path)
+	setMetadata(req, metadata)
 
 	response, err := c.client.Do(req)
 	if err != nil {
@@ -80,13 +136,10 @@ func (c *RequestClient) SendCreateRequest(path string, data []byte, isDirectory
 		return err
 	}
 
-	// we can just check for != 200 here as we know the server doesnt return any other success codes (2**)
-	if response.StatusCode != http.StatusOK {
-		slog.Debug("request failed with status code", "statusCode", response.StatusCode)
-		return fmt.Errorf("request failed with status code %d", response.StatusCode)
-	}
+	defer response.Body.Close()
 
-	return nil
+	_, err = c.checkResponse(response, path, entities.OperationCreated)
+	return err
 }
 
 func (c *RequestClient) SendDeleteRequest(path string) error {
@@ -107,6 +160,8 @@ func (c *RequestClient) SendDeleteRequest(path string) error {
 		slog.Debug("error creating request", "err", err)
 		os.Exit(1)
 	}
+	c.setPrecondition(req, path)
+	c.setVersion(req, path)
 
 	response, err := c.client.Do(req)
 	if err != nil {
@@ -114,12 +169,14 @@ func (c *RequestClient) SendDeleteRequest(path string) error {
 		return err
 	}
 
-	// we can just check for != 200 here as we know the server doesnt return any other success codes (2**)
-	if response.StatusCode != http.StatusOK {
-		slog.Error("request failed with status code", "statusCode", response.StatusCode)
-		return errors.New("request failed")
+	defer response.Body.Close()
+
+	applied, err := c.checkResponse(response, path, entities.OperationDeleted)
+	if err != nil || !applied {
+		return err
 	}
 
+	c.recordDelete(path)
 	return nil
 }
 
@@ -143,6 +200,9 @@ func (c *RequestClient) SendRenameRequest(oldPath, newPath string) error {
 		slog.Debug("error creating request", "err", err)
 		os.Exit(1)
 	}
+	c.setPrecondition(req, oldPath)
+	c.setTargetPrecondition(req, newPath)
+	c.setVersion(req, oldPath)
 
 	response, err := c.client.Do(req)
 	if err != nil {
@@ -150,16 +210,18 @@ func (c *RequestClient) SendRenameRequest(oldPath, newPath string) error {
 		return err
 	}
 
-	// we can just check for != 200 here as we know the server doesnt return any other success codes (2**)
-	if response.StatusCode != http.StatusOK {
-		slog.Error("request failed with status code", "statusCode", response.StatusCode)
-		return errors.New("request failed")
+	defer response.Body.Close()
+
+	applied, err := c.checkResponse(response, oldPath, entities.OperationRenamed)
+	if err != nil || !applied {
+		return err
 	}
 
+	c.recordRename(oldPath, newPath)
 	return nil
 }
 
-func (c *RequestClient) SendUpdateRequest(path string, data []byte) error {
+func (c *RequestClient) SendUpdateRequest(path string, data []byte, metadata entities.FileMetadata) error {
 	type updateRequestBody struct {
 		Path string
 		Data []byte
@@ -179,6 +241,9 @@ func (c *RequestClient) SendUpdateRequest(path string, data []byte) error {
 		slog.Debug("error creating request", "err", err)
 		os.Exit(1)
 	}
+	c.setPrecondition(req, path)
+	c.setVersion(req, path)
+	setMetadata(req, metadata)
 
 	response, err := c.client.Do(req)
 	if err != nil {
@@ -186,11 +251,803 @@ func (c *RequestClient) SendUpdateRequest(path string, data []byte) error {
 		return err
 	}
 
-	// we can just check for != 200 here as we know the server doesnt return any other success codes (2**)
+	defer response.Body.Close()
+
+	_, err = c.checkResponse(response, path, entities.OperationModified)
+	return err
+}
+
+// SendSymlinkRequest is a function that asks the server to create a symlink at path pointing at target, or to retarget
+// the symlink already there if replace is set. The target is sent as it is, the server never follows it.
+func (c *RequestClient) SendSymlinkRequest(path, target string, replace bool) error {
+	type symlinkRequestBody struct {
+		Path       string `json:"path"`
+		LinkTarget string `json:"linkTarget"`
+	}
+
+	method, operation := http.MethodPost, entities.OperationCreated
+	if replace {
+		method, operation = http.MethodPut, entities.OperationModified
+	}
+
+	response, err := c.sendConditionalJSON(method, fmt.Sprintf("%s/v1/file", c.baseURL), path, operation, entities.FileMetadata{}, symlinkRequestBody{
+		Path:       path,
+		LinkTarget: target,
+	})
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// SendHardLinkRequest is a function that asks the server to create a hard link at path to its copy of the file at
+// linkedPath, replacing anything already at path. ErrHardLinkUnavailable is returned if the server has no file at
+// linkedPath.
+func (c *RequestClient) SendHardLinkRequest(path, linkedPath string) error {
+	type hardLinkRequestBody struct {
+		Path       string `json:"path"`
+		HardLinkOf string `json:"hardLinkOf"`
+	}
+
+	response, err := c.sendConditionalJSON(http.MethodPost, fmt.Sprintf("%s/v1/file", c.baseURL), path, entities.OperationCreated, entities.FileMetadata{}, hardLinkRequestBody{
+		Path:       path,
+		HardLinkOf: linkedPath,
+	})
+	if err != nil {
+		var statusCodeErr *StatusCodeError
+		if errors.As(err, &statusCodeErr) && statusCodeErr.StatusCode == http.StatusNotFound {
+			return ErrHardLinkUnavailable
+		}
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// SendMetadataRequest is a function that asks the server to give the file or directory at path the mode, modification
+// time and owner in metadata, leaving its contents and version as they are.
+func (c *RequestClient) SendMetadataRequest(path string, metadata entities.FileMetadata) error {
+	// the server rejects a request with nothing to set, such as for a file whose permissions have all been removed
+	if metadata.IsZero() {
+		return nil
+	}
+
+	type metadataRequestBody struct {
+		Path string `json:"path"`
+	}
+
+	req, err := newJSONRequest(http.MethodPut, fmt.Sprintf("%s/v1/file/metadata", c.baseURL), metadataRequestBody{
+		Path: path,
+	})
+	if err != nil {
+		return err
+	}
+	setMetadata(req, metadata)
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending metadata request", "err", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	_, err = c.checkResponse(response, path, entities.OperationMetadata)
+	return err
+}
+
+// SendBatch is a function that sends a list of creates, updates, metadata changes, renames and deletes to the server in
+// a single request, to be applied in order. Each operation is sent with the same preconditions and version vector it
+// would have been sent with on its own, and its result is handled the same way, so the error returned for each one is
+// what sending it on its own would have returned. The second return value is only set if the batch as a whole failed,
+// in which case none of the results are known.
+func (c *RequestClient) SendBatch(operations []entities.BatchOperation) ([]error, error) {
+	type batchRequestBody struct {
+		Operations []entities.BatchOperation `json:"operations"`
+	}
+	type batchResponseBody struct {
+		Results []entities.BatchResult `json:"results"`
+	}
+
+	for i, operation := range operations {
+		operations[i].Headers = c.operationHeaders(operation)
+	}
+
+	req, err := newJSONRequest(http.MethodPost, fmt.Sprintf("%s/v1/batch", c.baseURL), batchRequestBody{
+		Operations: operations,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Client-ID", c.versions.ClientID())
+	if c.conflictPolicy != "" {
+		req.Header.Set("X-Conflict-Policy", c.conflictPolicy)
+	}
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending batch request", "err", err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		slog.Error("request failed with status code", "statusCode", response.StatusCode)
+		return nil, &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	var responseBody batchResponseBody
+	err = json.NewDecoder(response.Body).Decode(&responseBody)
+	if err != nil {
+		slog.Debug("unable to decode batch response body", "err", err)
+		return nil, err
+	}
+	if len(responseBody.Results) != len(operations) {
+		return nil, fmt.Errorf("batch of %d operations returned %d results", len(operations), len(responseBody.Results))
+	}
+
+	errs := make([]error, len(operations))
+	for i, operation := range operations {
+		result := responseBody.Results[i]
+		header := make(http.Header, len(result.Headers))
+		for name, value := range result.Headers {
+			header.Set(name, value)
+		}
+		operationResponse := &http.Response{
+			StatusCode: result.Status,
+			Header:     header,
+		}
+
+		path := operation.Path
+		if operation.Operation == entities.OperationRenamed {
+			path = operation.PreviousPath
+		}
+
+		applied, err := c.checkResponse(operationResponse, path, operation.Operation)
+		errs[i] = err
+		if err != nil || !applied {
+			continue
+		}
+
+		switch operation.Operation {
+		case entities.OperationDeleted:
+			c.recordDelete(operation.Path)
+		case entities.OperationRenamed:
+			c.recordRename(operation.PreviousPath, operation.Path)
+		}
+	}
+
+	return errs, nil
+}
+
+// operationHeaders is a function that returns the headers an operation within a batch would have been sent with on its
+// own, along with any it already has. The app's ID and conflict policy are the same for every operation, so they are
+// sent once for the whole batch.
+func (c *RequestClient) operationHeaders(operation entities.BatchOperation) map[string]string {
+	req := &http.Request{
+		Header: make(http.Header),
+	}
+	for name, value := range operation.Headers {
+		req.Header.Set(name, value)
+	}
+	switch operation.Operation {
+	case entities.OperationRenamed:
+		c.setPrecondition(req, operation.PreviousPath)
+		c.setTargetPrecondition(req, operation.Path)
+		c.setVersion(req, operation.PreviousPath)
+	case entities.OperationCreated:
+		if !operation.IsDirectory {
+			c.setPrecondition(req, operation.Path)
+		}
+		c.setVersion(req, operation.Path)
+	case entities.OperationMetadata:
+		// metadata changes aren't versioned
+	default:
+		c.setPrecondition(req, operation.Path)
+		c.setVersion(req, operation.Path)
+	}
+	req.Header.Del("X-Client-ID")
+	req.Header.Del("X-Conflict-Policy")
+
+	headers := make(map[string]string, len(req.Header))
+	for name := range req.Header {
+		headers[name] = req.Header.Get(name)
+	}
+
+	return headers
+}
+
+// GetManifest is a function that fetches the manifest of every file and directory in the destination directory.
+func (c *RequestClient) GetManifest() ([]entities.ManifestEntry, error) {
+	type manifestResponseBody struct {
+		Entries []entities.ManifestEntry `json:"entries"`
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/manifest", c.baseURL), nil)
+	if err != nil {
+		slog.Debug("error creating request", "err", err)
+		return nil, err
+	}
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending manifest request", "err", err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		slog.Error("request failed with status code", "statusCode", response.StatusCode)
+		return nil, &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	var responseBody manifestResponseBody
+	err = json.NewDecoder(response.Body).Decode(&responseBody)
+	if err != nil {
+		slog.Debug("unable to decode manifest response body", "err", err)
+		return nil, err
+	}
+
+	// the manifest is the most up to date view of the server's files, so it replaces any version tokens recorded before
+	etags := make(map[string]string, len(responseBody.Entries))
+	for _, entry := range responseBody.Entries {
+		if !entry.IsDirectory && entry.Hash != "" {
+			etags[entry.Path] = entities.ETag(entry.Hash)
+		}
+	}
+	err = c.etags.ReplaceETags(etags)
+	if err != nil {
+		slog.Warn("recording etags from manifest", "err", err)
+	}
+
+	return responseBody.Entries, nil
+}
+
+// GetChanges is a function that fetches the changes in the server's change feed after the given sequence number. If
+// there are none the server holds the request open for up to wait, returning as soon as one is made.
+func (c *RequestClient) GetChanges(ctx context.Context, since uint64, wait time.Duration) (entities.ChangeFeedPage, error) {
+	query := url.Values{
+		"since": []string{strconv.FormatUint(since, 10)},
+		"wait":  []string{wait.String()},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/changes?%s", c.baseURL, query.Encode()), nil)
+	if err != nil {
+		slog.Debug("error creating request", "err", err)
+		return entities.ChangeFeedPage{}, err
+	}
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending changes request", "err", err)
+		return entities.ChangeFeedPage{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		slog.Error("request failed with status code", "statusCode", response.StatusCode)
+		return entities.ChangeFeedPage{}, &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	var page entities.ChangeFeedPage
+	err = json.NewDecoder(response.Body).Decode(&page)
+	if err != nil {
+		slog.Debug("unable to decode changes response body", "err", err)
+		return entities.ChangeFeedPage{}, err
+	}
+
+	return page, nil
+}
+
+// DownloadFile is a function that streams the contents of the server's copy of the file at path to writer.
+func (c *RequestClient) DownloadFile(path string, writer io.Writer) error {
+	query := url.Values{"path": []string{path}}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/file?%s", c.baseURL, query.Encode()), nil)
+	if err != nil {
+		slog.Debug("error creating request", "err", err)
+		return err
+	}
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending download request", "err", err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		slog.Error("request failed with status code", "statusCode", response.StatusCode)
+		return &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	_, err = io.Copy(writer, response.Body)
+	return err
+}
+
+// UploadFile is a function that streams the file at localPath to the server in chunks, without loading the whole file
+// into memory. A SHA-256 hash of the streamed contents is sent when committing the upload, so the server can verify the
+// assembled file before moving it into place at path. If an earlier upload of the same, unchanged, file was interrupted
+// it is resumed from the point the server reached.
+func (c *RequestClient) UploadFile(path, localPath string, metadata entities.FileMetadata) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		slog.Debug("unable to open file for upload", "err", err)
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	session, err := c.resumeOrStartUpload(path, info)
+	if err != nil {
+		return err
+	}
+
+	// the hash covers the whole file, so any part the server already has is read back through the hasher without
+	// being sent again
+	hasher := sha256.New()
+	_, err = io.CopyN(hasher, file, session.Offset)
+	if err != nil {
+		slog.Debug("unable to read previously uploaded part of file", "err", err)
+		return err
+	}
+
+	chunk := make([]byte, uploadChunkSize)
+	for index := session.NextChunk; ; index++ {
+		n, readErr := io.ReadFull(file, chunk)
+		if readErr != nil && !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+			slog.Debug("unable to read file for upload", "err", readErr)
+			return readErr
+		}
+		if n == 0 {
+			break
+		}
+
+		hasher.Write(chunk[:n])
+		err = c.sendChunk(session.ID, index, chunk[:n])
+		if err != nil {
+			return err
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	err = c.commitUpload(path, session.ID, hex.EncodeToString(hasher.Sum(nil)), metadata)
+
+	// once the server has responded to the commit the session no longer exists, whether or not it succeeded
+	var statusCodeErr *StatusCodeError
+	if err == nil || errors.As(err, &statusCodeErr) {
+		removeErr := c.pendingUploads.RemovePendingUpload(path)
+		if removeErr != nil {
+			slog.Warn("removing pending upload", "path", path, "err", removeErr)
+		}
+	}
+
+	return err
+}
+
+// SendDelta is a function that sends only the parts of the file at localPath that differ from the server's copy at
+// path. The server's block signature for its copy is fetched and compared against the local file, and the resulting
+// delta is sent for the server to apply. ErrDeltaUnavailable is returned if the server has no copy of the file, too much
+// of it has changed, or the server's copy changed before the delta was applied, in which case the whole file should be
+// sent instead.
+func (c *RequestClient) SendDelta(path, localPath string, metadata entities.FileMetadata) error {
+	signature, err := c.getSignature(path)
+	if err != nil {
+		var statusCodeErr *StatusCodeError
+		if errors.As(err, &statusCodeErr) && statusCodeErr.StatusCode == http.StatusNotFound {
+			return ErrDeltaUnavailable
+		}
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		slog.Debug("unable to open file for delta", "err", err)
+		return err
+	}
+	defer file.Close()
+
+	operations, hash, err := computeDelta(file, signature, inlineUploadLimit)
+	if err != nil {
+		return err
+	}
+
+	if hash == signature.Hash {
+		slog.Debug("server already has file contents", "path", path)
+		err = c.etags.SetETag(path, entities.ETag(hash))
+		if err != nil || metadata.IsZero() {
+			return err
+		}
+		return c.SendMetadataRequest(path, metadata)
+	}
+
+	response, err := c.sendConditionalJSON(http.MethodPost, fmt.Sprintf("%s/v1/delta", c.baseURL), path, entities.OperationModified, metadata, entities.Delta{
+		Path:       path,
+		Hash:       hash,
+		BlockSize:  signature.BlockSize,
+		Operations: operations,
+	})
+	if err != nil {
+		var statusCodeErr *StatusCodeError
+		if errors.As(err, &statusCodeErr) && (statusCodeErr.StatusCode == http.StatusNotFound ||
+			statusCodeErr.StatusCode == http.StatusUnprocessableEntity) {
+			return ErrDeltaUnavailable
+		}
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+func (c *RequestClient) getSignature(path string) (entities.FileSignature, error) {
+	query := url.Values{"path": []string{path}}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/signature?%s", c.baseURL, query.Encode()), nil)
+	if err != nil {
+		slog.Debug("error creating request", "err", err)
+		return entities.FileSignature{}, err
+	}
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending signature request", "err", err)
+		return entities.FileSignature{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return entities.FileSignature{}, &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	var signature entities.FileSignature
+	err = json.NewDecoder(response.Body).Decode(&signature)
+	if err != nil {
+		slog.Debug("unable to decode file signature", "err", err)
+		return entities.FileSignature{}, err
+	}
+
+	return signature, nil
+}
+
+// resumeOrStartUpload is a function that returns the session of an interrupted upload of the file if the server still
+// has it, otherwise it starts a new session and records it so that it can be resumed.
+func (c *RequestClient) resumeOrStartUpload(path string, info os.FileInfo) (entities.UploadSession, error) {
+	pending, exists := c.pendingUploads.GetPendingUpload(path)
+	if exists && pending.Size == info.Size() && pending.ModTime.Equal(info.ModTime()) {
+		session, err := c.getUpload(pending.UploadID)
+		if err == nil {
+			slog.Info("resuming upload", "path", path, "offset", session.Offset)
+			return session, nil
+		}
+
+		var statusCodeErr *StatusCodeError
+		if !errors.As(err, &statusCodeErr) {
+			return entities.UploadSession{}, err
+		}
+		slog.Debug("server no longer has upload session, starting again", "path", path)
+	}
+
+	session, err := c.startUpload(path)
+	if err != nil {
+		return entities.UploadSession{}, err
+	}
+
+	err = c.pendingUploads.SavePendingUpload(entities.PendingUpload{
+		UploadID: session.ID,
+		Path:     path,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+	})
+	if err != nil {
+		return entities.UploadSession{}, err
+	}
+
+	return session, nil
+}
+
+func (c *RequestClient) getUpload(uploadID string) (entities.UploadSession, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/uploads/%s", c.baseURL, uploadID), nil)
+	if err != nil {
+		slog.Debug("error creating request", "err", err)
+		return entities.UploadSession{}, err
+	}
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending upload request", "err", err)
+		return entities.UploadSession{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return entities.UploadSession{}, &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	var session entities.UploadSession
+	err = json.NewDecoder(response.Body).Decode(&session)
+	if err != nil {
+		slog.Debug("unable to decode upload session", "err", err)
+		return entities.UploadSession{}, err
+	}
+
+	return session, nil
+}
+
+func (c *RequestClient) startUpload(path string) (entities.UploadSession, error) {
+	type startUploadRequestBody struct {
+		Path string `json:"path"`
+	}
+
+	response, err := c.sendJSON(http.MethodPost, fmt.Sprintf("%s/v1/uploads", c.baseURL), startUploadRequestBody{
+		Path: path,
+	})
+	if err != nil {
+		return entities.UploadSession{}, err
+	}
+	defer response.Body.Close()
+
+	var session entities.UploadSession
+	err = json.NewDecoder(response.Body).Decode(&session)
+	if err != nil {
+		slog.Debug("unable to decode upload session", "err", err)
+		return entities.UploadSession{}, err
+	}
+
+	return session, nil
+}
+
+func (c *RequestClient) sendChunk(uploadID string, index int, chunk []byte) error {
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/v1/uploads/%s/chunks/%d", c.baseURL, uploadID, index), bytes.NewReader(chunk))
+	if err != nil {
+		slog.Debug("error creating request", "err", err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending chunk", "err", err)
+		return err
+	}
+	defer response.Body.Close()
+
 	if response.StatusCode != http.StatusOK {
 		slog.Error("request failed with status code", "statusCode", response.StatusCode)
-		return errors.New("request failed")
+		return &StatusCodeError{StatusCode: response.StatusCode}
 	}
 
 	return nil
 }
+
+func (c *RequestClient) commitUpload(path, uploadID, hash string, metadata entities.FileMetadata) error {
+	type commitUploadRequestBody struct {
+		Hash string `json:"hash"`
+	}
+
+	response, err := c.sendConditionalJSON(http.MethodPost, fmt.Sprintf("%s/v1/uploads/%s/commit", c.baseURL, uploadID), path, entities.OperationModified, metadata, commitUploadRequestBody{
+		Hash: hash,
+	})
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// sendJSON is a function that sends a request with a JSON body, returning an error if the server does not respond with
+// a 200. The caller is responsible for closing the body of the returned response.
+func (c *RequestClient) sendJSON(method, url string, body any) (*http.Response, error) {
+	req, err := newJSONRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending request", "err", err)
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		slog.Error("request failed with status code", "statusCode", response.StatusCode)
+		return nil, &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	return response, nil
+}
+
+// sendConditionalJSON is a function that sends a request with a JSON body which changes the file at path, conditional on
+// the server's copy being the version last recorded for it. A ConflictError is returned if it isn't and the conflict
+// policy is fail, and the version token of the server's copy is recorded if the request succeeds. The file is given the
+// attributes in metadata once it has been changed. The caller is responsible for closing the body of the returned
+// response.
+func (c *RequestClient) sendConditionalJSON(method, url, path, operation string, metadata entities.FileMetadata, body any) (*http.Response, error) {
+	req, err := newJSONRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	c.setPrecondition(req, path)
+	c.setVersion(req, path)
+	setMetadata(req, metadata)
+
+	response, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("error sending request", "err", err)
+		return nil, err
+	}
+
+	_, err = c.checkResponse(response, path, operation)
+	if err != nil {
+		_ = response.Body.Close()
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func newJSONRequest(method, url string, body any) (*http.Request, error) {
+	requestBodyBytes, err := json.Marshal(body)
+	if err != nil {
+		slog.Debug("unable to marshal request body to byte array", "err", err)
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(requestBodyBytes))
+	if err != nil {
+		slog.Debug("error creating request", "err", err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// setPrecondition is a function that makes a request conditional on the server's copy of path being the version last
+// recorded for it, along with the policy to resolve a conflict with if it isn't. Requests for paths with no recorded
+// version, such as directories, are sent unconditionally.
+func (c *RequestClient) setPrecondition(req *http.Request, path string) {
+	etag, exists := c.etags.GetETag(path)
+	if exists {
+		req.Header.Set("If-Match", etag)
+	}
+	if c.conflictPolicy != "" {
+		req.Header.Set("X-Conflict-Policy", c.conflictPolicy)
+	}
+}
+
+// setVersion is a function that identifies the app a request changing path comes from, along with the version vector
+// the change was made against, so the server can tell which changes the app had seen when it made it.
+func (c *RequestClient) setVersion(req *http.Request, path string) {
+	req.Header.Set("X-Client-ID", c.versions.ClientID())
+	req.Header.Set("X-Version-Vector", c.versions.GetVersion(path).String())
+}
+
+// setMetadata is a function that adds the attributes the server should give the file a request changes, unless there
+// are none to give it.
+func setMetadata(req *http.Request, metadata entities.FileMetadata) {
+	if !metadata.IsZero() {
+		req.Header.Set("X-File-Metadata", metadata.String())
+	}
+}
+
+// setTargetPrecondition is a function that makes a rename conditional on the server's copy of the path it moves a file
+// to being the version last recorded for it, or on there being nothing there if no version has been recorded, so that
+// a rename doesn't silently replace a file the client hasn't seen.
+func (c *RequestClient) setTargetPrecondition(req *http.Request, path string) {
+	etag, exists := c.etags.GetETag(path)
+	if exists {
+		req.Header.Set("X-Target-If-Match", etag)
+		return
+	}
+	req.Header.Set("X-Target-If-None-Match", "*")
+}
+
+// checkResponse is a function that returns whether the server applied a request's change to path. A ConflictError is
+// returned if the server refused the request because its copy of path had changed, or a StatusCodeError for any other
+// response but a 200. Conflicts the server resolved are recorded in the client's conflict log, and the version token
+// of a successful response is recorded for path.
+func (c *RequestClient) checkResponse(response *http.Response, path, operation string) (bool, error) {
+	// we can just check for != 200 here as we know the server doesnt return any other success codes (2**)
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusPreconditionFailed:
+		slog.Warn("file has changed on the server", "path", path)
+		c.recordConflict(response, path, operation, entities.ConflictPolicyFail)
+		return false, &ConflictError{Path: path, ETag: response.Header.Get("ETag")}
+	default:
+		slog.Error("request failed with status code", "statusCode", response.StatusCode)
+		return false, &StatusCodeError{StatusCode: response.StatusCode}
+	}
+
+	applied := true
+	policy := response.Header.Get("X-Conflict-Resolution")
+	if policy != "" {
+		slog.Warn("server resolved conflict", "path", path, "policy", policy)
+		c.recordConflict(response, path, operation, policy)
+		applied = policy != entities.ConflictPolicyDestinationWins
+	}
+
+	// the server's version of a file it didn't change is restated in its change feed, and is pulled from there
+	if applied {
+		c.mergeVersion(response, path)
+	}
+
+	etag := response.Header.Get("ETag")
+	if etag == "" {
+		return applied, nil
+	}
+
+	err := c.etags.SetETag(path, etag)
+	if err != nil {
+		slog.Warn("recording etag", "path", path, "err", err)
+	}
+
+	return applied, nil
+}
+
+// mergeVersion is a function that records the version vector the server gave path once it applied a change. The change
+// has already been applied by the time it is recorded, so failing to record it is only logged.
+func (c *RequestClient) mergeVersion(response *http.Response, path string) {
+	header := response.Header.Get("X-Version-Vector")
+	if header == "" {
+		return
+	}
+
+	version, err := entities.ParseVersionVector(header)
+	if err != nil {
+		slog.Warn("invalid version vector in response", "path", path, "err", err)
+		return
+	}
+
+	err = c.versions.MergeVersion(path, version)
+	if err != nil {
+		slog.Warn("recording version vector", "path", path, "err", err)
+	}
+}
+
+// recordDelete is a function that forgets the version tokens of path, and anything within it, once the server has
+// deleted it.
+func (c *RequestClient) recordDelete(path string) {
+	err := c.etags.RemoveETags(path)
+	if err != nil {
+		slog.Warn("removing etags", "path", path, "err", err)
+	}
+}
+
+// recordRename is a function that moves the version tokens and version vectors of oldPath, and of anything within it,
+// to newPath once the server has renamed it.
+func (c *RequestClient) recordRename(oldPath, newPath string) {
+	err := c.etags.MoveETags(oldPath, newPath)
+	if err != nil {
+		slog.Warn("moving etags", "oldPath", oldPath, "newPath", newPath, "err", err)
+	}
+
+	err = c.versions.MoveVersions(oldPath, newPath)
+	if err != nil {
+		slog.Warn("moving version vectors", "oldPath", oldPath, "newPath", newPath, "err", err)
+	}
+}
+
+// recordConflict is a function that records a conflict on path in the client's conflict log. The change has already
+// been resolved by the time it is recorded, so failing to record it is only logged.
+func (c *RequestClient) recordConflict(response *http.Response, path, operation, policy string) {
+	expectedETag, _ := c.etags.GetETag(path)
+
+	err := c.conflicts.RecordConflict(entities.Conflict{
+		Path:           path,
+		Operation:      operation,
+		Policy:         policy,
+		ExpectedETag:   expectedETag,
+		CurrentETag:    response.Header.Get("ETag"),
+		ConflictedCopy: response.Header.Get("X-Conflicted-Copy"),
+		DetectedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("recording conflict", "path", path, "err", err)
+	}
+}
@@ -1,24 +1,35 @@
 package adapters
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsServerLive_HappyPath(t *testing.T) {
 	g := NewGomegaWithT(t)
 	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
 
 	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
-	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080")
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
 
 	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
+		Body:       http.NoBody,
 	}, nil)
 
 	isLive := client.IsServerLive()
@@ -28,13 +39,15 @@ func TestIsServerLive_HappyPath(t *testing.T) {
 func TestIsServerLive_HappyPathServerNotLive(t *testing.T) {
 	g := NewGomegaWithT(t)
 	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
 
 	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
-	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080")
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
 
 	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
 		Status:     "400 Bad Request",
 		StatusCode: 400,
+		Body:       http.NoBody,
 	}, nil)
 
 	isLive := client.IsServerLive()
@@ -44,13 +57,15 @@ func TestIsServerLive_HappyPathServerNotLive(t *testing.T) {
 func TestIsServerLive_RequestReturnsErr(t *testing.T) {
 	g := NewGomegaWithT(t)
 	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
 
 	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
-	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080")
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
 
 	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
 		Status:     "500 Internal Server Error",
 		StatusCode: 500,
+		Body:       http.NoBody,
 	}, errors.New("an error occurred"))
 
 	isLive := client.IsServerLive()
@@ -60,56 +75,744 @@ func TestIsServerLive_RequestReturnsErr(t *testing.T) {
 func TestSendCreateRequest_HappyPath(t *testing.T) {
 	g := NewGomegaWithT(t)
 	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
 
 	path := "/some/path/file.go"
 	data := []byte("some content")
 	isDirectory := false
 
 	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
-	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080")
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
 
 	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
 		Status:     "200 OK",
 		StatusCode: 200,
+		Body:       http.NoBody,
 	}, nil)
 
-	err := client.SendCreateRequest(path, data, isDirectory)
+	mockETags.EXPECT().GetETag(path).Return("", false).Times(1)
+
+	err := client.SendCreateRequest(path, data, isDirectory, entities.FileMetadata{})
 	g.Expect(err).ToNot(HaveOccurred())
 }
 
 func TestSendCreateRequest_ServerReturnsFailedStatusCode(t *testing.T) {
 	g := NewGomegaWithT(t)
 	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
 
 	path := "/some/path/file.go"
 	data := []byte("some content")
 	isDirectory := false
 
 	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
-	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080")
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
 
 	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
 		Status:     "500 Internal Server Error",
 		StatusCode: 500,
+		Body:       http.NoBody,
 	}, nil)
 
-	err := client.SendCreateRequest(path, data, isDirectory)
+	mockETags.EXPECT().GetETag(path).Return("", false).Times(1)
+
+	err := client.SendCreateRequest(path, data, isDirectory, entities.FileMetadata{})
 	g.Expect(err).To(MatchError("request failed with status code 500"))
 }
 
 func TestSendCreateRequest_RequestReturnsErr(t *testing.T) {
 	g := NewGomegaWithT(t)
 	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
 
 	path := "/some/path/file.go"
 	data := []byte("some content")
 	isDirectory := false
 
 	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
-	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080")
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
 
 	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(nil, errors.New("an error occurred"))
 
-	err := client.SendCreateRequest(path, data, isDirectory)
+	mockETags.EXPECT().GetETag(path).Return("", false).Times(1)
+
+	err := client.SendCreateRequest(path, data, isDirectory, entities.FileMetadata{})
 	g.Expect(err).To(MatchError("an error occurred"))
 }
+
+func TestGetManifest_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(`{"entries":[{"path":"/file.go","isDirectory":false,"size":12,"hash":"abc"}]}`)),
+	}, nil)
+
+	mockETags.EXPECT().ReplaceETags(map[string]string{"/file.go": `"abc"`}).Return(nil).Times(1)
+
+	manifest, err := client.GetManifest()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manifest).To(Equal([]entities.ManifestEntry{
+		{Path: "/file.go", Size: 12, Hash: "abc"},
+	}))
+}
+
+func TestGetManifest_ServerReturnsFailedStatusCode(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+		Status:     "500 Internal Server Error",
+		StatusCode: 500,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil)
+
+	_, err := client.GetManifest()
+	g.Expect(err).To(MatchError("request failed with status code 500"))
+}
+
+func TestUploadFile_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	localPath := filepath.Join(t.TempDir(), "file.go")
+	g.Expect(os.WriteFile(localPath, []byte("some content"), 0o644)).To(Succeed())
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	mockPendingUploads := mock_adapters.NewMockPendingUploadStore(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mockPendingUploads, mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockPendingUploads.EXPECT().GetPendingUpload("/file.go").Return(entities.PendingUpload{}, false)
+	mockPendingUploads.EXPECT().SavePendingUpload(gomock.Any()).DoAndReturn(func(upload entities.PendingUpload) error {
+		g.Expect(upload.UploadID).To(Equal("abc"))
+		g.Expect(upload.Path).To(Equal("/file.go"))
+		return nil
+	})
+	mockPendingUploads.EXPECT().RemovePendingUpload("/file.go").Return(nil)
+
+	gomock.InOrder(
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.Method).To(Equal(http.MethodPost))
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/uploads"))
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"uploadId":"abc","path":"/file.go"}`)),
+			}, nil
+		}),
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.Method).To(Equal(http.MethodPut))
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/uploads/abc/chunks/0"))
+			data, err := io.ReadAll(req.Body)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(Equal([]byte("some content")))
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/uploads/abc/commit"))
+			data, err := io.ReadAll(req.Body)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(MatchJSON(`{"hash":"290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"}`))
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+	)
+
+	mockETags.EXPECT().GetETag("/file.go").Return("", false).Times(1)
+
+	err := client.UploadFile("/file.go", localPath, entities.FileMetadata{})
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestUploadFile_CommitReturnsFailedStatusCode(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	localPath := filepath.Join(t.TempDir(), "file.go")
+	g.Expect(os.WriteFile(localPath, nil, 0o644)).To(Succeed())
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	mockPendingUploads := mock_adapters.NewMockPendingUploadStore(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mockPendingUploads, mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockPendingUploads.EXPECT().GetPendingUpload("/file.go").Return(entities.PendingUpload{}, false)
+	mockPendingUploads.EXPECT().SavePendingUpload(gomock.Any()).DoAndReturn(func(upload entities.PendingUpload) error {
+		g.Expect(upload.UploadID).To(Equal("abc"))
+		g.Expect(upload.Path).To(Equal("/file.go"))
+		return nil
+	})
+	mockPendingUploads.EXPECT().RemovePendingUpload("/file.go").Return(nil)
+
+	gomock.InOrder(
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"uploadId":"abc","path":"/file.go"}`)),
+		}, nil),
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+			StatusCode: 422,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil),
+	)
+
+	mockETags.EXPECT().GetETag("/file.go").Return("", false).Times(1)
+
+	err := client.UploadFile("/file.go", localPath, entities.FileMetadata{})
+	g.Expect(err).To(MatchError("request failed with status code 422"))
+}
+
+func TestUploadFile_ResumesPendingUpload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	localPath := filepath.Join(t.TempDir(), "file.go")
+	g.Expect(os.WriteFile(localPath, []byte("some content"), 0o644)).To(Succeed())
+	info, err := os.Stat(localPath)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	mockPendingUploads := mock_adapters.NewMockPendingUploadStore(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mockPendingUploads, mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockPendingUploads.EXPECT().GetPendingUpload("/file.go").Return(entities.PendingUpload{
+		UploadID: "abc",
+		Path:     "/file.go",
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+	}, true)
+	mockPendingUploads.EXPECT().RemovePendingUpload("/file.go").Return(nil)
+
+	gomock.InOrder(
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.Method).To(Equal(http.MethodGet))
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/uploads/abc"))
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"uploadId":"abc","path":"/file.go","nextChunk":1,"offset":12}`)),
+			}, nil
+		}),
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/uploads/abc/commit"))
+			data, err := io.ReadAll(req.Body)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(MatchJSON(`{"hash":"290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"}`))
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+	)
+
+	mockETags.EXPECT().GetETag("/file.go").Return("", false).Times(1)
+
+	err = client.UploadFile("/file.go", localPath, entities.FileMetadata{})
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendDelta_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	localPath := filepath.Join(t.TempDir(), "file.go")
+	g.Expect(os.WriteFile(localPath, []byte("some new content"), 0o644)).To(Succeed())
+
+	signature, err := buildFileSignature(strings.NewReader("some old content"), 4)
+	g.Expect(err).ToNot(HaveOccurred())
+	signatureBytes, err := json.Marshal(signature)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	gomock.InOrder(
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.Method).To(Equal(http.MethodGet))
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/signature?path=%2Ffile.go"))
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(signatureBytes))}, nil
+		}),
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.Method).To(Equal(http.MethodPost))
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/delta"))
+			var delta entities.Delta
+			g.Expect(json.NewDecoder(req.Body).Decode(&delta)).To(Succeed())
+			g.Expect(delta.Path).To(Equal("/file.go"))
+			g.Expect(delta.Operations).To(Equal([]entities.DeltaOperation{
+				{BlockIndex: 0, BlockCount: 1},
+				{Data: []byte(" new")},
+				{BlockIndex: 2, BlockCount: 2},
+			}))
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+	)
+
+	mockETags.EXPECT().GetETag("/file.go").Return("", false).Times(1)
+
+	err = client.SendDelta("/file.go", localPath, entities.FileMetadata{})
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendDelta_ServerHasNoCopy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+		StatusCode: 404,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil)
+
+	err := client.SendDelta("/file.go", "./source/path/file.go", entities.FileMetadata{})
+	g.Expect(err).To(MatchError(ErrDeltaUnavailable))
+}
+
+func TestSendMetadataRequest_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mock_adapters.NewMockETagStore(ctrl), "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.Method).To(Equal(http.MethodPut))
+		g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/file/metadata"))
+		g.Expect(req.Header.Get("X-File-Metadata")).To(Equal("mode=0755,uid=1000,gid=100"))
+		g.Expect(req.Header.Get("If-Match")).To(BeEmpty())
+		g.Expect(io.ReadAll(req.Body)).To(MatchJSON(`{"path":"/run.sh"}`))
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	err := client.SendMetadataRequest("/run.sh", entities.FileMetadata{Mode: 0o755, HasOwner: true, UID: 1000, GID: 100})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// there is nothing to send for metadata with nothing set
+	g.Expect(client.SendMetadataRequest("/run.sh", entities.FileMetadata{})).To(Succeed())
+}
+
+func TestSendSymlinkRequest_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/link").Return(`"abc"`, true).Times(1)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.Method).To(Equal(http.MethodPut))
+		g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/file"))
+		g.Expect(req.Header.Get("If-Match")).To(Equal(`"abc"`))
+		g.Expect(io.ReadAll(req.Body)).To(MatchJSON(`{"path":"/link","linkTarget":"../a.go"}`))
+		return &http.Response{StatusCode: 200, Header: http.Header{"Etag": []string{`"def"`}}, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	mockETags.EXPECT().SetETag("/link", `"def"`).Return(nil).Times(1)
+
+	err := client.SendSymlinkRequest("/link", "../a.go", true)
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendHardLinkRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/b.go").Return("", false).Times(2)
+	gomock.InOrder(
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			g.Expect(req.Method).To(Equal(http.MethodPost))
+			g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/file"))
+			g.Expect(io.ReadAll(req.Body)).To(MatchJSON(`{"path":"/b.go","hardLinkOf":"/a.go"}`))
+			return &http.Response{StatusCode: 200, Header: http.Header{"Etag": []string{`"abc"`}}, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+		mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+			StatusCode: 404,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil),
+	)
+	mockETags.EXPECT().SetETag("/b.go", `"abc"`).Return(nil).Times(1)
+
+	g.Expect(client.SendHardLinkRequest("/b.go", "/a.go")).To(Succeed())
+
+	// the server has no file to link to
+	g.Expect(client.SendHardLinkRequest("/b.go", "/a.go")).To(MatchError(ErrHardLinkUnavailable))
+}
+
+func TestSendUpdateRequest_SendsLastKnownETag(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/file.go").Return(`"abc"`, true).Times(1)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.Header.Get("If-Match")).To(Equal(`"abc"`))
+		return &http.Response{StatusCode: 200, Header: http.Header{"Etag": []string{`"def"`}}, Body: http.NoBody}, nil
+	})
+	mockETags.EXPECT().SetETag("/file.go", `"def"`).Return(nil).Times(1)
+
+	err := client.SendUpdateRequest("/file.go", []byte("some content"), entities.FileMetadata{})
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendUpdateRequest_Conflict(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	mockConflicts := mock_adapters.NewMockConflictRecorder(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mockConflicts, newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/file.go").Return(`"abc"`, true).Times(2)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+		StatusCode: 412,
+		Header:     http.Header{"Etag": []string{`"def"`}},
+		Body:       http.NoBody,
+	}, nil)
+	mockETags.EXPECT().SetETag(gomock.Any(), gomock.Any()).Times(0)
+	mockConflicts.EXPECT().RecordConflict(gomock.Any()).DoAndReturn(func(conflict entities.Conflict) error {
+		g.Expect(conflict.Path).To(Equal("/file.go"))
+		g.Expect(conflict.Operation).To(Equal(entities.OperationModified))
+		g.Expect(conflict.Policy).To(Equal(entities.ConflictPolicyFail))
+		g.Expect(conflict.ExpectedETag).To(Equal(`"abc"`))
+		g.Expect(conflict.CurrentETag).To(Equal(`"def"`))
+		return nil
+	}).Times(1)
+
+	err := client.SendUpdateRequest("/file.go", []byte("some content"), entities.FileMetadata{})
+	var conflictErr *ConflictError
+	g.Expect(errors.As(err, &conflictErr)).To(BeTrue())
+	g.Expect(conflictErr.Path).To(Equal("/file.go"))
+	g.Expect(conflictErr.ETag).To(Equal(`"def"`))
+}
+
+func TestSendRenameRequest_MovesETags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/old.go").Return(`"abc"`, true).Times(1)
+	mockETags.EXPECT().GetETag("/new.go").Return("", false).Times(1)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.Header.Get("If-Match")).To(Equal(`"abc"`))
+		g.Expect(req.Header.Get("X-Target-If-None-Match")).To(Equal("*"))
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	})
+	mockETags.EXPECT().MoveETags("/old.go", "/new.go").Return(nil).Times(1)
+
+	err := client.SendRenameRequest("/old.go", "/new.go")
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendDeleteRequest_RemovesETags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/dir").Return("", false).Times(1)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.Header.Get("If-Match")).To(BeEmpty())
+		return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+	})
+	mockETags.EXPECT().RemoveETags("/dir").Return(nil).Times(1)
+
+	err := client.SendDeleteRequest("/dir")
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendUpdateRequest_SendsConflictPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+	mockConflicts := mock_adapters.NewMockConflictRecorder(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, entities.ConflictPolicyKeepBoth, mockConflicts, newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/file.go").Return(`"abc"`, true).Times(2)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.Header.Get("X-Conflict-Policy")).To(Equal(entities.ConflictPolicyKeepBoth))
+		return &http.Response{
+			StatusCode: 200,
+			Header: http.Header{
+				"Etag":                  []string{`"ghi"`},
+				"X-Conflict-Resolution": []string{entities.ConflictPolicyKeepBoth},
+				"X-Conflicted-Copy":     []string{"/file (conflicted copy host 2026-10-18).go"},
+			},
+			Body: http.NoBody,
+		}, nil
+	})
+	mockConflicts.EXPECT().RecordConflict(gomock.Any()).DoAndReturn(func(conflict entities.Conflict) error {
+		g.Expect(conflict.Policy).To(Equal(entities.ConflictPolicyKeepBoth))
+		g.Expect(conflict.ConflictedCopy).To(Equal("/file (conflicted copy host 2026-10-18).go"))
+		return nil
+	}).Times(1)
+	mockETags.EXPECT().SetETag("/file.go", `"ghi"`).Return(nil).Times(1)
+
+	err := client.SendUpdateRequest("/file.go", []byte("some content"), entities.FileMetadata{})
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendDeleteRequest_DestinationWinsKeepsETags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+	mockConflicts := mock_adapters.NewMockConflictRecorder(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, entities.ConflictPolicyDestinationWins, mockConflicts, newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/file.go").Return(`"abc"`, true).Times(2)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"Etag":                  []string{`"def"`},
+			"X-Conflict-Resolution": []string{entities.ConflictPolicyDestinationWins},
+		},
+		Body: http.NoBody,
+	}, nil)
+	mockConflicts.EXPECT().RecordConflict(gomock.Any()).Return(nil).Times(1)
+	mockETags.EXPECT().SetETag("/file.go", `"def"`).Return(nil).Times(1)
+	mockETags.EXPECT().RemoveETags(gomock.Any()).Times(0)
+
+	err := client.SendDeleteRequest("/file.go")
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestSendUpdateRequest_SendsAndMergesVersionVector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+	versions := newTestVersionTracker(t)
+	g.Expect(versions.MergeVersion("/file.go", entities.VersionVector{"server": 1})).To(Succeed())
+	g.Expect(versions.RecordLocalChange("/file.go")).To(Succeed())
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), versions)
+
+	mockETags.EXPECT().GetETag("/file.go").Return(`"abc"`, true).Times(1)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.Header.Get("X-Client-ID")).To(Equal("laptop"))
+		g.Expect(req.Header.Get("X-Version-Vector")).To(Equal("laptop=1,server=1"))
+		return &http.Response{StatusCode: 200, Header: http.Header{
+			"Etag":             []string{`"def"`},
+			"X-Version-Vector": []string{"desktop=1,laptop=2,server=1"},
+		}, Body: http.NoBody}, nil
+	})
+	mockETags.EXPECT().SetETag("/file.go", `"def"`).Return(nil).Times(1)
+
+	err := client.SendUpdateRequest("/file.go", []byte("some content"), entities.FileMetadata{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(versions.GetVersion("/file.go")).To(Equal(entities.VersionVector{"desktop": 1, "laptop": 2, "server": 1}))
+}
+
+func TestSendUpdateRequest_DestinationWinsDoesNotMergeVersionVector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+	mockConflicts := mock_adapters.NewMockConflictRecorder(ctrl)
+	versions := newTestVersionTracker(t)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, entities.ConflictPolicyDestinationWins, mockConflicts, versions)
+
+	mockETags.EXPECT().GetETag("/file.go").Return(`"abc"`, true).Times(2)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{StatusCode: 200, Header: http.Header{
+		"Etag":                  []string{`"def"`},
+		"X-Conflict-Resolution": []string{entities.ConflictPolicyDestinationWins},
+		"X-Version-Vector":      []string{"desktop=1,server=1"},
+	}, Body: http.NoBody}, nil)
+	mockConflicts.EXPECT().RecordConflict(gomock.Any()).Return(nil).Times(1)
+	mockETags.EXPECT().SetETag("/file.go", `"def"`).Return(nil).Times(1)
+
+	err := client.SendUpdateRequest("/file.go", []byte("some content"), entities.FileMetadata{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(versions.GetVersion("/file.go")).To(BeEmpty())
+}
+
+func TestSendBatch_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+	mockConflicts := mock_adapters.NewMockConflictRecorder(ctrl)
+	versions := newTestVersionTracker(t)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mockConflicts, versions)
+
+	mockETags.EXPECT().GetETag("/file.go").Return(`"abc"`, true).Times(1)
+	mockETags.EXPECT().GetETag("/old.go").Return(`"ghi"`, true).Times(2)
+	mockETags.EXPECT().GetETag("/new.go").Return("", false).Times(1)
+	mockETags.EXPECT().GetETag("/gone.go").Return("", false).Times(1)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/batch"))
+		g.Expect(req.Header.Get("X-Client-ID")).To(Equal("laptop"))
+
+		var requestBody struct {
+			Operations []entities.BatchOperation `json:"operations"`
+		}
+		g.Expect(json.NewDecoder(req.Body).Decode(&requestBody)).To(Succeed())
+		g.Expect(requestBody.Operations).To(HaveLen(3))
+		g.Expect(requestBody.Operations[0].Headers).To(HaveKeyWithValue("If-Match", `"abc"`))
+		g.Expect(requestBody.Operations[0].Headers).ToNot(HaveKey("X-Client-Id"))
+		g.Expect(requestBody.Operations[1].Headers).To(HaveKeyWithValue("X-Target-If-None-Match", "*"))
+
+		return &http.Response{
+			StatusCode: 200,
+			Body: io.NopCloser(strings.NewReader(`{"results":[` +
+				`{"status":200,"headers":{"ETag":"\"def\"","X-Version-Vector":"laptop=1"}},` +
+				`{"status":412,"headers":{"ETag":"\"jkl\""},"message":"the file has changed on the server"},` +
+				`{"status":200}]}`)),
+		}, nil
+	})
+	mockETags.EXPECT().SetETag("/file.go", `"def"`).Return(nil).Times(1)
+	mockConflicts.EXPECT().RecordConflict(gomock.Any()).DoAndReturn(func(conflict entities.Conflict) error {
+		g.Expect(conflict.Path).To(Equal("/old.go"))
+		g.Expect(conflict.Operation).To(Equal(entities.OperationRenamed))
+		g.Expect(conflict.CurrentETag).To(Equal(`"jkl"`))
+		return nil
+	}).Times(1)
+	mockETags.EXPECT().MoveETags(gomock.Any(), gomock.Any()).Times(0)
+	mockETags.EXPECT().RemoveETags("/gone.go").Return(nil).Times(1)
+
+	errs, err := client.SendBatch([]entities.BatchOperation{
+		{Operation: entities.OperationModified, Path: "/file.go", Data: []byte("some content")},
+		{Operation: entities.OperationRenamed, Path: "/new.go", PreviousPath: "/old.go"},
+		{Operation: entities.OperationDeleted, Path: "/gone.go"},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs).To(HaveLen(3))
+	g.Expect(errs[0]).ToNot(HaveOccurred())
+	var conflictErr *ConflictError
+	g.Expect(errors.As(errs[1], &conflictErr)).To(BeTrue())
+	g.Expect(errs[2]).ToNot(HaveOccurred())
+	g.Expect(versions.GetVersion("/file.go")).To(Equal(entities.VersionVector{"laptop": 1}))
+}
+
+func TestSendBatch_ServerReturnsFailedStatusCode(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockETags.EXPECT().GetETag("/gone.go").Return("", false).Times(1)
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+		StatusCode: 500,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil)
+	mockETags.EXPECT().RemoveETags(gomock.Any()).Times(0)
+
+	errs, err := client.SendBatch([]entities.BatchOperation{{Operation: entities.OperationDeleted, Path: "/gone.go"}})
+	g.Expect(errs).To(BeNil())
+	g.Expect(err).To(MatchError(&StatusCodeError{StatusCode: 500}))
+}
+
+func TestGetChanges_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mock_adapters.NewMockETagStore(ctrl), "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		g.Expect(req.URL.String()).To(Equal("http://localhost:8080/v1/changes?since=3&wait=30s"))
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"feedId":"1","latest":4,"changes":[{"sequence":4,"path":"/a.go","operation":"DELETED","origin":"server","version":{"server":2}}]}`)),
+		}, nil
+	})
+
+	page, err := client.GetChanges(context.Background(), 3, 30*time.Second)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(page.FeedID).To(Equal("1"))
+	g.Expect(page.Latest).To(Equal(uint64(4)))
+	g.Expect(page.Changes).To(HaveLen(1))
+	g.Expect(page.Changes[0].Version).To(Equal(entities.VersionVector{"server": 2}))
+}
+
+func TestDownloadFile_NotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+
+	mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+	client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mock_adapters.NewMockETagStore(ctrl), "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+	mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{
+		StatusCode: 404,
+		Body:       io.NopCloser(strings.NewReader(`{"message":"file not found"}`)),
+	}, nil)
+
+	var buffer bytes.Buffer
+	err := client.DownloadFile("/a.go", &buffer)
+	var statusCodeErr *StatusCodeError
+	g.Expect(errors.As(err, &statusCodeErr)).To(BeTrue())
+	g.Expect(statusCodeErr.StatusCode).To(Equal(404))
+	g.Expect(buffer.Len()).To(BeZero())
+}
+
+// closeRecorder is a response body that records whether it has been closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (body *closeRecorder) Close() error {
+	body.closed = true
+	return nil
+}
+
+func TestSendRequests_CloseResponseBody(t *testing.T) {
+	requests := map[string]func(client *RequestClient) error{
+		"create": func(client *RequestClient) error {
+			return client.SendCreateRequest("/file.go", []byte("some content"), false, entities.FileMetadata{})
+		},
+		"delete": func(client *RequestClient) error { return client.SendDeleteRequest("/file.go") },
+		"rename": func(client *RequestClient) error { return client.SendRenameRequest("/file.go", "/new.go") },
+		"update": func(client *RequestClient) error {
+			return client.SendUpdateRequest("/file.go", []byte("some content"), entities.FileMetadata{})
+		},
+	}
+
+	for name, send := range requests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ctrl := gomock.NewController(t)
+			mockETags := mock_adapters.NewMockETagStore(ctrl)
+
+			mockHTTPClient := mock_adapters.NewMockHttpClient(ctrl)
+			client := NewHTTPClient(mockHTTPClient, "http://localhost:8080", mock_adapters.NewMockPendingUploadStore(ctrl), mockETags, "", mock_adapters.NewMockConflictRecorder(ctrl), newTestVersionTracker(t))
+
+			body := &closeRecorder{Reader: strings.NewReader("")}
+			mockETags.EXPECT().GetETag(gomock.Any()).Return("", false).AnyTimes()
+			mockETags.EXPECT().RemoveETags(gomock.Any()).Return(nil).AnyTimes()
+			mockETags.EXPECT().MoveETags(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockHTTPClient.EXPECT().Do(gomock.AssignableToTypeOf(&http.Request{})).Return(&http.Response{StatusCode: 200, Body: body}, nil)
+
+			g.Expect(send(client)).To(Succeed())
+			g.Expect(body.closed).To(BeTrue())
+		})
+	}
+}
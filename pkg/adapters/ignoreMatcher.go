@@ -0,0 +1,296 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IgnoreFileName is the name of the files in the source directory that list the paths the app shouldn't sync.
+const IgnoreFileName = ".dropboxignore"
+
+// IgnoreMatcher is a struct that decides which paths in the source directory are left out of syncing, using the
+// patterns in the .dropboxignore files in the source directory and a list of global patterns. The patterns follow the
+// same rules as a .gitignore file: the patterns in an ignore file apply to paths below the directory it is in, the last
+// pattern to match a path decides whether it is ignored, patterns in deeper ignore files take precedence over those
+// above them and the global patterns have the lowest precedence of all. Everything within an ignored directory is
+// ignored, and can't be included again by a negated pattern.
+//
+// Ignore files are loaded as the directories holding them are walked, with LoadDirectory. A nil IgnoreMatcher ignores
+// nothing.
+type IgnoreMatcher struct {
+	mu          sync.RWMutex
+	root        string
+	global      []ignoreRule
+	ignoreFiles map[string]ignoreFile
+}
+
+// ignoreFile is a struct that holds the rules loaded from a single ignore file, along with the contents they were
+// parsed from so that the file is only parsed again when it changes.
+type ignoreFile struct {
+	contents string
+	rules    []ignoreRule
+}
+
+// ignoreRule is a struct that holds a single pattern from an ignore file, compiled to match paths relative to the
+// directory of the file.
+type ignoreRule struct {
+	pattern       *regexp.Regexp
+	negated       bool
+	directoryOnly bool
+}
+
+// NewIgnoreMatcher is a function that creates an IgnoreMatcher for the source directory root, which ignores paths that
+// match globalPatterns unless an ignore file says otherwise. An error is returned if any of the global patterns are
+// invalid.
+func NewIgnoreMatcher(root string, globalPatterns []string) (*IgnoreMatcher, error) {
+	global := make([]ignoreRule, 0, len(globalPatterns))
+	for _, pattern := range globalPatterns {
+		rule, ok, err := parseIgnoreRule(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ignore pattern %q: %w", pattern, err)
+		}
+		if ok {
+			global = append(global, rule)
+		}
+	}
+
+	return &IgnoreMatcher{
+		root:        root,
+		global:      global,
+		ignoreFiles: make(map[string]ignoreFile),
+	}, nil
+}
+
+// LoadDirectory is a function that reads the ignore file in dir, if there is one, so that its patterns apply to the
+// paths below dir. It returns whether the patterns that apply have changed since dir was last loaded.
+func (matcher *IgnoreMatcher) LoadDirectory(dir string) bool {
+	if matcher == nil {
+		return false
+	}
+
+	key, ok := matcher.relativePath(dir)
+	if !ok {
+		return false
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Error("reading ignore file", "directory", dir, "err", err)
+		return false
+	}
+
+	matcher.mu.Lock()
+	defer matcher.mu.Unlock()
+
+	loaded, exists := matcher.ignoreFiles[key]
+	if err != nil {
+		delete(matcher.ignoreFiles, key)
+		return exists
+	}
+	if exists && loaded.contents == string(data) {
+		return false
+	}
+
+	rules := make([]ignoreRule, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		rule, ok, err := parseIgnoreRule(line)
+		if err != nil {
+			slog.Warn("skipping invalid ignore pattern", "directory", dir, "pattern", line, "err", err)
+			continue
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	matcher.ignoreFiles[key] = ignoreFile{
+		contents: string(data),
+		rules:    rules,
+	}
+
+	return true
+}
+
+// Ignored is a function that returns whether path, which is a directory if isDirectory is set, is left out of syncing,
+// either because it matches the ignore patterns or because a directory above it does.
+func (matcher *IgnoreMatcher) Ignored(path string, isDirectory bool) bool {
+	if matcher == nil {
+		return false
+	}
+
+	relative, ok := matcher.relativePath(path)
+	if !ok || relative == "" {
+		return false
+	}
+
+	matcher.mu.RLock()
+	defer matcher.mu.RUnlock()
+
+	parts := strings.Split(relative, "/")
+	for i := 1; i <= len(parts); i++ {
+		if matcher.matches(parts[:i], i < len(parts) || isDirectory) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches is a function that returns whether the patterns that apply to a path, split into its directories, ignore it.
+// The global patterns are checked first and then those of each ignore file from the root down, so that the last
+// pattern to match wins.
+func (matcher *IgnoreMatcher) matches(parts []string, isDirectory bool) bool {
+	ignored := false
+	check := func(rules []ignoreRule, path string) {
+		for _, rule := range rules {
+			if rule.directoryOnly && !isDirectory {
+				continue
+			}
+			if rule.pattern.MatchString(path) {
+				ignored = !rule.negated
+			}
+		}
+	}
+
+	check(matcher.global, strings.Join(parts, "/"))
+	for i := range parts {
+		loaded, exists := matcher.ignoreFiles[strings.Join(parts[:i], "/")]
+		if exists {
+			check(loaded.rules, strings.Join(parts[i:], "/"))
+		}
+	}
+
+	return ignored
+}
+
+// clone is a function that returns a copy of the IgnoreMatcher with the ignore files loaded so far, which doesn't
+// change when they are loaded again.
+func (matcher *IgnoreMatcher) clone() *IgnoreMatcher {
+	if matcher == nil {
+		return nil
+	}
+
+	matcher.mu.RLock()
+	defer matcher.mu.RUnlock()
+
+	ignoreFiles := make(map[string]ignoreFile, len(matcher.ignoreFiles))
+	for key, loaded := range matcher.ignoreFiles {
+		ignoreFiles[key] = loaded
+	}
+
+	return &IgnoreMatcher{
+		root:        matcher.root,
+		global:      matcher.global,
+		ignoreFiles: ignoreFiles,
+	}
+}
+
+// relativePath is a function that returns path relative to the root of the source directory, using forward slashes,
+// and false if it isn't within the source directory.
+func (matcher *IgnoreMatcher) relativePath(path string) (string, bool) {
+	relative, err := filepath.Rel(matcher.root, path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if relative == "." {
+		return "", true
+	}
+
+	return filepath.ToSlash(relative), true
+}
+
+// parseIgnoreRule is a function that parses a line of an ignore file. It returns false if the line is blank or a
+// comment.
+func parseIgnoreRule(line string) (ignoreRule, bool, error) {
+	line = strings.TrimSuffix(line, "\r")
+	// trailing spaces are dropped unless they are escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimSuffix(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false, nil
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negated = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.directoryOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false, nil
+	}
+
+	pattern, err := compileIgnorePattern(line)
+	if err != nil {
+		return ignoreRule{}, false, err
+	}
+	rule.pattern = pattern
+
+	return rule, true, nil
+}
+
+// compileIgnorePattern is a function that translates a gitignore style pattern into a regular expression matching
+// paths relative to the directory of the ignore file. A pattern with a slash at the start or in the middle only matches
+// relative to that directory, any other pattern matches a name at any depth below it.
+func compileIgnorePattern(pattern string) (*regexp.Regexp, error) {
+	var expression strings.Builder
+	expression.WriteString("^")
+	if !strings.Contains(pattern, "/") {
+		expression.WriteString("(?:.*/)?")
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	for i := 0; i < len(pattern); i++ {
+		atSegmentStart := i == 0 || pattern[i-1] == '/'
+		switch {
+		case atSegmentStart && strings.HasPrefix(pattern[i:], "**/"):
+			// any number of directories, including none
+			expression.WriteString("(?:.*/)?")
+			i += 2
+
+		case atSegmentStart && pattern[i:] == "**":
+			// everything within the directory
+			expression.WriteString(".*")
+			i++
+
+		case pattern[i] == '*':
+			expression.WriteString("[^/]*")
+
+		case pattern[i] == '?':
+			expression.WriteString("[^/]")
+
+		case pattern[i] == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				expression.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + class + "]")
+			i += end + 1
+
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			expression.WriteString(regexp.QuoteMeta(pattern[i+1 : i+2]))
+			i++
+
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expression.WriteString("$")
+
+	return regexp.Compile(expression.String())
+}
@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher_Ignored(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(root, "web", "src"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, IgnoreFileName), []byte(`# editor files
+*.swp
+*~
+\#*#
+
+/build/
+docs/**/*.pdf
+**/cache
+logs/**
+*.tmp
+!keep.tmp
+secret?.txt
+[abc].bin
+`), 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "web", IgnoreFileName), []byte("dist\n!*.log\n/local.json\n"), 0o644)).To(Succeed())
+
+	matcher, err := NewIgnoreMatcher(root, []string{".git/", "*.log"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(matcher.LoadDirectory(root)).To(BeTrue())
+	g.Expect(matcher.LoadDirectory(filepath.Join(root, "web"))).To(BeTrue())
+	g.Expect(matcher.LoadDirectory(filepath.Join(root, "web", "src"))).To(BeFalse())
+
+	tests := []struct {
+		path        string
+		isDirectory bool
+		ignored     bool
+	}{
+		{path: ".git", isDirectory: true, ignored: true},
+		{path: ".git/config", ignored: true},
+		{path: "sub/.git", isDirectory: true, ignored: true},
+		{path: ".git", ignored: false},
+		{path: "main.go.swp", ignored: true},
+		{path: "web/src/.main.go.swp", ignored: true},
+		{path: "main.go~", ignored: true},
+		{path: "#main.go#", ignored: true},
+		{path: "main.go", ignored: false},
+		{path: "build", isDirectory: true, ignored: true},
+		{path: "build/output", ignored: true},
+		{path: "web/build", isDirectory: true, ignored: false},
+		{path: "docs/guide.pdf", ignored: true},
+		{path: "docs/a/b/guide.pdf", ignored: true},
+		{path: "docs/guide.md", ignored: false},
+		{path: "cache", isDirectory: true, ignored: true},
+		{path: "a/b/cache/file", ignored: true},
+		{path: "logs", isDirectory: true, ignored: false},
+		{path: "logs/today", ignored: true},
+		{path: "a.tmp", ignored: true},
+		{path: "keep.tmp", ignored: false},
+		{path: "secret1.txt", ignored: true},
+		{path: "secret12.txt", ignored: false},
+		{path: "a.bin", ignored: true},
+		{path: "d.bin", ignored: false},
+		{path: "server.log", ignored: true},
+		{path: "web/server.log", ignored: false},
+		{path: "web/dist", isDirectory: true, ignored: true},
+		{path: "web/src/dist/app.js", ignored: true},
+		{path: "web/local.json", ignored: true},
+		{path: "web/src/local.json", ignored: false},
+		{path: "local.json", ignored: false},
+	}
+
+	for _, test := range tests {
+		g.Expect(matcher.Ignored(filepath.Join(root, filepath.FromSlash(test.path)), test.isDirectory)).
+			To(Equal(test.ignored), test.path)
+	}
+
+	g.Expect(matcher.Ignored(root, true)).To(BeFalse())
+	g.Expect(matcher.Ignored(filepath.Join(filepath.Dir(root), "main.go.swp"), false)).To(BeFalse())
+}
+
+func TestIgnoreMatcher_LoadDirectory_PicksUpChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	path := filepath.Join(root, "file.tmp")
+
+	matcher, err := NewIgnoreMatcher(root, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(matcher.LoadDirectory(root)).To(BeFalse())
+	g.Expect(matcher.Ignored(path, false)).To(BeFalse())
+
+	g.Expect(os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("*.tmp\n"), 0o644)).To(Succeed())
+	g.Expect(matcher.LoadDirectory(root)).To(BeTrue())
+	g.Expect(matcher.LoadDirectory(root)).To(BeFalse())
+	g.Expect(matcher.Ignored(path, false)).To(BeTrue())
+
+	// a copy taken beforehand keeps the rules it was taken with
+	previous := matcher.clone()
+	g.Expect(os.Remove(filepath.Join(root, IgnoreFileName))).To(Succeed())
+	g.Expect(matcher.LoadDirectory(root)).To(BeTrue())
+	g.Expect(matcher.Ignored(path, false)).To(BeFalse())
+	g.Expect(previous.Ignored(path, false)).To(BeTrue())
+}
+
+func TestIgnoreMatcher_Nil(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var matcher *IgnoreMatcher
+
+	g.Expect(matcher.LoadDirectory(t.TempDir())).To(BeFalse())
+	g.Expect(matcher.Ignored("./source/main.go.swp", false)).To(BeFalse())
+}
@@ -0,0 +1,486 @@
+//go:build linux
+
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// inotifyWatchMask is the set of inotify events watched on every directory in the source tree.
+	inotifyWatchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_DELETE |
+		syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+	// movePairingTimeout is how long an IN_MOVED_FROM waits for the IN_MOVED_TO with the same cookie before the file
+	// is treated as moved out of the source directory.
+	movePairingTimeout = 50 * time.Millisecond
+	// inotifyBufferSize fits a reasonable number of events with maximum length names into a single read.
+	inotifyBufferSize = 64 * (syscall.SizeofInotifyEvent + syscall.NAME_MAX + 1)
+)
+
+// InotifyWatcher is a FileWatcher that uses Linux inotify to receive filesystem events as they happen, rather than
+// rebuilding a snapshot of the whole source directory. Ignored paths aren't watched and no events are published for
+// them.
+type InotifyWatcher struct {
+	rootPath          string
+	ignoreMatcher     *IgnoreMatcher
+	inotifyFD         int
+	inotifyFile       *os.File
+	pathsByWatch      map[int]string
+	watchesByPath     map[string]int
+	pendingMoves      map[uint32]pendingMove
+	watchLimitReached bool
+	monitor           *DirectoryMonitor
+}
+
+// pendingMove is an IN_MOVED_FROM event that is waiting to be paired with its IN_MOVED_TO event.
+type pendingMove struct {
+	path        string
+	isDirectory bool
+	ignored     bool
+	receivedAt  time.Time
+}
+
+// NewInotifyWatcher is a function that creates an inotify instance and recursively adds a watch for every directory
+// under root that ignoreMatcher doesn't ignore. ignoreMatcher can be nil, in which case nothing is ignored. It returns
+// ErrWatchLimitReached if the tree has more directories than the kernel allows watches for.
+func NewInotifyWatcher(root string, ignoreMatcher *IgnoreMatcher) (*InotifyWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("initialising inotify: %w", err)
+	}
+
+	watcher := &InotifyWatcher{
+		rootPath:      root,
+		ignoreMatcher: ignoreMatcher,
+		inotifyFD:     fd,
+		// the descriptor is non-blocking so the runtime poller manages it, allowing read deadlines and Close to
+		// interrupt a pending Read. Fd() must not be called on the file as it switches it back to blocking mode
+		inotifyFile:   os.NewFile(uintptr(fd), "inotify"),
+		pathsByWatch:  make(map[int]string),
+		watchesByPath: make(map[string]int),
+		pendingMoves:  make(map[uint32]pendingMove),
+	}
+
+	watcher.watchTree(root, nil)
+	if watcher.watchLimitReached {
+		_ = watcher.inotifyFile.Close()
+		return nil, ErrWatchLimitReached
+	}
+
+	return watcher, nil
+}
+
+// RescanWith is a function that sets the DirectoryMonitor used to recover from the kernel's event queue overflowing. Its
+// snapshot is kept up to date with every event the watcher publishes, so that when events are dropped the source
+// directory can be compared with it to publish whatever was missed. Without one, dropped events are only logged.
+func (watcher *InotifyWatcher) RescanWith(monitor *DirectoryMonitor) {
+	watcher.monitor = monitor
+}
+
+// Run is a function that reads inotify events until the context is cancelled, translating them into
+// entities.FilesystemEvent values. If a new directory cannot be watched because the limit has been reached, the
+// current batch of events is finished and ErrWatchLimitReached is returned so the caller can fall back to polling.
+func (watcher *InotifyWatcher) Run(ctx context.Context, eventChan chan<- entities.FilesystemEvent) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = watcher.inotifyFile.Close()
+	}()
+
+	buffer := make([]byte, inotifyBufferSize)
+	for {
+		deadline := time.Time{}
+		if len(watcher.pendingMoves) > 0 {
+			deadline = time.Now().Add(movePairingTimeout)
+		}
+		if err := watcher.inotifyFile.SetReadDeadline(deadline); err != nil {
+			return err
+		}
+
+		n, err := watcher.inotifyFile.Read(buffer)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				watcher.flushPendingMoves(eventChan, time.Now())
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("reading inotify events: %w", err)
+		}
+
+		err = watcher.handleEvents(buffer[:n], eventChan)
+		if err != nil {
+			return err
+		}
+		watcher.flushPendingMoves(eventChan, time.Now().Add(-movePairingTimeout))
+
+		if watcher.watchLimitReached {
+			return ErrWatchLimitReached
+		}
+	}
+}
+
+// handleEvents is a function that decodes a buffer of raw inotify events and publishes the equivalent filesystem
+// events.
+func (watcher *InotifyWatcher) handleEvents(buffer []byte, eventChan chan<- entities.FilesystemEvent) error {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buffer); {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+		nameStart := offset + syscall.SizeofInotifyEvent
+		name := strings.TrimRight(string(buffer[nameStart:nameStart+int(raw.Len)]), "\x00")
+		offset = nameStart + int(raw.Len)
+
+		if raw.Mask&syscall.IN_Q_OVERFLOW != 0 {
+			err := watcher.rescan(eventChan)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if raw.Mask&syscall.IN_IGNORED != 0 {
+			watcher.forgetWatch(int(raw.Wd))
+			continue
+		}
+
+		directory, ok := watcher.pathsByWatch[int(raw.Wd)]
+		if !ok {
+			continue
+		}
+
+		// events about the watched directory itself are reported by its parent, apart from the root
+		if name == "" {
+			if raw.Mask&syscall.IN_DELETE_SELF != 0 && directory == watcher.rootPath {
+				return errors.New("source directory was deleted")
+			}
+			continue
+		}
+
+		path := filepath.Join(directory, name)
+		isDirectory := raw.Mask&syscall.IN_ISDIR != 0
+		if name == IgnoreFileName {
+			watcher.reloadIgnoreFile(directory, eventChan)
+		}
+		ignored := watcher.ignoreMatcher.Ignored(path, isDirectory)
+
+		switch {
+		case ignored && raw.Mask&(syscall.IN_CREATE|syscall.IN_CLOSE_WRITE|syscall.IN_ATTRIB|syscall.IN_DELETE) != 0:
+			continue
+
+		case raw.Mask&syscall.IN_CREATE != 0:
+			watcher.publishCreated(path, isDirectory, eventChan)
+
+		case raw.Mask&syscall.IN_CLOSE_WRITE != 0:
+			contents, err := readFileContents(path, nil)
+			if err != nil {
+				slog.Debug("reading modified file", "path", path, "err", err)
+				continue
+			}
+			watcher.publish(eventChan, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationModified,
+				FileContents: contents,
+			})
+
+		case raw.Mask&syscall.IN_ATTRIB != 0:
+			contents, err := readFileContents(path, nil)
+			if err != nil {
+				slog.Debug("reading file with changed attributes", "path", path, "err", err)
+				continue
+			}
+			watcher.publish(eventChan, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationMetadata,
+				FileContents: contents,
+			})
+
+		case raw.Mask&syscall.IN_DELETE != 0:
+			watcher.publish(eventChan, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationDeleted,
+				FileContents: entities.FileContents{IsDirectory: isDirectory},
+			})
+
+		case raw.Mask&syscall.IN_MOVED_FROM != 0:
+			watcher.pendingMoves[raw.Cookie] = pendingMove{
+				path:        path,
+				isDirectory: isDirectory,
+				ignored:     ignored,
+				receivedAt:  time.Now(),
+			}
+
+		case raw.Mask&syscall.IN_MOVED_TO != 0:
+			move, paired := watcher.pendingMoves[raw.Cookie]
+			if !paired || move.ignored {
+				// moved in from outside of the source directory, or from an ignored path
+				delete(watcher.pendingMoves, raw.Cookie)
+				if !ignored {
+					watcher.publishCreated(path, isDirectory, eventChan)
+				}
+				continue
+			}
+			delete(watcher.pendingMoves, raw.Cookie)
+
+			if isDirectory {
+				watcher.renameWatches(move.path, path)
+			}
+
+			if ignored {
+				// moved to an ignored path, which as far as the destination is concerned is the same as deleting it
+				if isDirectory {
+					watcher.removeWatches(path)
+				}
+				watcher.publish(eventChan, entities.FilesystemEvent{
+					Name:         move.path,
+					Operation:    entities.OperationDeleted,
+					FileContents: entities.FileContents{IsDirectory: isDirectory},
+				})
+				continue
+			}
+
+			contents, err := readFileContents(path, nil)
+			if err != nil {
+				contents = entities.FileContents{IsDirectory: isDirectory}
+			}
+			watcher.publish(eventChan, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationRenamed,
+				PreviousPath: move.path,
+				FileContents: contents,
+			})
+		}
+	}
+
+	return nil
+}
+
+// publish is a function that publishes an event, recording it in the snapshot used to rescan the source directory.
+func (watcher *InotifyWatcher) publish(eventChan chan<- entities.FilesystemEvent, event entities.FilesystemEvent) {
+	if watcher.monitor != nil {
+		watcher.monitor.applyEvent(event)
+	}
+	eventChan <- event
+}
+
+// rescan is a function that recovers from the kernel dropping events because its queue overflowed. Every directory is
+// watched again, picking up any that were created or moved since, and the source directory is compared with the
+// snapshot of everything published so far to publish what was missed. The other half of a pending move may have been
+// dropped, so pending moves are left for the comparison to work out.
+func (watcher *InotifyWatcher) rescan(eventChan chan<- entities.FilesystemEvent) error {
+	slog.Warn("inotify event queue overflowed, rescanning source directory")
+	watcher.pendingMoves = make(map[uint32]pendingMove)
+	watcher.watchTree(watcher.rootPath, nil)
+
+	if watcher.monitor == nil {
+		slog.Warn("unable to rescan source directory, some changes may not be replicated")
+		return nil
+	}
+
+	err := watcher.monitor.PollForFileChanges(eventChan)
+	if err != nil {
+		return err
+	}
+
+	// directories that were deleted or moved out of the source directory are no longer watched
+	for path := range watcher.watchesByPath {
+		if contents, exists := watcher.monitor.previousSnapshot[path]; path != watcher.rootPath && (!exists || !contents.IsDirectory) {
+			watcher.removeWatches(path)
+		}
+	}
+
+	return nil
+}
+
+// publishCreated is a function that publishes a CREATE event for a new path. New directories are walked so that any
+// entries created before the watch was added are also published.
+func (watcher *InotifyWatcher) publishCreated(path string, isDirectory bool, eventChan chan<- entities.FilesystemEvent) {
+	contents, err := readFileContents(path, nil)
+	if err != nil {
+		slog.Debug("reading created file", "path", path, "err", err)
+		return
+	}
+
+	watcher.publish(eventChan, entities.FilesystemEvent{
+		Name:         path,
+		Operation:    entities.OperationCreated,
+		FileContents: contents,
+	})
+
+	if isDirectory {
+		watcher.watchTree(path, eventChan)
+	}
+}
+
+// flushPendingMoves is a function that treats every IN_MOVED_FROM received before the cutoff that was never paired as
+// the path being moved out of the source directory.
+func (watcher *InotifyWatcher) flushPendingMoves(eventChan chan<- entities.FilesystemEvent, cutoff time.Time) {
+	for cookie, move := range watcher.pendingMoves {
+		if move.receivedAt.After(cutoff) {
+			continue
+		}
+		delete(watcher.pendingMoves, cookie)
+		if move.ignored {
+			continue
+		}
+
+		// the directory is still being watched at its new location outside of the source directory
+		if move.isDirectory {
+			watcher.removeWatches(move.path)
+		}
+
+		watcher.publish(eventChan, entities.FilesystemEvent{
+			Name:         move.path,
+			Operation:    entities.OperationDeleted,
+			FileContents: entities.FileContents{IsDirectory: move.isDirectory},
+		})
+	}
+}
+
+// watchTree is a function that adds a watch to dir and every directory below it that isn't ignored, loading the ignore
+// file in each one. If eventChan is not nil, a CREATE event is published for every entry below dir that isn't ignored.
+// Running out of watches is recorded rather than stopping the walk, so that every entry is still published before
+// falling back to polling.
+func (watcher *InotifyWatcher) watchTree(dir string, eventChan chan<- entities.FilesystemEvent) {
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			slog.Error("error accessing path", "path", path, "err", err)
+			return nil
+		}
+
+		if watcher.ignoreMatcher.Ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			watcher.ignoreMatcher.LoadDirectory(path)
+		}
+
+		if path != dir && eventChan != nil {
+			contents, err := readFileContents(path, nil)
+			if err != nil {
+				slog.Debug("reading file contents", "path", path, "err", err)
+				return nil
+			}
+			watcher.publish(eventChan, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationCreated,
+				FileContents: contents,
+			})
+		}
+
+		if !d.IsDir() || watcher.watchLimitReached {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(watcher.inotifyFD, path, inotifyWatchMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				slog.Warn("inotify watch limit reached", "path", path)
+				watcher.watchLimitReached = true
+				return nil
+			}
+			slog.Error("adding inotify watch", "path", path, "err", err)
+			return nil
+		}
+		// a directory that is watched already keeps its watch, which has to be moved if it was renamed unnoticed
+		if previousPath, exists := watcher.pathsByWatch[wd]; exists && watcher.watchesByPath[previousPath] == wd {
+			delete(watcher.watchesByPath, previousPath)
+		}
+		watcher.pathsByWatch[wd] = path
+		watcher.watchesByPath[path] = wd
+
+		return nil
+	})
+}
+
+// reloadIgnoreFile is a function that loads the ignore file in dir again after it has changed. Entries below dir that
+// are no longer ignored are published as created and watched, and directories that have become ignored stop being
+// watched. Entries that have become ignored are left as they are in the destination rather than deleted.
+func (watcher *InotifyWatcher) reloadIgnoreFile(dir string, eventChan chan<- entities.FilesystemEvent) {
+	previous := watcher.ignoreMatcher.clone()
+	if !watcher.ignoreMatcher.LoadDirectory(dir) {
+		return
+	}
+
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return nil
+		}
+
+		wasIgnored := previous.Ignored(path, d.IsDir())
+		ignored := watcher.ignoreMatcher.Ignored(path, d.IsDir())
+		switch {
+		case ignored && !wasIgnored && d.IsDir():
+			watcher.removeWatches(path)
+			return fs.SkipDir
+
+		case ignored && d.IsDir():
+			return fs.SkipDir
+
+		case wasIgnored && !ignored:
+			watcher.publishCreated(path, d.IsDir(), eventChan)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+		}
+
+		return nil
+	})
+}
+
+// renameWatches is a function that updates the path of every watch at or below oldPath after a directory was renamed
+// within the source directory.
+func (watcher *InotifyWatcher) renameWatches(oldPath, newPath string) {
+	for path, wd := range watcher.watchesByPath {
+		if path != oldPath && !strings.HasPrefix(path, oldPath+string(filepath.Separator)) {
+			continue
+		}
+		renamedPath := newPath + strings.TrimPrefix(path, oldPath)
+		delete(watcher.watchesByPath, path)
+		watcher.watchesByPath[renamedPath] = wd
+		watcher.pathsByWatch[wd] = renamedPath
+	}
+}
+
+// removeWatches is a function that removes every watch at or below path.
+func (watcher *InotifyWatcher) removeWatches(path string) {
+	for watchedPath, wd := range watcher.watchesByPath {
+		if watchedPath != path && !strings.HasPrefix(watchedPath, path+string(filepath.Separator)) {
+			continue
+		}
+		_, err := syscall.InotifyRmWatch(watcher.inotifyFD, uint32(wd))
+		if err != nil {
+			slog.Debug("removing inotify watch", "path", watchedPath, "err", err)
+		}
+		watcher.forgetWatch(wd)
+	}
+}
+
+// forgetWatch is a function that removes a watch descriptor from the watchers bookkeeping.
+func (watcher *InotifyWatcher) forgetWatch(wd int) {
+	path, ok := watcher.pathsByWatch[wd]
+	if !ok {
+		return
+	}
+	delete(watcher.pathsByWatch, wd)
+	if watcher.watchesByPath[path] == wd {
+		delete(watcher.watchesByPath, path)
+	}
+}
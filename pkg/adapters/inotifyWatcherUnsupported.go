@@ -0,0 +1,22 @@
+//go:build !linux
+
+package adapters
+
+import (
+	"context"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+)
+
+// InotifyWatcher is unavailable outside of Linux, NewInotifyWatcher always returns ErrInotifyUnsupported so that the
+// caller falls back to the polling DirectoryMonitor.
+type InotifyWatcher struct{}
+
+func NewInotifyWatcher(root string, ignoreMatcher *IgnoreMatcher) (*InotifyWatcher, error) {
+	return nil, ErrInotifyUnsupported
+}
+
+func (watcher *InotifyWatcher) Run(ctx context.Context, eventChan chan<- entities.FilesystemEvent) error {
+	return ErrInotifyUnsupported
+}
+
+func (watcher *InotifyWatcher) RescanWith(monitor *DirectoryMonitor) {}
@@ -0,0 +1,209 @@
+//go:build linux
+
+package adapters
+
+import (
+	"context"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func startInotifyWatcher(t *testing.T, root string) chan entities.FilesystemEvent {
+	t.Helper()
+	return startInotifyWatcherWithIgnores(t, root, nil)
+}
+
+func startInotifyWatcherWithIgnores(t *testing.T, root string, ignoreMatcher *IgnoreMatcher) chan entities.FilesystemEvent {
+	t.Helper()
+	g := NewGomegaWithT(t)
+
+	watcher, err := NewInotifyWatcher(root, ignoreMatcher)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	eventChan := make(chan entities.FilesystemEvent, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = watcher.Run(ctx, eventChan)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return eventChan
+}
+
+func receiveEvent(t *testing.T, eventChan chan entities.FilesystemEvent) entities.FilesystemEvent {
+	t.Helper()
+	select {
+	case event := <-eventChan:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for filesystem event")
+	}
+	return entities.FilesystemEvent{}
+}
+
+func TestInotifyWatcher_CreateAndModifyFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	eventChan := startInotifyWatcher(t, root)
+
+	path := filepath.Join(root, "file.go")
+	g.Expect(os.WriteFile(path, []byte("some content"), 0o644)).To(Succeed())
+
+	event := receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+
+	event = receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+	g.Expect(event.FileContents.Size).To(Equal(int64(len("some content"))))
+	g.Expect(event.FileContents.Hash).To(Equal("290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"))
+}
+
+func TestInotifyWatcher_RenameFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	oldPath := filepath.Join(root, "old-file.go")
+	newPath := filepath.Join(root, "new-file.go")
+	g.Expect(os.WriteFile(oldPath, []byte("some content"), 0o644)).To(Succeed())
+	eventChan := startInotifyWatcher(t, root)
+
+	g.Expect(os.Rename(oldPath, newPath)).To(Succeed())
+
+	event := receiveEvent(t, eventChan)
+	g.Expect(event.Operation).To(Equal(entities.OperationRenamed))
+	g.Expect(event.Name).To(Equal(newPath))
+	g.Expect(event.PreviousPath).To(Equal(oldPath))
+}
+
+func TestInotifyWatcher_MoveOutOfSourceIsDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	outside := t.TempDir()
+	path := filepath.Join(root, "file.go")
+	g.Expect(os.WriteFile(path, []byte("some content"), 0o644)).To(Succeed())
+	eventChan := startInotifyWatcher(t, root)
+
+	g.Expect(os.Rename(path, filepath.Join(outside, "file.go"))).To(Succeed())
+
+	event := receiveEvent(t, eventChan)
+	g.Expect(event.Operation).To(Equal(entities.OperationDeleted))
+	g.Expect(event.Name).To(Equal(path))
+}
+
+func TestInotifyWatcher_WatchesNewDirectories(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	eventChan := startInotifyWatcher(t, root)
+
+	directory := filepath.Join(root, "sub")
+	g.Expect(os.Mkdir(directory, 0o755)).To(Succeed())
+
+	event := receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(directory))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+	g.Expect(event.FileContents.IsDirectory).To(BeTrue())
+
+	path := filepath.Join(directory, "file.go")
+	g.Expect(os.WriteFile(path, nil, 0o644)).To(Succeed())
+
+	event = receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+}
+
+func TestInotifyWatcher_IgnoredPaths(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("*.swp\nbuild/\n"), 0o644)).To(Succeed())
+	ignoreMatcher, err := NewIgnoreMatcher(root, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	eventChan := startInotifyWatcherWithIgnores(t, root, ignoreMatcher)
+
+	g.Expect(os.Mkdir(filepath.Join(root, "build"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "build", "output"), nil, 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, ".file.go.swp"), nil, 0o644)).To(Succeed())
+	path := filepath.Join(root, "file.go")
+	g.Expect(os.WriteFile(path, nil, 0o644)).To(Succeed())
+
+	event := receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+	event = receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(path))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+
+	// paths that are no longer ignored are published as soon as the ignore file changes
+	g.Expect(os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("*.swp\n"), 0o644)).To(Succeed())
+
+	event = receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "build")))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+	event = receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "build", "output")))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+	event = receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(filepath.Join(root, IgnoreFileName)))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+
+	// and the directory is watched from then on
+	g.Expect(os.WriteFile(filepath.Join(root, "build", "output"), []byte("some content"), 0o644)).To(Succeed())
+	event = receiveEvent(t, eventChan)
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "build", "output")))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+}
+
+func TestInotifyWatcher_QueueOverflowRescans(t *testing.T) {
+	g := NewGomegaWithT(t)
+	root := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(root, "a.go"), []byte("a"), 0o644)).To(Succeed())
+	g.Expect(os.Mkdir(filepath.Join(root, "old"), 0o755)).To(Succeed())
+
+	watcher, err := NewInotifyWatcher(root, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(func() { _ = watcher.inotifyFile.Close() })
+	monitor, err := NewDirectoryMonitor(root, nil, false, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	watcher.RescanWith(monitor)
+
+	// changes whose events the kernel drops
+	g.Expect(os.WriteFile(filepath.Join(root, "a.go"), []byte("ab"), 0o644)).To(Succeed())
+	g.Expect(os.Rename(filepath.Join(root, "old"), filepath.Join(root, "new"))).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, "new", "b.go"), []byte("b"), 0o644)).To(Succeed())
+
+	overflow := syscall.InotifyEvent{Wd: -1, Mask: syscall.IN_Q_OVERFLOW}
+	eventChan := make(chan entities.FilesystemEvent, 10)
+	g.Expect(watcher.handleEvents(unsafe.Slice((*byte)(unsafe.Pointer(&overflow)), syscall.SizeofInotifyEvent), eventChan)).To(Succeed())
+
+	g.Expect(eventChan).To(HaveLen(3))
+	event := <-eventChan
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "a.go")))
+	g.Expect(event.Operation).To(Equal(entities.OperationModified))
+	event = <-eventChan
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "new")))
+	g.Expect(event.Operation).To(Equal(entities.OperationRenamed))
+	g.Expect(event.PreviousPath).To(Equal(filepath.Join(root, "old")))
+	event = <-eventChan
+	g.Expect(event.Name).To(Equal(filepath.Join(root, "new", "b.go")))
+	g.Expect(event.Operation).To(Equal(entities.OperationCreated))
+
+	// the renamed directory's watch follows it
+	g.Expect(watcher.watchesByPath).To(HaveKey(filepath.Join(root, "new")))
+	g.Expect(watcher.watchesByPath).ToNot(HaveKey(filepath.Join(root, "old")))
+
+	// and events published afterwards are compared with the rescanned state
+	watcher.publish(eventChan, entities.FilesystemEvent{Name: filepath.Join(root, "new", "b.go"), Operation: entities.OperationDeleted})
+	<-eventChan
+	g.Expect(monitor.previousSnapshot).ToNot(HaveKey(filepath.Join(root, "new", "b.go")))
+}
@@ -0,0 +1,243 @@
+package adapters
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outboxFailedDirectory is the directory within the outbox that events the server rejected are moved to, so that they
+// are kept for inspection without blocking the events queued behind them.
+const outboxFailedDirectory = "failed"
+
+// Outbox is a struct that durably queues filesystem events until the server has applied them. Each event is written to
+// its own file in the outbox directory, named after a sequence number so that events are sent in the order they were
+// queued, and the file is only removed once the server has responded with a 200.
+type Outbox struct {
+	mu           sync.Mutex
+	directory    string
+	entries      []outboxEntry
+	nextSequence uint64
+	ready        chan struct{}
+}
+
+// outboxEntry is the layout of a single file in the outbox.
+type outboxEntry struct {
+	Sequence   uint64                   `json:"sequence"`
+	Event      entities.FilesystemEvent `json:"event"`
+	EnqueuedAt time.Time                `json:"enqueuedAt"`
+}
+
+// NewOutbox is a function that creates the outbox directory and loads any events left in it by a previous run of the
+// app, so that they are sent before anything new.
+func NewOutbox(directory string) (*Outbox, error) {
+	err := os.MkdirAll(filepath.Join(directory, outboxFailedDirectory), 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("creating outbox directory: %w", err)
+	}
+
+	outbox := &Outbox{
+		directory:    directory,
+		nextSequence: 1,
+		ready:        make(chan struct{}, 1),
+	}
+
+	err = outbox.load()
+	if err != nil {
+		return nil, fmt.Errorf("loading outbox: %w", err)
+	}
+
+	if len(outbox.entries) > 0 {
+		slog.Info("loaded events queued by a previous run", "events", len(outbox.entries))
+		outbox.signal()
+	}
+
+	return outbox, nil
+}
+
+// Enqueue is a function that writes an event to the outbox. The event is on disk by the time it returns.
+func (outbox *Outbox) Enqueue(event entities.FilesystemEvent) error {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	entry := outboxEntry{
+		Sequence:   outbox.nextSequence,
+		Event:      event,
+		EnqueuedAt: time.Now().UTC(),
+	}
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(outbox.entryPath(entry.Sequence), entryBytes, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing outbox entry: %w", err)
+	}
+
+	outbox.nextSequence++
+	outbox.entries = append(outbox.entries, entry)
+	outbox.signal()
+
+	return nil
+}
+
+// Len is a function that returns the number of events waiting to be sent.
+func (outbox *Outbox) Len() int {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	return len(outbox.entries)
+}
+
+// Ready is a function that returns a channel which receives a value whenever an event is queued.
+func (outbox *Outbox) Ready() <-chan struct{} {
+	return outbox.ready
+}
+
+// peek is a function that returns the oldest event in the outbox without removing it.
+func (outbox *Outbox) peek() (outboxEntry, bool) {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	if len(outbox.entries) == 0 {
+		return outboxEntry{}, false
+	}
+
+	return outbox.entries[0], true
+}
+
+// peekN is a function that returns up to n of the oldest events in the outbox, oldest first, without removing them.
+func (outbox *Outbox) peekN(n int) []outboxEntry {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	return slices.Clone(outbox.entries[:min(n, len(outbox.entries))])
+}
+
+// queued is a function that returns those of entries that are still in the outbox.
+func (outbox *Outbox) queued(entries []outboxEntry) []outboxEntry {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	return slices.DeleteFunc(slices.Clone(entries), func(entry outboxEntry) bool {
+		return !slices.ContainsFunc(outbox.entries, func(queued outboxEntry) bool {
+			return queued.Sequence == entry.Sequence
+		})
+	})
+}
+
+// remove is a function that deletes an event from the outbox once it no longer needs sending.
+func (outbox *Outbox) remove(sequence uint64) error {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	err := os.Remove(outbox.entryPath(sequence))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	outbox.deleteEntry(sequence)
+	return nil
+}
+
+// moveToFailed is a function that moves an event the server rejected out of the queue and into the failed directory.
+func (outbox *Outbox) moveToFailed(sequence uint64) error {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	err := os.Rename(outbox.entryPath(sequence), filepath.Join(outbox.directory, outboxFailedDirectory, entryFileName(sequence)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	outbox.deleteEntry(sequence)
+	return nil
+}
+
+func (outbox *Outbox) deleteEntry(sequence uint64) {
+	outbox.entries = slices.DeleteFunc(outbox.entries, func(entry outboxEntry) bool {
+		return entry.Sequence == sequence
+	})
+}
+
+// signal is a function that wakes up anything waiting on Ready, without blocking if it has already been woken.
+func (outbox *Outbox) signal() {
+	select {
+	case outbox.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (outbox *Outbox) load() error {
+	dirEntries, err := os.ReadDir(outbox.directory)
+	if err != nil {
+		return err
+	}
+
+	for _, dirEntry := range dirEntries {
+		name, isEntry := strings.CutSuffix(dirEntry.Name(), ".json")
+		if !isEntry || dirEntry.IsDir() {
+			continue
+		}
+		sequence, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		entryBytes, err := os.ReadFile(outbox.entryPath(sequence))
+		if err != nil {
+			return err
+		}
+
+		var entry outboxEntry
+		err = json.Unmarshal(entryBytes, &entry)
+		if err != nil {
+			// entries are written atomically, so this can only be caused by something else changing the file
+			slog.Warn("skipping unreadable outbox entry", "sequence", sequence, "err", err)
+			continue
+		}
+
+		outbox.entries = append(outbox.entries, entry)
+		outbox.nextSequence = max(outbox.nextSequence, sequence+1)
+	}
+
+	slices.SortFunc(outbox.entries, func(a, b outboxEntry) int {
+		return cmp.Compare(a.Sequence, b.Sequence)
+	})
+
+	// sequence numbers carry on from failed entries too, so a new entry never reuses the name of a failed one
+	failedEntries, err := os.ReadDir(filepath.Join(outbox.directory, outboxFailedDirectory))
+	if err != nil {
+		return err
+	}
+	for _, dirEntry := range failedEntries {
+		sequence, err := strconv.ParseUint(strings.TrimSuffix(dirEntry.Name(), ".json"), 10, 64)
+		if err == nil {
+			outbox.nextSequence = max(outbox.nextSequence, sequence+1)
+		}
+	}
+
+	return nil
+}
+
+func (outbox *Outbox) entryPath(sequence uint64) string {
+	return filepath.Join(outbox.directory, entryFileName(sequence))
+}
+
+// entryFileName is a function that returns the name of the file for an entry. Sequence numbers are zero padded so that
+// the files sort in the order they were queued.
+func entryFileName(sequence uint64) string {
+	return fmt.Sprintf("%020d.json", sequence)
+}
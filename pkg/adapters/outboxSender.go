@@ -0,0 +1,361 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RetryPolicy is a struct that sets out how long the OutboxSender waits before sending an event again after a failure.
+// The delay doubles with every consecutive failure, from InitialDelay up to MaxDelay.
+type RetryPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// delay is a function that returns how long to wait after the given number of consecutive failures. A random jitter of
+// up to half the delay is taken off, so that clients recovering from the same outage don't all retry at once.
+func (policy RetryPolicy) delay(failures int) time.Duration {
+	delay := max(policy.InitialDelay, time.Millisecond)
+	for i := 1; i < failures && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, max(policy.MaxDelay, policy.InitialDelay))
+
+	return delay - rand.N(delay/2+1)
+}
+
+// BatchPolicy is a struct that sets out how the OutboxSender groups queued events into batches. A batch holds up to
+// MaxEvents events whose files add up to no more than MaxBytes, though a single event is always sent however large it
+// is. Once an event is queued the OutboxSender waits for up to Window for more to be queued alongside it before sending.
+// Batching is disabled if MaxEvents is 1 or less.
+type BatchPolicy struct {
+	MaxEvents int
+	MaxBytes  int64
+	Window    time.Duration
+}
+
+// enabled is a function that returns whether events are sent in batches.
+func (policy BatchPolicy) enabled() bool {
+	return policy.MaxEvents > 1
+}
+
+// dispatchLookahead is how many of the oldest events in the outbox the OutboxSender looks through for ones it can send
+// alongside those already being sent.
+const dispatchLookahead = 1000
+
+// OutboxSender is a struct that sends the events in an Outbox to the server, grouping them into batches where it can.
+// Up to concurrency events or batches are sent at once, but an event is never sent before one queued ahead of it on
+// the same path, on a directory above it or on anything within it, so each file's changes are applied in order and a
+// directory is created before, and deleted or renamed after, the changes to what is inside it. An event is only removed
+// from the outbox once the server has applied it, anything else is retried with exponential backoff, so the destination
+// catches up with the source after any outage.
+type OutboxSender struct {
+	outbox       *Outbox
+	eventSender  EventSender
+	acknowledger EventAcknowledger
+	retryPolicy  RetryPolicy
+	batchPolicy  BatchPolicy
+	concurrency  int
+}
+
+// NewOutboxSender is a function that creates an OutboxSender which sends up to concurrency events or batches at once,
+// and acknowledges each event with acknowledger once the server has applied it.
+func NewOutboxSender(outbox *Outbox, eventSender EventSender, acknowledger EventAcknowledger, retryPolicy RetryPolicy, batchPolicy BatchPolicy, concurrency int) *OutboxSender {
+	return &OutboxSender{
+		outbox:       outbox,
+		eventSender:  eventSender,
+		acknowledger: acknowledger,
+		retryPolicy:  retryPolicy,
+		batchPolicy:  batchPolicy,
+		concurrency:  max(concurrency, 1),
+	}
+}
+
+// EventSender is an interface that is implemented by the EventProcessor. This allows for mocking of the
+// EventProcessor functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/eventSender.go  . "EventSender"
+type EventSender interface {
+	ProcessEvent(event entities.FilesystemEvent) error
+	IsBatchable(event entities.FilesystemEvent) bool
+	ProcessBatch(events []entities.FilesystemEvent) ([]error, error)
+}
+
+// EventAcknowledger is an interface that is implemented by the SyncStateStore. This allows for mocking of the
+// SyncStateStore functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/eventAcknowledger.go  . "EventAcknowledger"
+type EventAcknowledger interface {
+	AcknowledgeEvent(event entities.FilesystemEvent) error
+}
+
+// Run is a function that sends events as they are queued until the context is cancelled.
+func (sender *OutboxSender) Run(ctx context.Context) error {
+	for {
+		err := sender.Flush(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sender.outbox.Ready():
+		}
+
+		if !sender.waitForBatch(ctx) {
+			return nil
+		}
+	}
+}
+
+// waitForBatch is a function that gives events queued in quick succession the chance to be sent in the same batch, by
+// waiting for the batch window unless a full batch is already queued. It returns false if the context is cancelled.
+func (sender *OutboxSender) waitForBatch(ctx context.Context) bool {
+	if !sender.batchPolicy.enabled() || sender.batchPolicy.Window <= 0 || sender.outbox.Len() >= sender.batchPolicy.MaxEvents {
+		return true
+	}
+
+	timer := time.NewTimer(sender.batchPolicy.Window)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Flush is a function that sends every event in the outbox, retrying any that fail, and returns once the outbox is
+// empty. It returns early with the context's error if the context is cancelled, once the requests already being sent
+// have finished.
+func (sender *OutboxSender) Flush(ctx context.Context) error {
+	type dispatchResult struct {
+		entries []outboxEntry
+		err     error
+	}
+
+	inFlight := make(map[uint64]struct{})
+	results := make(chan dispatchResult)
+	running := 0
+	var err error
+	for {
+		if err == nil {
+			for _, entries := range sender.nextDispatch(inFlight, sender.concurrency-running) {
+				for _, entry := range entries {
+					inFlight[entry.Sequence] = struct{}{}
+				}
+				running++
+				go func() {
+					results <- dispatchResult{entries: entries, err: sender.sendUntilSettled(ctx, entries)}
+				}()
+			}
+		}
+
+		if running == 0 {
+			return err
+		}
+
+		result := <-results
+		running--
+		for _, entry := range result.entries {
+			delete(inFlight, entry.Sequence)
+		}
+		if result.err != nil && err == nil {
+			err = result.err
+		}
+	}
+}
+
+// nextDispatch is a function that returns up to slots events or batches of events that can be sent alongside those
+// already in flight. An event can be sent once nothing queued ahead of it that overlaps its paths is still waiting to
+// be sent.
+func (sender *OutboxSender) nextDispatch(inFlight map[uint64]struct{}, slots int) [][]outboxEntry {
+	if slots <= 0 {
+		return nil
+	}
+
+	entries := sender.outbox.peekN(dispatchLookahead)
+	dispatch := make([][]outboxEntry, 0, slots)
+	claimed := make(map[uint64]struct{})
+	waiting := newPathSet()
+	for i, entry := range entries {
+		if len(dispatch) == slots {
+			break
+		}
+
+		_, sending := inFlight[entry.Sequence]
+		_, batched := claimed[entry.Sequence]
+		if !sending && !batched && !waiting.overlaps(entry.Event) {
+			batch := sender.batchFrom(entries[i:], inFlight, claimed, waiting)
+			for _, batchEntry := range batch {
+				claimed[batchEntry.Sequence] = struct{}{}
+			}
+			dispatch = append(dispatch, batch)
+		}
+
+		waiting.add(entry.Event)
+	}
+
+	return dispatch
+}
+
+// batchFrom is a function that returns the first of entries along with as many of the batchable events after it as fit
+// in a batch, skipping over any that have to wait for an event that isn't in the batch.
+func (sender *OutboxSender) batchFrom(entries []outboxEntry, inFlight, claimed map[uint64]struct{}, waiting *pathSet) []outboxEntry {
+	batch := []outboxEntry{entries[0]}
+	if !sender.batchPolicy.enabled() || !sender.eventSender.IsBatchable(entries[0].Event) {
+		return batch
+	}
+
+	size := entries[0].Event.FileContents.Size
+	skipped := newPathSet()
+	for _, entry := range entries[1:] {
+		if len(batch) == sender.batchPolicy.MaxEvents {
+			break
+		}
+
+		_, sending := inFlight[entry.Sequence]
+		_, batched := claimed[entry.Sequence]
+		if sending || batched || waiting.overlaps(entry.Event) || skipped.overlaps(entry.Event) || !sender.eventSender.IsBatchable(entry.Event) {
+			skipped.add(entry.Event)
+			continue
+		}
+
+		if size+entry.Event.FileContents.Size > sender.batchPolicy.MaxBytes {
+			break
+		}
+		size += entry.Event.FileContents.Size
+		batch = append(batch, entry)
+	}
+
+	return batch
+}
+
+// sendUntilSettled is a function that sends an event or batch of events, retrying those that fail with exponential
+// backoff until none of them need sending. It returns early with the context's error if the context is cancelled.
+func (sender *OutboxSender) sendUntilSettled(ctx context.Context, entries []outboxEntry) error {
+	failures := 0
+	for {
+		var err error
+		if len(entries) == 1 {
+			err = sender.send(entries[0])
+		} else {
+			err = sender.sendBatch(entries)
+		}
+		if err == nil {
+			return nil
+		}
+
+		// only the events that still need sending are sent again
+		entries = sender.outbox.queued(entries)
+		if len(entries) == 0 {
+			return nil
+		}
+
+		failures++
+		delay := sender.retryPolicy.delay(failures)
+		slog.Warn("sending event failed, retrying", "operation", entries[0].Event.Operation, "filePath", entries[0].Event.Name,
+			"events", len(entries), "failures", failures, "retryIn", delay, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send is a function that sends a single event and removes it from the outbox if it no longer needs sending. An error
+// is returned if the event should be sent again.
+func (sender *OutboxSender) send(entry outboxEntry) error {
+	return sender.settle(entry, sender.eventSender.ProcessEvent(entry.Event))
+}
+
+// sendBatch is a function that sends a batch of events and removes each one from the outbox if it no longer needs
+// sending. Every event is settled even if one before it failed, as the server has processed each of them. An error is
+// returned covering the events that should be sent again, which are left in the outbox.
+func (sender *OutboxSender) sendBatch(entries []outboxEntry) error {
+	events := make([]entities.FilesystemEvent, 0, len(entries))
+	for _, entry := range entries {
+		events = append(events, entry.Event)
+	}
+
+	errs, err := sender.eventSender.ProcessBatch(events)
+	if err != nil {
+		return err
+	}
+
+	var resend []error
+	for i, entry := range entries {
+		err = sender.settle(entry, errs[i])
+		if err != nil {
+			resend = append(resend, err)
+		}
+	}
+
+	return errors.Join(resend...)
+}
+
+// settle is a function that removes an event from the outbox if, given the error sending it returned, it no longer
+// needs sending. The error is returned if the event should be sent again.
+func (sender *OutboxSender) settle(entry outboxEntry, err error) error {
+	event := entry.Event
+	switch {
+	case err == nil:
+		ackErr := sender.acknowledger.AcknowledgeEvent(event)
+		if ackErr != nil {
+			// the server has the change, at worst it is sent again the next time the app starts
+			slog.Error("acknowledging event", "filePath", event.Name, "err", ackErr)
+		}
+		slog.Info("processed filesystem event", "operation", event.Operation, "filePath", event.Name)
+		return sender.outbox.remove(entry.Sequence)
+
+	case errors.Is(err, os.ErrNotExist):
+		// the file changed again before it could be sent, the event for that change is further back in the queue
+		slog.Info("file no longer exists, skipping event", "operation", event.Operation, "filePath", event.Name)
+		return sender.outbox.remove(entry.Sequence)
+
+	case errors.As(err, new(*ConflictError)):
+		// the server's copy changed since the app last saw it, so the event is set aside rather than overwriting it
+		slog.Error("file has changed on the server, moving event to the failed outbox", "operation", event.Operation,
+			"filePath", event.Name, "err", err)
+		return sender.outbox.moveToFailed(entry.Sequence)
+
+	case isRejected(err):
+		// sending the event again would get the same response, so it is set aside rather than blocking the queue
+		slog.Error("server rejected event, moving it to the failed outbox", "operation", event.Operation,
+			"filePath", event.Name, "err", err)
+		return sender.outbox.moveToFailed(entry.Sequence)
+
+	default:
+		return err
+	}
+}
+
+// isRejected is a function that returns whether the server responded to a request with a client error, other than
+// those that can succeed if the request is sent again.
+func isRejected(err error) bool {
+	var statusCodeErr *StatusCodeError
+	if !errors.As(err, &statusCodeErr) {
+		return false
+	}
+
+	switch statusCodeErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusFailedDependency:
+		return false
+	}
+
+	return statusCodeErr.StatusCode >= 400 && statusCodeErr.StatusCode < 500
+}
@@ -0,0 +1,307 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	InitialDelay: time.Millisecond,
+	MaxDelay:     4 * time.Millisecond,
+}
+
+func newTestOutbox(t *testing.T, events ...entities.FilesystemEvent) *Outbox {
+	t.Helper()
+	g := NewGomegaWithT(t)
+
+	outbox, err := NewOutbox(t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, event := range events {
+		g.Expect(outbox.Enqueue(event)).To(Succeed())
+	}
+
+	return outbox
+}
+
+func TestOutboxSender_Flush_SendsEventsInOrder(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	first := entities.FilesystemEvent{Name: "./source/first.go", Operation: entities.OperationCreated}
+	second := entities.FilesystemEvent{Name: "./source/second.go", Operation: entities.OperationDeleted}
+	outbox := newTestOutbox(t, first, second)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{}, 1)
+
+	gomock.InOrder(
+		mockEventSender.EXPECT().ProcessEvent(first).Return(nil),
+		mockAcknowledger.EXPECT().AcknowledgeEvent(first).Return(nil),
+		mockEventSender.EXPECT().ProcessEvent(second).Return(nil),
+		mockAcknowledger.EXPECT().AcknowledgeEvent(second).Return(nil),
+	)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_RetriesUntilEventIsSent(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	event := entities.FilesystemEvent{Name: "./source/file.go", Operation: entities.OperationCreated}
+	outbox := newTestOutbox(t, event)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{}, 1)
+
+	gomock.InOrder(
+		mockEventSender.EXPECT().ProcessEvent(event).Return(errors.New("connection refused")),
+		mockEventSender.EXPECT().ProcessEvent(event).Return(&StatusCodeError{StatusCode: 503}),
+		mockEventSender.EXPECT().ProcessEvent(event).Return(nil),
+		mockAcknowledger.EXPECT().AcknowledgeEvent(event).Return(nil),
+	)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_StopsWhenContextIsCancelled(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+
+	event := entities.FilesystemEvent{Name: "./source/file.go", Operation: entities.OperationCreated}
+	outbox := newTestOutbox(t, event)
+	sender := NewOutboxSender(outbox, mockEventSender, mock_adapters.NewMockEventAcknowledger(ctrl), RetryPolicy{
+		InitialDelay: time.Hour,
+		MaxDelay:     time.Hour,
+	}, BatchPolicy{}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockEventSender.EXPECT().ProcessEvent(event).DoAndReturn(func(entities.FilesystemEvent) error {
+		cancel()
+		return errors.New("connection refused")
+	})
+
+	g.Expect(sender.Flush(ctx)).To(MatchError(context.Canceled))
+	// the event stays queued for the next run
+	g.Expect(outbox.Len()).To(Equal(1))
+}
+
+func TestOutboxSender_Flush_SetsAsideRejectedEvents(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	rejected := entities.FilesystemEvent{Name: "./source/rejected.go", Operation: entities.OperationRenamed}
+	conflicted := entities.FilesystemEvent{Name: "./source/conflicted.go", Operation: entities.OperationModified}
+	missing := entities.FilesystemEvent{Name: "./source/missing.go", Operation: entities.OperationCreated}
+	outbox := newTestOutbox(t, rejected, conflicted, missing)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{}, 1)
+
+	gomock.InOrder(
+		mockEventSender.EXPECT().ProcessEvent(rejected).Return(&StatusCodeError{StatusCode: 400}),
+		mockEventSender.EXPECT().ProcessEvent(conflicted).Return(&ConflictError{Path: "/conflicted.go"}),
+		mockEventSender.EXPECT().ProcessEvent(missing).Return(os.ErrNotExist),
+	)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(gomock.Any()).Times(0)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_SendsBatchableEventsTogether(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	created := entities.FilesystemEvent{Name: "./source/created.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Size: 10}}
+	renamed := entities.FilesystemEvent{Name: "./source/renamed.go", PreviousPath: "./source/old.go", Operation: entities.OperationRenamed}
+	large := entities.FilesystemEvent{Name: "./source/large.go", Operation: entities.OperationModified, FileContents: entities.FileContents{Size: 10 << 20}}
+	deleted := entities.FilesystemEvent{Name: "./source/deleted.go", Operation: entities.OperationDeleted}
+	outbox := newTestOutbox(t, created, renamed, large, deleted)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{MaxEvents: 10, MaxBytes: 1 << 20}, 1)
+
+	mockEventSender.EXPECT().IsBatchable(gomock.Any()).DoAndReturn(func(event entities.FilesystemEvent) bool {
+		return event.Name != large.Name
+	}).AnyTimes()
+	gomock.InOrder(
+		// the large file is sent on its own, and nothing else is queued on its path so the events after it can be batched
+		mockEventSender.EXPECT().ProcessBatch([]entities.FilesystemEvent{created, renamed, deleted}).Return([]error{nil, nil, nil}, nil),
+		mockEventSender.EXPECT().ProcessEvent(large).Return(nil),
+	)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(gomock.Any()).Return(nil).Times(4)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_LimitsBatchSize(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	events := make([]entities.FilesystemEvent, 0, 5)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		events = append(events, entities.FilesystemEvent{Name: "./source/" + name, Operation: entities.OperationCreated, FileContents: entities.FileContents{Size: 4}})
+	}
+	outbox := newTestOutbox(t, events...)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{MaxEvents: 3, MaxBytes: 10}, 1)
+
+	mockEventSender.EXPECT().IsBatchable(gomock.Any()).Return(true).AnyTimes()
+	gomock.InOrder(
+		mockEventSender.EXPECT().ProcessBatch(events[0:2]).Return([]error{nil, nil}, nil),
+		mockEventSender.EXPECT().ProcessBatch(events[2:4]).Return([]error{nil, nil}, nil),
+		mockEventSender.EXPECT().ProcessEvent(events[4]).Return(nil),
+	)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(gomock.Any()).Return(nil).Times(5)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_RetriesBatchFromFailedEvent(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	applied := entities.FilesystemEvent{Name: "./source/applied.go", Operation: entities.OperationDeleted}
+	rejected := entities.FilesystemEvent{Name: "./source/rejected.go", Operation: entities.OperationDeleted}
+	failed := entities.FilesystemEvent{Name: "./source/failed.go", Operation: entities.OperationDeleted}
+	skipped := entities.FilesystemEvent{Name: "./source/skipped.go", Operation: entities.OperationDeleted}
+	outbox := newTestOutbox(t, applied, rejected, failed, skipped)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{MaxEvents: 10, MaxBytes: 1 << 20}, 1)
+
+	mockEventSender.EXPECT().IsBatchable(gomock.Any()).Return(true).AnyTimes()
+	gomock.InOrder(
+		mockEventSender.EXPECT().ProcessBatch([]entities.FilesystemEvent{applied, rejected, failed, skipped}).
+			Return([]error{nil, &StatusCodeError{StatusCode: 400}, &StatusCodeError{StatusCode: 500}, &StatusCodeError{StatusCode: 424}}, nil),
+		// the batch as a whole fails, so it is sent again from the same event
+		mockEventSender.EXPECT().ProcessBatch([]entities.FilesystemEvent{failed, skipped}).Return(nil, errors.New("connection refused")),
+		mockEventSender.EXPECT().ProcessBatch([]entities.FilesystemEvent{failed, skipped}).Return([]error{nil, nil}, nil),
+	)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(applied).Return(nil)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(failed).Return(nil)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(skipped).Return(nil)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_SettlesEventsAfterFailedEvent(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	failed := entities.FilesystemEvent{Name: "./source/failed.go", Operation: entities.OperationDeleted}
+	applied := entities.FilesystemEvent{Name: "./source/applied.go", Operation: entities.OperationDeleted}
+	rejected := entities.FilesystemEvent{Name: "./source/rejected.go", Operation: entities.OperationDeleted}
+	outbox := newTestOutbox(t, failed, applied, rejected)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{MaxEvents: 10, MaxBytes: 1 << 20}, 1)
+
+	mockEventSender.EXPECT().IsBatchable(gomock.Any()).Return(true).AnyTimes()
+	gomock.InOrder(
+		mockEventSender.EXPECT().ProcessBatch([]entities.FilesystemEvent{failed, applied, rejected}).
+			Return([]error{&StatusCodeError{StatusCode: 500}, nil, &StatusCodeError{StatusCode: 400}}, nil),
+		// the server applied the events after the failed one, so only the failed one is sent again
+		mockEventSender.EXPECT().ProcessEvent(failed).Return(nil),
+	)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(applied).Return(nil)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(failed).Return(nil)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_SendsUnrelatedEventsConcurrently(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	large := entities.FilesystemEvent{Name: "./source/large.go", Operation: entities.OperationModified}
+	small := entities.FilesystemEvent{Name: "./source/small.go", Operation: entities.OperationModified}
+	outbox := newTestOutbox(t, large, small)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{}, 2)
+
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	mockEventSender.EXPECT().ProcessEvent(gomock.Any()).DoAndReturn(func(event entities.FilesystemEvent) error {
+		started <- event.Name
+		<-release
+		return nil
+	}).Times(2)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(gomock.Any()).Return(nil).Times(2)
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- sender.Flush(context.Background())
+	}()
+
+	// neither event finishes until both have been started
+	g.Eventually(started).Should(HaveLen(2))
+	close(release)
+	g.Eventually(flushed).Should(Receive(BeNil()))
+	g.Expect(outbox.Len()).To(BeZero())
+}
+
+func TestOutboxSender_Flush_OrdersEventsOnRelatedPaths(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockEventSender := mock_adapters.NewMockEventSender(ctrl)
+	mockAcknowledger := mock_adapters.NewMockEventAcknowledger(ctrl)
+
+	directory := entities.FilesystemEvent{Name: "./source/dir", Operation: entities.OperationCreated, FileContents: entities.FileContents{IsDirectory: true}}
+	file := entities.FilesystemEvent{Name: "./source/dir/a.go", Operation: entities.OperationCreated}
+	other := entities.FilesystemEvent{Name: "./source/other.go", Operation: entities.OperationCreated}
+	renamed := entities.FilesystemEvent{Name: "./source/moved", PreviousPath: "./source/dir", Operation: entities.OperationRenamed}
+	outbox := newTestOutbox(t, directory, file, other, renamed)
+	sender := NewOutboxSender(outbox, mockEventSender, mockAcknowledger, testRetryPolicy, BatchPolicy{}, 4)
+
+	var mu sync.Mutex
+	steps := make([]string, 0, 8)
+	mockEventSender.EXPECT().ProcessEvent(gomock.Any()).DoAndReturn(func(event entities.FilesystemEvent) error {
+		mu.Lock()
+		steps = append(steps, "start "+event.Name)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		steps = append(steps, "end "+event.Name)
+		mu.Unlock()
+		return nil
+	}).Times(4)
+	mockAcknowledger.EXPECT().AcknowledgeEvent(gomock.Any()).Return(nil).Times(4)
+
+	g.Expect(sender.Flush(context.Background())).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+
+	// the file waits for its directory to be created, the rename waits for the file, and the unrelated file waits for
+	// neither
+	g.Expect(slices.Index(steps, "end ./source/dir")).To(BeNumerically("<", slices.Index(steps, "start ./source/dir/a.go")))
+	g.Expect(slices.Index(steps, "end ./source/dir/a.go")).To(BeNumerically("<", slices.Index(steps, "start ./source/moved")))
+	g.Expect(slices.Index(steps, "start ./source/other.go")).To(BeNumerically("<", slices.Index(steps, "end ./source/dir")))
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	g := NewGomegaWithT(t)
+	policy := RetryPolicy{InitialDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	g.Expect(policy.delay(1)).To(BeNumerically("~", 750*time.Millisecond, 250*time.Millisecond))
+	g.Expect(policy.delay(3)).To(BeNumerically("~", 3*time.Second, time.Second))
+	g.Expect(policy.delay(20)).To(BeNumerically("~", 7500*time.Millisecond, 2500*time.Millisecond))
+}
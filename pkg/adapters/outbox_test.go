@@ -0,0 +1,60 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutbox_ReloadsQueuedEventsInOrder(t *testing.T) {
+	g := NewGomegaWithT(t)
+	directory := t.TempDir()
+
+	outbox, err := NewOutbox(directory)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, name := range []string{"/first.go", "/second.go", "/third.go"} {
+		g.Expect(outbox.Enqueue(entities.FilesystemEvent{Name: name, Operation: entities.OperationCreated})).To(Succeed())
+	}
+
+	first, exists := outbox.peek()
+	g.Expect(exists).To(BeTrue())
+	g.Expect(outbox.remove(first.Sequence)).To(Succeed())
+
+	reloaded, err := NewOutbox(directory)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reloaded.Len()).To(Equal(2))
+	g.Expect(reloaded.Ready()).To(Receive())
+
+	entry, exists := reloaded.peek()
+	g.Expect(exists).To(BeTrue())
+	g.Expect(entry.Event.Name).To(Equal("/second.go"))
+
+	// new events are queued behind the ones that were reloaded
+	g.Expect(reloaded.Enqueue(entities.FilesystemEvent{Name: "/fourth.go", Operation: entities.OperationCreated})).To(Succeed())
+	g.Expect(reloaded.entries[2].Sequence).To(Equal(uint64(4)))
+}
+
+func TestOutbox_MoveToFailed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	directory := t.TempDir()
+
+	outbox, err := NewOutbox(directory)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(outbox.Enqueue(entities.FilesystemEvent{Name: "/file.go", Operation: entities.OperationDeleted})).To(Succeed())
+
+	entry, _ := outbox.peek()
+	g.Expect(outbox.moveToFailed(entry.Sequence)).To(Succeed())
+	g.Expect(outbox.Len()).To(BeZero())
+	g.Expect(filepath.Join(directory, outboxFailedDirectory, entryFileName(entry.Sequence))).To(BeAnExistingFile())
+
+	reloaded, err := NewOutbox(directory)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reloaded.Len()).To(BeZero())
+	g.Expect(reloaded.Enqueue(entities.FilesystemEvent{Name: "/file.go", Operation: entities.OperationCreated})).To(Succeed())
+	g.Expect(reloaded.entries[0].Sequence).To(Equal(entry.Sequence + 1))
+
+	_, err = os.Stat(reloaded.entryPath(entry.Sequence))
+	g.Expect(err).To(MatchError(os.ErrNotExist))
+}
@@ -0,0 +1,93 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"os"
+	"sync"
+)
+
+// PendingUploadTracker is a struct that persists the upload sessions the app has started but not yet committed, keyed
+// by the path being uploaded, so that an interrupted upload can be resumed after the app restarts.
+type PendingUploadTracker struct {
+	mu       sync.Mutex
+	filePath string
+	uploads  map[string]entities.PendingUpload
+}
+
+var _ PendingUploadStore = &PendingUploadTracker{}
+
+// NewPendingUploadTracker is a function that loads the pending uploads recorded by a previous run of the app.
+func NewPendingUploadTracker(filePath string) (*PendingUploadTracker, error) {
+	tracker := &PendingUploadTracker{
+		filePath: filePath,
+		uploads:  make(map[string]entities.PendingUpload),
+	}
+
+	uploadBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return tracker, nil
+		}
+		return nil, fmt.Errorf("reading pending uploads: %w", err)
+	}
+
+	err = json.Unmarshal(uploadBytes, &tracker.uploads)
+	if err != nil {
+		return nil, fmt.Errorf("decoding pending uploads: %w", err)
+	}
+
+	return tracker, nil
+}
+
+// PendingUploadStore is an interface that sets out the functions implemented by the PendingUploadTracker. This allows
+// for mocking of the PendingUploadTracker functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/pendingUploadStore.go  . "PendingUploadStore"
+type PendingUploadStore interface {
+	GetPendingUpload(path string) (entities.PendingUpload, bool)
+	SavePendingUpload(upload entities.PendingUpload) error
+	RemovePendingUpload(path string) error
+}
+
+// GetPendingUpload is a function that returns the uncommitted upload for path, if there is one.
+func (tracker *PendingUploadTracker) GetPendingUpload(path string) (entities.PendingUpload, bool) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	upload, exists := tracker.uploads[path]
+	return upload, exists
+}
+
+// SavePendingUpload is a function that records an upload session, replacing any previous session for the same path.
+func (tracker *PendingUploadTracker) SavePendingUpload(upload entities.PendingUpload) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.uploads[upload.Path] = upload
+	return tracker.save()
+}
+
+// RemovePendingUpload is a function that forgets the upload session for path once it has been committed or abandoned.
+func (tracker *PendingUploadTracker) RemovePendingUpload(path string) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if _, exists := tracker.uploads[path]; !exists {
+		return nil
+	}
+
+	delete(tracker.uploads, path)
+	return tracker.save()
+}
+
+func (tracker *PendingUploadTracker) save() error {
+	uploadBytes, err := json.Marshal(tracker.uploads)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(tracker.filePath, uploadBytes, 0o600)
+}
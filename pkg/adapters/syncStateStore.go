@@ -0,0 +1,403 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	journalOperationRecord      = "record"
+	journalOperationAcknowledge = "acknowledge"
+
+	// syncStateCompactionThreshold is the number of journal entries after which the journal is folded into the
+	// state file.
+	syncStateCompactionThreshold = 1000
+)
+
+// SyncStateStore is a struct that persists the state of every path in the source directory between runs of the app,
+// along with whether the server has acknowledged that state. It is made up of a state file holding a full snapshot and
+// an append only journal of the changes made since the snapshot was written, which is periodically compacted.
+type SyncStateStore struct {
+	mu                sync.Mutex
+	statePath         string
+	sourceDirectory   string
+	preserveOwnership bool
+	records           map[string]syncStateRecord
+	existed           bool
+	journal           *os.File
+	journalEntries    int
+}
+
+var _ EventAcknowledger = &SyncStateStore{}
+
+// syncStateRecord is the persisted state of a single path in the source directory.
+type syncStateRecord struct {
+	entities.FileContents
+	Acknowledged bool `json:"acknowledged"`
+}
+
+// syncStateSnapshot is the layout of the state file.
+type syncStateSnapshot struct {
+	SourceDirectory string                     `json:"sourceDirectory"`
+	Records         map[string]syncStateRecord `json:"records"`
+}
+
+// syncStateJournalEntry is a single line of the journal.
+type syncStateJournalEntry struct {
+	Operation string                   `json:"operation"`
+	Event     entities.FilesystemEvent `json:"event"`
+}
+
+// NewSyncStateStore is a function that loads the state left by a previous run, replaying any journal entries on top of
+// the state file. State recorded for a different source directory is discarded. Owners are only compared when deciding
+// what to queue if preserveOwnership is set.
+func NewSyncStateStore(statePath, sourceDirectory string, preserveOwnership bool) (*SyncStateStore, error) {
+	store := &SyncStateStore{
+		statePath:         statePath,
+		sourceDirectory:   sourceDirectory,
+		preserveOwnership: preserveOwnership,
+		records:           make(map[string]syncStateRecord),
+	}
+
+	err := store.load()
+	if err != nil {
+		return nil, err
+	}
+
+	// fold the replayed journal into the state file so the new journal starts out empty
+	err = store.compact()
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// AcknowledgedSnapshot is a function that returns the state of the source directory as last acknowledged by the server.
+// It returns nil if there is no state from a previous run.
+func (store *SyncStateStore) AcknowledgedSnapshot() map[string]entities.FileContents {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if !store.existed {
+		return nil
+	}
+
+	snapshot := make(map[string]entities.FileContents, len(store.records))
+	for path, record := range store.records {
+		if record.Acknowledged {
+			snapshot[path] = record.FileContents
+		}
+	}
+
+	return snapshot
+}
+
+// RecordEvent is a function that records a filesystem event that is about to be sent to the server. The new state of
+// the path is stored as unacknowledged, so it will be sent again on the next startup if the server never confirms it.
+// Deletes and the previous path of a rename are left in place until they are acknowledged.
+func (store *SyncStateStore) RecordEvent(event entities.FilesystemEvent) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.applyRecord(event)
+	return store.appendJournal(journalOperationRecord, event)
+}
+
+// AcknowledgeEvent is a function that marks a filesystem event as applied by the server.
+func (store *SyncStateStore) AcknowledgeEvent(event entities.FilesystemEvent) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.applyAcknowledge(event)
+	return store.appendJournal(journalOperationAcknowledge, event)
+}
+
+// EventsToQueue is a function that returns the events to queue in place of event, taking account of what is recorded
+// in the sync state about the paths it touches. A file moved by copying it, which some tools do, can have different
+// metadata to the file it was moved from, so a rename is followed by a change to the metadata if it does. Events for
+// files with several hard links are expanded as described in hardLinkEvents.
+func (store *SyncStateStore) EventsToQueue(event entities.FilesystemEvent) []entities.FilesystemEvent {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if event.Operation == entities.OperationRenamed {
+		record, exists := store.records[event.PreviousPath]
+		if exists && record.SameKind(event.FileContents) && event.FileContents.MetadataDiffers(record.FileContents, store.preserveOwnership) {
+			return []entities.FilesystemEvent{event, {
+				Name:         event.Name,
+				Operation:    entities.OperationMetadata,
+				FileContents: event.FileContents,
+			}}
+		}
+	}
+
+	return store.hardLinkEvents(event)
+}
+
+// RecordedContents is a function that returns the contents last recorded for path, and false if nothing is recorded
+// for it.
+func (store *SyncStateStore) RecordedContents(path string) (entities.FileContents, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	record, exists := store.records[path]
+	return record.FileContents, exists
+}
+
+// hardLinkEvents is a function that returns the events to queue in place of event, taking account of the other hard
+// links to the same file recorded in the sync state that are still links to it. A created file is sent as a new link
+// to one of them, if there are any. Writing to a file changes the contents of all of its links, but the server replaces
+// the file it writes to rather than writing into it, so a modified file is followed by events that link its other
+// paths to it again. A modification that relinking has already recorded needs no events.
+func (store *SyncStateStore) hardLinkEvents(event entities.FilesystemEvent) []entities.FilesystemEvent {
+	if !event.FileContents.IsHardLinked() {
+		return []entities.FilesystemEvent{event}
+	}
+
+	switch event.Operation {
+	case entities.OperationCreated:
+		links := store.hardLinks(event.Name, event.FileContents.ID())
+		if event.HardLinkOf == "" && len(links) > 0 {
+			event.HardLinkOf = links[0]
+		}
+
+	case entities.OperationModified:
+		record, exists := store.records[event.Name]
+		if exists && record.ID() == event.FileContents.ID() && record.Hash == event.FileContents.Hash {
+			return nil
+		}
+
+		events := []entities.FilesystemEvent{event}
+		for _, path := range store.hardLinks(event.Name, event.FileContents.ID()) {
+			events = append(events, entities.FilesystemEvent{
+				Name:         path,
+				Operation:    entities.OperationCreated,
+				HardLinkOf:   event.Name,
+				FileContents: event.FileContents,
+			})
+		}
+		return events
+	}
+
+	return []entities.FilesystemEvent{event}
+}
+
+// hardLinks is a function that returns the recorded paths other than path that are hard links to the file with the
+// given id, in order. Records can be out of date, such as for a path whose delete hasn't been acknowledged yet, so each
+// path is only returned if it is still a link to the file.
+func (store *SyncStateStore) hardLinks(path string, id entities.FileID) []string {
+	links := make([]string, 0)
+	for recordPath, record := range store.records {
+		if recordPath == path || record.IsDirectory || record.IsSymlink || record.ID() != id {
+			continue
+		}
+
+		currentID, err := readFileID(recordPath)
+		if err == nil && currentID == id {
+			links = append(links, recordPath)
+		}
+	}
+	slices.Sort(links)
+
+	return links
+}
+
+// Close is a function that compacts the journal into the state file and closes it.
+func (store *SyncStateStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.compact()
+	if err != nil {
+		return err
+	}
+
+	return store.journal.Close()
+}
+
+func (store *SyncStateStore) applyRecord(event entities.FilesystemEvent) {
+	switch event.Operation {
+	case entities.OperationCreated, entities.OperationModified, entities.OperationMetadata, entities.OperationRenamed:
+		store.records[event.Name] = syncStateRecord{FileContents: event.FileContents}
+	}
+}
+
+func (store *SyncStateStore) applyAcknowledge(event entities.FilesystemEvent) {
+	switch event.Operation {
+	case entities.OperationCreated, entities.OperationModified, entities.OperationMetadata:
+		store.acknowledgeContents(event.Name, event.FileContents)
+
+	case entities.OperationRenamed:
+		if event.FileContents.IsDirectory {
+			store.moveDescendants(event.PreviousPath, event.Name)
+		}
+		delete(store.records, event.PreviousPath)
+		store.acknowledgeContents(event.Name, event.FileContents)
+
+	case entities.OperationDeleted:
+		delete(store.records, event.Name)
+		store.removeDescendants(event.Name)
+	}
+}
+
+// acknowledgeContents is a function that marks the record for a path as acknowledged, as long as it has not been
+// replaced by a newer change since the acknowledged event was recorded. A newer change to only the file's metadata
+// leaves the record unacknowledged too, so that it is still sent if the app stops before it is.
+func (store *SyncStateStore) acknowledgeContents(path string, contents entities.FileContents) {
+	record, exists := store.records[path]
+	if !exists {
+		return
+	}
+
+	if record.Hash != contents.Hash || record.Inode != contents.Inode || record.LinkTarget != contents.LinkTarget ||
+		record.MetadataDiffers(contents, store.preserveOwnership) {
+		return
+	}
+
+	record.Acknowledged = true
+	store.records[path] = record
+}
+
+func (store *SyncStateStore) moveDescendants(oldPath, newPath string) {
+	prefix := oldPath + string(filepath.Separator)
+	for path, record := range store.records {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		delete(store.records, path)
+		store.records[newPath+strings.TrimPrefix(path, oldPath)] = record
+	}
+}
+
+func (store *SyncStateStore) removeDescendants(path string) {
+	prefix := path + string(filepath.Separator)
+	for recordPath := range store.records {
+		if strings.HasPrefix(recordPath, prefix) {
+			delete(store.records, recordPath)
+		}
+	}
+}
+
+func (store *SyncStateStore) journalPath() string {
+	return store.statePath + ".journal"
+}
+
+func (store *SyncStateStore) load() error {
+	stateBytes, err := os.ReadFile(store.statePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading sync state: %w", err)
+	}
+
+	if err == nil {
+		var snapshot syncStateSnapshot
+		err = json.Unmarshal(stateBytes, &snapshot)
+		if err != nil {
+			return fmt.Errorf("decoding sync state: %w", err)
+		}
+
+		if snapshot.SourceDirectory != store.sourceDirectory {
+			slog.Warn("sync state belongs to a different source directory, ignoring it",
+				"stateSourceDirectory", snapshot.SourceDirectory)
+			err = os.Remove(store.journalPath())
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			return nil
+		}
+
+		store.existed = true
+		if snapshot.Records != nil {
+			store.records = snapshot.Records
+		}
+	}
+
+	journal, err := os.Open(store.journalPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("opening sync state journal: %w", err)
+	}
+	defer journal.Close()
+
+	store.existed = true
+	scanner := bufio.NewScanner(journal)
+	for scanner.Scan() {
+		var entry syncStateJournalEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			// a partially written final line is expected if the app stopped mid write
+			slog.Warn("skipping unreadable sync state journal entry", "err", err)
+			continue
+		}
+
+		switch entry.Operation {
+		case journalOperationRecord:
+			store.applyRecord(entry.Event)
+		case journalOperationAcknowledge:
+			store.applyAcknowledge(entry.Event)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (store *SyncStateStore) appendJournal(operation string, event entities.FilesystemEvent) error {
+	entryBytes, err := json.Marshal(syncStateJournalEntry{
+		Operation: operation,
+		Event:     event,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = store.journal.Write(append(entryBytes, '\n'))
+	if err != nil {
+		return fmt.Errorf("writing sync state journal: %w", err)
+	}
+
+	store.journalEntries++
+	if store.journalEntries >= syncStateCompactionThreshold {
+		return store.compact()
+	}
+
+	return nil
+}
+
+// compact is a function that writes every record to the state file and starts a new, empty journal.
+func (store *SyncStateStore) compact() error {
+	stateBytes, err := json.Marshal(syncStateSnapshot{
+		SourceDirectory: store.sourceDirectory,
+		Records:         store.records,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(store.statePath, stateBytes, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+
+	if store.journal != nil {
+		_ = store.journal.Close()
+	}
+
+	store.journal, err = os.OpenFile(store.journalPath(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening sync state journal: %w", err)
+	}
+	store.journalEntries = 0
+
+	return nil
+}
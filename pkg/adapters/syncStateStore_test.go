@@ -0,0 +1,234 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncStateStore_NoPreviousState(t *testing.T) {
+	g := NewGomegaWithT(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := NewSyncStateStore(statePath, "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer store.Close()
+
+	g.Expect(store.AcknowledgedSnapshot()).To(BeNil())
+}
+
+func TestSyncStateStore_OnlyAcknowledgedStateIsReloaded(t *testing.T) {
+	g := NewGomegaWithT(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := NewSyncStateStore(statePath, "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	acknowledged := entities.FilesystemEvent{
+		Name:         "source/path/acknowledged.go",
+		Operation:    entities.OperationCreated,
+		FileContents: entities.FileContents{Inode: 1, Hash: "abc"},
+	}
+	unacknowledged := entities.FilesystemEvent{
+		Name:         "source/path/unacknowledged.go",
+		Operation:    entities.OperationCreated,
+		FileContents: entities.FileContents{Inode: 2, Hash: "def"},
+	}
+
+	g.Expect(store.RecordEvent(acknowledged)).To(Succeed())
+	g.Expect(store.AcknowledgeEvent(acknowledged)).To(Succeed())
+	g.Expect(store.RecordEvent(unacknowledged)).To(Succeed())
+
+	// reload from the journal without compacting, as if the app had crashed
+	reloaded, err := NewSyncStateStore(statePath, "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer reloaded.Close()
+
+	g.Expect(reloaded.AcknowledgedSnapshot()).To(Equal(map[string]entities.FileContents{
+		"source/path/acknowledged.go": acknowledged.FileContents,
+	}))
+}
+
+func TestSyncStateStore_AcknowledgingStaleEventIsIgnored(t *testing.T) {
+	g := NewGomegaWithT(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := NewSyncStateStore(statePath, "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer store.Close()
+
+	first := entities.FilesystemEvent{
+		Name:         "source/path/file.go",
+		Operation:    entities.OperationModified,
+		FileContents: entities.FileContents{Inode: 1, Hash: "abc"},
+	}
+	second := first
+	second.FileContents.Hash = "def"
+
+	g.Expect(store.RecordEvent(first)).To(Succeed())
+	g.Expect(store.RecordEvent(second)).To(Succeed())
+	g.Expect(store.AcknowledgeEvent(first)).To(Succeed())
+
+	g.Expect(store.AcknowledgedSnapshot()).To(BeEmpty())
+}
+
+func TestSyncStateStore_AcknowledgingEventBeforeMetadataChangeIsIgnored(t *testing.T) {
+	g := NewGomegaWithT(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := NewSyncStateStore(statePath, "./source/path", true)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer store.Close()
+
+	first := entities.FilesystemEvent{
+		Name:      "source/path/file.go",
+		Operation: entities.OperationModified,
+		FileContents: entities.FileContents{
+			Inode:   1,
+			Hash:    "abc",
+			ModTime: time.Date(2025, 5, 19, 21, 51, 24, 0, time.UTC),
+			Mode:    0o644,
+		},
+	}
+
+	for _, change := range map[string]func(*entities.FileContents){
+		"mode":    func(contents *entities.FileContents) { contents.Mode = 0o755 },
+		"modTime": func(contents *entities.FileContents) { contents.ModTime = contents.ModTime.Add(time.Second) },
+		"owner":   func(contents *entities.FileContents) { contents.UID = 1000 },
+	} {
+		second := first
+		second.Operation = entities.OperationMetadata
+		change(&second.FileContents)
+
+		g.Expect(store.RecordEvent(first)).To(Succeed())
+		g.Expect(store.RecordEvent(second)).To(Succeed())
+		g.Expect(store.AcknowledgeEvent(first)).To(Succeed())
+		g.Expect(store.records[first.Name].Acknowledged).To(BeFalse())
+
+		g.Expect(store.AcknowledgeEvent(second)).To(Succeed())
+		g.Expect(store.records[first.Name].Acknowledged).To(BeTrue())
+	}
+}
+
+func TestSyncStateStore_RenameAndDeleteDirectories(t *testing.T) {
+	g := NewGomegaWithT(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := NewSyncStateStore(statePath, "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	events := []entities.FilesystemEvent{
+		{Name: "source/path/dir", Operation: entities.OperationCreated, FileContents: entities.FileContents{Inode: 1, IsDirectory: true}},
+		{Name: "source/path/dir/file.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Inode: 2, Hash: "abc"}},
+		{Name: "source/path/other", Operation: entities.OperationCreated, FileContents: entities.FileContents{Inode: 3, IsDirectory: true}},
+		{Name: "source/path/other/file.go", Operation: entities.OperationCreated, FileContents: entities.FileContents{Inode: 4, Hash: "def"}},
+		{Name: "source/path/renamed", PreviousPath: "source/path/dir", Operation: entities.OperationRenamed, FileContents: entities.FileContents{Inode: 1, IsDirectory: true}},
+		{Name: "source/path/other", Operation: entities.OperationDeleted, FileContents: entities.FileContents{IsDirectory: true}},
+	}
+	for _, event := range events {
+		g.Expect(store.RecordEvent(event)).To(Succeed())
+		g.Expect(store.AcknowledgeEvent(event)).To(Succeed())
+	}
+	g.Expect(store.Close()).To(Succeed())
+
+	reloaded, err := NewSyncStateStore(statePath, "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer reloaded.Close()
+
+	g.Expect(reloaded.AcknowledgedSnapshot()).To(Equal(map[string]entities.FileContents{
+		"source/path/renamed":         {Inode: 1, IsDirectory: true},
+		"source/path/renamed/file.go": {Inode: 2, Hash: "abc"},
+	}))
+}
+
+func TestSyncStateStore_DifferentSourceDirectoryIsDiscarded(t *testing.T) {
+	g := NewGomegaWithT(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := NewSyncStateStore(statePath, "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(store.Close()).To(Succeed())
+
+	reloaded, err := NewSyncStateStore(statePath, "./other/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer reloaded.Close()
+
+	g.Expect(reloaded.AcknowledgedSnapshot()).To(BeNil())
+}
+
+func TestSyncStateStore_EventsToQueue_HardLinks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	source := t.TempDir()
+	store, err := NewSyncStateStore(filepath.Join(t.TempDir(), "state.json"), source, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer store.Close()
+
+	pathA, pathB := filepath.Join(source, "a.txt"), filepath.Join(source, "b.txt")
+	g.Expect(os.WriteFile(pathA, []byte("a"), 0o644)).To(Succeed())
+	g.Expect(os.Link(pathA, pathB)).To(Succeed())
+	contents, err := readFileContents(pathA, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	created := entities.FilesystemEvent{Name: pathA, Operation: entities.OperationCreated, FileContents: contents}
+	g.Expect(store.EventsToQueue(created)).To(Equal([]entities.FilesystemEvent{created}))
+	g.Expect(store.RecordEvent(created)).To(Succeed())
+
+	// the second link is sent as a link to the first
+	linked := entities.FilesystemEvent{Name: pathB, Operation: entities.OperationCreated, FileContents: contents}
+	events := store.EventsToQueue(linked)
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0].HardLinkOf).To(Equal(pathA))
+	g.Expect(store.RecordEvent(events[0])).To(Succeed())
+
+	// writing to one link relinks the other to it once it has been sent
+	g.Expect(os.WriteFile(pathA, []byte("new"), 0o644)).To(Succeed())
+	contents, err = readFileContents(pathA, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	modified := entities.FilesystemEvent{Name: pathA, Operation: entities.OperationModified, FileContents: contents}
+	events = store.EventsToQueue(modified)
+	g.Expect(events).To(Equal([]entities.FilesystemEvent{
+		modified,
+		{Name: pathB, Operation: entities.OperationCreated, HardLinkOf: pathA, FileContents: contents},
+	}))
+	for _, event := range events {
+		g.Expect(store.RecordEvent(event)).To(Succeed())
+	}
+
+	// so the modification reported for the other link has already been dealt with
+	g.Expect(store.EventsToQueue(entities.FilesystemEvent{Name: pathB, Operation: entities.OperationModified, FileContents: contents})).To(BeEmpty())
+
+	// a link that has been removed isn't linked to
+	g.Expect(os.Remove(pathA)).To(Succeed())
+	g.Expect(store.EventsToQueue(entities.FilesystemEvent{Name: filepath.Join(source, "c.txt"), Operation: entities.OperationCreated, FileContents: contents})[0].HardLinkOf).To(Equal(pathB))
+}
+
+func TestSyncStateStore_EventsToQueue_Renames(t *testing.T) {
+	g := NewGomegaWithT(t)
+	store, err := NewSyncStateStore(filepath.Join(t.TempDir(), "state.json"), "./source/path", false)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer store.Close()
+
+	original := entities.FileContents{Inode: 1, Size: 3, Hash: "abc", Mode: 0o644, ModTime: time.Unix(100, 0)}
+	g.Expect(store.RecordEvent(entities.FilesystemEvent{Name: "./source/path/a.go", Operation: entities.OperationCreated, FileContents: original})).To(Succeed())
+	g.Expect(store.RecordEvent(entities.FilesystemEvent{Name: "./source/path/b.go", Operation: entities.OperationCreated, FileContents: original})).To(Succeed())
+	contents, exists := store.RecordedContents("./source/path/a.go")
+	g.Expect(exists).To(BeTrue())
+	g.Expect(contents).To(Equal(original))
+
+	// a file moved as it is needs nothing more
+	renamed := entities.FilesystemEvent{Name: "./source/path/c.go", PreviousPath: "./source/path/a.go", Operation: entities.OperationRenamed, FileContents: original}
+	g.Expect(store.EventsToQueue(renamed)).To(Equal([]entities.FilesystemEvent{renamed}))
+
+	// but a copy of it made when it was moved has its own metadata
+	copied := original
+	copied.Inode, copied.ModTime = 2, time.Unix(200, 0)
+	renamed = entities.FilesystemEvent{Name: "./source/path/d.go", PreviousPath: "./source/path/b.go", Operation: entities.OperationRenamed, FileContents: copied}
+	g.Expect(store.EventsToQueue(renamed)).To(Equal([]entities.FilesystemEvent{
+		renamed,
+		{Name: "./source/path/d.go", Operation: entities.OperationMetadata, FileContents: copied},
+	}))
+}
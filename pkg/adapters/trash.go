@@ -0,0 +1,416 @@
+package adapters
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// trashPurgeInterval is how often the Trash purges the entries that have passed their maximum age.
+const trashPurgeInterval = 1 * time.Hour
+
+// TrashRetention is a struct that sets out how long deleted entries are kept in the trash before they are purged, and
+// how many are kept at most. A zero value for either doesn't limit the trash by it.
+type TrashRetention struct {
+	MaxAge     time.Duration
+	MaxEntries int
+}
+
+// Trash is a struct that keeps files and directories deleted from the destination directory so that they can be
+// undeleted. Each deleted entry is moved into its own directory in the trash, alongside a file describing where it was
+// deleted from and when.
+type Trash struct {
+	mu              sync.Mutex
+	directory       string
+	destinationPath string
+	retention       TrashRetention
+}
+
+var _ TrashBin = &Trash{}
+
+// trashEntryFile and trashDataFile are the names of the metadata and the deleted entry within each entry's directory.
+const (
+	trashEntryFile = "entry.json"
+	trashDataFile  = "data"
+)
+
+// NewTrash is a function that creates a Trash which keeps entries deleted from destinationPath within directory. Any
+// entries that have expired while the server was stopped are purged.
+func NewTrash(directory, destinationPath string, retention TrashRetention) (*Trash, error) {
+	err := os.MkdirAll(directory, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("creating trash directory: %w", err)
+	}
+
+	trash := &Trash{
+		directory:       directory,
+		destinationPath: destinationPath,
+		retention:       retention,
+	}
+
+	err = trash.Purge()
+	if err != nil {
+		return nil, fmt.Errorf("purging trash: %w", err)
+	}
+
+	return trash, nil
+}
+
+// TrashBin is an interface that sets out the functions implemented by the Trash. This allows for mocking of the Trash
+// functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/trashBin.go  . "TrashBin"
+type TrashBin interface {
+	ListTrash() ([]entities.TrashEntry, error)
+	RestoreFromTrash(trashID string, pathResolver func(string) (string, error)) (entities.TrashEntry, error)
+}
+
+// MoveToTrash is a function that moves the file or directory at path out of the destination directory and into the
+// trash. Nothing is done if path doesn't exist.
+func (trash *Trash) MoveToTrash(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	relativePath, err := filepath.Rel(trash.destinationPath, path)
+	if err != nil {
+		return err
+	}
+
+	trash.mu.Lock()
+	defer trash.mu.Unlock()
+
+	entry := entities.TrashEntry{
+		ID:           strconv.FormatInt(time.Now().UnixNano(), 10),
+		OriginalPath: "/" + filepath.ToSlash(relativePath),
+		IsDirectory:  info.IsDir(),
+		DeletedAt:    time.Now().UTC(),
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		entry.LinkTarget, err = os.Readlink(path)
+		if err != nil {
+			return err
+		}
+	}
+	for trash.entryExists(entry.ID) {
+		id, _ := strconv.ParseInt(entry.ID, 10, 64)
+		entry.ID = strconv.FormatInt(id+1, 10)
+	}
+
+	err = os.Mkdir(trash.entryDirectory(entry.ID), 0o700)
+	if err != nil {
+		return err
+	}
+
+	// the metadata is written first, an entry without its data is left behind by a crash and removed when loaded
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomically(filepath.Join(trash.entryDirectory(entry.ID), trashEntryFile), entryBytes, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing trash entry: %w", err)
+	}
+
+	err = moveEntry(path, filepath.Join(trash.entryDirectory(entry.ID), trashDataFile))
+	if err != nil {
+		return fmt.Errorf("moving %s to trash: %w", entry.OriginalPath, err)
+	}
+
+	err = syncDirectory(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("moved to trash", "path", entry.OriginalPath, "trashId", entry.ID)
+	_, err = trash.purge()
+	return err
+}
+
+// ListTrash is a function that returns the entries in the trash, most recently deleted first. Entries that have
+// expired are purged rather than listed.
+func (trash *Trash) ListTrash() ([]entities.TrashEntry, error) {
+	trash.mu.Lock()
+	defer trash.mu.Unlock()
+
+	entries, err := trash.purge()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// RestoreFromTrash is a function that moves an entry in the trash back to the path it was deleted from. The original
+// path is turned into a location within the destination directory by pathResolver, which rejects it if it now leads
+// outside of the destination directory. pathResolver can wait for other changes to the original path to finish, so the
+// trash isn't locked while it runs. An entry is not restored over anything that has since been created at its
+// original path, or once it has expired.
+func (trash *Trash) RestoreFromTrash(trashID string, pathResolver func(string) (string, error)) (entities.TrashEntry, error) {
+	// IDs are only ever numbers, anything else could refer to a path outside of the trash
+	if _, err := strconv.ParseUint(trashID, 10, 64); err != nil {
+		return entities.TrashEntry{}, entities.ErrTrashEntryNotFound
+	}
+
+	trash.mu.Lock()
+	entry, err := trash.loadEntry(trashID)
+	trash.mu.Unlock()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entities.TrashEntry{}, entities.ErrTrashEntryNotFound
+		}
+		return entities.TrashEntry{}, err
+	}
+
+	originalPath, err := pathResolver(entry.OriginalPath)
+	if err != nil {
+		return entities.TrashEntry{}, err
+	}
+
+	trash.mu.Lock()
+	defer trash.mu.Unlock()
+
+	_, err = trash.purge()
+	if err != nil {
+		return entities.TrashEntry{}, err
+	}
+
+	// the entry may have been restored or purged while the trash was unlocked
+	if !trash.entryExists(trashID) {
+		return entities.TrashEntry{}, entities.ErrTrashEntryNotFound
+	}
+
+	_, err = os.Lstat(originalPath)
+	if err == nil {
+		return entities.TrashEntry{}, entities.ErrRestoreTargetExists
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return entities.TrashEntry{}, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(originalPath), 0755)
+	if err != nil {
+		return entities.TrashEntry{}, err
+	}
+
+	err = moveEntry(filepath.Join(trash.entryDirectory(trashID), trashDataFile), originalPath)
+	if err != nil {
+		return entities.TrashEntry{}, fmt.Errorf("restoring %s from trash: %w", entry.OriginalPath, err)
+	}
+
+	err = syncDirectory(filepath.Dir(originalPath))
+	if err != nil {
+		return entities.TrashEntry{}, err
+	}
+
+	err = os.RemoveAll(trash.entryDirectory(trashID))
+	if err != nil {
+		slog.Warn("removing restored trash entry", "trashId", trashID, "err", err)
+	}
+
+	slog.Info("restored from trash", "path", entry.OriginalPath, "trashId", trashID)
+	return entry, nil
+}
+
+// Purge is a function that permanently removes the entries that fall outside of the retention policy, oldest first.
+func (trash *Trash) Purge() error {
+	trash.mu.Lock()
+	defer trash.mu.Unlock()
+
+	_, err := trash.purge()
+	return err
+}
+
+// Run is a function that purges the entries that have passed their maximum age every trashPurgeInterval, until the
+// context is cancelled. The oldest entries beyond the maximum count are purged as new ones are added, so there is
+// nothing to do without a maximum age.
+func (trash *Trash) Run(ctx context.Context) {
+	if trash.retention.MaxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := trash.Purge()
+			if err != nil {
+				slog.Warn("purging trash", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// purge is a function that removes the entries that fall outside of the retention policy, returning the entries that
+// are left, oldest first.
+func (trash *Trash) purge() ([]entities.TrashEntry, error) {
+	entries, err := trash.loadEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := 0
+	if trash.retention.MaxEntries > 0 && len(entries) > trash.retention.MaxEntries {
+		expired = len(entries) - trash.retention.MaxEntries
+	}
+	if trash.retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-trash.retention.MaxAge)
+		for expired < len(entries) && entries[expired].DeletedAt.Before(cutoff) {
+			expired++
+		}
+	}
+
+	for _, entry := range entries[:expired] {
+		err := os.RemoveAll(trash.entryDirectory(entry.ID))
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("purged from trash", "path", entry.OriginalPath, "trashId", entry.ID)
+	}
+
+	return entries[expired:], nil
+}
+
+// loadEntries is a function that returns the entries in the trash, oldest first.
+func (trash *Trash) loadEntries() ([]entities.TrashEntry, error) {
+	dirEntries, err := os.ReadDir(trash.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entities.TrashEntry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		entry, err := trash.loadEntry(dirEntry.Name())
+		if err != nil {
+			slog.Warn("removing incomplete trash entry", "trashId", dirEntry.Name(), "err", err)
+			_ = os.RemoveAll(trash.entryDirectory(dirEntry.Name()))
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b entities.TrashEntry) int {
+		return cmp.Or(a.DeletedAt.Compare(b.DeletedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	return entries, nil
+}
+
+// loadEntry is a function that reads the metadata of an entry, returning an error if its data is missing.
+func (trash *Trash) loadEntry(trashID string) (entities.TrashEntry, error) {
+	entryBytes, err := os.ReadFile(filepath.Join(trash.entryDirectory(trashID), trashEntryFile))
+	if err != nil {
+		return entities.TrashEntry{}, err
+	}
+
+	var entry entities.TrashEntry
+	err = json.Unmarshal(entryBytes, &entry)
+	if err != nil {
+		return entities.TrashEntry{}, fmt.Errorf("decoding trash entry: %w", err)
+	}
+
+	_, err = os.Lstat(filepath.Join(trash.entryDirectory(trashID), trashDataFile))
+	if err != nil {
+		return entities.TrashEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (trash *Trash) entryExists(trashID string) bool {
+	_, err := os.Lstat(trash.entryDirectory(trashID))
+	return err == nil
+}
+
+func (trash *Trash) entryDirectory(trashID string) string {
+	return filepath.Join(trash.directory, trashID)
+}
+
+// moveEntry is a function that moves a file or directory from oldPath to newPath. If they are on different filesystems
+// the entry is copied and the original removed once the copy is complete.
+func moveEntry(oldPath, newPath string) error {
+	err := os.Rename(oldPath, newPath)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	err = copyTree(oldPath, newPath)
+	if err != nil {
+		_ = os.RemoveAll(newPath)
+		return err
+	}
+
+	return os.RemoveAll(oldPath)
+}
+
+// copyTree is a function that copies the file, directory or symlink at source to destination, keeping permissions.
+func copyTree(source, destination string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relativePath)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, target)
+		case d.Type().IsRegular():
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			return replaceFile(target, info.Mode().Perm(), func(writer io.Writer) error {
+				_, err := io.Copy(writer, file)
+				return err
+			})
+		default:
+			// sockets, devices and pipes can't be synced, so there is nothing to keep
+			return nil
+		}
+	})
+}
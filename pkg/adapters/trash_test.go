@@ -0,0 +1,152 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestTrash(t *testing.T, retention TrashRetention) (*Trash, string) {
+	t.Helper()
+	g := NewGomegaWithT(t)
+
+	destination := t.TempDir()
+	trash, err := NewTrash(t.TempDir(), destination, retention)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return trash, destination
+}
+
+// joinDestination returns a path resolver that places request paths within destination without checking them.
+func joinDestination(destination string) func(string) (string, error) {
+	return func(path string) (string, error) {
+		return filepath.Join(destination, filepath.FromSlash(path)), nil
+	}
+}
+
+func TestTrash_DeleteAndRestoreDirectory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	trash, destination := newTestTrash(t, TrashRetention{})
+	writer := NewFileWriter(destination, nil, trash)
+	path := filepath.Join(destination, "sub", "file.go")
+
+	g.Expect(writer.CreateFile(path, []byte("contents"), false)).To(Succeed())
+	g.Expect(writer.DeleteFile(filepath.Join(destination, "sub"))).To(Succeed())
+	g.Expect(filepath.Join(destination, "sub")).ToNot(BeAnExistingFile())
+
+	entries, err := trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+	g.Expect(entries[0].OriginalPath).To(Equal("/sub"))
+	g.Expect(entries[0].IsDirectory).To(BeTrue())
+	g.Expect(entries[0].DeletedAt).To(BeTemporally("~", time.Now(), time.Minute))
+
+	restored, err := trash.RestoreFromTrash(entries[0].ID, joinDestination(destination))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(restored).To(Equal(entries[0]))
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("contents")))
+
+	entries, err = trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+}
+
+func TestTrash_RestoreDoesNotReplaceExistingFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	trash, destination := newTestTrash(t, TrashRetention{})
+	writer := NewFileWriter(destination, nil, trash)
+	path := filepath.Join(destination, "file.go")
+
+	g.Expect(writer.CreateFile(path, []byte("old"), false)).To(Succeed())
+	g.Expect(writer.DeleteFile(path)).To(Succeed())
+	g.Expect(writer.CreateFile(path, []byte("new"), false)).To(Succeed())
+
+	entries, err := trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+
+	_, err = trash.RestoreFromTrash(entries[0].ID, joinDestination(destination))
+	g.Expect(err).To(MatchError(entities.ErrRestoreTargetExists))
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("new")))
+
+	_, err = trash.RestoreFromTrash("../"+entries[0].ID, joinDestination(destination))
+	g.Expect(err).To(MatchError(entities.ErrTrashEntryNotFound))
+}
+
+func TestTrash_RestoreRejectedByPathResolver(t *testing.T) {
+	g := NewGomegaWithT(t)
+	trash, destination := newTestTrash(t, TrashRetention{})
+	writer := NewFileWriter(destination, nil, trash)
+	path := filepath.Join(destination, "sub", "file.go")
+
+	g.Expect(writer.CreateFile(path, []byte("contents"), false)).To(Succeed())
+	g.Expect(writer.DeleteFile(path)).To(Succeed())
+
+	entries, err := trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+
+	// the parent directory has been replaced by a symlink leading outside of the destination directory
+	_, err = trash.RestoreFromTrash(entries[0].ID, func(string) (string, error) {
+		return "", entities.ErrInvalidPath
+	})
+	g.Expect(err).To(MatchError(entities.ErrInvalidPath))
+	g.Expect(path).ToNot(BeAnExistingFile())
+
+	entries, err = trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+}
+
+func TestTrash_RestoresSymlink(t *testing.T) {
+	g := NewGomegaWithT(t)
+	trash, destination := newTestTrash(t, TrashRetention{})
+	writer := NewFileWriter(destination, nil, trash)
+	path := filepath.Join(destination, "link")
+
+	g.Expect(writer.CreateSymlink(path, "target.go")).To(Succeed())
+	g.Expect(writer.DeleteFile(path)).To(Succeed())
+
+	entries, err := trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+	g.Expect(entries[0].LinkTarget).To(Equal("target.go"))
+
+	_, err = trash.RestoreFromTrash(entries[0].ID, joinDestination(destination))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(os.Readlink(path)).To(Equal("target.go"))
+}
+
+func TestTrash_PurgesOldestEntries(t *testing.T) {
+	g := NewGomegaWithT(t)
+	trash, destination := newTestTrash(t, TrashRetention{MaxEntries: 2})
+	writer := NewFileWriter(destination, nil, trash)
+
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		path := filepath.Join(destination, name)
+		g.Expect(writer.CreateFile(path, []byte(name), false)).To(Succeed())
+		g.Expect(writer.DeleteFile(path)).To(Succeed())
+	}
+
+	entries, err := trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(2))
+	g.Expect(entries[0].OriginalPath).To(Equal("/c.go"))
+	g.Expect(entries[1].OriginalPath).To(Equal("/b.go"))
+
+	// expired entries are purged when the trash is listed or restored from, not only when something is deleted
+	trash.retention = TrashRetention{MaxAge: time.Nanosecond}
+	_, err = trash.RestoreFromTrash(entries[0].ID, joinDestination(destination))
+	g.Expect(err).To(MatchError(entities.ErrTrashEntryNotFound))
+	g.Expect(filepath.Join(destination, "c.go")).ToNot(BeAnExistingFile())
+
+	entries, err = trash.ListTrash()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+	dirEntries, err := os.ReadDir(trash.directory)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(dirEntries).To(BeEmpty())
+}
@@ -0,0 +1,318 @@
+package adapters
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uploadSessionExpiry is how long an upload session can go without receiving a chunk before it is discarded when the
+// server starts.
+const uploadSessionExpiry = 7 * 24 * time.Hour
+
+type UploadManager struct {
+	uploadDirectory string
+	mu              sync.Mutex
+	sessions        map[string]*uploadSession
+}
+
+// uploadSession guards a single upload so that chunks for different uploads can be written concurrently.
+type uploadSession struct {
+	mu      sync.Mutex
+	session entities.UploadSession
+}
+
+var _ UploadStore = &UploadManager{}
+
+// NewUploadManager is a function that creates the upload directory and reloads any sessions persisted by a previous
+// run of the server, so that clients can resume them. Expired sessions and files that do not belong to a session are
+// removed.
+func NewUploadManager(uploadDirectory string) (*UploadManager, error) {
+	err := os.MkdirAll(uploadDirectory, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("creating upload directory: %w", err)
+	}
+
+	manager := &UploadManager{
+		uploadDirectory: uploadDirectory,
+		sessions:        make(map[string]*uploadSession),
+	}
+
+	err = manager.loadSessions()
+	if err != nil {
+		return nil, fmt.Errorf("loading upload sessions: %w", err)
+	}
+
+	return manager, nil
+}
+
+// UploadStore is an interface that sets out the functions implemented by the UploadManager. This allows for mocking of
+// the UploadManager functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/uploadStore.go  . "UploadStore"
+type UploadStore interface {
+	StartUpload(path string) (entities.UploadSession, error)
+	GetUpload(uploadID string) (entities.UploadSession, error)
+	WriteChunk(uploadID string, index int, chunk io.Reader) error
+	CompleteUpload(uploadID, hash string) (entities.UploadSession, string, error)
+	DiscardUpload(uploadID string) error
+}
+
+// StartUpload is a function that creates a new upload session for the file at path, along with the empty temporary
+// file its chunks are assembled into.
+func (manager *UploadManager) StartUpload(path string) (entities.UploadSession, error) {
+	idBytes := make([]byte, 16)
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return entities.UploadSession{}, err
+	}
+
+	session := entities.UploadSession{
+		ID:        hex.EncodeToString(idBytes),
+		Path:      path,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	file, err := os.OpenFile(manager.partPath(session.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		slog.Debug("failed to create upload file", "err", err)
+		return entities.UploadSession{}, err
+	}
+	err = file.Close()
+	if err != nil {
+		return entities.UploadSession{}, err
+	}
+
+	err = manager.saveSession(session)
+	if err != nil {
+		_ = os.Remove(manager.partPath(session.ID))
+		return entities.UploadSession{}, err
+	}
+
+	manager.mu.Lock()
+	manager.sessions[session.ID] = &uploadSession{session: session}
+	manager.mu.Unlock()
+
+	return session, nil
+}
+
+// GetUpload is a function that returns how much of an upload has been received, so that a client can resume it.
+func (manager *UploadManager) GetUpload(uploadID string) (entities.UploadSession, error) {
+	upload, err := manager.lookup(uploadID)
+	if err != nil {
+		return entities.UploadSession{}, err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	return upload.session, nil
+}
+
+// WriteChunk is a function that streams a chunk onto the end of an upload's temporary file. Chunks must arrive in
+// order, a chunk that has already been received is ignored so that retried requests are safe. The new offset is
+// persisted once the chunk is on disk.
+func (manager *UploadManager) WriteChunk(uploadID string, index int, chunk io.Reader) error {
+	upload, err := manager.lookup(uploadID)
+	if err != nil {
+		return err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if index < upload.session.NextChunk {
+		return nil
+	}
+	if index > upload.session.NextChunk {
+		return entities.ErrUnexpectedChunk
+	}
+
+	file, err := os.OpenFile(manager.partPath(uploadID), os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(upload.session.Offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(file, chunk)
+	if err == nil {
+		// the chunk must be on disk before the offset that includes it is persisted
+		err = file.Sync()
+	}
+	if err != nil {
+		// discard the partial chunk so the client can send it again
+		_ = file.Truncate(upload.session.Offset)
+		slog.Debug("failed to write chunk", "uploadId", uploadID, "index", index, "err", err)
+		return err
+	}
+
+	session := upload.session
+	session.NextChunk++
+	session.Offset += written
+	session.UpdatedAt = time.Now().UTC()
+
+	err = manager.saveSession(session)
+	if err != nil {
+		_ = file.Truncate(upload.session.Offset)
+		return err
+	}
+	upload.session = session
+
+	return nil
+}
+
+// CompleteUpload is a function that verifies the assembled file matches the hash of the whole file. It returns the
+// session and the path of the assembled file, which the caller is responsible for moving into place before ending the
+// session with DiscardUpload. The session is kept until then, so that a commit that fails after this point can be
+// retried. If the hash does not match, the upload is discarded.
+func (manager *UploadManager) CompleteUpload(uploadID, hash string) (entities.UploadSession, string, error) {
+	upload, err := manager.lookup(uploadID)
+	if err != nil {
+		return entities.UploadSession{}, "", err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	assembledPath := manager.partPath(uploadID)
+	assembledHash, err := hashFile(assembledPath)
+	if err != nil {
+		return entities.UploadSession{}, "", err
+	}
+
+	if assembledHash != hash {
+		manager.forget(uploadID)
+		return entities.UploadSession{}, "", entities.ErrUploadHashMismatch
+	}
+
+	return upload.session, assembledPath, nil
+}
+
+// DiscardUpload is a function that ends an upload session, removing its persisted state along with the assembled file
+// if it hasn't been moved into place.
+func (manager *UploadManager) DiscardUpload(uploadID string) error {
+	upload, err := manager.lookup(uploadID)
+	if err != nil {
+		return err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	manager.forget(uploadID)
+	return nil
+}
+
+// forget is a function that removes a session and its files.
+func (manager *UploadManager) forget(uploadID string) {
+	manager.mu.Lock()
+	delete(manager.sessions, uploadID)
+	manager.mu.Unlock()
+
+	manager.removeUploadFiles(uploadID)
+}
+
+// loadSessions is a function that reloads every persisted session from the upload directory.
+func (manager *UploadManager) loadSessions() error {
+	entries, err := os.ReadDir(manager.uploadDirectory)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		uploadID, isSession := strings.CutSuffix(entry.Name(), ".json")
+		if !isSession {
+			continue
+		}
+
+		sessionBytes, err := os.ReadFile(manager.sessionPath(uploadID))
+		if err != nil {
+			return err
+		}
+
+		var session entities.UploadSession
+		err = json.Unmarshal(sessionBytes, &session)
+		if err != nil || session.ID != uploadID || time.Since(session.UpdatedAt) > uploadSessionExpiry {
+			slog.Info("discarding upload session", "uploadId", uploadID)
+			manager.removeUploadFiles(uploadID)
+			continue
+		}
+
+		// anything written after the persisted offset is a chunk that was interrupted, so it is discarded
+		err = os.Truncate(manager.partPath(uploadID), session.Offset)
+		if err != nil {
+			slog.Warn("discarding upload session", "uploadId", uploadID, "err", err)
+			manager.removeUploadFiles(uploadID)
+			continue
+		}
+
+		manager.sessions[uploadID] = &uploadSession{session: session}
+	}
+
+	// remove any assembled files that no longer have a session, such as those left when the server stopped part way
+	// through discarding one
+	for _, entry := range entries {
+		uploadID, isPart := strings.CutSuffix(entry.Name(), ".part")
+		if !isPart {
+			continue
+		}
+		if _, exists := manager.sessions[uploadID]; !exists {
+			manager.removeUploadFiles(uploadID)
+		}
+	}
+
+	return nil
+}
+
+func (manager *UploadManager) saveSession(session entities.UploadSession) error {
+	sessionBytes, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(manager.sessionPath(session.ID), sessionBytes, 0o600)
+}
+
+func (manager *UploadManager) removeUploadFiles(uploadID string) {
+	for _, path := range []string{manager.sessionPath(uploadID), manager.partPath(uploadID)} {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("removing upload file", "path", path, "err", err)
+		}
+	}
+}
+
+func (manager *UploadManager) lookup(uploadID string) (*uploadSession, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	upload, exists := manager.sessions[uploadID]
+	if !exists {
+		return nil, entities.ErrUploadNotFound
+	}
+
+	return upload, nil
+}
+
+func (manager *UploadManager) partPath(uploadID string) string {
+	return filepath.Join(manager.uploadDirectory, uploadID+".part")
+}
+
+func (manager *UploadManager) sessionPath(uploadID string) string {
+	return filepath.Join(manager.uploadDirectory, uploadID+".json")
+}
@@ -0,0 +1,129 @@
+package adapters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"testing"
+)
+
+func TestUploadManager_AssemblesChunksInOrder(t *testing.T) {
+	g := NewGomegaWithT(t)
+	manager, err := NewUploadManager(t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	session, err := manager.StartUpload("/some/path.go")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(manager.WriteChunk(session.ID, 0, bytes.NewReader([]byte("some ")))).To(Succeed())
+	// a retried chunk is ignored
+	g.Expect(manager.WriteChunk(session.ID, 0, bytes.NewReader([]byte("some ")))).To(Succeed())
+	g.Expect(manager.WriteChunk(session.ID, 2, bytes.NewReader([]byte("!")))).To(MatchError(entities.ErrUnexpectedChunk))
+	g.Expect(manager.WriteChunk(session.ID, 1, bytes.NewReader([]byte("content")))).To(Succeed())
+
+	hash := sha256.Sum256([]byte("some content"))
+	completed, assembledPath, err := manager.CompleteUpload(session.ID, hex.EncodeToString(hash[:]))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(completed.Path).To(Equal("/some/path.go"))
+	g.Expect(completed.Offset).To(Equal(int64(len("some content"))))
+
+	data, err := os.ReadFile(assembledPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(Equal([]byte("some content")))
+
+	g.Expect(manager.DiscardUpload(session.ID)).To(Succeed())
+	g.Expect(assembledPath).ToNot(BeAnExistingFile())
+	g.Expect(manager.sessionPath(session.ID)).ToNot(BeAnExistingFile())
+
+	_, _, err = manager.CompleteUpload(session.ID, hex.EncodeToString(hash[:]))
+	g.Expect(err).To(MatchError(entities.ErrUploadNotFound))
+}
+
+func TestUploadManager_CommitCanBeRetriedUntilDiscarded(t *testing.T) {
+	g := NewGomegaWithT(t)
+	uploadDirectory := t.TempDir()
+	manager, err := NewUploadManager(uploadDirectory)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	session, err := manager.StartUpload("/some/path.go")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manager.WriteChunk(session.ID, 0, bytes.NewReader([]byte("some content")))).To(Succeed())
+	hash := sha256.Sum256([]byte("some content"))
+
+	// the file couldn't be installed, so the commit is sent again, even after a restart
+	_, assembledPath, err := manager.CompleteUpload(session.ID, hex.EncodeToString(hash[:]))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	restarted, err := NewUploadManager(uploadDirectory)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, retriedPath, err := restarted.CompleteUpload(session.ID, hex.EncodeToString(hash[:]))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(retriedPath).To(Equal(assembledPath))
+
+	data, err := os.ReadFile(retriedPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(Equal([]byte("some content")))
+}
+
+func TestUploadManager_HashMismatchDiscardsUpload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	manager, err := NewUploadManager(t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	session, err := manager.StartUpload("/some/path.go")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manager.WriteChunk(session.ID, 0, bytes.NewReader([]byte("some content")))).To(Succeed())
+
+	_, _, err = manager.CompleteUpload(session.ID, "not-the-hash")
+	g.Expect(err).To(MatchError(entities.ErrUploadHashMismatch))
+	g.Expect(manager.partPath(session.ID)).ToNot(BeAnExistingFile())
+	g.Expect(manager.sessionPath(session.ID)).ToNot(BeAnExistingFile())
+}
+
+func TestUploadManager_UnknownUpload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	manager, err := NewUploadManager(t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	err = manager.WriteChunk("unknown", 0, bytes.NewReader(nil))
+	g.Expect(err).To(MatchError(entities.ErrUploadNotFound))
+}
+
+func TestUploadManager_ResumesSessionsAfterRestart(t *testing.T) {
+	g := NewGomegaWithT(t)
+	uploadDirectory := t.TempDir()
+	manager, err := NewUploadManager(uploadDirectory)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	session, err := manager.StartUpload("/some/path.go")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(manager.WriteChunk(session.ID, 0, bytes.NewReader([]byte("some ")))).To(Succeed())
+
+	// simulate a chunk that was only partly written when the server stopped
+	file, err := os.OpenFile(manager.partPath(session.ID), os.O_WRONLY|os.O_APPEND, 0o600)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = file.WriteString("cont")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(file.Close()).To(Succeed())
+
+	restarted, err := NewUploadManager(uploadDirectory)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	resumed, err := restarted.GetUpload(session.ID)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resumed.NextChunk).To(Equal(1))
+	g.Expect(resumed.Offset).To(Equal(int64(len("some "))))
+
+	g.Expect(restarted.WriteChunk(session.ID, 1, bytes.NewReader([]byte("content")))).To(Succeed())
+
+	hash := sha256.Sum256([]byte("some content"))
+	_, assembledPath, err := restarted.CompleteUpload(session.ID, hex.EncodeToString(hash[:]))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	data, err := os.ReadFile(assembledPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(Equal([]byte("some content")))
+}
@@ -0,0 +1,402 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// versionPruneInterval is how often the VersionStore removes the versions that have passed their maximum age.
+const versionPruneInterval = 1 * time.Hour
+
+// VersionRetention is a struct that sets out how many previous versions of each file are kept, and for how long. A
+// MaxAge of zero keeps versions until they are pushed out by newer ones.
+type VersionRetention struct {
+	MaxVersions int
+	MaxAge      time.Duration
+}
+
+// VersionStore is a struct that keeps copies of files in the destination directory before they are overwritten or
+// deleted, so that they can be restored. The versions of each file are kept in their own directory, named after a hash
+// of the file's path, alongside an index describing them.
+type VersionStore struct {
+	mu              sync.Mutex
+	directory       string
+	destinationPath string
+	retention       VersionRetention
+}
+
+var _ VersionHistory = &VersionStore{}
+
+// NewVersionStore is a function that creates a VersionStore which keeps versions of files in destinationPath within
+// directory. Any versions that have expired while the server was stopped are removed.
+func NewVersionStore(directory, destinationPath string, retention VersionRetention) (*VersionStore, error) {
+	err := os.MkdirAll(directory, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("creating versions directory: %w", err)
+	}
+
+	store := &VersionStore{
+		directory:       directory,
+		destinationPath: destinationPath,
+		retention:       retention,
+	}
+
+	err = store.Prune()
+	if err != nil {
+		return nil, fmt.Errorf("pruning versions: %w", err)
+	}
+
+	return store, nil
+}
+
+// VersionHistory is an interface that sets out the functions implemented by the VersionStore. This allows for mocking
+// of the VersionStore functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/versionHistory.go  . "VersionHistory"
+type VersionHistory interface {
+	ListVersions(path string) ([]entities.FileVersion, error)
+	RestoreVersion(path, versionID string) (entities.FileVersion, error)
+}
+
+// SaveVersion is a function that keeps a copy of the file at path before it is replaced. If path is a directory, every
+// file within it is kept. Nothing is kept if path doesn't exist or is a symlink, which has no contents of its own.
+func (store *VersionStore) SaveVersion(path string) error {
+	if store.retention.MaxVersions <= 0 {
+		return nil
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil
+	}
+	if !info.IsDir() {
+		return store.saveFileVersion(path)
+	}
+
+	return filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return store.saveFileVersion(filePath)
+	})
+}
+
+// ListVersions is a function that returns the kept versions of the file at path, newest first. Versions that have
+// expired since they were kept are removed rather than listed.
+func (store *VersionStore) ListVersions(path string) ([]entities.FileVersion, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	relativePath, err := store.relativePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := store.loadVersions(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// RestoreVersion is a function that puts a kept version of a file back in place at path. The contents being replaced
+// are kept as a new version first, so a restore can itself be undone.
+func (store *VersionStore) RestoreVersion(path, versionID string) (entities.FileVersion, error) {
+	relativePath, err := store.relativePath(path)
+	if err != nil {
+		return entities.FileVersion{}, err
+	}
+
+	store.mu.Lock()
+	versions, err := store.loadVersions(relativePath)
+	if err != nil {
+		store.mu.Unlock()
+		return entities.FileVersion{}, err
+	}
+
+	index := slices.IndexFunc(versions, func(version entities.FileVersion) bool {
+		return version.ID == versionID
+	})
+	if index == -1 {
+		store.mu.Unlock()
+		return entities.FileVersion{}, entities.ErrVersionNotFound
+	}
+	version := versions[index]
+
+	// the version is opened before the current contents are saved, in case saving them pushes it out of the store
+	versionFile, err := os.Open(store.dataPath(relativePath, versionID))
+	store.mu.Unlock()
+	if err != nil {
+		return entities.FileVersion{}, err
+	}
+	defer versionFile.Close()
+
+	err = store.SaveVersion(path)
+	if err != nil {
+		return entities.FileVersion{}, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return entities.FileVersion{}, err
+	}
+
+	err = replaceFile(path, fileModeOrDefault(path, defaultFileMode), func(writer io.Writer) error {
+		_, err := io.Copy(writer, versionFile)
+		return err
+	})
+	if err != nil {
+		return entities.FileVersion{}, err
+	}
+
+	slog.Info("restored file version", "path", relativePath, "versionId", versionID)
+	return version, nil
+}
+
+// Prune is a function that removes the versions of every file that fall outside of the retention policy.
+func (store *VersionStore) Prune() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	dirEntries, err := os.ReadDir(store.directory)
+	if err != nil {
+		return err
+	}
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		versions, err := readIndex(filepath.Join(store.directory, dirEntry.Name(), "index.json"))
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			continue
+		}
+
+		_, err = store.pruneVersions(versions[0].Path, versions)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run is a function that removes the versions that have passed their maximum age every versionPruneInterval, until the
+// context is cancelled. Versions beyond the maximum count are removed as newer ones are kept, so there is nothing to do
+// without a maximum age.
+func (store *VersionStore) Run(ctx context.Context) {
+	if store.retention.MaxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(versionPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := store.Prune()
+			if err != nil {
+				slog.Warn("pruning versions", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (store *VersionStore) saveFileVersion(path string) error {
+	relativePath, err := store.relativePath(path)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err = os.MkdirAll(store.pathDirectory(relativePath), 0o700)
+	if err != nil {
+		return err
+	}
+
+	versions, err := store.loadIndex(relativePath)
+	if err != nil {
+		return err
+	}
+
+	version := entities.FileVersion{
+		ID:        store.nextVersionID(versions),
+		Path:      relativePath,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	err = replaceFile(store.dataPath(relativePath, version.ID), 0o600, func(writer io.Writer) error {
+		var copyErr error
+		version.Size, copyErr = io.Copy(writer, io.TeeReader(file, hasher))
+		return copyErr
+	})
+	if err != nil {
+		return fmt.Errorf("saving version of %s: %w", relativePath, err)
+	}
+	version.Hash = hex.EncodeToString(hasher.Sum(nil))
+
+	versions = store.prune(relativePath, append(versions, version))
+	return store.saveIndex(relativePath, versions)
+}
+
+// prune is a function that removes the versions that fall outside of the retention policy, oldest first.
+func (store *VersionStore) prune(relativePath string, versions []entities.FileVersion) []entities.FileVersion {
+	keep := versions
+	if len(keep) > store.retention.MaxVersions {
+		keep = keep[len(keep)-store.retention.MaxVersions:]
+	}
+	if store.retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-store.retention.MaxAge)
+		keep = slices.DeleteFunc(slices.Clone(keep), func(version entities.FileVersion) bool {
+			return version.CreatedAt.Before(cutoff)
+		})
+	}
+
+	for _, version := range versions {
+		if slices.ContainsFunc(keep, func(kept entities.FileVersion) bool { return kept.ID == version.ID }) {
+			continue
+		}
+		err := os.Remove(store.dataPath(relativePath, version.ID))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("removing expired version", "path", relativePath, "versionId", version.ID, "err", err)
+		}
+	}
+
+	return keep
+}
+
+// loadVersions is a function that returns the versions of a file that are within the retention policy.
+func (store *VersionStore) loadVersions(relativePath string) ([]entities.FileVersion, error) {
+	versions, err := store.loadIndex(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.pruneVersions(relativePath, versions)
+}
+
+// pruneVersions is a function that removes the versions of a file that fall outside of the retention policy, updating
+// its index if any were removed. The file's directory is removed once it has no versions left.
+func (store *VersionStore) pruneVersions(relativePath string, versions []entities.FileVersion) ([]entities.FileVersion, error) {
+	keep := store.prune(relativePath, versions)
+	switch {
+	case len(keep) == len(versions):
+		return keep, nil
+	case len(keep) == 0:
+		return keep, os.RemoveAll(store.pathDirectory(relativePath))
+	default:
+		return keep, store.saveIndex(relativePath, keep)
+	}
+}
+
+// nextVersionID is a function that returns an ID for a new version. IDs are the time the version was created, so that
+// they sort in the order the versions were kept.
+func (store *VersionStore) nextVersionID(versions []entities.FileVersion) string {
+	id := time.Now().UnixNano()
+	if len(versions) > 0 {
+		last, err := strconv.ParseInt(versions[len(versions)-1].ID, 10, 64)
+		if err == nil && last >= id {
+			id = last + 1
+		}
+	}
+
+	return strconv.FormatInt(id, 10)
+}
+
+func (store *VersionStore) loadIndex(relativePath string) ([]entities.FileVersion, error) {
+	return readIndex(store.indexPath(relativePath))
+}
+
+// readIndex is a function that reads the versions of a file from the index at indexPath, oldest first.
+func readIndex(indexPath string) ([]entities.FileVersion, error) {
+	indexBytes, err := os.ReadFile(indexPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make([]entities.FileVersion, 0), nil
+		}
+		return nil, err
+	}
+
+	var versions []entities.FileVersion
+	err = json.Unmarshal(indexBytes, &versions)
+	if err != nil {
+		return nil, fmt.Errorf("decoding version index: %w", err)
+	}
+
+	return versions, nil
+}
+
+func (store *VersionStore) saveIndex(relativePath string, versions []entities.FileVersion) error {
+	indexBytes, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(store.indexPath(relativePath), indexBytes, 0o600)
+}
+
+// relativePath is a function that returns path relative to the destination directory, in the same form as the paths
+// sent in requests to the server.
+func (store *VersionStore) relativePath(path string) (string, error) {
+	relativePath, err := filepath.Rel(store.destinationPath, path)
+	if err != nil {
+		return "", err
+	}
+
+	return "/" + filepath.ToSlash(relativePath), nil
+}
+
+func (store *VersionStore) pathDirectory(relativePath string) string {
+	sum := sha256.Sum256([]byte(relativePath))
+	return filepath.Join(store.directory, hex.EncodeToString(sum[:]))
+}
+
+func (store *VersionStore) indexPath(relativePath string) string {
+	return filepath.Join(store.pathDirectory(relativePath), "index.json")
+}
+
+func (store *VersionStore) dataPath(relativePath, versionID string) string {
+	return filepath.Join(store.pathDirectory(relativePath), versionID)
+}
@@ -0,0 +1,129 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestVersionStore(t *testing.T, retention VersionRetention) (*VersionStore, string) {
+	t.Helper()
+	g := NewGomegaWithT(t)
+
+	destination := t.TempDir()
+	store, err := NewVersionStore(t.TempDir(), destination, retention)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return store, destination
+}
+
+func TestVersionStore_KeepsVersionsOnUpdateAndDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	store, destination := newTestVersionStore(t, VersionRetention{MaxVersions: 10})
+	writer := NewFileWriter(destination, store, nil)
+	path := filepath.Join(destination, "sub", "file.go")
+
+	g.Expect(writer.CreateFile(path, []byte("first"), false)).To(Succeed())
+	g.Expect(writer.UpdateFile(path, []byte("second"))).To(Succeed())
+	g.Expect(writer.DeleteFile(filepath.Join(destination, "sub"))).To(Succeed())
+
+	versions, err := store.ListVersions(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(versions).To(HaveLen(2))
+	g.Expect(versions[0].Path).To(Equal("/sub/file.go"))
+	g.Expect(versions[0].Size).To(Equal(int64(len("second"))))
+	g.Expect(versions[1].Size).To(Equal(int64(len("first"))))
+
+	restored, err := store.RestoreVersion(path, versions[1].ID)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(restored.ID).To(Equal(versions[1].ID))
+	g.Expect(os.ReadFile(path)).To(Equal([]byte("first")))
+
+	// restoring keeps what it replaced
+	g.Expect(writer.UpdateFile(path, []byte("third"))).To(Succeed())
+	versions, err = store.ListVersions(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(versions).To(HaveLen(3))
+	g.Expect(versions[0].Size).To(Equal(int64(len("first"))))
+}
+
+func TestVersionStore_RestoreUnknownVersion(t *testing.T) {
+	g := NewGomegaWithT(t)
+	store, destination := newTestVersionStore(t, VersionRetention{MaxVersions: 10})
+
+	_, err := store.RestoreVersion(filepath.Join(destination, "file.go"), "unknown")
+	g.Expect(err).To(MatchError(entities.ErrVersionNotFound))
+}
+
+func TestVersionStore_PrunesByCountAndAge(t *testing.T) {
+	g := NewGomegaWithT(t)
+	store, destination := newTestVersionStore(t, VersionRetention{MaxVersions: 2, MaxAge: time.Hour})
+	path := filepath.Join(destination, "file.go")
+
+	for _, contents := range []string{"first", "second", "third"} {
+		g.Expect(os.WriteFile(path, []byte(contents), 0o644)).To(Succeed())
+		g.Expect(store.SaveVersion(path)).To(Succeed())
+	}
+
+	versions, err := store.ListVersions(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(versions).To(HaveLen(2))
+	g.Expect(versions[1].Size).To(Equal(int64(len("second"))))
+
+	entries, err := os.ReadDir(store.pathDirectory("/file.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+	// the index and the data of the two versions kept
+	g.Expect(entries).To(HaveLen(3))
+
+	// versions older than the maximum age are removed the next time a version is kept
+	versions[0].CreatedAt = time.Now().Add(-2 * time.Hour)
+	versions[1].CreatedAt = time.Now().Add(-2 * time.Hour)
+	g.Expect(store.saveIndex("/file.go", versions)).To(Succeed())
+	g.Expect(store.SaveVersion(path)).To(Succeed())
+
+	versions, err = store.ListVersions(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(versions).To(HaveLen(1))
+}
+
+func TestVersionStore_RemovesExpiredVersionsOfUnchangedFiles(t *testing.T) {
+	g := NewGomegaWithT(t)
+	store, destination := newTestVersionStore(t, VersionRetention{MaxVersions: 10, MaxAge: time.Hour})
+
+	for _, name := range []string{"listed.go", "swept.go"} {
+		path := filepath.Join(destination, name)
+		g.Expect(os.WriteFile(path, []byte(name), 0o644)).To(Succeed())
+		g.Expect(store.SaveVersion(path)).To(Succeed())
+		g.Expect(store.SaveVersion(path)).To(Succeed())
+
+		versions, err := store.loadIndex("/" + name)
+		g.Expect(err).ToNot(HaveOccurred())
+		versions[0].CreatedAt = time.Now().Add(-2 * time.Hour)
+		g.Expect(store.saveIndex("/"+name, versions)).To(Succeed())
+	}
+
+	// expired versions aren't listed even though the file hasn't changed since
+	versions, err := store.ListVersions(filepath.Join(destination, "listed.go"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(versions).To(HaveLen(1))
+
+	swept, err := store.loadIndex("/swept.go")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = store.RestoreVersion(filepath.Join(destination, "swept.go"), swept[0].ID)
+	g.Expect(err).To(MatchError(entities.ErrVersionNotFound))
+
+	// once every version of a file has expired its directory is removed when the store is next opened
+	swept, err = store.loadIndex("/swept.go")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(swept).To(HaveLen(1))
+	swept[0].CreatedAt = time.Now().Add(-2 * time.Hour)
+	g.Expect(store.saveIndex("/swept.go", swept)).To(Succeed())
+
+	reopened, err := NewVersionStore(store.directory, destination, store.retention)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reopened.pathDirectory("/swept.go")).ToNot(BeADirectory())
+	g.Expect(reopened.pathDirectory("/listed.go")).To(BeADirectory())
+}
@@ -0,0 +1,157 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"os"
+	"strings"
+	"sync"
+)
+
+// VersionVectorTracker is a struct that persists the version vector the app holds for each file, keyed by the path sent
+// to the server, along with how far through the server's change feed the app has pulled. Vectors are kept after a file
+// is deleted, so that a change to the deleted file can still be told apart from one that follows on from the delete.
+type VersionVectorTracker struct {
+	mu       sync.Mutex
+	filePath string
+	clientID string
+	state    versionVectorState
+}
+
+var _ VersionVectorStore = &VersionVectorTracker{}
+
+// versionVectorState is the layout of the file the VersionVectorTracker is persisted to.
+type versionVectorState struct {
+	FeedID   string                            `json:"feedId"`
+	Sequence uint64                            `json:"sequence"`
+	Versions map[string]entities.VersionVector `json:"versions"`
+}
+
+// NewVersionVectorTracker is a function that loads the version vectors recorded by a previous run of the app. Local
+// changes are counted against clientID.
+func NewVersionVectorTracker(filePath, clientID string) (*VersionVectorTracker, error) {
+	tracker := &VersionVectorTracker{
+		filePath: filePath,
+		clientID: clientID,
+		state: versionVectorState{
+			Versions: make(map[string]entities.VersionVector),
+		},
+	}
+
+	stateBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return tracker, nil
+		}
+		return nil, fmt.Errorf("reading version vectors: %w", err)
+	}
+
+	err = json.Unmarshal(stateBytes, &tracker.state)
+	if err != nil {
+		return nil, fmt.Errorf("decoding version vectors: %w", err)
+	}
+	if tracker.state.Versions == nil {
+		tracker.state.Versions = make(map[string]entities.VersionVector)
+	}
+
+	return tracker, nil
+}
+
+// VersionVectorStore is an interface that sets out the functions implemented by the VersionVectorTracker. This allows
+// for mocking of the VersionVectorTracker functionality in tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -destination=../../mocks/versionVectorStore.go  . "VersionVectorStore"
+type VersionVectorStore interface {
+	ClientID() string
+	GetVersion(path string) entities.VersionVector
+	MergeVersion(path string, version entities.VersionVector) error
+	RecordLocalChange(path string) error
+	MoveVersions(oldPath, newPath string) error
+	Cursor() (string, uint64)
+	SetCursor(feedID string, sequence uint64) error
+}
+
+// ClientID is a function that returns the replica local changes are counted against.
+func (tracker *VersionVectorTracker) ClientID() string {
+	return tracker.clientID
+}
+
+// GetVersion is a function that returns the version vector of the file at path, which is empty if the app has never
+// seen a change to it.
+func (tracker *VersionVectorTracker) GetVersion(path string) entities.VersionVector {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	return tracker.state.Versions[path].Merge(nil)
+}
+
+// MergeVersion is a function that records that the app has seen every change in version to the file at path.
+func (tracker *VersionVectorTracker) MergeVersion(path string, version entities.VersionVector) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	merged := tracker.state.Versions[path].Merge(version)
+	if merged.Compare(tracker.state.Versions[path]) == entities.VersionsEqual {
+		return nil
+	}
+
+	tracker.state.Versions[path] = merged
+	return tracker.save()
+}
+
+// RecordLocalChange is a function that counts a change made to the file at path in the source directory against the
+// app, so that until the server has seen it the file's version is concurrent with any change made elsewhere.
+func (tracker *VersionVectorTracker) RecordLocalChange(path string) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.state.Versions[path] = tracker.state.Versions[path].Increment(tracker.clientID)
+	return tracker.save()
+}
+
+// MoveVersions is a function that merges the version vector of oldPath, and of anything within it, into newPath once it
+// has been renamed. The old paths keep their vectors, as they have effectively been deleted.
+func (tracker *VersionVectorTracker) MoveVersions(oldPath, newPath string) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	for path, version := range tracker.state.Versions {
+		if path != oldPath && !strings.HasPrefix(path, oldPath+"/") {
+			continue
+		}
+		movedPath := newPath + strings.TrimPrefix(path, oldPath)
+		tracker.state.Versions[movedPath] = tracker.state.Versions[movedPath].Merge(version)
+	}
+
+	return tracker.save()
+}
+
+// Cursor is a function that returns the ID of the server's change feed and the sequence number of the last change
+// pulled from it.
+func (tracker *VersionVectorTracker) Cursor() (string, uint64) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	return tracker.state.FeedID, tracker.state.Sequence
+}
+
+// SetCursor is a function that records the last change pulled from the server's change feed.
+func (tracker *VersionVectorTracker) SetCursor(feedID string, sequence uint64) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.state.FeedID = feedID
+	tracker.state.Sequence = sequence
+	return tracker.save()
+}
+
+func (tracker *VersionVectorTracker) save() error {
+	stateBytes, err := json.Marshal(tracker.state)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(tracker.filePath, stateBytes, 0o600)
+}
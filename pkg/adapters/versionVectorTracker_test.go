@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	. "github.com/onsi/gomega"
+	"path/filepath"
+	"testing"
+)
+
+// newTestVersionTracker is a function that creates an empty VersionVectorTracker for the app "laptop".
+func newTestVersionTracker(t *testing.T) *VersionVectorTracker {
+	tracker, err := NewVersionVectorTracker(filepath.Join(t.TempDir(), "versions"), "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tracker
+}
+
+func TestVersionVectorTracker_PersistsVersions(t *testing.T) {
+	g := NewGomegaWithT(t)
+	filePath := filepath.Join(t.TempDir(), "versions")
+
+	tracker, err := NewVersionVectorTracker(filePath, "laptop")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(tracker.MergeVersion("/dir/a.go", entities.VersionVector{"server": 2})).To(Succeed())
+	g.Expect(tracker.RecordLocalChange("/dir/a.go")).To(Succeed())
+	g.Expect(tracker.MergeVersion("/moved/a.go", entities.VersionVector{"desktop": 1})).To(Succeed())
+	g.Expect(tracker.MoveVersions("/dir", "/moved")).To(Succeed())
+	g.Expect(tracker.SetCursor("feed", 7)).To(Succeed())
+
+	reloaded, err := NewVersionVectorTracker(filePath, "laptop")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reloaded.GetVersion("/moved/a.go")).To(Equal(entities.VersionVector{"desktop": 1, "laptop": 1, "server": 2}))
+	// the old path keeps its vector, as a file deleted from there
+	g.Expect(reloaded.GetVersion("/dir/a.go")).To(Equal(entities.VersionVector{"laptop": 1, "server": 2}))
+	g.Expect(reloaded.GetVersion("/missing.go")).To(BeEmpty())
+
+	feedID, sequence := reloaded.Cursor()
+	g.Expect(feedID).To(Equal("feed"))
+	g.Expect(sequence).To(Equal(uint64(7)))
+}
@@ -10,17 +10,34 @@ import (
 // NewRouter is a function that reates a simple Gin router for the http server. It uses handler funcs to allow for
 // dependency injection at the endpoint level, this restricts access for each endpoint to the exact dependencies they
 // need.
-func NewRouter(destinationDir string, fileWriter adapters.FileModifier) *gin.Engine {
+func NewRouter(destinationDir string, pathLocker *usecases.PathLocker, fileWriter adapters.FileModifier, fileReader adapters.DestinationReader, uploadStore adapters.UploadStore, versionHistory adapters.VersionHistory, trashBin adapters.TrashBin, conflictHandler adapters.ConflictHandler, changeLog adapters.ChangeLog) *gin.Engine {
 	r := gin.Default()
 	v1 := r.Group("/v1")
 	{
 		v1.GET("/health/live", func(c *gin.Context) {
 			c.Status(http.StatusOK)
 		})
-		v1.POST("/file", usecases.NewCreateNewFile(fileWriter.CreateFile, destinationDir))
-		v1.DELETE("/file", usecases.NewDeleteFile(fileWriter.DeleteFile, destinationDir))
-		v1.PATCH("/file", usecases.NewRenameFile(fileWriter.RenameFile, destinationDir))
-		v1.PUT("/file", usecases.NewUpdateFileContents(fileWriter.UpdateFile, destinationDir))
+		v1.POST("/file", usecases.NewCreateNewFile(fileWriter.CreateFile, fileWriter.CreateSymlink, fileWriter.CreateHardLink, fileWriter.SetMetadata, fileReader.FileETag, conflictHandler.ResolveConflict, changeLog.RecordChange, pathLocker.Lock, destinationDir))
+		v1.DELETE("/file", usecases.NewDeleteFile(fileWriter.DeleteFile, fileReader.FileETag, conflictHandler.ResolveConflict, changeLog.RecordChange, pathLocker.Lock, destinationDir))
+		v1.PATCH("/file", usecases.NewRenameFile(fileWriter.RenameFile, fileReader.FileETag, conflictHandler.ResolveConflict, changeLog.RecordChange, pathLocker.Lock, destinationDir))
+		v1.GET("/file", usecases.NewGetFile(fileReader.OpenFile, destinationDir))
+		v1.PUT("/file", usecases.NewUpdateFileContents(fileWriter.UpdateFile, fileWriter.CreateSymlink, fileWriter.SetMetadata, fileReader.FileETag, conflictHandler.ResolveConflict, changeLog.RecordChange, pathLocker.Lock, destinationDir))
+		v1.PUT("/file/metadata", usecases.NewUpdateFileMetadata(fileWriter.SetMetadata, destinationDir))
+		v1.POST("/batch", usecases.NewBatch(r))
+		v1.GET("/manifest", usecases.NewGetManifest(fileReader.BuildManifest))
+		v1.GET("/signature", usecases.NewGetSignature(fileReader.BuildSignature, destinationDir))
+		v1.POST("/delta", usecases.NewApplyDelta(fileWriter.ApplyDelta, fileWriter.SetMetadata, fileReader.FileETag, conflictHandler.ResolveConflict, changeLog.RecordChange, pathLocker.Lock, destinationDir))
+		v1.GET("/versions", usecases.NewListVersions(versionHistory.ListVersions, destinationDir))
+		v1.POST("/versions/restore", usecases.NewRestoreVersion(versionHistory.RestoreVersion, fileReader.FileETag, changeLog.RecordChange, pathLocker.Lock, destinationDir))
+		v1.GET("/changes", usecases.NewListChanges(changeLog.ChangesSince))
+		v1.GET("/tree", usecases.NewGetTree(fileReader.ListTree, destinationDir))
+		v1.GET("/conflicts", usecases.NewListConflicts(conflictHandler.ListConflicts))
+		v1.GET("/trash", usecases.NewListTrash(trashBin.ListTrash))
+		v1.POST("/trash/restore", usecases.NewRestoreFromTrash(trashBin.RestoreFromTrash, fileReader.FileETag, fileReader.ListTree, changeLog.RecordChange, pathLocker.Lock, destinationDir))
+		v1.POST("/uploads", usecases.NewStartUpload(uploadStore.StartUpload, destinationDir))
+		v1.GET("/uploads/:uploadId", usecases.NewGetUpload(uploadStore.GetUpload))
+		v1.PUT("/uploads/:uploadId/chunks/:index", usecases.NewUploadChunk(uploadStore.WriteChunk))
+		v1.POST("/uploads/:uploadId/commit", usecases.NewCommitUpload(uploadStore.GetUpload, uploadStore.CompleteUpload, uploadStore.DiscardUpload, fileWriter.InstallFile, fileWriter.SetMetadata, fileReader.FileETag, conflictHandler.ResolveConflict, changeLog.RecordChange, pathLocker.Lock, destinationDir))
 	}
 
 	return r
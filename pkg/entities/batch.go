@@ -0,0 +1,26 @@
+package entities
+
+// MaxBatchOperations is the largest number of operations the server applies from a single batch.
+const MaxBatchOperations = 1000
+
+// BatchOperation is a struct that represents a single create, update, rename or delete within a batch. Data holds the
+// contents of a created or updated file, so only files small enough to send inline are batched, and LinkTarget the
+// target of a created or updated symlink. Headers holds the request headers the operation would have been sent with on
+// its own, such as its preconditions and version vector.
+type BatchOperation struct {
+	Operation    string            `json:"operation"`
+	Path         string            `json:"path"`
+	PreviousPath string            `json:"previousPath,omitempty"`
+	IsDirectory  bool              `json:"isDirectory,omitempty"`
+	Data         []byte            `json:"data,omitempty"`
+	LinkTarget   string            `json:"linkTarget,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+}
+
+// BatchResult is a struct that represents the outcome of a single operation within a batch. Status and Headers are the
+// status code and response headers the operation would have been given on its own, and Message describes why it failed.
+type BatchResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Message string            `json:"message,omitempty"`
+}
@@ -0,0 +1,155 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerReplicaID is the replica that changes made directly to the destination directory are attributed to.
+const ServerReplicaID = "server"
+
+var ErrInvalidVersionVector = errors.New("invalid version vector")
+
+// VersionOrder is how two version vectors of the same file relate to each other.
+type VersionOrder int
+
+const (
+	// VersionsEqual means both vectors describe the same version of the file.
+	VersionsEqual VersionOrder = iota
+	// VersionBefore means the vector describes a version the other vector has already seen.
+	VersionBefore
+	// VersionAfter means the vector describes a version that follows on from the other.
+	VersionAfter
+	// VersionsConcurrent means each vector has seen changes the other hasn't, so the file has been changed on both sides.
+	VersionsConcurrent
+)
+
+// VersionVector is a map of replica, the server or one of the apps, to the number of changes that replica has made to a
+// file. Comparing the vectors two replicas hold for a file tells them whether one has seen every change the other has,
+// or whether the file has been changed on both sides.
+type VersionVector map[string]uint64
+
+// Increment is a function that returns a copy of the vector with a change made by replica added.
+func (v VersionVector) Increment(replica string) VersionVector {
+	incremented := v.Merge(nil)
+	incremented[replica]++
+
+	return incremented
+}
+
+// Merge is a function that returns a copy of the vector that has also seen every change in other.
+func (v VersionVector) Merge(other VersionVector) VersionVector {
+	merged := make(VersionVector, len(v)+len(other))
+	for replica, count := range v {
+		merged[replica] = count
+	}
+	for replica, count := range other {
+		if count > merged[replica] {
+			merged[replica] = count
+		}
+	}
+
+	return merged
+}
+
+// Compare is a function that returns how the vector relates to other.
+func (v VersionVector) Compare(other VersionVector) VersionOrder {
+	before, after := false, false
+	for replica, count := range v {
+		if count > other[replica] {
+			after = true
+		}
+	}
+	for replica, count := range other {
+		if count > v[replica] {
+			before = true
+		}
+	}
+
+	switch {
+	case before && after:
+		return VersionsConcurrent
+	case before:
+		return VersionBefore
+	case after:
+		return VersionAfter
+	default:
+		return VersionsEqual
+	}
+}
+
+// String is a function that returns the vector in the form used by the X-Version-Vector header, a comma separated list
+// of replica=count pairs sorted by replica.
+func (v VersionVector) String() string {
+	replicas := make([]string, 0, len(v))
+	for replica := range v {
+		replicas = append(replicas, replica)
+	}
+	sort.Strings(replicas)
+
+	pairs := make([]string, 0, len(replicas))
+	for _, replica := range replicas {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", replica, v[replica]))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// ParseVersionVector is a function that reads a version vector in the form returned by VersionVector.String. An empty
+// header is an empty vector.
+func ParseVersionVector(header string) (VersionVector, error) {
+	vector := make(VersionVector)
+	if strings.TrimSpace(header) == "" {
+		return vector, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		replica, count, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || !IsReplicaID(replica) {
+			return nil, ErrInvalidVersionVector
+		}
+
+		parsedCount, err := strconv.ParseUint(count, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidVersionVector
+		}
+		vector[replica] = parsedCount
+	}
+
+	return vector, nil
+}
+
+// IsReplicaID is a function that returns whether id can be used to identify a replica in a version vector.
+func IsReplicaID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, ",= \t\r\n")
+}
+
+// Change is a struct that describes a change made to the destination directory, either by a request from an app or
+// directly to the destination. Sequence orders the changes in the server's change feed, Origin is the replica that made
+// the change and Version is the file's version vector once the change was made. LinkTarget is only set for a symlink, and
+// HardLinkOf for a file created as another hard link to the file at that path.
+type Change struct {
+	Sequence     uint64        `json:"sequence"`
+	Path         string        `json:"path"`
+	Operation    string        `json:"operation"`
+	PreviousPath string        `json:"previousPath,omitempty"`
+	IsDirectory  bool          `json:"isDirectory"`
+	Hash         string        `json:"hash,omitempty"`
+	LinkTarget   string        `json:"linkTarget,omitempty"`
+	HardLinkOf   string        `json:"hardLinkOf,omitempty"`
+	Origin       string        `json:"origin"`
+	Version      VersionVector `json:"version"`
+	ChangedAt    time.Time     `json:"changedAt"`
+}
+
+// ChangeFeedPage is a struct that holds the changes in the server's change feed after a given sequence number. FeedID
+// identifies the feed the sequence numbers belong to, Latest is the sequence number of the most recent change.
+type ChangeFeedPage struct {
+	FeedID  string   `json:"feedId"`
+	Latest  uint64   `json:"latest"`
+	Changes []Change `json:"changes"`
+}
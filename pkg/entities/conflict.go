@@ -0,0 +1,38 @@
+package entities
+
+import "time"
+
+// The policies for resolving a conflict, where a file in the destination directory has changed since the client last
+// saw it.
+const (
+	// ConflictPolicySourceWins applies the change from the source directory, replacing the destination's copy.
+	ConflictPolicySourceWins = "source-wins"
+	// ConflictPolicyDestinationWins leaves the destination's copy as it is and drops the change.
+	ConflictPolicyDestinationWins = "destination-wins"
+	// ConflictPolicyKeepBoth moves the destination's copy aside to a conflicted copy, then applies the change.
+	ConflictPolicyKeepBoth = "keep-both"
+	// ConflictPolicyFail refuses the change, leaving the conflict to be resolved by hand.
+	ConflictPolicyFail = "fail"
+)
+
+// IsConflictPolicy is a function that returns whether policy is one of the conflict policies.
+func IsConflictPolicy(policy string) bool {
+	switch policy {
+	case ConflictPolicySourceWins, ConflictPolicyDestinationWins, ConflictPolicyKeepBoth, ConflictPolicyFail:
+		return true
+	}
+
+	return false
+}
+
+// Conflict is a struct that describes a change to a file that conflicted with a change made to the destination
+// directory, and how it was resolved. ConflictedCopy is the path the destination's copy was moved to, if it was kept.
+type Conflict struct {
+	Path           string    `json:"path"`
+	Operation      string    `json:"operation"`
+	Policy         string    `json:"policy"`
+	ExpectedETag   string    `json:"expectedETag,omitempty"`
+	CurrentETag    string    `json:"currentETag,omitempty"`
+	ConflictedCopy string    `json:"conflictedCopy,omitempty"`
+	DetectedAt     time.Time `json:"detectedAt"`
+}
@@ -0,0 +1,43 @@
+package entities
+
+import "errors"
+
+var (
+	ErrInvalidDelta      = errors.New("delta refers to blocks outside of the destination file")
+	ErrDeltaHashMismatch = errors.New("file produced by the delta does not match the expected hash")
+)
+
+// FileSignature is a struct that describes the server's copy of a file as a list of fixed size blocks, so that a client
+// can work out which parts of its own copy the server already has. Every block is BlockSize bytes long apart from the
+// last, which may be shorter.
+type FileSignature struct {
+	Path      string           `json:"path"`
+	Size      int64            `json:"size"`
+	Hash      string           `json:"hash"`
+	BlockSize int              `json:"blockSize"`
+	Blocks    []BlockSignature `json:"blocks"`
+}
+
+// BlockSignature is a struct that holds the checksums of a single block of a file. Weak is a rolling checksum that can
+// be cheaply recalculated at every offset of the client's copy, Strong is a SHA-256 hash used to confirm a match.
+type BlockSignature struct {
+	Weak   uint32 `json:"weak"`
+	Strong string `json:"strong"`
+}
+
+// Delta is a struct that describes how to build the new version of a file from the server's current copy. Hash is the
+// SHA-256 hash of the new version, which the server checks before replacing its copy.
+type Delta struct {
+	Path       string           `json:"path"`
+	Hash       string           `json:"hash"`
+	BlockSize  int              `json:"blockSize"`
+	Operations []DeltaOperation `json:"operations"`
+}
+
+// DeltaOperation is a struct that either copies BlockCount consecutive blocks, starting at BlockIndex, from the server's
+// current copy of a file, or inserts Data that the server does not have.
+type DeltaOperation struct {
+	BlockIndex int    `json:"blockIndex,omitempty"`
+	BlockCount int    `json:"blockCount,omitempty"`
+	Data       []byte `json:"data,omitempty"`
+}
@@ -0,0 +1,25 @@
+package entities
+
+import "strings"
+
+// ETag is a function that returns the version token the server gives a file with the given content hash. It is the hash
+// quoted, in the form used by the ETag, If-Match and If-None-Match headers.
+func ETag(hash string) string {
+	return `"` + hash + `"`
+}
+
+// ETagListContains is a function that returns whether etag is one of the comma separated tags in an If-Match or
+// If-None-Match header. An empty etag, for something that has no contents to tag, is never contained.
+func ETagListContains(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
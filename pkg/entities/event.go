@@ -1,25 +1,109 @@
 package entities
 
+import (
+	"io/fs"
+	"time"
+)
+
 const (
 	OperationCreated  = "CREATE"
 	OperationModified = "MODIFIED"
 	OperationDeleted  = "DELETED"
 	OperationRenamed  = "RENAMED"
+	// OperationMetadata is a change to a file's mode, modification time or owner that leaves its contents as they are.
+	OperationMetadata = "METADATA"
 )
 
 // FilesystemEvent is a struct that represents a file event. It stores the name of the file and the operation that
-// happened to it, along with the metadata.
+// happened to it, along with the metadata. HardLinkOf is only set for a created file that is another hard link to a file
+// already in the source directory, and is the path of that file.
 type FilesystemEvent struct {
-	Name         string
-	Operation    string
-	PreviousPath string
-	FileContents FileContents
+	Name         string       `json:"name"`
+	Operation    string       `json:"operation"`
+	PreviousPath string       `json:"previousPath,omitempty"`
+	HardLinkOf   string       `json:"hardLinkOf,omitempty"`
+	FileContents FileContents `json:"fileContents"`
 }
 
-// FileContents is a struct that represents a file's metadata. It does whether it is a directory, its inode and the
-// contents of the file.
+// FileContents is a struct that represents a file's metadata. It does whether it is a directory, the device it is on,
+// its inode and number of hard links, size, modification time, mode, owner and a hash of its contents. Data is only
+// populated when the contents of a file have been read to be sent to the server, it is never persisted. A symlink is
+// never followed, it has the target it points at in place of contents, and its hash is the LinkTargetHash of the
+// target.
 type FileContents struct {
-	IsDirectory bool
-	Inode       uint64
-	Data        []byte
+	IsDirectory bool        `json:"isDirectory"`
+	IsSymlink   bool        `json:"isSymlink,omitempty"`
+	LinkTarget  string      `json:"linkTarget,omitempty"`
+	Device      uint64      `json:"device,omitempty"`
+	Inode       uint64      `json:"inode"`
+	LinkCount   uint64      `json:"linkCount,omitempty"`
+	Size        int64       `json:"size"`
+	ModTime     time.Time   `json:"modTime"`
+	Mode        fs.FileMode `json:"mode,omitempty"`
+	UID         uint32      `json:"uid,omitempty"`
+	GID         uint32      `json:"gid,omitempty"`
+	Hash        string      `json:"hash,omitempty"`
+	Data        []byte      `json:"-"`
+}
+
+// ID is a function that returns the FileID of the file, which it shares with any other hard links to it.
+func (contents FileContents) ID() FileID {
+	return FileID{Device: contents.Device, Inode: contents.Inode}
+}
+
+// IsHardLinked is a function that returns whether the file is a regular file with more than one hard link to it.
+func (contents FileContents) IsHardLinked() bool {
+	return !contents.IsDirectory && !contents.IsSymlink && contents.LinkCount > 1
+}
+
+// Metadata is a function that returns the attributes of the file that are copied to the destination, including its
+// owner if withOwner is set. A directory's modification time changes whenever anything inside it does, so it isn't
+// copied. Setting the mode or modification time of a symlink would change the entry it points at instead, so symlinks
+// have no metadata.
+func (contents FileContents) Metadata(withOwner bool) FileMetadata {
+	if contents.IsSymlink {
+		return FileMetadata{}
+	}
+
+	metadata := FileMetadata{
+		Mode: contents.Mode & PreservedModeBits,
+	}
+	if !contents.IsDirectory {
+		metadata.ModTime = contents.ModTime
+	}
+	if withOwner {
+		metadata.HasOwner = true
+		metadata.UID = contents.UID
+		metadata.GID = contents.GID
+	}
+
+	return metadata
+}
+
+// MetadataDiffers is a function that returns whether the attributes copied to the destination differ between two
+// versions of a file. The owner is only compared if withOwner is set, as it is otherwise not copied.
+func (contents FileContents) MetadataDiffers(other FileContents, withOwner bool) bool {
+	metadata, otherMetadata := contents.Metadata(withOwner), other.Metadata(withOwner)
+	return metadata.Mode != otherMetadata.Mode || !metadata.ModTime.Equal(otherMetadata.ModTime) ||
+		metadata.UID != otherMetadata.UID || metadata.GID != otherMetadata.GID
+}
+
+// SameKind is a function that returns whether two entries are both files, both directories or both symlinks.
+func (contents FileContents) SameKind(other FileContents) bool {
+	return contents.IsDirectory == other.IsDirectory && contents.IsSymlink == other.IsSymlink
+}
+
+// SameContents is a function that returns whether two entries have the same contents, which for files is the same size
+// and hash and for symlinks the same target. Directories are the same kind of entry as each other and nothing more.
+func (contents FileContents) SameContents(other FileContents) bool {
+	switch {
+	case !contents.SameKind(other):
+		return false
+	case contents.IsSymlink:
+		return contents.LinkTarget == other.LinkTarget
+	case contents.IsDirectory:
+		return true
+	}
+
+	return contents.Size == other.Size && contents.Hash != "" && contents.Hash == other.Hash
 }
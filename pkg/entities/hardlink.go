@@ -0,0 +1,13 @@
+package entities
+
+import "errors"
+
+// ErrInvalidHardLink is returned when a request to create a hard link also carries contents.
+var ErrInvalidHardLink = errors.New("a hard link can't have contents")
+
+// FileID is a struct that identifies a file by the device it is stored on and its inode, which every hard link to the
+// file shares. Inodes are only unique within a device.
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
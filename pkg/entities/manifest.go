@@ -0,0 +1,29 @@
+package entities
+
+import (
+	"io/fs"
+	"time"
+)
+
+// ManifestEntry is a struct that describes a single path in the destination directory. Paths are relative to the
+// destination directory and start with a separator, in the same form as the paths sent in requests to the server. The
+// modification time is only set for files. A symlink's hash is the LinkTargetHash of its target.
+type ManifestEntry struct {
+	Path        string      `json:"path"`
+	IsDirectory bool        `json:"isDirectory"`
+	Size        int64       `json:"size"`
+	Hash        string      `json:"hash,omitempty"`
+	IsSymlink   bool        `json:"isSymlink,omitempty"`
+	LinkTarget  string      `json:"linkTarget,omitempty"`
+	Mode        fs.FileMode `json:"mode,omitempty"`
+	ModTime     *time.Time  `json:"modTime,omitempty"`
+}
+
+// ModifiedAt is a function that returns the modification time of the entry, or the zero time if it doesn't have one.
+func (entry ManifestEntry) ModifiedAt() time.Time {
+	if entry.ModTime == nil {
+		return time.Time{}
+	}
+
+	return *entry.ModTime
+}
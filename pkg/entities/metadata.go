@@ -0,0 +1,135 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PreservedModeBits are the parts of a file's mode that are copied to the destination, its permissions along with the
+// setuid, setgid and sticky bits.
+const PreservedModeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+var ErrInvalidFileMetadata = errors.New("invalid file metadata")
+
+// FileMetadata is a struct that represents the attributes of a file that the server copies onto the destination along
+// with its contents. A zero Mode or ModTime is left as it is, and the owner is only changed if HasOwner is set.
+type FileMetadata struct {
+	Mode     fs.FileMode
+	ModTime  time.Time
+	HasOwner bool
+	UID      uint32
+	GID      uint32
+}
+
+// IsZero is a function that returns whether the metadata has nothing to apply.
+func (m FileMetadata) IsZero() bool {
+	return m.Mode == 0 && m.ModTime.IsZero() && !m.HasOwner
+}
+
+// String is a function that returns the metadata in the form used by the X-File-Metadata header, a comma separated list
+// of the attributes that are set, for example mode=0755,mtime=2025-05-19T21:51:24.123456789Z,uid=1000,gid=1000. The
+// mode is in octal, with the setuid, setgid and sticky bits in the leading digit as they are for chmod.
+func (m FileMetadata) String() string {
+	pairs := make([]string, 0, 4)
+	if m.Mode != 0 {
+		pairs = append(pairs, fmt.Sprintf("mode=%04o", unixMode(m.Mode)))
+	}
+	if !m.ModTime.IsZero() {
+		pairs = append(pairs, "mtime="+m.ModTime.UTC().Format(time.RFC3339Nano))
+	}
+	if m.HasOwner {
+		pairs = append(pairs, fmt.Sprintf("uid=%d", m.UID), fmt.Sprintf("gid=%d", m.GID))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// ParseFileMetadata is a function that reads metadata in the form returned by FileMetadata.String. An empty header is
+// metadata with nothing set. The uid and gid must be given together.
+func ParseFileMetadata(header string) (FileMetadata, error) {
+	var metadata FileMetadata
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	hasUID, hasGID := false, false
+	for _, pair := range strings.Split(header, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return FileMetadata{}, ErrInvalidFileMetadata
+		}
+
+		switch name {
+		case "mode":
+			mode, err := strconv.ParseUint(value, 8, 32)
+			if err != nil || mode > 0o7777 || mode == 0 {
+				return FileMetadata{}, ErrInvalidFileMetadata
+			}
+			metadata.Mode = goMode(uint32(mode))
+
+		case "mtime":
+			modTime, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return FileMetadata{}, ErrInvalidFileMetadata
+			}
+			metadata.ModTime = modTime
+
+		case "uid", "gid":
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return FileMetadata{}, ErrInvalidFileMetadata
+			}
+			if name == "uid" {
+				metadata.UID, hasUID = uint32(id), true
+			} else {
+				metadata.GID, hasGID = uint32(id), true
+			}
+
+		default:
+			return FileMetadata{}, ErrInvalidFileMetadata
+		}
+	}
+
+	if hasUID != hasGID {
+		return FileMetadata{}, ErrInvalidFileMetadata
+	}
+	metadata.HasOwner = hasUID
+
+	return metadata, nil
+}
+
+// unixMode is a function that converts a Go file mode into the octal form used by chmod.
+func unixMode(mode fs.FileMode) uint32 {
+	unix := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		unix |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		unix |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		unix |= 0o1000
+	}
+
+	return unix
+}
+
+// goMode is a function that converts the octal form of a mode used by chmod into a Go file mode.
+func goMode(unix uint32) fs.FileMode {
+	mode := fs.FileMode(unix) & fs.ModePerm
+	if unix&0o4000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if unix&0o2000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if unix&0o1000 != 0 {
+		mode |= fs.ModeSticky
+	}
+
+	return mode
+}
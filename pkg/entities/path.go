@@ -0,0 +1,12 @@
+package entities
+
+import "errors"
+
+// ErrInvalidPath is returned when a path in a request does not resolve to a location within the destination directory.
+var ErrInvalidPath = errors.New("invalid path")
+
+// ErrNotAFile is returned when a request reads the contents of a path that is a directory.
+var ErrNotAFile = errors.New("not a file")
+
+// ErrNotADirectory is returned when a request lists the contents of a path that is a file.
+var ErrNotADirectory = errors.New("not a directory")
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// The policies for replicating a symlink whose target is an absolute path, or a relative path that leads outside of the
+// source directory. Targets that stay within the source directory are always replicated as they are.
+const (
+	// SymlinkPolicyKeep replicates the symlink with its target as it is.
+	SymlinkPolicyKeep = "keep"
+	// SymlinkPolicyRewriteRelative rewrites an absolute target within the source directory to be relative to the
+	// symlink, so that it points at the same entry in the destination. Symlinks leading outside of the source directory
+	// are skipped.
+	SymlinkPolicyRewriteRelative = "rewrite-relative"
+	// SymlinkPolicySkip leaves the symlink out of the destination.
+	SymlinkPolicySkip = "skip"
+)
+
+// ErrInvalidSymlink is returned when a request to create a symlink also carries contents.
+var ErrInvalidSymlink = errors.New("a symlink can't have contents")
+
+// IsSymlinkPolicy is a function that returns whether policy is one of the symlink policies.
+func IsSymlinkPolicy(policy string) bool {
+	switch policy {
+	case SymlinkPolicyKeep, SymlinkPolicyRewriteRelative, SymlinkPolicySkip:
+		return true
+	}
+
+	return false
+}
+
+// LinkTargetHash is a function that returns the hash of a symlink, which stands in for the hash of a file's contents.
+// It is the hex encoded SHA-256 hash of the link's target, so a symlink's version changes whenever it is retargeted.
+func LinkTargetHash(target string) string {
+	hash := sha256.Sum256([]byte(target))
+	return hex.EncodeToString(hash[:])
+}
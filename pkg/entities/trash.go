@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrTrashEntryNotFound  = errors.New("trash entry not found")
+	ErrRestoreTargetExists = errors.New("a file already exists at the original path")
+)
+
+// TrashEntry is a struct that describes a file or directory deleted from the destination directory and kept in the
+// server's trash until it is purged. LinkTarget is only set for symlinks.
+type TrashEntry struct {
+	ID           string    `json:"trashId"`
+	OriginalPath string    `json:"originalPath"`
+	IsDirectory  bool      `json:"isDirectory"`
+	LinkTarget   string    `json:"linkTarget,omitempty"`
+	DeletedAt    time.Time `json:"deletedAt"`
+}
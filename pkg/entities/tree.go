@@ -0,0 +1,29 @@
+package entities
+
+import "time"
+
+const (
+	EntryTypeFile      = "file"
+	EntryTypeDirectory = "directory"
+	EntryTypeSymlink   = "symlink"
+)
+
+// TreeEntry is a struct that describes a single path in the destination directory as returned by a listing. Mode is the
+// permission bits in octal, and Hash, a SHA-256 hash of the contents, is only set for files. LinkTarget is only set for
+// symlinks.
+type TreeEntry struct {
+	Path       string    `json:"path"`
+	Type       string    `json:"type"`
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	ModTime    time.Time `json:"mtime"`
+	Hash       string    `json:"hash,omitempty"`
+	LinkTarget string    `json:"linkTarget,omitempty"`
+}
+
+// TreePage is a struct that holds a page of a listing of the destination directory. NextCursor is passed back to fetch
+// the next page, it is empty on the last page.
+type TreePage struct {
+	Entries    []TreeEntry `json:"entries"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUploadNotFound     = errors.New("upload session not found")
+	ErrUnexpectedChunk    = errors.New("chunk received out of order")
+	ErrUploadHashMismatch = errors.New("uploaded contents do not match the expected hash")
+)
+
+// UploadSession is a struct that represents a chunked upload of a single file. Chunks are numbered from zero and
+// appended to the session in order, NextChunk and Offset describe how much of the file has been received so far.
+type UploadSession struct {
+	ID        string    `json:"uploadId"`
+	Path      string    `json:"path"`
+	NextChunk int       `json:"nextChunk"`
+	Offset    int64     `json:"offset"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PendingUpload is a struct that the app uses to remember an upload session it has started, so that it can resume the
+// upload after a restart. The size and modification time of the local file are recorded so that a session is not
+// resumed if the file has changed since it started.
+type PendingUpload struct {
+	UploadID string    `json:"uploadId"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+}
@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrVersionNotFound = errors.New("version not found")
+
+// FileVersion is a struct that describes a previous version of a file in the destination directory, kept by the server
+// when the file was overwritten or deleted. CreatedAt is when the version was replaced.
+type FileVersion struct {
+	ID        string    `json:"versionId"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"createdAt"`
+}
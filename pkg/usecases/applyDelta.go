@@ -0,0 +1,98 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+type ApplyDeltaRequestBody struct {
+	Path       string                    `json:"path" binding:"required"`
+	Hash       string                    `json:"hash" binding:"required"`
+	BlockSize  int                       `json:"blockSize" binding:"required"`
+	Operations []entities.DeltaOperation `json:"operations"`
+}
+
+// NewApplyDelta is a function that returns a handler which rebuilds a file in the destination directory from a delta
+// against its current contents, and sets any metadata in the X-File-Metadata header on it.
+func NewApplyDelta(deltaApplier func(string, entities.Delta) error, metadataSetter func(string, entities.FileMetadata) error, fileTagger func(string) (string, bool, error), conflictResolver func(string, entities.Conflict) (entities.Conflict, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request ApplyDeltaRequestBody
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			slog.Warn("failed to bind json body for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		change, ok := requestChange(c, request.Path, entities.OperationModified)
+		if !ok {
+			return
+		}
+
+		metadata, ok := requestMetadata(c)
+		if !ok {
+			return
+		}
+
+		defer pathLocker(filePathInDestinationDir)()
+		if !checkPreconditions(c, requestPreconditions(c), fileTagger, conflictResolver, changeRecorder, filePathInDestinationDir, change) {
+			return
+		}
+
+		err = deltaApplier(filePathInDestinationDir, entities.Delta{
+			Path:       request.Path,
+			Hash:       request.Hash,
+			BlockSize:  request.BlockSize,
+			Operations: request.Operations,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "file not found",
+				})
+			case errors.Is(err, entities.ErrInvalidDelta):
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"message": "a bad request error occurred",
+				})
+			case errors.Is(err, entities.ErrDeltaHashMismatch):
+				c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+					"message": "file produced by the delta does not match the expected hash",
+				})
+			default:
+				slog.Error("applying delta", "err", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"message": "an internal server error occurred",
+				})
+			}
+			return
+		}
+
+		if !applyMetadata(c, metadataSetter, filePathInDestinationDir, metadata) {
+			return
+		}
+
+		change.Hash = request.Hash
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
+		c.Header("ETag", entities.ETag(request.Hash))
+		c.Status(http.StatusOK)
+	}
+}
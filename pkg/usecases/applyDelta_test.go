@@ -0,0 +1,93 @@
+package usecases_test
+
+import (
+	"bytes"
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplyDelta_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/delta", bytes.NewReader([]byte(
+		`{"path":"/some/path.go","hash":"abc","blockSize":4,"operations":[{"blockIndex":1,"blockCount":2},{"data":"c29tZQ=="}]}`,
+	)))
+
+	mockFileWriter.EXPECT().ApplyDelta("./dest/some/path.go", entities.Delta{
+		Path:      "/some/path.go",
+		Hash:      "abc",
+		BlockSize: 4,
+		Operations: []entities.DeltaOperation{
+			{BlockIndex: 1, BlockCount: 2},
+			{Data: []byte("some")},
+		},
+	}).Return(nil).Times(1)
+
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationModified, Hash: "abc", Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{Version: entities.VersionVector{"anonymous": 1}}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Version-Vector")).To(Equal("anonymous=1"))
+}
+
+func TestApplyDelta_ValidationError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/delta", bytes.NewReader([]byte(`{"path":"/some/path.go"}`)))
+
+	mockFileWriter.EXPECT().ApplyDelta(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestApplyDelta_HashMismatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/delta", bytes.NewReader([]byte(`{"path":"/some/path.go","hash":"abc","blockSize":4}`)))
+
+	mockFileWriter.EXPECT().ApplyDelta("./dest/some/path.go", gomock.Any()).Return(entities.ErrDeltaHashMismatch).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"file produced by the delta does not match the expected hash"}`))
+}
+
+func TestApplyDelta_FileWriterReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/delta", bytes.NewReader([]byte(`{"path":"/some/path.go","hash":"abc","blockSize":4}`)))
+
+	mockFileWriter.EXPECT().ApplyDelta("./dest/some/path.go", gomock.Any()).Return(errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
@@ -0,0 +1,185 @@
+package usecases
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+// maxBatchBodySize is the largest batch request body the server reads, operations carry the contents of files inline so
+// it is several times the largest file a client sends that way.
+const maxBatchBodySize = 64 << 20
+
+var (
+	// batchRequestHeaders are the headers an operation can set for itself, overriding those the batch was sent with.
+	batchRequestHeaders = []string{"If-Match", "If-None-Match", "X-Target-If-Match", "X-Target-If-None-Match", "X-Version-Vector", "X-Conflict-Policy", "X-File-Metadata"}
+	// batchResponseHeaders are the response headers of each operation that are returned in its result.
+	batchResponseHeaders = []string{"ETag", "X-Version-Vector", "X-Conflict-Resolution", "X-Conflicted-Copy"}
+)
+
+type BatchRequestBody struct {
+	Operations []entities.BatchOperation `json:"operations" binding:"required"`
+}
+
+type BatchResponseBody struct {
+	Results []entities.BatchResult `json:"results"`
+}
+
+// NewBatch is a function that returns a handler which applies a list of file operations in order, as if each had been
+// sent to the file endpoints of handler on its own, and responds with the result of each one. An operation failing
+// doesn't stop the ones after it from being applied, unless it failed with a server error, in which case the rest are
+// not attempted and are given a 424 so that they can be sent again. The X-Client-ID and X-Conflict-Policy headers of
+// the batch apply to every operation in it.
+func NewBatch(handler http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchBodySize)
+
+		var request BatchRequestBody
+		err := c.ShouldBindJSON(&request)
+		if err != nil || len(request.Operations) == 0 || len(request.Operations) > entities.MaxBatchOperations {
+			slog.Warn("failed to bind json body for request", "operations", len(request.Operations), "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		results := make([]entities.BatchResult, 0, len(request.Operations))
+		attempting := true
+		for _, operation := range request.Operations {
+			if !attempting {
+				results = append(results, entities.BatchResult{
+					Status:  http.StatusFailedDependency,
+					Message: "not attempted as an earlier operation failed",
+				})
+				continue
+			}
+
+			result := applyBatchOperation(c, handler, operation)
+			if result.Status >= http.StatusInternalServerError {
+				attempting = false
+			}
+			results = append(results, result)
+		}
+
+		c.JSON(http.StatusOK, BatchResponseBody{
+			Results: results,
+		})
+	}
+}
+
+// applyBatchOperation is a function that serves a single operation from a batch through handler, as the request it
+// would have been sent as on its own.
+func applyBatchOperation(c *gin.Context, handler http.Handler, operation entities.BatchOperation) entities.BatchResult {
+	method, url := "", "/v1/file"
+	switch operation.Operation {
+	case entities.OperationCreated:
+		method = http.MethodPost
+	case entities.OperationModified:
+		method = http.MethodPut
+	case entities.OperationRenamed:
+		method = http.MethodPatch
+	case entities.OperationDeleted:
+		method = http.MethodDelete
+	case entities.OperationMetadata:
+		method, url = http.MethodPut, "/v1/file/metadata"
+	default:
+		slog.Warn("unknown batch operation", "operation", operation.Operation)
+		return entities.BatchResult{
+			Status:  http.StatusBadRequest,
+			Message: "a bad request error occurred",
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"path":         operation.Path,
+		"previousPath": operation.PreviousPath,
+		"isDirectory":  operation.IsDirectory,
+		"data":         operation.Data,
+		"linkTarget":   operation.LinkTarget,
+	})
+	if err != nil {
+		slog.Error("encoding batch operation", "err", err)
+		return entities.BatchResult{
+			Status:  http.StatusInternalServerError,
+			Message: "an internal server error occurred",
+		}
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, url, bytes.NewReader(body))
+	if err != nil {
+		slog.Error("creating batch operation request", "err", err)
+		return entities.BatchResult{
+			Status:  http.StatusInternalServerError,
+			Message: "an internal server error occurred",
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Client-ID", c.GetHeader("X-Client-ID"))
+	req.Header.Set("X-Conflict-Policy", c.GetHeader("X-Conflict-Policy"))
+	operationHeaders := make(http.Header, len(operation.Headers))
+	for name, value := range operation.Headers {
+		operationHeaders.Set(name, value)
+	}
+	for _, name := range batchRequestHeaders {
+		value := operationHeaders.Get(name)
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	writer := newBatchResponseWriter()
+	handler.ServeHTTP(writer, req)
+
+	result := entities.BatchResult{
+		Status: writer.status,
+	}
+	for _, name := range batchResponseHeaders {
+		value := writer.header.Get(name)
+		if value == "" {
+			continue
+		}
+		if result.Headers == nil {
+			result.Headers = make(map[string]string)
+		}
+		result.Headers[name] = value
+	}
+
+	var responseBody struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(writer.body.Bytes(), &responseBody) == nil {
+		result.Message = responseBody.Message
+	}
+
+	return result
+}
+
+// batchResponseWriter is a struct that holds the response to a single operation within a batch.
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchResponseWriter() *batchResponseWriter {
+	return &batchResponseWriter{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (writer *batchResponseWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *batchResponseWriter) Write(data []byte) (int, error) {
+	return writer.body.Write(data)
+}
+
+func (writer *batchResponseWriter) WriteHeader(status int) {
+	writer.status = status
+}
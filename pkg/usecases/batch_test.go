@@ -0,0 +1,128 @@
+package usecases_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBatch_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mockChangeLog)
+
+	requestBodyBytes, err := json.Marshal(usecases.BatchRequestBody{
+		Operations: []entities.BatchOperation{
+			{Operation: entities.OperationCreated, Path: "/dir", IsDirectory: true},
+			{Operation: entities.OperationCreated, Path: "/dir/a.go", Data: []byte("some content"), Headers: map[string]string{"X-Version-Vector": "laptop=2"}},
+			{Operation: entities.OperationModified, Path: "/b.go", Data: []byte("some content"), Headers: map[string]string{"If-Match": `"abc"`}},
+			{Operation: entities.OperationRenamed, Path: "/d.go", PreviousPath: "/c.go"},
+			{Operation: "COPIED", Path: "/e.go"},
+		},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/batch", bytes.NewReader(requestBodyBytes))
+	req.Header.Set("X-Client-ID", "laptop")
+
+	gomock.InOrder(
+		mockFileWriter.EXPECT().CreateFile("./dest/dir", nil, true).Return(nil),
+		mockFileWriter.EXPECT().CreateFile("./dest/dir/a.go", []byte("some content"), false).Return(nil),
+		mockFileWriter.EXPECT().RenameFile("./dest/c.go", "./dest/d.go").Return(nil),
+	)
+	mockFileReader.EXPECT().FileETag("./dest/b.go").Return(`"def"`, true, nil)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/b.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil)
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).DoAndReturn(func(change entities.Change) (entities.Change, error) {
+		g.Expect(change.Origin).To(Equal("laptop"))
+		if change.Path == "/dir/a.go" {
+			g.Expect(change.Version).To(Equal(entities.VersionVector{"laptop": 2}))
+		}
+		return entities.Change{Version: entities.VersionVector{"laptop": 3}}, nil
+	}).Times(3)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+
+	var response usecases.BatchResponseBody
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+	g.Expect(response.Results).To(HaveLen(5))
+	g.Expect(response.Results[0].Status).To(Equal(http.StatusOK))
+	g.Expect(response.Results[1].Status).To(Equal(http.StatusOK))
+	g.Expect(response.Results[1].Headers).To(HaveKeyWithValue("ETag", `"290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"`))
+	g.Expect(response.Results[1].Headers).To(HaveKeyWithValue("X-Version-Vector", "laptop=3"))
+	g.Expect(response.Results[2].Status).To(Equal(http.StatusPreconditionFailed))
+	g.Expect(response.Results[2].Headers).To(HaveKeyWithValue("ETag", `"def"`))
+	g.Expect(response.Results[2].Message).To(Equal("the file has changed on the server"))
+	g.Expect(response.Results[3].Status).To(Equal(http.StatusOK))
+	g.Expect(response.Results[4].Status).To(Equal(http.StatusBadRequest))
+}
+
+func TestBatch_StopsAtServerError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	requestBodyBytes, err := json.Marshal(usecases.BatchRequestBody{
+		Operations: []entities.BatchOperation{
+			{Operation: entities.OperationDeleted, Path: "/a.go"},
+			{Operation: entities.OperationDeleted, Path: "/b.go"},
+			{Operation: entities.OperationDeleted, Path: "/c.go"},
+		},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/batch", bytes.NewReader(requestBodyBytes))
+
+	mockFileWriter.EXPECT().DeleteFile("./dest/a.go").Return(nil)
+	mockFileWriter.EXPECT().DeleteFile("./dest/b.go").Return(errors.New("disk full"))
+	mockFileWriter.EXPECT().DeleteFile("./dest/c.go").Times(0)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+
+	var response usecases.BatchResponseBody
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+	g.Expect(response.Results).To(HaveLen(3))
+	g.Expect(response.Results[0].Status).To(Equal(http.StatusOK))
+	g.Expect(response.Results[1].Status).To(Equal(http.StatusInternalServerError))
+	g.Expect(response.Results[2].Status).To(Equal(http.StatusFailedDependency))
+}
+
+func TestBatch_ValidationError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	tooMany, err := json.Marshal(usecases.BatchRequestBody{
+		Operations: make([]entities.BatchOperation, entities.MaxBatchOperations+1),
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	for _, body := range [][]byte{[]byte(`{"operations":[]}`), []byte(`{`), tooMany} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/batch", bytes.NewReader(body))
+
+		router.ServeHTTP(w, req)
+		g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	}
+}
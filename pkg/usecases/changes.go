@@ -0,0 +1,54 @@
+package usecases
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+// anonymousClientID is the replica that changes are attributed to when a request doesn't identify the app that sent it.
+const anonymousClientID = "anonymous"
+
+// requestChange is a function that returns the change a request makes to path, attributed to the app in its
+// X-Client-ID header and made against the version vector in its X-Version-Vector header. If either header is invalid
+// the request is responded to with a 400 and false is returned.
+func requestChange(c *gin.Context, path, operation string) (entities.Change, bool) {
+	origin := c.GetHeader("X-Client-ID")
+	if origin == "" {
+		origin = anonymousClientID
+	}
+
+	version, err := entities.ParseVersionVector(c.GetHeader("X-Version-Vector"))
+	if err != nil || !entities.IsReplicaID(origin) || origin == entities.ServerReplicaID {
+		slog.Warn("invalid change headers", "clientId", origin, "err", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "a bad request error occurred",
+		})
+		return entities.Change{}, false
+	}
+
+	return entities.Change{
+		Path:      path,
+		Operation: operation,
+		Origin:    origin,
+		Version:   version,
+	}, true
+}
+
+// recordChange is a function that records a change that has been applied to the destination directory in the change
+// feed, and returns the file's new version vector in the X-Version-Vector header. If false is returned the request has
+// been responded to.
+func recordChange(c *gin.Context, changeRecorder func(entities.Change) (entities.Change, error), change entities.Change) bool {
+	recorded, err := changeRecorder(change)
+	if err != nil {
+		slog.Error("recording change", "path", change.Path, "err", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"message": "an internal server error occurred",
+		})
+		return false
+	}
+
+	c.Header("X-Version-Vector", recorded.Version.String())
+	return true
+}
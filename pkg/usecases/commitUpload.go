@@ -0,0 +1,148 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+type CommitUploadRequestBody struct {
+	Hash string `json:"hash" binding:"required"`
+}
+
+// NewCommitUpload is a function that returns a handler which verifies an upload against the hash of the whole file and
+// moves the assembled file into place in the destination directory. If the request has preconditions they are evaluated
+// against the file being replaced before the upload is completed, so that a failed precondition leaves the upload to be
+// committed again, unless the conflict is resolved by keeping the destination's copy. Any metadata in the
+// X-File-Metadata header is set on the file once it is in place. The upload is only discarded once the file is in place,
+// so a commit that fails before then can be retried.
+func NewCommitUpload(uploadGetter func(string) (entities.UploadSession, error), uploadCompleter func(string, string) (entities.UploadSession, string, error), uploadDiscarder func(string) error, fileInstaller func(string, string) error, metadataSetter func(string, entities.FileMetadata) error, fileTagger func(string) (string, bool, error), conflictResolver func(string, entities.Conflict) (entities.Conflict, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request CommitUploadRequestBody
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			slog.Warn("failed to bind json body for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		change, ok := requestChange(c, "", entities.OperationModified)
+		if !ok {
+			return
+		}
+
+		metadata, ok := requestMetadata(c)
+		if !ok {
+			return
+		}
+
+		if !requestPreconditions(c).isEmpty() {
+			session, err := uploadGetter(c.Param("uploadId"))
+			if err != nil {
+				if errors.Is(err, entities.ErrUploadNotFound) {
+					c.JSON(http.StatusNotFound, map[string]interface{}{
+						"message": "upload not found",
+					})
+					return
+				}
+
+				slog.Error("getting upload", "err", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"message": "an internal server error occurred",
+				})
+				return
+			}
+
+			filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, session.Path)
+			if err != nil {
+				slog.Warn("rejected request path", "path", session.Path, "err", err)
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"message": "a bad request error occurred",
+				})
+				return
+			}
+
+			defer pathLocker(filePathInDestinationDir)()
+			change.Path = session.Path
+			if !checkPreconditions(c, requestPreconditions(c), fileTagger, conflictResolver, changeRecorder, filePathInDestinationDir, change) {
+				// the upload is never going to be committed when the destination's copy is kept, so it is discarded
+				if c.Writer.Header().Get("X-Conflict-Resolution") == entities.ConflictPolicyDestinationWins {
+					discardUpload(uploadDiscarder, c.Param("uploadId"))
+				}
+				return
+			}
+		}
+
+		session, assembledPath, err := uploadCompleter(c.Param("uploadId"), request.Hash)
+		if err != nil {
+			if errors.Is(err, entities.ErrUploadNotFound) {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "upload not found",
+				})
+				return
+			}
+			if errors.Is(err, entities.ErrUploadHashMismatch) {
+				c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+					"message": "uploaded contents do not match the expected hash",
+				})
+				return
+			}
+
+			slog.Error("completing upload", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, session.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", session.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+		if requestPreconditions(c).isEmpty() {
+			// otherwise the path was locked before the preconditions were checked
+			defer pathLocker(filePathInDestinationDir)()
+		}
+
+		err = fileInstaller(filePathInDestinationDir, assembledPath)
+		if err != nil {
+			slog.Error("installing uploaded file", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		discardUpload(uploadDiscarder, c.Param("uploadId"))
+
+		if !applyMetadata(c, metadataSetter, filePathInDestinationDir, metadata) {
+			return
+		}
+
+		change.Path = session.Path
+		change.Hash = request.Hash
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
+		c.Header("ETag", entities.ETag(request.Hash))
+		c.Status(http.StatusOK)
+	}
+}
+
+// discardUpload is a function that ends an upload session that is no longer needed. Failing to is only logged, as the
+// session is left to expire.
+func discardUpload(uploadDiscarder func(string) error, uploadID string) {
+	err := uploadDiscarder(uploadID)
+	if err != nil {
+		slog.Warn("discarding upload", "uploadId", uploadID, "err", err)
+	}
+}
@@ -0,0 +1,207 @@
+package usecases_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/adapters"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommitUpload_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/abc/commit", bytes.NewReader([]byte(`{"hash":"def"}`)))
+
+	mockUploadStore.EXPECT().CompleteUpload("abc", "def").
+		Return(entities.UploadSession{ID: "abc", Path: "/some/path.go"}, "/uploads/abc.part", nil).Times(1)
+	mockFileWriter.EXPECT().InstallFile("./dest/some/path.go", "/uploads/abc.part").
+		Return(nil).Times(1)
+	mockUploadStore.EXPECT().DiscardUpload("abc").Return(nil).Times(1)
+
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationModified, Hash: "def", Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{Version: entities.VersionVector{"anonymous": 1}}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+}
+
+func TestCommitUpload_HashMismatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/abc/commit", bytes.NewReader([]byte(`{"hash":"def"}`)))
+
+	mockUploadStore.EXPECT().CompleteUpload("abc", "def").
+		Return(entities.UploadSession{}, "", entities.ErrUploadHashMismatch).Times(1)
+	mockFileWriter.EXPECT().InstallFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"uploaded contents do not match the expected hash"}`))
+}
+
+func TestCommitUpload_FileModifierReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/abc/commit", bytes.NewReader([]byte(`{"hash":"def"}`)))
+
+	mockUploadStore.EXPECT().CompleteUpload("abc", "def").
+		Return(entities.UploadSession{ID: "abc", Path: "/some/path.go"}, "/uploads/abc.part", nil).Times(1)
+	mockFileWriter.EXPECT().InstallFile("./dest/some/path.go", "/uploads/abc.part").
+		Return(errors.New("an error occurred")).Times(1)
+	// the upload is kept so the commit can be retried
+	mockUploadStore.EXPECT().DiscardUpload(gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
+
+func TestCommitUpload_IfMatchSucceeds(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/abc/commit", bytes.NewReader([]byte(`{"hash":"def"}`)))
+	req.Header.Set("If-Match", `"ghi"`)
+
+	mockUploadStore.EXPECT().GetUpload("abc").
+		Return(entities.UploadSession{ID: "abc", Path: "/some/path.go"}, nil).Times(1)
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"ghi"`, true, nil).Times(1)
+	mockUploadStore.EXPECT().CompleteUpload("abc", "def").
+		Return(entities.UploadSession{ID: "abc", Path: "/some/path.go"}, "/uploads/abc.part", nil).Times(1)
+	mockFileWriter.EXPECT().InstallFile("./dest/some/path.go", "/uploads/abc.part").
+		Return(nil).Times(1)
+	mockUploadStore.EXPECT().DiscardUpload("abc").Return(nil).Times(1)
+
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"def"`))
+}
+
+func TestCommitUpload_IfMatchFails(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/abc/commit", bytes.NewReader([]byte(`{"hash":"def"}`)))
+	req.Header.Set("If-Match", `"ghi"`)
+
+	mockUploadStore.EXPECT().GetUpload("abc").
+		Return(entities.UploadSession{ID: "abc", Path: "/some/path.go"}, nil).Times(1)
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"jkl"`, true, nil).Times(1)
+	mockUploadStore.EXPECT().CompleteUpload(gomock.Any(), gomock.Any()).Times(0)
+	mockFileWriter.EXPECT().InstallFile(gomock.Any(), gomock.Any()).Times(0)
+	mockUploadStore.EXPECT().DiscardUpload(gomock.Any()).Times(0)
+
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", entities.Conflict{Path: "/some/path.go", Operation: entities.OperationModified, ExpectedETag: `"ghi"`, CurrentETag: `"jkl"`}).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(1)
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+}
+
+func TestCommitUpload_DestinationWinsDiscardsUpload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/abc/commit", bytes.NewReader([]byte(`{"hash":"def"}`)))
+	req.Header.Set("If-Match", `"ghi"`)
+	req.Header.Set("X-Conflict-Policy", entities.ConflictPolicyDestinationWins)
+
+	mockUploadStore.EXPECT().GetUpload("abc").
+		Return(entities.UploadSession{ID: "abc", Path: "/some/path.go"}, nil).Times(1)
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"jkl"`, true, nil).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyDestinationWins}, nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, nil).Times(1)
+	mockUploadStore.EXPECT().CompleteUpload(gomock.Any(), gomock.Any()).Times(0)
+	mockFileWriter.EXPECT().InstallFile(gomock.Any(), gomock.Any()).Times(0)
+	mockUploadStore.EXPECT().DiscardUpload("abc").Return(nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Conflict-Resolution")).To(Equal(entities.ConflictPolicyDestinationWins))
+}
+
+func TestCommitUpload_RetriedAfterInstallFails(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	uploadStore, err := adapters.NewUploadManager(t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), uploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	session, err := uploadStore.StartUpload("/some/path.go")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(uploadStore.WriteChunk(session.ID, 0, bytes.NewReader([]byte("some content")))).To(Succeed())
+	hash := sha256.Sum256([]byte("some content"))
+	body := fmt.Sprintf(`{"hash":"%s"}`, hex.EncodeToString(hash[:]))
+
+	gomock.InOrder(
+		mockFileWriter.EXPECT().InstallFile("./dest/some/path.go", gomock.Any()).Return(errors.New("an error occurred")),
+		mockFileWriter.EXPECT().InstallFile("./dest/some/path.go", gomock.Any()).Return(nil),
+	)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).Return(entities.Change{}, nil).Times(1)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/"+session.ID+"/commit", strings.NewReader(body)))
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+
+	// the upload is still there to be committed again
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/uploads/"+session.ID+"/commit", strings.NewReader(body)))
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+
+	// and is gone once it has been
+	_, err = uploadStore.GetUpload(session.ID)
+	g.Expect(err).To(MatchError(entities.ErrUploadNotFound))
+}
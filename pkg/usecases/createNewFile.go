@@ -1,22 +1,38 @@
 package usecases
 
 import (
-	"fmt"
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
 	"github.com/gin-gonic/gin"
 	"log/slog"
 	"net/http"
+	"os"
+	"strings"
 )
 
 type CreateFileRequestBody struct {
 	Path        string `json:"path" binding:"required"`
 	IsDirectory bool   `json:"isDirectory"`
 	Data        []byte `json:"data"`
+	LinkTarget  string `json:"linkTarget"`
+	HardLinkOf  string `json:"hardLinkOf"`
 }
 
-func NewCreateNewFile(fileCreator func(string, []byte, bool) error, destinationDir string) gin.HandlerFunc {
+// NewCreateNewFile is a function that returns a handler which creates a file or directory in the destination directory.
+// A request with If-None-Match: * only succeeds if nothing exists at the path yet. Any mode, modification time and owner
+// in the X-File-Metadata header are set on the new file or directory. A request with a linkTarget creates a symlink to
+// it instead, replacing any file or symlink already at the path, and a request with hardLinkOf creates a hard link to the
+// file at that path, which is a 404 if there isn't one.
+func NewCreateNewFile(fileCreator func(string, []byte, bool) error, symlinkCreator func(string, string) error, hardLinkCreator func(string, string) error, metadataSetter func(string, entities.FileMetadata) error, fileTagger func(string) (string, bool, error), conflictResolver func(string, entities.Conflict) (entities.Conflict, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var request CreateFileRequestBody
 		err := c.ShouldBindJSON(&request)
+		if err == nil && request.LinkTarget != "" && (request.IsDirectory || len(request.Data) > 0) {
+			err = entities.ErrInvalidSymlink
+		}
+		if err == nil && request.HardLinkOf != "" && (request.IsDirectory || len(request.Data) > 0 || request.LinkTarget != "") {
+			err = entities.ErrInvalidHardLink
+		}
 		if err != nil {
 			slog.Warn("failed to bind json body for request", "err", err)
 			c.JSON(http.StatusBadRequest, map[string]interface{}{
@@ -25,8 +41,64 @@ func NewCreateNewFile(fileCreator func(string, []byte, bool) error, destinationD
 			return
 		}
 
-		filePathInDestinationDir := fmt.Sprintf("%s%s", destinationDir, request.Path)
-		err = fileCreator(filePathInDestinationDir, request.Data, request.IsDirectory)
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		var linkedPathInDestinationDir string
+		if request.HardLinkOf != "" {
+			linkedPathInDestinationDir, err = ResolveDestinationPath(destinationDir, request.HardLinkOf)
+			if err != nil {
+				slog.Warn("rejected request path", "path", request.HardLinkOf, "err", err)
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"message": "a bad request error occurred",
+				})
+				return
+			}
+		}
+
+		change, ok := requestChange(c, request.Path, entities.OperationCreated)
+		if !ok {
+			return
+		}
+
+		metadata, ok := requestMetadata(c)
+		if !ok {
+			return
+		}
+
+		defer pathLocker(filePathInDestinationDir)()
+		if !checkPreconditions(c, requestPreconditions(c), fileTagger, conflictResolver, changeRecorder, filePathInDestinationDir, change) {
+			return
+		}
+
+		switch {
+		case request.LinkTarget != "":
+			err = symlinkCreator(filePathInDestinationDir, request.LinkTarget)
+		case request.HardLinkOf != "":
+			err = hardLinkCreator(linkedPathInDestinationDir, filePathInDestinationDir)
+		default:
+			err = fileCreator(filePathInDestinationDir, request.Data, request.IsDirectory)
+		}
+		if errors.Is(err, os.ErrNotExist) && request.HardLinkOf != "" {
+			slog.Warn("no file to link to", "path", request.HardLinkOf)
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"message": "file not found",
+			})
+			return
+		}
+		if errors.Is(err, entities.ErrNotAFile) && request.HardLinkOf != "" {
+			slog.Warn("unable to link to entry that isn't a file", "path", request.HardLinkOf)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
 		if err != nil {
 			slog.Error("creating new file", "err", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -35,6 +107,36 @@ func NewCreateNewFile(fileCreator func(string, []byte, bool) error, destinationD
 			return
 		}
 
+		if !applyMetadata(c, metadataSetter, filePathInDestinationDir, metadata) {
+			return
+		}
+
+		change.IsDirectory = request.IsDirectory
+		switch {
+		case request.LinkTarget != "":
+			change.Hash = entities.LinkTargetHash(request.LinkTarget)
+			change.LinkTarget = request.LinkTarget
+		case request.HardLinkOf != "":
+			etag, _, err := fileTagger(filePathInDestinationDir)
+			if err != nil {
+				slog.Error("getting etag of hard link", "err", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"message": "an internal server error occurred",
+				})
+				return
+			}
+			change.Hash = strings.Trim(etag, `"`)
+			change.HardLinkOf = request.HardLinkOf
+		case !request.IsDirectory:
+			change.Hash = hashData(request.Data)
+		}
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
+		if !request.IsDirectory {
+			c.Header("ETag", entities.ETag(change.Hash))
+		}
 		c.Status(http.StatusOK)
 	}
 }
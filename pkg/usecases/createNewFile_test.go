@@ -6,11 +6,15 @@ import (
 	"errors"
 	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
 	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestCreateNewFile_HappyPath(t *testing.T) {
@@ -18,7 +22,8 @@ func TestCreateNewFile_HappyPath(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
 
 	type createRequestBody struct {
 		Path        string `json:"path"`
@@ -34,13 +39,143 @@ func TestCreateNewFile_HappyPath(t *testing.T) {
 	g.Expect(err).ToNot(HaveOccurred())
 
 	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader(requestBodyBytes))
+	req.Header.Set("X-Client-ID", "laptop")
+	req.Header.Set("X-Version-Vector", "laptop=2,server=1")
 
 	mockFileWriter.EXPECT().CreateFile("./dest/some/path.go", requestBody.Data, false).
 		Return(nil).Times(1)
 
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationCreated, Hash: "290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56", Origin: "laptop", Version: entities.VersionVector{"laptop": 2, "server": 1}}).
+		Return(entities.Change{Version: entities.VersionVector{"laptop": 3, "server": 1}}, nil).Times(1)
+
 	router.ServeHTTP(w, req)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Version-Vector")).To(Equal("laptop=3,server=1"))
+}
+
+func TestCreateNewFile_SetsMetadata(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/run.sh","data":"ZWNobw=="}`)))
+	req.Header.Set("X-File-Metadata", "mode=4755,mtime=2025-05-19T21:51:24Z,uid=1000,gid=100")
+
+	gomock.InOrder(
+		mockFileWriter.EXPECT().CreateFile("./dest/run.sh", []byte("echo"), false).Return(nil),
+		mockFileWriter.EXPECT().SetMetadata("./dest/run.sh", entities.FileMetadata{
+			Mode:     0o755 | fs.ModeSetuid,
+			ModTime:  time.Date(2025, 5, 19, 21, 51, 24, 0, time.UTC),
+			HasOwner: true,
+			UID:      1000,
+			GID:      100,
+		}).Return(nil),
+		mockChangeLog.EXPECT().RecordChange(gomock.Any()).Return(entities.Change{}, nil),
+	)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+}
+
+func TestCreateNewFile_InvalidMetadata(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/run.sh"}`)))
+	req.Header.Set("X-File-Metadata", "mode=0755,uid=1000")
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestCreateNewFile_Symlink(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/link","linkTarget":"../a.go"}`)))
+
+	mockFileWriter.EXPECT().CreateSymlink("./dest/some/link", "../a.go").Return(nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/link", Operation: entities.OperationCreated, Hash: entities.LinkTargetHash("../a.go"), LinkTarget: "../a.go", Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("ETag")).To(Equal(entities.ETag(entities.LinkTargetHash("../a.go"))))
+}
+
+func TestCreateNewFile_SymlinkWithData(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/link","linkTarget":"../a.go","data":"ZWNobw=="}`)))
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestCreateNewFile_HardLink(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/b.go","hardLinkOf":"/a.go"}`)))
+
+	mockFileWriter.EXPECT().CreateHardLink("./dest/a.go", "./dest/b.go").Return(nil).Times(1)
+	mockFileReader.EXPECT().FileETag("./dest/b.go").Return(`"hash"`, true, nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/b.go", Operation: entities.OperationCreated, Hash: "hash", HardLinkOf: "/a.go", Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"hash"`))
+}
+
+func TestCreateNewFile_HardLinkTargetMissing(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/b.go","hardLinkOf":"/a.go"}`)))
+
+	mockFileWriter.EXPECT().CreateHardLink("./dest/a.go", "./dest/b.go").Return(&fs.PathError{Op: "lstat", Path: "./dest/a.go", Err: fs.ErrNotExist}).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"file not found"}`))
+}
+
+func TestCreateNewFile_HardLinkWithData(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/b.go","hardLinkOf":"/a.go","data":"ZWNobw=="}`)))
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
 }
 
 func TestCreateNewFile_ValidationError(t *testing.T) {
@@ -48,7 +183,7 @@ func TestCreateNewFile_ValidationError(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
 
 	type createRequestBody struct {
 		Path        string `json:"path"`
@@ -80,7 +215,7 @@ func TestCreateNewFile_FileModifierReturnsError(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
 
 	type createRequestBody struct {
 		Path        string `json:"path"`
@@ -106,3 +241,41 @@ func TestCreateNewFile_FileModifierReturnsError(t *testing.T) {
 	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
 	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
 }
+
+func TestCreateNewFile_PathOutsideDestination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/../../etc/cron.d/job","data":"c29tZQ=="}`)))
+
+	mockFileWriter.EXPECT().CreateFile(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestCreateNewFile_IfNoneMatchFileExists(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-None-Match", "*")
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"abc"`, true, nil).Times(1)
+	mockFileWriter.EXPECT().CreateFile(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", entities.Conflict{Path: "/some/path.go", Operation: entities.OperationCreated, ExpectedETag: "not *", CurrentETag: `"abc"`}).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(1)
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"abc"`))
+}
@@ -1,7 +1,7 @@
 package usecases
 
 import (
-	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
 	"github.com/gin-gonic/gin"
 	"log/slog"
 	"net/http"
@@ -11,7 +11,9 @@ type DeleteFileRequestBody struct {
 	Path string `json:"path" binding:"required"`
 }
 
-func NewDeleteFile(fileDeleter func(string) error, destinationDir string) gin.HandlerFunc {
+// NewDeleteFile is a function that returns a handler which deletes a file or directory from the destination directory, as
+// long as the request's preconditions hold for it.
+func NewDeleteFile(fileDeleter func(string) error, fileTagger func(string) (string, bool, error), conflictResolver func(string, entities.Conflict) (entities.Conflict, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var request DeleteFileRequestBody
 		err := c.ShouldBindJSON(&request)
@@ -20,9 +22,28 @@ func NewDeleteFile(fileDeleter func(string) error, destinationDir string) gin.Ha
 			c.JSON(http.StatusBadRequest, map[string]interface{}{
 				"message": "a bad request error occurred",
 			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		change, ok := requestChange(c, request.Path, entities.OperationDeleted)
+		if !ok {
+			return
+		}
+
+		defer pathLocker(filePathInDestinationDir)()
+		if !checkPreconditions(c, requestPreconditions(c), fileTagger, conflictResolver, changeRecorder, filePathInDestinationDir, change) {
+			return
 		}
 
-		filePathInDestinationDir := fmt.Sprintf("%s%s", destinationDir, request.Path)
 		err = fileDeleter(filePathInDestinationDir)
 		if err != nil {
 			slog.Error("deleting file", "err", err)
@@ -32,6 +53,10 @@ func NewDeleteFile(fileDeleter func(string) error, destinationDir string) gin.Ha
 			return
 		}
 
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
 		c.Status(http.StatusOK)
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
 	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
 	"net/http"
@@ -18,7 +20,8 @@ func TestDeleteFile_HappyPath(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
 
 	type deleteRequestBody struct {
 		Path string `json:"path"`
@@ -35,6 +38,9 @@ func TestDeleteFile_HappyPath(t *testing.T) {
 	mockFileWriter.EXPECT().DeleteFile("./dest/some/path.go").
 		Return(nil).Times(1)
 
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationDeleted, Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{}, nil).Times(1)
+
 	router.ServeHTTP(w, req)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(w.Code).To(Equal(http.StatusOK))
@@ -45,7 +51,7 @@ func TestDeleteFile_ReturnsErr(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
 
 	type deleteRequestBody struct {
 		Path string `json:"path"`
@@ -66,3 +72,40 @@ func TestDeleteFile_ReturnsErr(t *testing.T) {
 	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
 	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
 }
+
+func TestDeleteFile_PathOutsideDestination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/../.."}`)))
+
+	mockFileWriter.EXPECT().DeleteFile(gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestDeleteFile_IfMatchFails(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodDelete, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go"}`)))
+	req.Header.Set("If-Match", `"abc", "def"`)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"ghi"`, true, nil).Times(1)
+	mockFileWriter.EXPECT().DeleteFile(gomock.Any()).Times(0)
+
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(1)
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+}
@@ -0,0 +1,85 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveDestinationPath is a function that turns a path from a request into the location it refers to within the
+// destination directory. Request paths are relative to the destination directory and start with a separator, such as
+// /some/file.go. The path is rejected with entities.ErrInvalidPath if it is empty, relative, refers to the destination
+// directory itself, contains a . or .. element, or if any directory along it is a symlink that leads outside of the
+// destination directory.
+func ResolveDestinationPath(destinationDir, requestPath string) (string, error) {
+	if !strings.HasPrefix(requestPath, "/") || strings.ContainsRune(requestPath, 0) {
+		return "", fmt.Errorf("%w: %q", entities.ErrInvalidPath, requestPath)
+	}
+
+	for _, element := range strings.Split(requestPath, "/") {
+		if element == "." || element == ".." {
+			return "", fmt.Errorf("%w: %q", entities.ErrInvalidPath, requestPath)
+		}
+	}
+
+	cleanPath := path.Clean(requestPath)
+	if cleanPath == "/" {
+		return "", fmt.Errorf("%w: %q refers to the destination directory", entities.ErrInvalidPath, requestPath)
+	}
+
+	resolvedPath := destinationDir + filepath.FromSlash(cleanPath)
+	err := checkSymlinksWithin(destinationDir, resolvedPath)
+	if err != nil {
+		return "", err
+	}
+
+	return resolvedPath, nil
+}
+
+// checkSymlinksWithin is a function that checks the closest existing parent directory of path is within root once any
+// symlinks have been followed. The last element of path is not followed, as operations on the destination replace or
+// remove a symlink rather than writing through it.
+func checkSymlinksWithin(root, path string) error {
+	realRoot, err := evalAbsolute(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// nothing exists within the destination directory yet, so there are no symlinks to follow
+			return nil
+		}
+		return fmt.Errorf("%w: %v", entities.ErrInvalidPath, err)
+	}
+
+	parent := filepath.Dir(path)
+	for {
+		realParent, err := evalAbsolute(parent)
+		if err == nil {
+			relativePath, err := filepath.Rel(realRoot, realParent)
+			if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+				return fmt.Errorf("%w: %q leads outside of the destination directory", entities.ErrInvalidPath, path)
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %v", entities.ErrInvalidPath, err)
+		}
+
+		next := filepath.Dir(parent)
+		if next == parent {
+			return nil
+		}
+		parent = next
+	}
+}
+
+func evalAbsolute(path string) (string, error) {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.EvalSymlinks(absolutePath)
+}
@@ -0,0 +1,68 @@
+package usecases_test
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDestinationPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestPath  string
+		expectedPath string
+		expectedErr  error
+	}{
+		{name: "file", requestPath: "/some/path.go", expectedPath: "./dest/some/path.go"},
+		{name: "repeated separators", requestPath: "//some//path.go", expectedPath: "./dest/some/path.go"},
+		{name: "trailing separator", requestPath: "/some/dir/", expectedPath: "./dest/some/dir"},
+		{name: "empty", requestPath: "", expectedErr: entities.ErrInvalidPath},
+		{name: "relative", requestPath: "some/path.go", expectedErr: entities.ErrInvalidPath},
+		{name: "destination directory", requestPath: "/", expectedErr: entities.ErrInvalidPath},
+		{name: "parent directory", requestPath: "/../../etc/passwd", expectedErr: entities.ErrInvalidPath},
+		{name: "parent directory within destination", requestPath: "/some/../path.go", expectedErr: entities.ErrInvalidPath},
+		{name: "current directory", requestPath: "/./path.go", expectedErr: entities.ErrInvalidPath},
+		{name: "null byte", requestPath: "/some/path.go\x00", expectedErr: entities.ErrInvalidPath},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			resolvedPath, err := usecases.ResolveDestinationPath("./dest", test.requestPath)
+			if test.expectedErr != nil {
+				g.Expect(err).To(MatchError(test.expectedErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(resolvedPath).To(Equal(test.expectedPath))
+		})
+	}
+}
+
+func TestResolveDestinationPath_SymlinkEscape(t *testing.T) {
+	g := NewGomegaWithT(t)
+	destination := t.TempDir()
+	outside := t.TempDir()
+	g.Expect(os.Symlink(outside, filepath.Join(destination, "escape"))).To(Succeed())
+	g.Expect(os.Mkdir(filepath.Join(destination, "inside"), 0o755)).To(Succeed())
+	g.Expect(os.Symlink(filepath.Join(destination, "inside"), filepath.Join(destination, "link"))).To(Succeed())
+
+	_, err := usecases.ResolveDestinationPath(destination, "/escape/file.go")
+	g.Expect(err).To(MatchError(entities.ErrInvalidPath))
+
+	_, err = usecases.ResolveDestinationPath(destination, "/escape/new/dir/file.go")
+	g.Expect(err).To(MatchError(entities.ErrInvalidPath))
+
+	// symlinks that stay within the destination are followed
+	resolvedPath, err := usecases.ResolveDestinationPath(destination, "/link/file.go")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resolvedPath).To(Equal(filepath.Join(destination, "link", "file.go")))
+
+	// the symlink itself can be replaced or removed
+	resolvedPath, err = usecases.ResolveDestinationPath(destination, "/escape")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(resolvedPath).To(Equal(filepath.Join(destination, "escape")))
+}
@@ -0,0 +1,66 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+type GetFileRequestQuery struct {
+	Path string `form:"path" binding:"required"`
+}
+
+// NewGetFile is a function that returns a handler which streams the contents of a file in the destination directory.
+// The file's version token is returned in the ETag header, and Range, If-Range and If-None-Match requests are honoured.
+// The Content-Type is worked out from the file's extension, or its contents if it doesn't have a known one.
+func NewGetFile(fileOpener func(string) (io.ReadSeekCloser, entities.TreeEntry, error), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request GetFileRequestQuery
+		err := c.ShouldBindQuery(&request)
+		if err != nil {
+			slog.Warn("failed to bind query for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		file, entry, err := fileOpener(filePathInDestinationDir)
+		if err != nil {
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "file not found",
+				})
+			case errors.Is(err, entities.ErrNotAFile):
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"message": "a bad request error occurred",
+				})
+			default:
+				slog.Error("opening file", "err", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"message": "an internal server error occurred",
+				})
+			}
+			return
+		}
+		defer file.Close()
+
+		c.Header("ETag", entities.ETag(entry.Hash))
+		http.ServeContent(c.Writer, c.Request, filepath.Base(filePathInDestinationDir), entry.ModTime, file)
+	}
+}
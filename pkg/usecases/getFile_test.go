@@ -0,0 +1,140 @@
+package usecases_test
+
+import (
+	"bytes"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error {
+	return nil
+}
+
+var fileEntry = entities.TreeEntry{
+	Path:    "/some/path.txt",
+	Type:    entities.EntryTypeFile,
+	Size:    12,
+	Mode:    "0644",
+	ModTime: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+	Hash:    "abc",
+}
+
+func TestGetFile_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/file?path=/some/path.txt", nil)
+
+	mockFileReader.EXPECT().OpenFile("./dest/some/path.txt").
+		Return(nopReadSeekCloser{bytes.NewReader([]byte("some content"))}, fileEntry, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("Content-Length")).To(Equal("12"))
+	g.Expect(w.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"abc"`))
+	g.Expect(w.Header().Get("Last-Modified")).To(Equal("Sun, 18 Oct 2026 09:00:00 GMT"))
+	g.Expect(w.Body.String()).To(Equal("some content"))
+}
+
+func TestGetFile_Range(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/file?path=/some/path.txt", nil)
+	req.Header.Set("Range", "bytes=5-")
+
+	mockFileReader.EXPECT().OpenFile("./dest/some/path.txt").
+		Return(nopReadSeekCloser{bytes.NewReader([]byte("some content"))}, fileEntry, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPartialContent))
+	g.Expect(w.Header().Get("Content-Range")).To(Equal("bytes 5-11/12"))
+	g.Expect(w.Header().Get("Content-Length")).To(Equal("7"))
+	g.Expect(w.Body.String()).To(Equal("content"))
+}
+
+func TestGetFile_NotModified(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/file?path=/some/path.txt", nil)
+	req.Header.Set("If-None-Match", `"abc"`)
+
+	mockFileReader.EXPECT().OpenFile("./dest/some/path.txt").
+		Return(nopReadSeekCloser{bytes.NewReader([]byte("some content"))}, fileEntry, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotModified))
+	g.Expect(w.Body.String()).To(BeEmpty())
+}
+
+func TestGetFile_FileNotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/file?path=/some/path.go", nil)
+
+	mockFileReader.EXPECT().OpenFile("./dest/some/path.go").Return(nil, entities.TreeEntry{}, os.ErrNotExist).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"file not found"}`))
+}
+
+func TestGetFile_Directory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/file?path=/some", nil)
+
+	mockFileReader.EXPECT().OpenFile("./dest/some").Return(nil, entities.TreeEntry{}, entities.ErrNotAFile).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+}
+
+func TestGetFile_PathOutsideDestination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/file?path=/../secret", nil)
+
+	mockFileReader.EXPECT().OpenFile(gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
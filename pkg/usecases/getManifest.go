@@ -0,0 +1,29 @@
+package usecases
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+type GetManifestResponseBody struct {
+	Entries []entities.ManifestEntry `json:"entries"`
+}
+
+func NewGetManifest(manifestBuilder func() ([]entities.ManifestEntry, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manifest, err := manifestBuilder()
+		if err != nil {
+			slog.Error("building manifest of destination directory", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetManifestResponseBody{
+			Entries: manifest,
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package usecases_test
+
+import (
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetManifest_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/manifest", nil)
+	modTime := time.Date(2025, 5, 19, 21, 51, 24, 0, time.UTC)
+
+	mockFileReader.EXPECT().BuildManifest().Return([]entities.ManifestEntry{
+		{Path: "/some", IsDirectory: true, Mode: 0o755},
+		{Path: "/some/path.go", Size: 12, Hash: "abc", Mode: 0o644, ModTime: &modTime},
+	}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"entries":[
+		{"path":"/some","isDirectory":true,"size":0,"mode":493},
+		{"path":"/some/path.go","isDirectory":false,"size":12,"hash":"abc","mode":420,"modTime":"2025-05-19T21:51:24Z"}
+	]}`))
+}
+
+func TestGetManifest_FileReaderReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/manifest", nil)
+
+	mockFileReader.EXPECT().BuildManifest().Return(nil, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+type GetSignatureRequestQuery struct {
+	Path string `form:"path" binding:"required"`
+}
+
+// NewGetSignature is a function that returns a handler which responds with the block signature of a file in the
+// destination directory, so that a client can send just the parts of the file that have changed.
+func NewGetSignature(signatureBuilder func(string) (entities.FileSignature, error), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request GetSignatureRequestQuery
+		err := c.ShouldBindQuery(&request)
+		if err != nil {
+			slog.Warn("failed to bind query for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		signature, err := signatureBuilder(filePathInDestinationDir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "file not found",
+				})
+				return
+			}
+
+			slog.Error("building file signature", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+		signature.Path = request.Path
+
+		c.JSON(http.StatusOK, signature)
+	}
+}
@@ -0,0 +1,84 @@
+package usecases_test
+
+import (
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestGetSignature_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/signature?path=/some/path.go", nil)
+
+	mockFileReader.EXPECT().BuildSignature("./dest/some/path.go").Return(entities.FileSignature{
+		Size:      12,
+		Hash:      "abc",
+		BlockSize: 2048,
+		Blocks:    []entities.BlockSignature{{Weak: 1, Strong: "def"}},
+	}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"path":"/some/path.go","size":12,"hash":"abc","blockSize":2048,"blocks":[{"weak":1,"strong":"def"}]}`))
+}
+
+func TestGetSignature_ValidationError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/signature", nil)
+
+	mockFileReader.EXPECT().BuildSignature(gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestGetSignature_FileNotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/signature?path=/some/path.go", nil)
+
+	mockFileReader.EXPECT().BuildSignature("./dest/some/path.go").Return(entities.FileSignature{}, os.ErrNotExist).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"file not found"}`))
+}
+
+func TestGetSignature_FileReaderReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/signature?path=/some/path.go", nil)
+
+	mockFileReader.EXPECT().BuildSignature("./dest/some/path.go").Return(entities.FileSignature{}, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
@@ -0,0 +1,82 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// defaultTreePageSize is the number of entries in a page of a listing when the request doesn't give a limit.
+	defaultTreePageSize = 100
+	// maxTreePageSize is the most entries returned in a page of a listing.
+	maxTreePageSize = 1000
+)
+
+type GetTreeRequestQuery struct {
+	Path      string `form:"path"`
+	Recursive bool   `form:"recursive"`
+	Cursor    string `form:"cursor"`
+	Limit     int    `form:"limit"`
+}
+
+// NewGetTree is a function that returns a handler which lists the entries within a directory in the destination
+// directory, with the type, size, permissions, modification time and hash of each. The path query parameter defaults to
+// the destination directory itself, and the recursive query parameter lists everything below it rather than only its
+// immediate children. Listings are returned a page of up to limit entries at a time, the nextCursor of each page is
+// passed back as the cursor query parameter to fetch the next.
+func NewGetTree(treeLister func(string, bool, string, int) (entities.TreePage, error), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request GetTreeRequestQuery
+		err := c.ShouldBindQuery(&request)
+		if err != nil || request.Limit < 0 || request.Limit > maxTreePageSize ||
+			(request.Cursor != "" && !strings.HasPrefix(request.Cursor, "/")) {
+			slog.Warn("failed to bind query for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+		if request.Limit == 0 {
+			request.Limit = defaultTreePageSize
+		}
+
+		directoryPath := destinationDir
+		if request.Path != "" && request.Path != "/" {
+			directoryPath, err = ResolveDestinationPath(destinationDir, request.Path)
+			if err != nil {
+				slog.Warn("rejected request path", "path", request.Path, "err", err)
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"message": "a bad request error occurred",
+				})
+				return
+			}
+		}
+
+		page, err := treeLister(directoryPath, request.Recursive, request.Cursor, request.Limit)
+		if err != nil {
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "directory not found",
+				})
+			case errors.Is(err, entities.ErrNotADirectory):
+				c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"message": "a bad request error occurred",
+				})
+			default:
+				slog.Error("listing directory", "err", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"message": "an internal server error occurred",
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
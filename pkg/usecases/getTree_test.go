@@ -0,0 +1,103 @@
+package usecases_test
+
+import (
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetTree_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/tree?path=/some&recursive=true&cursor=/some/a.go&limit=2", nil)
+
+	mockFileReader.EXPECT().ListTree("./dest/some", true, "/some/a.go", 2).Return(entities.TreePage{
+		Entries: []entities.TreeEntry{
+			{Path: "/some/b.go", Type: entities.EntryTypeFile, Size: 12, Mode: "0644", ModTime: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC), Hash: "abc"},
+			{Path: "/some/dir", Type: entities.EntryTypeDirectory, Mode: "0755", ModTime: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)},
+		},
+		NextCursor: "/some/dir",
+	}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"entries":[
+		{"path":"/some/b.go","type":"file","size":12,"mode":"0644","mtime":"2026-10-18T09:00:00Z","hash":"abc"},
+		{"path":"/some/dir","type":"directory","size":0,"mode":"0755","mtime":"2026-10-18T09:00:00Z"}
+	],"nextCursor":"/some/dir"}`))
+}
+
+func TestGetTree_DefaultsToDestinationDirectory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/tree", nil)
+
+	mockFileReader.EXPECT().ListTree("./dest", false, "", 100).Return(entities.TreePage{Entries: []entities.TreeEntry{}}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(Equal(`{"entries":[]}`))
+}
+
+func TestGetTree_InvalidQuery(t *testing.T) {
+	for _, query := range []string{"limit=-1", "limit=1001", "cursor=a.go", "path=/../secret", "recursive=maybe"} {
+		t.Run(query, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(t)
+			mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+			router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/tree?"+query, nil)
+
+			mockFileReader.EXPECT().ListTree(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			router.ServeHTTP(w, req)
+			g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+			g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+		})
+	}
+}
+
+func TestGetTree_ListerReturnsErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		err          error
+		expectedCode int
+	}{
+		"not found":     {err: os.ErrNotExist, expectedCode: http.StatusNotFound},
+		"not directory": {err: entities.ErrNotADirectory, expectedCode: http.StatusBadRequest},
+		"other":         {err: errors.New("an error occurred"), expectedCode: http.StatusInternalServerError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(t)
+			mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+			router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/tree?path=/some", nil)
+
+			mockFileReader.EXPECT().ListTree("./dest/some", false, "", 100).Return(entities.TreePage{}, test.err).Times(1)
+
+			router.ServeHTTP(w, req)
+			g.Expect(w.Code).To(Equal(test.expectedCode))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+// NewGetUpload is a function that returns a handler which reports how much of an upload session the server has
+// received, so that a client can resume it from that point.
+func NewGetUpload(uploadGetter func(string) (entities.UploadSession, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, err := uploadGetter(c.Param("uploadId"))
+		if err != nil {
+			if errors.Is(err, entities.ErrUploadNotFound) {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "upload not found",
+				})
+				return
+			}
+
+			slog.Error("getting upload", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, session)
+	}
+}
@@ -0,0 +1,63 @@
+package usecases_test
+
+import (
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetUpload_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/uploads/abc", nil)
+
+	mockUploadStore.EXPECT().GetUpload("abc").
+		Return(entities.UploadSession{ID: "abc", Path: "/some/path.go", NextChunk: 2, Offset: 100}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"uploadId":"abc","path":"/some/path.go","nextChunk":2,"offset":100,"updatedAt":"0001-01-01T00:00:00Z"}`))
+}
+
+func TestGetUpload_UploadNotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/uploads/abc", nil)
+
+	mockUploadStore.EXPECT().GetUpload("abc").Return(entities.UploadSession{}, entities.ErrUploadNotFound).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"upload not found"}`))
+}
+
+func TestGetUpload_UploadStoreReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/uploads/abc", nil)
+
+	mockUploadStore.EXPECT().GetUpload("abc").Return(entities.UploadSession{}, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
@@ -0,0 +1,47 @@
+package usecases
+
+import (
+	"context"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxChangesWait is the longest a request for changes is held open waiting for a new one.
+const maxChangesWait = time.Minute
+
+type ListChangesRequestQuery struct {
+	Since uint64        `form:"since"`
+	Wait  time.Duration `form:"wait"`
+}
+
+// NewListChanges is a function that returns a handler which lists the changes made to the destination directory after
+// the sequence number in the since query parameter, oldest first, along with the app or server that made each one. If
+// there are none yet the request is held open for up to the duration in the wait query parameter, so that apps can
+// long-poll for the changes made by everyone else.
+func NewListChanges(changeLister func(context.Context, uint64, time.Duration) (entities.ChangeFeedPage, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request ListChangesRequestQuery
+		err := c.ShouldBindQuery(&request)
+		if err != nil || request.Wait < 0 {
+			slog.Warn("failed to bind query for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		page, err := changeLister(c.Request.Context(), request.Since, min(request.Wait, maxChangesWait))
+		if err != nil {
+			slog.Error("listing changes", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
@@ -0,0 +1,112 @@
+package usecases_test
+
+import (
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListChanges_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/changes?since=4", nil)
+
+	mockChangeLog.EXPECT().ChangesSince(gomock.Any(), uint64(4), time.Duration(0)).Return(entities.ChangeFeedPage{
+		FeedID: "1",
+		Latest: 5,
+		Changes: []entities.Change{
+			{
+				Sequence:  5,
+				Path:      "/some/path.go",
+				Operation: entities.OperationModified,
+				Hash:      "abc",
+				Origin:    entities.ServerReplicaID,
+				Version:   entities.VersionVector{"laptop": 1, "server": 2},
+				ChangedAt: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+			},
+		},
+	}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"feedId":"1","latest":5,"changes":[
+		{"sequence":5,"path":"/some/path.go","operation":"MODIFIED","isDirectory":false,"hash":"abc","origin":"server","version":{"laptop":1,"server":2},"changedAt":"2026-10-18T09:00:00Z"}
+	]}`))
+}
+
+func TestListChanges_InvalidSince(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/changes?since=-1", nil)
+
+	mockChangeLog.EXPECT().ChangesSince(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestListChanges_ChangeLogReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/changes", nil)
+
+	mockChangeLog.EXPECT().ChangesSince(gomock.Any(), uint64(0), time.Duration(0)).Return(entities.ChangeFeedPage{}, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
+
+func TestListChanges_WaitsForChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/changes?since=2&wait=10m", nil)
+
+	// the wait is capped so that requests aren't held open indefinitely
+	mockChangeLog.EXPECT().ChangesSince(gomock.Any(), uint64(2), time.Minute).
+		Return(entities.ChangeFeedPage{FeedID: "1", Latest: 2, Changes: []entities.Change{}}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"feedId":"1","latest":2,"changes":[]}`))
+}
+
+func TestListChanges_InvalidWait(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/changes?wait=-5s", nil)
+
+	mockChangeLog.EXPECT().ChangesSince(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+}
@@ -0,0 +1,31 @@
+package usecases
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+type ListConflictsResponseBody struct {
+	Conflicts []entities.Conflict `json:"conflicts"`
+}
+
+// NewListConflicts is a function that returns a handler which lists every conflict the server has resolved, and how,
+// oldest first.
+func NewListConflicts(conflictLister func() ([]entities.Conflict, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conflicts, err := conflictLister()
+		if err != nil {
+			slog.Error("listing conflicts", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, ListConflictsResponseBody{
+			Conflicts: conflicts,
+		})
+	}
+}
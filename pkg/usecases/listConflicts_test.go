@@ -0,0 +1,59 @@
+package usecases_test
+
+import (
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListConflicts_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/conflicts", nil)
+
+	mockConflictHandler.EXPECT().ListConflicts().Return([]entities.Conflict{
+		{
+			Path:           "/some/path.go",
+			Operation:      entities.OperationModified,
+			Policy:         entities.ConflictPolicyKeepBoth,
+			ExpectedETag:   `"abc"`,
+			CurrentETag:    `"def"`,
+			ConflictedCopy: "/some/path (conflicted copy host 2026-10-18).go",
+			DetectedAt:     time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+		},
+	}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"conflicts":[
+		{"path":"/some/path.go","operation":"MODIFIED","policy":"keep-both","expectedETag":"\"abc\"","currentETag":"\"def\"","conflictedCopy":"/some/path (conflicted copy host 2026-10-18).go","detectedAt":"2026-10-18T09:00:00Z"}
+	]}`))
+}
+
+func TestListConflicts_ConflictHandlerReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/v1/conflicts", nil)
+
+	mockConflictHandler.EXPECT().ListConflicts().Return(nil, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
@@ -0,0 +1,31 @@
+package usecases
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+type ListTrashResponseBody struct {
+	Entries []entities.TrashEntry `json:"entries"`
+}
+
+// NewListTrash is a function that returns a handler which lists the files and directories deleted from the destination
+// directory that are still in the trash, most recently deleted first.
+func NewListTrash(trashLister func() ([]entities.TrashEntry, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entries, err := trashLister()
+		if err != nil {
+			slog.Error("listing trash", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, ListTrashResponseBody{
+			Entries: entries,
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package usecases
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+type ListVersionsRequestQuery struct {
+	Path string `form:"path" binding:"required"`
+}
+
+type ListVersionsResponseBody struct {
+	Versions []entities.FileVersion `json:"versions"`
+}
+
+// NewListVersions is a function that returns a handler which lists the previous versions the server has kept of a file
+// in the destination directory, newest first.
+func NewListVersions(versionLister func(string) ([]entities.FileVersion, error), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request ListVersionsRequestQuery
+		err := c.ShouldBindQuery(&request)
+		if err != nil {
+			slog.Warn("failed to bind query for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		versions, err := versionLister(filePathInDestinationDir)
+		if err != nil {
+			slog.Error("listing file versions", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, ListVersionsResponseBody{
+			Versions: versions,
+		})
+	}
+}
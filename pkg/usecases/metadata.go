@@ -0,0 +1,42 @@
+package usecases
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+// requestMetadata is a function that returns the metadata a request sets on the file it changes, from its
+// X-File-Metadata header. If the header is invalid the request is responded to with a 400 and false is returned.
+func requestMetadata(c *gin.Context) (entities.FileMetadata, bool) {
+	metadata, err := entities.ParseFileMetadata(c.GetHeader("X-File-Metadata"))
+	if err != nil {
+		slog.Warn("invalid file metadata header", "header", c.GetHeader("X-File-Metadata"), "err", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "a bad request error occurred",
+		})
+		return entities.FileMetadata{}, false
+	}
+
+	return metadata, true
+}
+
+// applyMetadata is a function that sets the metadata from a request on the file at path once its contents have been
+// written. If false is returned the request has been responded to.
+func applyMetadata(c *gin.Context, metadataSetter func(string, entities.FileMetadata) error, path string, metadata entities.FileMetadata) bool {
+	if metadata.IsZero() {
+		return true
+	}
+
+	err := metadataSetter(path, metadata)
+	if err != nil {
+		slog.Error("setting file metadata", "err", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"message": "an internal server error occurred",
+		})
+		return false
+	}
+
+	return true
+}
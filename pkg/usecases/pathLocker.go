@@ -0,0 +1,89 @@
+package usecases
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// PathLocker is a struct that serializes the requests that change the same part of the destination directory, so that
+// the preconditions checked by a request still hold when its change is applied. A path is locked against changes to
+// itself and to any directory above it, so a directory can't be deleted or renamed while something inside it is being
+// changed, but changes to different files in the same directory can be made at the same time.
+type PathLocker struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+// pathLock is the lock on a single path. users counts the requests holding or waiting for it, so that it can be removed
+// once there are none.
+type pathLock struct {
+	mu    sync.RWMutex
+	users int
+}
+
+// NewPathLocker is a function that creates a PathLocker with nothing locked.
+func NewPathLocker() *PathLocker {
+	return &PathLocker{locks: make(map[string]*pathLock)}
+}
+
+// Lock is a function that locks each of paths against other requests changing them or any directory above them,
+// returning a function that unlocks them again. The paths are locked exclusively and the directories above them shared,
+// always in the same order, so that requests locking overlapping paths can't deadlock.
+func (locker *PathLocker) Lock(paths ...string) func() {
+	exclusive := make(map[string]bool)
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		exclusive[path] = true
+		for child, parent := path, filepath.Dir(path); parent != child; child, parent = parent, filepath.Dir(parent) {
+			if _, seen := exclusive[parent]; !seen {
+				exclusive[parent] = false
+			}
+		}
+	}
+
+	ordered := slices.Sorted(maps.Keys(exclusive))
+	for _, path := range ordered {
+		locker.lock(path, exclusive[path])
+	}
+
+	return func() {
+		for _, path := range slices.Backward(ordered) {
+			locker.unlock(path, exclusive[path])
+		}
+	}
+}
+
+func (locker *PathLocker) lock(path string, exclusive bool) {
+	locker.mu.Lock()
+	lock, exists := locker.locks[path]
+	if !exists {
+		lock = &pathLock{}
+		locker.locks[path] = lock
+	}
+	lock.users++
+	locker.mu.Unlock()
+
+	if exclusive {
+		lock.mu.Lock()
+	} else {
+		lock.mu.RLock()
+	}
+}
+
+func (locker *PathLocker) unlock(path string, exclusive bool) {
+	locker.mu.Lock()
+	defer locker.mu.Unlock()
+
+	lock := locker.locks[path]
+	if exclusive {
+		lock.mu.Unlock()
+	} else {
+		lock.mu.RUnlock()
+	}
+	lock.users--
+	if lock.users == 0 {
+		delete(locker.locks, path)
+	}
+}
@@ -0,0 +1,50 @@
+package usecases_test
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestPathLocker_LocksDirectoriesAboveAPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	locker := usecases.NewPathLocker()
+
+	unlockFile := locker.Lock("./dest/dir/file.go")
+
+	// another file in the same directory can be changed at the same time
+	unlockSibling := locker.Lock("./dest/dir/other.go")
+	unlockSibling()
+
+	// but the directory itself can't be deleted or renamed until the file is unlocked
+	directoryLocked := make(chan struct{})
+	go func() {
+		unlock := locker.Lock("./dest/dir")
+		close(directoryLocked)
+		unlock()
+	}()
+	g.Consistently(directoryLocked, 20*time.Millisecond).ShouldNot(BeClosed())
+
+	unlockFile()
+	g.Eventually(directoryLocked).Should(BeClosed())
+}
+
+func TestPathLocker_LocksPathsInsideADirectory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	locker := usecases.NewPathLocker()
+
+	// a rename of a directory into itself locks the same path both exclusively and as a parent
+	unlockDirectory := locker.Lock("./dest/dir", "./dest/dir/sub")
+
+	fileLocked := make(chan struct{})
+	go func() {
+		unlock := locker.Lock("./dest/dir/file.go")
+		close(fileLocked)
+		unlock()
+	}()
+	g.Consistently(fileLocked, 20*time.Millisecond).ShouldNot(BeClosed())
+
+	unlockDirectory()
+	g.Eventually(fileLocked).Should(BeClosed())
+}
@@ -0,0 +1,169 @@
+package usecases
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// preconditions is a struct that holds the conditions a request places on the current version of a file, from its
+// If-Match and If-None-Match headers.
+type preconditions struct {
+	ifMatch     string
+	ifNoneMatch string
+}
+
+// requestPreconditions is a function that returns the preconditions a request places on the file it changes.
+func requestPreconditions(c *gin.Context) preconditions {
+	return preconditions{
+		ifMatch:     c.GetHeader("If-Match"),
+		ifNoneMatch: c.GetHeader("If-None-Match"),
+	}
+}
+
+// targetPreconditions is a function that returns the preconditions a rename places on the path it moves a file to,
+// from its X-Target-If-Match and X-Target-If-None-Match headers.
+func targetPreconditions(c *gin.Context) preconditions {
+	return preconditions{
+		ifMatch:     c.GetHeader("X-Target-If-Match"),
+		ifNoneMatch: c.GetHeader("X-Target-If-None-Match"),
+	}
+}
+
+func (p preconditions) isEmpty() bool {
+	return p.ifMatch == "" && p.ifNoneMatch == ""
+}
+
+// hold is a function that returns whether the preconditions hold for a file with the given ETag, or for a missing file
+// if exists is false.
+func (p preconditions) hold(etag string, exists bool) bool {
+	if p.ifMatch != "" && (!exists || (p.ifMatch != "*" && !entities.ETagListContains(p.ifMatch, etag))) {
+		return false
+	}
+	if p.ifNoneMatch != "" && exists && (p.ifNoneMatch == "*" || entities.ETagListContains(p.ifNoneMatch, etag)) {
+		return false
+	}
+
+	return true
+}
+
+// expected is a function that returns the version of the file the client expected, for the conflict log.
+func (p preconditions) expected() string {
+	if p.ifMatch != "" {
+		return p.ifMatch
+	}
+
+	return "not " + p.ifNoneMatch
+}
+
+// checkPreconditions is a function that evaluates a request's preconditions against the current version of the file at
+// path, and returns whether the request's change should be applied. If the preconditions fail, the conflict is resolved
+// with the policy in the request's X-Conflict-Policy header, or the server's default policy:
+//   - source-wins and keep-both return true, keep-both having moved the destination's copy aside first
+//   - destination-wins responds with a 200 without applying the change
+//   - fail responds with a 412
+//
+// The resolved policy and any conflicted copy are returned in the X-Conflict-Resolution and X-Conflicted-Copy headers.
+// If false is returned the request has been responded to. The file is only read if the request has preconditions. The
+// caller holds the lock on path from its PathLocker until the change has been applied, so the file can't change in
+// between.
+func checkPreconditions(c *gin.Context, p preconditions, fileTagger func(string) (string, bool, error), conflictResolver func(string, entities.Conflict) (entities.Conflict, error), changeRecorder func(entities.Change) (entities.Change, error), path string, change entities.Change) bool {
+	if p.isEmpty() {
+		return true
+	}
+
+	etag, exists, err := fileTagger(path)
+	if err != nil {
+		slog.Error("getting file etag", "err", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"message": "an internal server error occurred",
+		})
+		return false
+	}
+
+	if p.hold(etag, exists) {
+		return true
+	}
+
+	policy := c.GetHeader("X-Conflict-Policy")
+	if policy != "" && !entities.IsConflictPolicy(policy) {
+		slog.Warn("unknown conflict policy", "policy", policy)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "a bad request error occurred",
+		})
+		return false
+	}
+
+	conflict, err := conflictResolver(path, entities.Conflict{
+		Path:         change.Path,
+		Operation:    change.Operation,
+		Policy:       policy,
+		ExpectedETag: p.expected(),
+		CurrentETag:  etag,
+	})
+	if err != nil {
+		slog.Error("resolving conflict", "err", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"message": "an internal server error occurred",
+		})
+		return false
+	}
+
+	c.Header("X-Conflict-Resolution", conflict.Policy)
+	if conflict.ConflictedCopy != "" {
+		c.Header("X-Conflicted-Copy", conflict.ConflictedCopy)
+	}
+
+	switch conflict.Policy {
+	case entities.ConflictPolicySourceWins, entities.ConflictPolicyKeepBoth:
+		return true
+
+	case entities.ConflictPolicyDestinationWins:
+		// the app's change is dropped, so the destination's copy is recorded as a newer change for the app to pull
+		restated := entities.Change{
+			Path:        change.Path,
+			Operation:   entities.OperationModified,
+			IsDirectory: exists && etag == "",
+			Hash:        strings.Trim(etag, `"`),
+			Origin:      entities.ServerReplicaID,
+			Version:     change.Version,
+		}
+		if !exists {
+			restated.Operation = entities.OperationDeleted
+		}
+		_, err = changeRecorder(restated)
+		if err != nil {
+			slog.Error("recording change", "path", change.Path, "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return false
+		}
+
+		if etag != "" {
+			c.Header("ETag", etag)
+		}
+		c.Status(http.StatusOK)
+		return false
+
+	default:
+		if etag != "" {
+			c.Header("ETag", etag)
+		}
+		c.JSON(http.StatusPreconditionFailed, map[string]interface{}{
+			"message": "the file has changed on the server",
+		})
+		return false
+	}
+}
+
+// hashData is a function that returns the hex encoded SHA-256 hash of data, the same hash the server uses for the ETag
+// of a file with those contents.
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
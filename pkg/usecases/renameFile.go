@@ -1,7 +1,7 @@
 package usecases
 
 import (
-	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
 	"github.com/gin-gonic/gin"
 	"log/slog"
 	"net/http"
@@ -12,7 +12,10 @@ type RenameFileRequestBody struct {
 	PreviousPath string `json:"previousPath"`
 }
 
-func NewRenameFile(fileRenamingFunc func(string, string) error, destinationDir string) gin.HandlerFunc {
+// NewRenameFile is a function that returns a handler which moves a file or directory within the destination directory.
+// The request's preconditions are evaluated against the file being moved, and its X-Target preconditions against
+// anything already at the path it is moved to.
+func NewRenameFile(fileRenamingFunc func(string, string) error, fileTagger func(string) (string, bool, error), conflictResolver func(string, entities.Conflict) (entities.Conflict, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var request RenameFileRequestBody
 		err := c.ShouldBindJSON(&request)
@@ -24,8 +27,41 @@ func NewRenameFile(fileRenamingFunc func(string, string) error, destinationDir s
 			return
 		}
 
-		oldFilePathInDestinationDir := fmt.Sprintf("%s%s", destinationDir, request.PreviousPath)
-		newFilePathInDestinationDir := fmt.Sprintf("%s%s", destinationDir, request.Path)
+		oldFilePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.PreviousPath)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.PreviousPath, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+		newFilePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		change, ok := requestChange(c, request.Path, entities.OperationRenamed)
+		if !ok {
+			return
+		}
+		change.PreviousPath = request.PreviousPath
+
+		defer pathLocker(oldFilePathInDestinationDir, newFilePathInDestinationDir)()
+
+		// the source preconditions are checked as a change to the file being moved
+		sourceChange := change
+		sourceChange.Path = request.PreviousPath
+		if !checkPreconditions(c, requestPreconditions(c), fileTagger, conflictResolver, changeRecorder, oldFilePathInDestinationDir, sourceChange) {
+			return
+		}
+		if newFilePathInDestinationDir != oldFilePathInDestinationDir &&
+			!checkPreconditions(c, targetPreconditions(c), fileTagger, conflictResolver, changeRecorder, newFilePathInDestinationDir, change) {
+			return
+		}
 
 		err = fileRenamingFunc(oldFilePathInDestinationDir, newFilePathInDestinationDir)
 		if err != nil {
@@ -36,6 +72,10 @@ func NewRenameFile(fileRenamingFunc func(string, string) error, destinationDir s
 			return
 		}
 
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
 		c.Status(http.StatusOK)
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
 	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
 	"net/http"
@@ -18,7 +20,8 @@ func TestRenameFile_HappyPath(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
 
 	type renameFileRequestBody struct {
 		Path         string `json:"path" binding:"required"`
@@ -37,6 +40,9 @@ func TestRenameFile_HappyPath(t *testing.T) {
 	mockFileWriter.EXPECT().RenameFile("./dest/some/old-path.go", "./dest/some/new-path.go").
 		Return(nil).Times(1)
 
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/new-path.go", Operation: entities.OperationRenamed, PreviousPath: "/some/old-path.go", Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{}, nil).Times(1)
+
 	router.ServeHTTP(w, req)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(w.Code).To(Equal(http.StatusOK))
@@ -47,7 +53,7 @@ func TestRenameFile_ValidationErr(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
 
 	type renameFileRequestBody struct {
 		Path         string `json:"path" binding:"required"`
@@ -73,7 +79,7 @@ func TestRenameFile_ReturnsErr(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
 
 	type renameFileRequestBody struct {
 		Path         string `json:"path" binding:"required"`
@@ -97,3 +103,91 @@ func TestRenameFile_ReturnsErr(t *testing.T) {
 	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
 	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
 }
+
+func TestRenameFile_PathOutsideDestination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPatch, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","previousPath":"/../../etc/passwd"}`)))
+
+	mockFileWriter.EXPECT().RenameFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestRenameFile_IfMatchFails(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPatch, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/new/path.go","previousPath":"/old/path.go"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+
+	mockFileReader.EXPECT().FileETag("./dest/old/path.go").Return(`"def"`, true, nil).Times(1)
+	mockFileWriter.EXPECT().RenameFile(gomock.Any(), gomock.Any()).Times(0)
+
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/old/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(1)
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+}
+
+func TestRenameFile_TargetIfNoneMatchFails(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPatch, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/new/path.go","previousPath":"/old/path.go"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+	req.Header.Set("X-Target-If-None-Match", "*")
+
+	mockFileReader.EXPECT().FileETag("./dest/old/path.go").Return(`"abc"`, true, nil).Times(1)
+	mockFileReader.EXPECT().FileETag("./dest/new/path.go").Return(`"def"`, true, nil).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/new/path.go", entities.Conflict{Path: "/new/path.go", Operation: entities.OperationRenamed, ExpectedETag: "not *", CurrentETag: `"def"`}).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(1)
+	mockFileWriter.EXPECT().RenameFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"def"`))
+}
+
+func TestRenameFile_TargetConflictKeepBoth(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPatch, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/new/path.go","previousPath":"/old/path.go"}`)))
+	req.Header.Set("X-Target-If-None-Match", "*")
+	req.Header.Set("X-Conflict-Policy", entities.ConflictPolicyKeepBoth)
+
+	mockFileReader.EXPECT().FileETag("./dest/new/path.go").Return(`"def"`, true, nil).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/new/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyKeepBoth, ConflictedCopy: "/new/path (conflicted copy host 2026-10-18).go"}, nil).Times(1)
+	mockFileWriter.EXPECT().RenameFile("./dest/old/path.go", "./dest/new/path.go").Return(nil).Times(1)
+
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Conflicted-Copy")).To(Equal("/new/path (conflicted copy host 2026-10-18).go"))
+}
@@ -0,0 +1,159 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type RestoreFromTrashRequestBody struct {
+	TrashID string `json:"trashId" binding:"required"`
+}
+
+// NewRestoreFromTrash is a function that returns a handler which undeletes an entry in the trash, moving it back to the
+// path in the destination directory it was deleted from. The entry is recorded in the change feed as created, along
+// with everything within it if it is a directory, so that other apps pull it back down.
+func NewRestoreFromTrash(trashRestorer func(string, func(string) (string, error)) (entities.TrashEntry, error), fileTagger func(string) (string, bool, error), treeLister func(string, bool, string, int) (entities.TreePage, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request RestoreFromTrashRequestBody
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			slog.Warn("failed to bind json body for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		change, ok := requestChange(c, "", entities.OperationCreated)
+		if !ok {
+			return
+		}
+
+		// the original path is locked once it is known, and stays locked until the restore has been recorded
+		unlock := func() {}
+		defer func() {
+			unlock()
+		}()
+		entry, err := trashRestorer(request.TrashID, func(path string) (string, error) {
+			filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, path)
+			if err != nil {
+				return "", err
+			}
+
+			unlock = pathLocker(filePathInDestinationDir)
+			return filePathInDestinationDir, nil
+		})
+		if err != nil {
+			if errors.Is(err, entities.ErrTrashEntryNotFound) {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "trash entry not found",
+				})
+				return
+			}
+			if errors.Is(err, entities.ErrRestoreTargetExists) {
+				c.JSON(http.StatusConflict, map[string]interface{}{
+					"message": "a file already exists at the original path",
+				})
+				return
+			}
+			if errors.Is(err, entities.ErrInvalidPath) {
+				slog.Warn("rejected trash entry path", "trashId", request.TrashID, "err", err)
+				c.JSON(http.StatusConflict, map[string]interface{}{
+					"message": "the original path is no longer within the destination directory",
+				})
+				return
+			}
+
+			slog.Error("restoring from trash", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, entry.OriginalPath)
+		if err != nil {
+			slog.Error("resolving restored path", "path", entry.OriginalPath, "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		change.Path = entry.OriginalPath
+		change.IsDirectory = entry.IsDirectory
+		switch {
+		case entry.LinkTarget != "":
+			change.Hash = entities.LinkTargetHash(entry.LinkTarget)
+			change.LinkTarget = entry.LinkTarget
+		case !entry.IsDirectory:
+			etag, _, err := fileTagger(filePathInDestinationDir)
+			if err != nil {
+				slog.Error("getting etag of restored file", "err", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"message": "an internal server error occurred",
+				})
+				return
+			}
+			change.Hash = strings.Trim(etag, `"`)
+		}
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
+		if entry.IsDirectory && !recordRestoredTree(c, treeLister, changeRecorder, filePathInDestinationDir, change.Origin) {
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// recordRestoredTree is a function that records the creation of everything within a directory restored from the trash
+// in the change feed, attributed to origin. If false is returned the request has been responded to.
+func recordRestoredTree(c *gin.Context, treeLister func(string, bool, string, int) (entities.TreePage, error), changeRecorder func(entities.Change) (entities.Change, error), directoryPath, origin string) bool {
+	cursor := ""
+	for {
+		page, err := treeLister(directoryPath, true, cursor, maxTreePageSize)
+		if err != nil {
+			slog.Error("listing restored directory", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return false
+		}
+
+		for _, treeEntry := range page.Entries {
+			change := entities.Change{
+				Path:        treeEntry.Path,
+				Operation:   entities.OperationCreated,
+				IsDirectory: treeEntry.Type == entities.EntryTypeDirectory,
+				Hash:        treeEntry.Hash,
+				Origin:      origin,
+				Version:     entities.VersionVector{},
+			}
+			if treeEntry.Type == entities.EntryTypeSymlink {
+				change.Hash = entities.LinkTargetHash(treeEntry.LinkTarget)
+				change.LinkTarget = treeEntry.LinkTarget
+			}
+
+			_, err := changeRecorder(change)
+			if err != nil {
+				slog.Error("recording change", "path", change.Path, "err", err)
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"message": "an internal server error occurred",
+				})
+				return false
+			}
+		}
+
+		if page.NextCursor == "" {
+			return true
+		}
+		cursor = page.NextCursor
+	}
+}
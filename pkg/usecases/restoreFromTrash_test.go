@@ -0,0 +1,170 @@
+package usecases_test
+
+import (
+	"bytes"
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRestoreFromTrash_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockTrashBin := mock_adapters.NewMockTrashBin(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mockTrashBin, mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/trash/restore", bytes.NewReader([]byte(`{"trashId":"1"}`)))
+
+	mockTrashBin.EXPECT().RestoreFromTrash("1", gomock.Any()).Return(entities.TrashEntry{
+		ID: "1", OriginalPath: "/some/path.go", DeletedAt: time.Date(2025, 5, 19, 21, 0, 0, 0, time.UTC),
+	}, nil).Times(1)
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"abc"`, true, nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{Version: entities.VersionVector{"anonymous": 1}}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Version-Vector")).To(Equal(entities.VersionVector{"anonymous": 1}.String()))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"trashId":"1","originalPath":"/some/path.go","isDirectory":false,"deletedAt":"2025-05-19T21:00:00Z"}`))
+}
+
+func TestRestoreFromTrash_RecordsEverythingWithinDirectory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockTrashBin := mock_adapters.NewMockTrashBin(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mockTrashBin, mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/trash/restore", bytes.NewReader([]byte(`{"trashId":"1"}`)))
+	req.Header.Set("X-Client-ID", "laptop")
+
+	mockTrashBin.EXPECT().RestoreFromTrash("1", gomock.Any()).Return(entities.TrashEntry{
+		ID: "1", OriginalPath: "/sub", IsDirectory: true, DeletedAt: time.Date(2025, 5, 19, 21, 0, 0, 0, time.UTC),
+	}, nil).Times(1)
+	gomock.InOrder(
+		mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/sub", Operation: entities.OperationCreated, IsDirectory: true, Origin: "laptop", Version: entities.VersionVector{}}).
+			Return(entities.Change{Version: entities.VersionVector{"laptop": 1}}, nil).Times(1),
+		mockFileReader.EXPECT().ListTree("./dest/sub", true, "", 1000).Return(entities.TreePage{
+			Entries: []entities.TreeEntry{
+				{Path: "/sub/dir", Type: entities.EntryTypeDirectory},
+				{Path: "/sub/dir/file.go", Type: entities.EntryTypeFile, Hash: "abc"},
+			},
+			NextCursor: "/sub/dir/file.go",
+		}, nil).Times(1),
+		mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/sub/dir", Operation: entities.OperationCreated, IsDirectory: true, Origin: "laptop", Version: entities.VersionVector{}}).
+			Return(entities.Change{}, nil).Times(1),
+		mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/sub/dir/file.go", Operation: entities.OperationCreated, Hash: "abc", Origin: "laptop", Version: entities.VersionVector{}}).
+			Return(entities.Change{}, nil).Times(1),
+		mockFileReader.EXPECT().ListTree("./dest/sub", true, "/sub/dir/file.go", 1000).Return(entities.TreePage{
+			Entries: []entities.TreeEntry{
+				{Path: "/sub/link", Type: entities.EntryTypeSymlink, LinkTarget: "dir/file.go"},
+			},
+		}, nil).Times(1),
+		mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/sub/link", Operation: entities.OperationCreated, Hash: entities.LinkTargetHash("dir/file.go"), LinkTarget: "dir/file.go", Origin: "laptop", Version: entities.VersionVector{}}).
+			Return(entities.Change{}, nil).Times(1),
+	)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Version-Vector")).To(Equal(entities.VersionVector{"laptop": 1}.String()))
+}
+
+func TestRestoreFromTrash_OriginalPathOutsideDestination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockTrashBin := mock_adapters.NewMockTrashBin(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mockTrashBin, mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/trash/restore", bytes.NewReader([]byte(`{"trashId":"1"}`)))
+
+	mockTrashBin.EXPECT().RestoreFromTrash("1", gomock.Any()).
+		DoAndReturn(func(trashID string, pathResolver func(string) (string, error)) (entities.TrashEntry, error) {
+			_, err := pathResolver("/../outside.go")
+			return entities.TrashEntry{}, err
+		}).Times(1)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusConflict))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"the original path is no longer within the destination directory"}`))
+}
+
+func TestRestoreFromTrash_ValidationError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockTrashBin := mock_adapters.NewMockTrashBin(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mockTrashBin, mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/trash/restore", bytes.NewReader([]byte(`{}`)))
+
+	mockTrashBin.EXPECT().RestoreFromTrash(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestRestoreFromTrash_EntryNotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockTrashBin := mock_adapters.NewMockTrashBin(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mockTrashBin, mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/trash/restore", bytes.NewReader([]byte(`{"trashId":"1"}`)))
+
+	mockTrashBin.EXPECT().RestoreFromTrash("1", gomock.Any()).Return(entities.TrashEntry{}, entities.ErrTrashEntryNotFound).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"trash entry not found"}`))
+}
+
+func TestRestoreFromTrash_TargetExists(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockTrashBin := mock_adapters.NewMockTrashBin(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mockTrashBin, mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/trash/restore", bytes.NewReader([]byte(`{"trashId":"1"}`)))
+
+	mockTrashBin.EXPECT().RestoreFromTrash("1", gomock.Any()).Return(entities.TrashEntry{}, entities.ErrRestoreTargetExists).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusConflict))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a file already exists at the original path"}`))
+}
+
+func TestRestoreFromTrash_TrashBinReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockTrashBin := mock_adapters.NewMockTrashBin(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mockTrashBin, mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/trash/restore", bytes.NewReader([]byte(`{"trashId":"1"}`)))
+
+	mockTrashBin.EXPECT().RestoreFromTrash("1", gomock.Any()).Return(entities.TrashEntry{}, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
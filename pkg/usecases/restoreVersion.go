@@ -0,0 +1,82 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+type RestoreVersionRequestBody struct {
+	Path      string `json:"path" binding:"required"`
+	VersionID string `json:"versionId" binding:"required"`
+}
+
+// NewRestoreVersion is a function that returns a handler which puts a previous version of a file back in place in the
+// destination directory. The restore is recorded in the change feed, as a modification of the file or its creation if
+// it no longer existed, so that other apps pull the restored contents.
+func NewRestoreVersion(versionRestorer func(string, string) (entities.FileVersion, error), fileTagger func(string) (string, bool, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request RestoreVersionRequestBody
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			slog.Warn("failed to bind json body for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		change, ok := requestChange(c, request.Path, entities.OperationModified)
+		if !ok {
+			return
+		}
+
+		defer pathLocker(filePathInDestinationDir)()
+		_, exists, err := fileTagger(filePathInDestinationDir)
+		if err != nil {
+			slog.Error("getting file etag", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+		if !exists {
+			change.Operation = entities.OperationCreated
+		}
+
+		version, err := versionRestorer(filePathInDestinationDir, request.VersionID)
+		if err != nil {
+			if errors.Is(err, entities.ErrVersionNotFound) {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "version not found",
+				})
+				return
+			}
+
+			slog.Error("restoring file version", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		change.Hash = version.Hash
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
+		c.Header("ETag", entities.ETag(version.Hash))
+		c.JSON(http.StatusOK, version)
+	}
+}
@@ -0,0 +1,141 @@
+package usecases_test
+
+import (
+	"bytes"
+	"errors"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRestoreVersion_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockVersionHistory := mock_adapters.NewMockVersionHistory(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mockVersionHistory, mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/versions/restore", bytes.NewReader([]byte(`{"path":"/some/path.go","versionId":"2"}`)))
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"xyz"`, true, nil).Times(1)
+	mockVersionHistory.EXPECT().RestoreVersion("./dest/some/path.go", "2").
+		Return(entities.FileVersion{ID: "2", Path: "/some/path.go", Size: 12, Hash: "abc"}, nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationModified, Origin: "anonymous", Version: entities.VersionVector{}, Hash: "abc"}).
+		Return(entities.Change{Version: entities.VersionVector{"server": 3}}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("ETag")).To(Equal(entities.ETag("abc")))
+	g.Expect(w.Header().Get("X-Version-Vector")).To(Equal(entities.VersionVector{"server": 3}.String()))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"versionId":"2","path":"/some/path.go","size":12,"hash":"abc","createdAt":"0001-01-01T00:00:00Z"}`))
+}
+
+func TestRestoreVersion_RestoresDeletedFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockVersionHistory := mock_adapters.NewMockVersionHistory(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mockVersionHistory, mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/versions/restore", bytes.NewReader([]byte(`{"path":"/some/path.go","versionId":"2"}`)))
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return("", false, nil).Times(1)
+	mockVersionHistory.EXPECT().RestoreVersion("./dest/some/path.go", "2").
+		Return(entities.FileVersion{ID: "2", Path: "/some/path.go", Size: 12, Hash: "abc"}, nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationCreated, Origin: "anonymous", Version: entities.VersionVector{}, Hash: "abc"}).
+		Return(entities.Change{Version: entities.VersionVector{"server": 3}}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("ETag")).To(Equal(entities.ETag("abc")))
+	g.Expect(w.Header().Get("X-Version-Vector")).To(Equal(entities.VersionVector{"server": 3}.String()))
+	g.Expect(w.Body.String()).To(MatchJSON(`{"versionId":"2","path":"/some/path.go","size":12,"hash":"abc","createdAt":"0001-01-01T00:00:00Z"}`))
+}
+
+func TestRestoreVersion_ValidationError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockVersionHistory := mock_adapters.NewMockVersionHistory(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mockVersionHistory, mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/versions/restore", bytes.NewReader([]byte(`{"path":"/some/path.go"}`)))
+
+	mockVersionHistory.EXPECT().RestoreVersion(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestRestoreVersion_VersionNotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockVersionHistory := mock_adapters.NewMockVersionHistory(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mockVersionHistory, mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/versions/restore", bytes.NewReader([]byte(`{"path":"/some/path.go","versionId":"2"}`)))
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"xyz"`, true, nil).Times(1)
+	mockVersionHistory.EXPECT().RestoreVersion("./dest/some/path.go", "2").
+		Return(entities.FileVersion{}, entities.ErrVersionNotFound).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"version not found"}`))
+}
+
+func TestRestoreVersion_VersionHistoryReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockVersionHistory := mock_adapters.NewMockVersionHistory(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mockVersionHistory, mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/versions/restore", bytes.NewReader([]byte(`{"path":"/some/path.go","versionId":"2"}`)))
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"xyz"`, true, nil).Times(1)
+	mockVersionHistory.EXPECT().RestoreVersion("./dest/some/path.go", "2").
+		Return(entities.FileVersion{}, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
+
+func TestRestoreVersion_ChangeLogReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockVersionHistory := mock_adapters.NewMockVersionHistory(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mockVersionHistory, mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/versions/restore", bytes.NewReader([]byte(`{"path":"/some/path.go","versionId":"2"}`)))
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"xyz"`, true, nil).Times(1)
+	mockVersionHistory.EXPECT().RestoreVersion("./dest/some/path.go", "2").
+		Return(entities.FileVersion{ID: "2", Path: "/some/path.go", Size: 12, Hash: "abc"}, nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
@@ -0,0 +1,48 @@
+package usecases
+
+import (
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+type StartUploadRequestBody struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// NewStartUpload is a function that returns a handler which starts a chunked upload of a file. The path is checked up
+// front, so that a client doesn't upload the whole file only for it to be rejected when the upload is committed.
+func NewStartUpload(uploadStarter func(string) (entities.UploadSession, error), destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request StartUploadRequestBody
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			slog.Warn("failed to bind json body for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		_, err = ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		session, err := uploadStarter(request.Path)
+		if err != nil {
+			slog.Error("starting upload", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, session)
+	}
+}
@@ -1,21 +1,29 @@
 package usecases
 
 import (
-	"fmt"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
 	"github.com/gin-gonic/gin"
 	"log/slog"
 	"net/http"
 )
 
 type UpdateFileContentsRequestBody struct {
-	Path string `json:"path" binding:"required"`
-	Data []byte `json:"data"`
+	Path       string `json:"path" binding:"required"`
+	Data       []byte `json:"data"`
+	LinkTarget string `json:"linkTarget"`
 }
 
-func NewUpdateFileContents(fileUpdater func(string, []byte) error, destinationDir string) gin.HandlerFunc {
+// NewUpdateFileContents is a function that returns a handler which replaces the contents of a file in the destination
+// directory, as long as the request's preconditions hold for the file's current contents. Any metadata in the
+// X-File-Metadata header is set on the file once its contents are replaced. A request with a linkTarget replaces the
+// file or symlink at the path with a symlink to it.
+func NewUpdateFileContents(fileUpdater func(string, []byte) error, symlinkCreator func(string, string) error, metadataSetter func(string, entities.FileMetadata) error, fileTagger func(string) (string, bool, error), conflictResolver func(string, entities.Conflict) (entities.Conflict, error), changeRecorder func(entities.Change) (entities.Change, error), pathLocker func(...string) func(), destinationDir string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var request UpdateFileContentsRequestBody
 		err := c.ShouldBindJSON(&request)
+		if err == nil && request.LinkTarget != "" && len(request.Data) > 0 {
+			err = entities.ErrInvalidSymlink
+		}
 		if err != nil {
 			slog.Warn("failed to bind json body for request", "err", err)
 			c.JSON(http.StatusBadRequest, map[string]interface{}{
@@ -24,8 +32,35 @@ func NewUpdateFileContents(fileUpdater func(string, []byte) error, destinationDi
 			return
 		}
 
-		filePathInDestinationDir := fmt.Sprintf("%s%s", destinationDir, request.Path)
-		err = fileUpdater(filePathInDestinationDir, request.Data)
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		change, ok := requestChange(c, request.Path, entities.OperationModified)
+		if !ok {
+			return
+		}
+
+		metadata, ok := requestMetadata(c)
+		if !ok {
+			return
+		}
+
+		defer pathLocker(filePathInDestinationDir)()
+		if !checkPreconditions(c, requestPreconditions(c), fileTagger, conflictResolver, changeRecorder, filePathInDestinationDir, change) {
+			return
+		}
+
+		if request.LinkTarget != "" {
+			err = symlinkCreator(filePathInDestinationDir, request.LinkTarget)
+		} else {
+			err = fileUpdater(filePathInDestinationDir, request.Data)
+		}
 		if err != nil {
 			slog.Error("updating file contents", "err", err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -34,6 +69,20 @@ func NewUpdateFileContents(fileUpdater func(string, []byte) error, destinationDi
 			return
 		}
 
+		if !applyMetadata(c, metadataSetter, filePathInDestinationDir, metadata) {
+			return
+		}
+
+		change.Hash = hashData(request.Data)
+		if request.LinkTarget != "" {
+			change.Hash = entities.LinkTargetHash(request.LinkTarget)
+			change.LinkTarget = request.LinkTarget
+		}
+		if !recordChange(c, changeRecorder, change) {
+			return
+		}
+
+		c.Header("ETag", entities.ETag(change.Hash))
 		c.Status(http.StatusOK)
 	}
 }
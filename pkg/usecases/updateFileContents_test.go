@@ -6,11 +6,15 @@ import (
 	"errors"
 	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
 	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestUpdateFileContents_HappyPath(t *testing.T) {
@@ -18,7 +22,8 @@ func TestUpdateFileContents_HappyPath(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
 
 	type updateFileContentsRequestBody struct {
 		Path string `json:"path" binding:"required"`
@@ -37,6 +42,9 @@ func TestUpdateFileContents_HappyPath(t *testing.T) {
 	mockFileWriter.EXPECT().UpdateFile("./dest/some/path.go", requestBody.Data).
 		Return(nil).Times(1)
 
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationModified, Hash: "290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56", Origin: "anonymous", Version: entities.VersionVector{}}).
+		Return(entities.Change{}, nil).Times(1)
+
 	router.ServeHTTP(w, req)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(w.Code).To(Equal(http.StatusOK))
@@ -47,7 +55,7 @@ func TestUpdateFileContents_ValidationErr(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
 
 	type updateFileContentsRequestBody struct {
 		Path string `json:"path" binding:"required"`
@@ -76,7 +84,7 @@ func TestUpdateFileContents_ReturnsErr(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctrl := gomock.NewController(t)
 	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
-	router := drivers.NewRouter("./dest", mockFileWriter)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
 
 	type updateFileContentsRequestBody struct {
 		Path string `json:"path" binding:"required"`
@@ -100,3 +108,323 @@ func TestUpdateFileContents_ReturnsErr(t *testing.T) {
 	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
 	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
 }
+
+func TestUpdateFileContents_PathOutsideDestination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/../../path.go","data":"c29tZQ=="}`)))
+
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestUpdateFileContents_IfMatchSucceeds(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"abc"`, true, nil).Times(1)
+	mockFileWriter.EXPECT().UpdateFile("./dest/some/path.go", []byte("some content")).
+		Return(nil).Times(1)
+
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"290f493c44f5d63d06b374d0a5abd292fae38b92cab2fae5efefe1b0e9347f56"`))
+}
+
+func TestUpdateFileContents_IfMatchFails(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"def"`, true, nil).Times(1)
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", entities.Conflict{Path: "/some/path.go", Operation: entities.OperationModified, ExpectedETag: `"abc"`, CurrentETag: `"def"`}).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(1)
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"def"`))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"the file has changed on the server"}`))
+}
+
+func TestUpdateFileContents_IfMatchFileMissing(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", "*")
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return("", false, nil).Times(1)
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(1)
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+}
+
+func TestUpdateFileContents_FileTaggerReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return("", false, errors.New("an error occurred")).Times(1)
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
+
+func TestUpdateFileContents_ConflictKeepBoth(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+	req.Header.Set("X-Conflict-Policy", entities.ConflictPolicyKeepBoth)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"def"`, true, nil).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", entities.Conflict{Path: "/some/path.go", Operation: entities.OperationModified, Policy: entities.ConflictPolicyKeepBoth, ExpectedETag: `"abc"`, CurrentETag: `"def"`}).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyKeepBoth, ConflictedCopy: "/some/path (conflicted copy host 2026-10-18).go"}, nil).Times(1)
+	mockFileWriter.EXPECT().UpdateFile("./dest/some/path.go", []byte("some content")).Return(nil).Times(1)
+
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Conflict-Resolution")).To(Equal(entities.ConflictPolicyKeepBoth))
+	g.Expect(w.Header().Get("X-Conflicted-Copy")).To(Equal("/some/path (conflicted copy host 2026-10-18).go"))
+}
+
+func TestUpdateFileContents_ConflictSourceWins(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+	req.Header.Set("X-Conflict-Policy", entities.ConflictPolicySourceWins)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"def"`, true, nil).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicySourceWins}, nil).Times(1)
+	mockFileWriter.EXPECT().UpdateFile("./dest/some/path.go", []byte("some content")).Return(nil).Times(1)
+
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Conflict-Resolution")).To(Equal(entities.ConflictPolicySourceWins))
+	g.Expect(w.Header().Get("X-Conflicted-Copy")).To(BeEmpty())
+}
+
+func TestUpdateFileContents_ConflictDestinationWins(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+	req.Header.Set("X-Conflict-Policy", entities.ConflictPolicyDestinationWins)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"def"`, true, nil).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyDestinationWins}, nil).Times(1)
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	mockChangeLog.EXPECT().RecordChange(entities.Change{Path: "/some/path.go", Operation: entities.OperationModified, Hash: "def", Origin: entities.ServerReplicaID, Version: entities.VersionVector{}}).
+		Return(entities.Change{}, nil).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+	g.Expect(w.Header().Get("X-Conflict-Resolution")).To(Equal(entities.ConflictPolicyDestinationWins))
+	g.Expect(w.Header().Get("ETag")).To(Equal(`"def"`))
+}
+
+func TestUpdateFileContents_UnknownConflictPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+	req.Header.Set("X-Conflict-Policy", "newest-wins")
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"def"`, true, nil).Times(1)
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestUpdateFileContents_ConflictResolverReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("If-Match", `"abc"`)
+
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").Return(`"def"`, true, nil).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", gomock.Any()).
+		Return(entities.Conflict{}, errors.New("an error occurred")).Times(1)
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
+
+func TestUpdateFileContents_InvalidVersionVector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+	req.Header.Set("X-Version-Vector", "laptop=two")
+
+	mockFileWriter.EXPECT().UpdateFile(gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestUpdateFileContents_ChangeRecorderReturnsError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mock_adapters.NewMockDestinationReader(ctrl), mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mockChangeLog)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+
+	mockFileWriter.EXPECT().UpdateFile("./dest/some/path.go", []byte("some content")).Return(nil).Times(1)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).Return(entities.Change{}, errors.New("an error occurred")).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"an internal server error occurred"}`))
+}
+
+func TestUpdateFileContents_ConcurrentIfMatchOnlyOneApplied(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	mockFileWriter := mock_adapters.NewMockFileModifier(ctrl)
+	mockFileReader := mock_adapters.NewMockDestinationReader(ctrl)
+	mockConflictHandler := mock_adapters.NewMockConflictHandler(ctrl)
+	mockChangeLog := mock_adapters.NewMockChangeLog(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mockFileWriter, mockFileReader, mock_adapters.NewMockUploadStore(ctrl), mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mockConflictHandler, mockChangeLog)
+
+	// the file's etag only changes once an update has been written, so without locking every request would see "abc"
+	var mu sync.Mutex
+	etag := `"abc"`
+	mockFileReader.EXPECT().FileETag("./dest/some/path.go").DoAndReturn(func(string) (string, bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return etag, true, nil
+	}).AnyTimes()
+	mockFileWriter.EXPECT().UpdateFile("./dest/some/path.go", gomock.Any()).DoAndReturn(func(string, []byte) error {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		etag = `"def"`
+		return nil
+	}).Times(1)
+	mockConflictHandler.EXPECT().ResolveConflict("./dest/some/path.go", gomock.Any()).
+		Return(entities.Conflict{Policy: entities.ConflictPolicyFail}, nil).Times(9)
+	mockChangeLog.EXPECT().RecordChange(gomock.Any()).
+		Return(entities.Change{}, nil).Times(1)
+
+	codes := make(chan int, 10)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/file", bytes.NewReader([]byte(`{"path":"/some/path.go","data":"c29tZSBjb250ZW50"}`)))
+			req.Header.Set("If-Match", `"abc"`)
+			router.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	applied := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			applied++
+			continue
+		}
+		g.Expect(code).To(Equal(http.StatusPreconditionFailed))
+	}
+	g.Expect(applied).To(Equal(1))
+}
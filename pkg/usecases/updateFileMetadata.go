@@ -0,0 +1,70 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+type UpdateFileMetadataRequestBody struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// NewUpdateFileMetadata is a function that returns a handler which sets the metadata in the request's X-File-Metadata
+// header on a file or directory in the destination directory, without changing its contents. As the contents are left
+// as they are, the request has no preconditions and no change is recorded in the change feed.
+func NewUpdateFileMetadata(metadataSetter func(string, entities.FileMetadata) error, destinationDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request UpdateFileMetadataRequestBody
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			slog.Warn("failed to bind json body for request", "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		filePathInDestinationDir, err := ResolveDestinationPath(destinationDir, request.Path)
+		if err != nil {
+			slog.Warn("rejected request path", "path", request.Path, "err", err)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		metadata, ok := requestMetadata(c)
+		if !ok {
+			return
+		}
+		if metadata.IsZero() {
+			slog.Warn("no file metadata in request", "path", request.Path)
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		err = metadataSetter(filePathInDestinationDir, metadata)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "file not found",
+				})
+				return
+			}
+
+			slog.Error("setting file metadata", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
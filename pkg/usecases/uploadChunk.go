@@ -0,0 +1,50 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// NewUploadChunk is a function that returns a handler which streams the raw body of the request onto the end of an
+// upload session, without reading the chunk into memory.
+func NewUploadChunk(chunkWriter func(string, int, io.Reader) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		index, err := strconv.Atoi(c.Param("index"))
+		if err != nil || index < 0 {
+			slog.Warn("invalid chunk index", "index", c.Param("index"))
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"message": "a bad request error occurred",
+			})
+			return
+		}
+
+		err = chunkWriter(c.Param("uploadId"), index, c.Request.Body)
+		if err != nil {
+			if errors.Is(err, entities.ErrUploadNotFound) {
+				c.JSON(http.StatusNotFound, map[string]interface{}{
+					"message": "upload not found",
+				})
+				return
+			}
+			if errors.Is(err, entities.ErrUnexpectedChunk) {
+				c.JSON(http.StatusConflict, map[string]interface{}{
+					"message": "chunk received out of order",
+				})
+				return
+			}
+
+			slog.Error("writing upload chunk", "err", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"message": "an internal server error occurred",
+			})
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,86 @@
+package usecases_test
+
+import (
+	"bytes"
+	mock_adapters "github.com/AlecSmith96/dopbox/mocks"
+	"github.com/AlecSmith96/dopbox/pkg/drivers"
+	"github.com/AlecSmith96/dopbox/pkg/entities"
+	"github.com/AlecSmith96/dopbox/pkg/usecases"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUploadChunk_HappyPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/uploads/abc/chunks/2", bytes.NewReader([]byte("some content")))
+
+	mockUploadStore.EXPECT().WriteChunk("abc", 2, gomock.Any()).
+		DoAndReturn(func(uploadID string, index int, chunk io.Reader) error {
+			data, err := io.ReadAll(chunk)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(Equal([]byte("some content")))
+			return nil
+		}).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+}
+
+func TestUploadChunk_InvalidIndex(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/uploads/abc/chunks/first", bytes.NewReader([]byte("some content")))
+
+	mockUploadStore.EXPECT().WriteChunk(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"a bad request error occurred"}`))
+}
+
+func TestUploadChunk_UploadNotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/uploads/abc/chunks/0", bytes.NewReader([]byte("some content")))
+
+	mockUploadStore.EXPECT().WriteChunk("abc", 0, gomock.Any()).
+		Return(entities.ErrUploadNotFound).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"upload not found"}`))
+}
+
+func TestUploadChunk_OutOfOrder(t *testing.T) {
+	g := NewGomegaWithT(t)
+	w := httptest.NewRecorder()
+	ctrl := gomock.NewController(t)
+	mockUploadStore := mock_adapters.NewMockUploadStore(ctrl)
+	router := drivers.NewRouter("./dest", usecases.NewPathLocker(), mock_adapters.NewMockFileModifier(ctrl), mock_adapters.NewMockDestinationReader(ctrl), mockUploadStore, mock_adapters.NewMockVersionHistory(ctrl), mock_adapters.NewMockTrashBin(ctrl), mock_adapters.NewMockConflictHandler(ctrl), mock_adapters.NewMockChangeLog(ctrl))
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080/v1/uploads/abc/chunks/5", bytes.NewReader([]byte("some content")))
+
+	mockUploadStore.EXPECT().WriteChunk("abc", 5, gomock.Any()).
+		Return(entities.ErrUnexpectedChunk).Times(1)
+
+	router.ServeHTTP(w, req)
+	g.Expect(w.Code).To(Equal(http.StatusConflict))
+	g.Expect(w.Body.String()).To(Equal(`{"message":"chunk received out of order"}`))
+}